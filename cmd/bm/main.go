@@ -6,25 +6,19 @@
 package main
 
 import (
-	"os"
-
 	"bucket-manager/cmd/cli"
-	"bucket-manager/cmd/tui"
+	"bucket-manager/internal/config"
 	"bucket-manager/internal/logger"
 )
 
-// main is the entry point of the application that determines whether to run
-// in CLI or TUI mode based on command-line arguments.
-// If arguments are provided, CLI mode is selected; otherwise TUI mode starts.
+// main is the entry point of the application. Every invocation goes through
+// the CLI; without a subcommand, it starts the TUI (see cli.RunCLI), so the
+// global flags such as --read-only apply to the TUI as well.
 func main() {
-	// Determine mode based on command line arguments
-	if len(os.Args) > 1 {
-		// Initialize logger for CLI mode (clean by default)
-		logger.InitCLI(false, false)
-		cli.RunCLI()
-	} else {
-		// Initialize logger for TUI mode (file only)
-		logger.InitTUI()
-		tui.RunTUI()
-	}
+	// Apply the configured log file location and rotation before logging starts
+	logger.SetFileOptions(config.ReadLogFileOptions())
+
+	// Initialize logger for CLI mode (clean by default); the TUI replaces it
+	logger.InitCLI(false, false)
+	cli.RunCLI()
 }
@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's batch.go file records the progress of stack actions run on
+// several stacks at once, so that a batch that was interrupted or partly
+// failed can be resumed with --resume, skipping the stacks it already did.
+
+package cli
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// batchStateFile is the file in the state directory batch progress is kept in.
+const batchStateFile = "batches.json"
+
+// batchMaxAge is how long an unfinished batch can still be resumed.
+const batchMaxAge = 7 * 24 * time.Hour
+
+// batchRun is the recorded progress of a stack action run on several stacks.
+type batchRun struct {
+	Action    string    `json:"action"`
+	Args      []string  `json:"args"`      // Stack identifiers and patterns as given
+	Started   time.Time `json:"started"`   // When the batch was first run
+	Succeeded []string  `json:"succeeded"` // Identifiers of the stacks the action succeeded on
+}
+
+// batchKey identifies the batches of the same command: the action and its
+// stack arguments.
+func batchKey(action string, args []string) string {
+	return action + " " + strings.Join(args, " ")
+}
+
+// batchStatePath returns the path of the batch state file.
+func batchStatePath() (string, error) {
+	stateDir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, batchStateFile), nil
+}
+
+// loadBatchRuns returns the unfinished batches, keyed by batchKey.
+func loadBatchRuns() (map[string]batchRun, error) {
+	path, err := batchStatePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]batchRun{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch state: %w", err)
+	}
+	runs := map[string]batchRun{}
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("failed to parse batch state %s: %w", path, err)
+	}
+	return runs, nil
+}
+
+// updateBatchRuns applies mutate to the unfinished batches and saves them,
+// dropping batches older than batchMaxAge. The state file is locked meanwhile,
+// so that batches run at the same time don't drop each other's progress.
+func updateBatchRuns(mutate func(runs map[string]batchRun)) error {
+	path, err := batchStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create the state directory: %w", err)
+	}
+	unlock, err := util.LockFile(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock batch state: %w", err)
+	}
+	defer unlock()
+
+	runs, err := loadBatchRuns()
+	if err != nil {
+		return err
+	}
+	mutate(runs)
+	for key, run := range runs {
+		if time.Since(run.Started) > batchMaxAge {
+			delete(runs, key)
+		}
+	}
+
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode batch state: %w", err)
+	}
+	if err := util.WriteFileAtomic(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save batch state: %w", err)
+	}
+	return nil
+}
+
+// batchTracker records the progress of one batch. Failing to record it is
+// logged and otherwise ignored: it mustn't stop the action.
+type batchTracker struct {
+	key string
+	run batchRun
+}
+
+// startBatch starts recording a batch of action on the stacks given by args.
+// With resume, the unfinished batch of the same command is continued and
+// returned, or nil if there is none.
+func startBatch(action string, args []string, resume bool) (*batchTracker, *batchRun) {
+	tracker := &batchTracker{
+		key: batchKey(action, args),
+		run: batchRun{Action: action, Args: args, Started: time.Now()},
+	}
+
+	var resumed *batchRun
+	if resume {
+		runs, err := loadBatchRuns()
+		if err != nil {
+			logger.Warn("Could not load batch state", "error", err)
+			errorColor.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if run, ok := runs[tracker.key]; ok && time.Since(run.Started) <= batchMaxAge {
+			tracker.run = run
+			resumed = &run
+		}
+	}
+
+	tracker.save()
+	return tracker, resumed
+}
+
+// succeeded records that the action succeeded on the stack with the given
+// identifier.
+func (t *batchTracker) succeeded(identifier string) {
+	if !slices.Contains(t.run.Succeeded, identifier) {
+		t.run.Succeeded = append(t.run.Succeeded, identifier)
+	}
+	t.save()
+}
+
+// finish forgets the batch once the action succeeded on all its stacks.
+func (t *batchTracker) finish() {
+	err := updateBatchRuns(func(runs map[string]batchRun) {
+		delete(runs, t.key)
+	})
+	if err != nil {
+		logger.Warn("Could not clear finished batch", "batch", t.key, "error", err)
+	}
+}
+
+// save records the batch's progress.
+func (t *batchTracker) save() {
+	err := updateBatchRuns(func(runs map[string]batchRun) {
+		runs[t.key] = t.run
+	})
+	if err != nil {
+		logger.Warn("Could not record batch progress", "batch", t.key, "error", err)
+	}
+}
+
+// addResumeFlag registers the --resume flag on a stack action command.
+func addResumeFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("resume", false, "Skip the stacks already done by the last interrupted or failed run of the same command")
+}
+
+// resumeFromFlags returns the --resume value, false if it isn't registered.
+func resumeFromFlags(cmd *cobra.Command) bool {
+	resume, _ := cmd.Flags().GetBool("resume")
+	return resume
+}
+
+// renameBatchHost rewrites the "oldName:stack" identifiers recorded in the
+// unfinished batches after the SSH host oldName is renamed to newName, so that
+// they can still be resumed with the new name.
+func renameBatchHost(oldName, newName string) error {
+	rename := func(identifiers []string) []string {
+		renamed := make([]string, len(identifiers))
+		for i, identifier := range identifiers {
+			if stackName, ok := strings.CutPrefix(identifier, oldName+":"); ok {
+				identifier = newName + ":" + stackName
+			}
+			renamed[i] = identifier
+		}
+		return renamed
+	}
+	return updateBatchRuns(func(runs map[string]batchRun) {
+		renamed := make(map[string]batchRun, len(runs))
+		for _, run := range runs {
+			run.Args = rename(run.Args)
+			run.Succeeded = rename(run.Succeeded)
+			renamed[batchKey(run.Action, run.Args)] = run
+		}
+		clear(runs)
+		maps.Copy(runs, renamed)
+	})
+}
@@ -9,7 +9,10 @@ package cli
 import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 
@@ -19,14 +22,7 @@ import (
 // discoverLocalStacksForCompletion performs local discovery for completion, ignoring "not found" errors.
 // This provides a more user-friendly experience where tab completion works even if directories don't exist yet.
 func discoverLocalStacksForCompletion() ([]discovery.Stack, error) {
-	localRootDir, err := discovery.GetComposeRootDirectory()
-	if err != nil {
-		if strings.Contains(err.Error(), "could not find") {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return discovery.FindLocalStacks(localRootDir)
+	return discovery.DiscoverLocalStacks("")
 }
 
 // discoverRemoteStacksForCompletion performs discovery on a specific remote host for completion.
@@ -48,7 +44,7 @@ func discoverRemoteStacksForCompletion(remoteName string) ([]discovery.Stack, er
 		return nil, nil
 	}
 
-	stacks, _ := discovery.FindRemoteStacks(targetHost)
+	stacks, _ := discovery.FindRemoteStacks(targetHost, "")
 	return stacks, nil
 }
 
@@ -74,8 +70,8 @@ func discoverAllRemoteStacksForCompletion() ([]discovery.Stack, []error) {
 		hostConfig := cfg.SSHHosts[i] // Capture loop variable
 		go func(hc config.SSHHost) {
 			defer wg.Done()
-			stacks, err := discovery.FindRemoteStacks(&hc)
-			if err != nil {
+			stacks, err := discovery.FindRemoteStacks(&hc, "")
+			if err != nil && !errors.Is(err, discovery.ErrRootNotFound) {
 				errorChan <- fmt.Errorf("remote discovery failed for %s: %w", hc.Name, err)
 				return
 			}
@@ -112,6 +108,21 @@ func discoverAllRemoteStacksForCompletion() ([]discovery.Stack, []error) {
 	return remoteStacks, discoveryErrors
 }
 
+// hostScopesForCompletion returns "local" and the names of enabled configured hosts.
+func hostScopesForCompletion() []string {
+	hosts := []string{"local"}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return hosts
+	}
+	for _, host := range cfg.SSHHosts {
+		if !host.Disabled {
+			hosts = append(hosts, host.Name)
+		}
+	}
+	return hosts
+}
+
 // stackCompletionFunc provides dynamic completion for stack identifiers.
 func stackCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	suggestionMap := make(map[string]struct{}) // Use map for deduplication
@@ -132,6 +143,16 @@ func stackCompletionFunc(cmd *cobra.Command, args []string, toComplete string) (
 	targetStack := toComplete
 	hasColon := strings.Contains(toComplete, ":")
 
+	// Suggest "host:" scopes, which target every stack on a host
+	if !hasColon {
+		for _, host := range hostScopesForCompletion() {
+			scope := host + ":"
+			if _, exists := alreadySpecified[scope]; !exists && strings.HasPrefix(scope, toComplete) {
+				suggestionMap[scope] = struct{}{}
+			}
+		}
+	}
+
 	if hasColon {
 		parts := strings.SplitN(toComplete, ":", 2)
 		targetServer = parts[0]
@@ -226,7 +247,8 @@ func stackCompletionFunc(cmd *cobra.Command, args []string, toComplete string) (
 	return suggestions, cobra.ShellCompDirectiveNoFileComp
 }
 
-// hostCompletionFunc provides dynamic completion for host identifiers ("local" or remote names).
+// hostCompletionFunc provides dynamic completion for host identifiers ("local",
+// remote names or "@group").
 func hostCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	suggestions := []string{"local"} // Always suggest local
 
@@ -237,6 +259,9 @@ func hostCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([
 				suggestions = append(suggestions, host.Name)
 			}
 		}
+		for _, group := range slices.Sorted(maps.Keys(cfg.Groups)) {
+			suggestions = append(suggestions, "@"+group)
+		}
 	}
 
 	finalSuggestions := []string{}
@@ -248,3 +273,31 @@ func hostCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([
 
 	return finalSuggestions, cobra.ShellCompDirectiveNoFileComp
 }
+
+// groupCompletionFunc completes the names of configured host groups.
+func groupCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var suggestions []string
+	cfg, err := config.LoadConfig()
+	if err == nil {
+		for _, group := range slices.Sorted(maps.Keys(cfg.Groups)) {
+			if strings.HasPrefix(group, toComplete) {
+				suggestions = append(suggestions, group)
+			}
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// sshHostCompletionFunc completes the names of configured SSH hosts.
+func sshHostCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var suggestions []string
+	cfg, err := config.LoadConfig()
+	if err == nil {
+		for _, host := range cfg.SSHHosts {
+			if strings.HasPrefix(host.Name, toComplete) && !slices.Contains(args, host.Name) {
+				suggestions = append(suggestions, host.Name)
+			}
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -7,10 +7,14 @@ import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
 	"bucket-manager/internal/logger"
+	"bucket-manager/internal/runner"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -37,22 +41,18 @@ If set, this overrides the default search paths (~/bucket, ~/compose-bucket).
 To revert to default behavior, set the path to an empty string: bm config set-local-root ""`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("changing the configuration")
 		localRootPath := args[0]
 
-		cfg, err := config.LoadConfig()
-		if err != nil {
-			logger.Errorf("Error loading configuration: %v", err)
-			os.Exit(1)
-		}
-
 		if localRootPath != "" && !strings.HasPrefix(localRootPath, "/") && !strings.HasPrefix(localRootPath, "~/") {
 			logger.Error("Error: Path must be absolute or start with '~/'")
 			os.Exit(1)
 		}
 
-		cfg.LocalRoot = localRootPath
-
-		err = config.SaveConfig(cfg)
+		err := config.UpdateConfig(func(cfg *config.Config) error {
+			cfg.LocalRoot = localRootPath
+			return nil
+		})
 		if err != nil {
 			logger.Errorf("Error saving configuration: %v", err)
 			os.Exit(1)
@@ -108,7 +108,7 @@ var configGetLocalRootCmd = &cobra.Command{
 			}
 			successColor.Printf("Effective path being used: %s %s\n", activePath, source)
 
-		} else if strings.Contains(activeErr.Error(), "could not find") {
+		} else if errors.Is(activeErr, discovery.ErrRootNotFound) {
 			if cfg.LocalRoot != "" {
 				fmt.Printf("Warning: Configured path '%s' not found, and no default path exists.\n", cfg.LocalRoot)
 			} else {
@@ -123,38 +123,43 @@ var configGetLocalRootCmd = &cobra.Command{
 // Runtime configuration commands
 var configSetRuntimeCmd = &cobra.Command{
 	Use:   "set-runtime <runtime>",
-	Short: "Set the container runtime (podman or docker)",
+	Short: "Set the container runtime (auto, podman or docker)",
 	Long: `Sets the container runtime to use for compose operations.
-Valid values are 'podman' or 'docker'. This affects all stack operations.
+Valid values are 'auto', 'podman' or 'docker'. This affects all stack
+operations, except on hosts that set their own container_runtime. With 'auto',
+each host uses the first of podman and docker that has a working compose.
 
 Examples:
   bm config set-runtime docker    # Use Docker
-  bm config set-runtime podman    # Use Podman (default)`,
+  bm config set-runtime podman    # Use Podman
+  bm config set-runtime auto      # Detect the runtime on each host (default)`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("changing the configuration")
 		runtime := strings.ToLower(args[0])
 
 		// Validate runtime
-		if runtime != "podman" && runtime != "docker" {
-			logger.Error("Error: Runtime must be either 'podman' or 'docker'")
+		if !slices.Contains(config.ContainerRuntimes, runtime) {
+			logger.Error("Error: Runtime must be 'auto', 'podman' or 'docker'")
 			os.Exit(1)
 		}
 
-		cfg, err := config.LoadConfig()
-		if err != nil {
-			logger.Errorf("Error loading configuration: %v", err)
-			os.Exit(1)
-		}
-
-		cfg.ContainerRuntime = runtime
-
-		err = config.SaveConfig(cfg)
+		err := config.UpdateConfig(func(cfg *config.Config) error {
+			cfg.ContainerRuntime = runtime
+			if runtime == config.ContainerRuntimeAuto {
+				cfg.ContainerRuntime = ""
+			}
+			return nil
+		})
 		if err != nil {
 			logger.Errorf("Error saving configuration: %v", err)
 			os.Exit(1)
 		}
 
 		successColor.Printf("Container runtime set to: %s\n", runtime)
+		if runtime == config.ContainerRuntimeAuto {
+			return
+		}
 
 		// Show a helpful tip about compose files
 		fmt.Println("\nTip: Make sure your compose files are compatible with the runtime chosen.")
@@ -174,6 +179,9 @@ var configGetRuntimeCmd = &cobra.Command{
 		}
 
 		currentRuntime := cfg.ContainerRuntime
+		if currentRuntime == "" {
+			currentRuntime = config.ContainerRuntimeAuto
+		}
 		fmt.Printf("Current container runtime: %s", identifierColor.Sprint(currentRuntime))
 		if cfg.ContainerRuntime == "" {
 			fmt.Print(" (default)")
@@ -181,11 +189,101 @@ var configGetRuntimeCmd = &cobra.Command{
 		fmt.Println()
 
 		// Show which binary will be used
-		fmt.Printf("Commands will use: %s compose\n", currentRuntime)
+		if currentRuntime != config.ContainerRuntimeAuto {
+			fmt.Printf("Commands will use: %s compose\n", currentRuntime)
+		} else if detected, err := runner.DetectRuntime(nil); err != nil {
+			statusPartialColor.Printf("Commands will use: podman compose (%v)\n", err)
+		} else {
+			fmt.Printf("Commands will use: %s compose locally (detected at %s)\n", detected.Runtime, detected.Path)
+		}
+		for _, host := range cfg.SSHHosts {
+			if host.ContainerRuntime != "" {
+				fmt.Printf("Host %s uses: %s\n", identifierColor.Sprint(host.Name), host.ContainerRuntime)
+			}
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration for mistakes",
+	Long: `Loads the configuration and reports problems such as duplicate or incomplete
+hosts, missing key files, invalid ports and an inaccessible local root.
+Plaintext passwords and settings that fall back to defaults are reported as
+warnings. The container runtime and compose versions of local and every enabled
+host are listed too (--skip-versions skips connecting to the hosts), as are the
+BM_* environment variables overriding the file. Exits with a non-zero status if
+any configuration errors are found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath, _ := config.DefaultConfigPath()
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if overrides := cfg.EnvOverrides(); len(overrides) > 0 {
+			fmt.Printf("Overridden from the environment: %s\n\n", strings.Join(overrides, ", "))
+		}
+
+		if skip, _ := cmd.Flags().GetBool("skip-versions"); !skip {
+			printComposeVersions(cfg)
+		}
+
+		issues := cfg.Validate()
+		errorCount := 0
+		for _, issue := range issues {
+			if issue.Warning {
+				statusPartialColor.Printf("warning: %s\n", issue)
+			} else {
+				errorCount++
+				errorColor.Printf("error: %s\n", issue)
+			}
+		}
+
+		if len(issues) == 0 {
+			successColor.Printf("%s is valid.\n", configPath)
+			return
+		}
+		fmt.Printf("\n%d error(s), %d warning(s) in %s\n", errorCount, len(issues)-errorCount, configPath)
+		if errorCount > 0 {
+			os.Exit(1)
+		}
 	},
 }
 
+// printComposeVersions lists the runtime and compose versions of local and every
+// enabled host, checking the hosts concurrently.
+func printComposeVersions(cfg config.Config) {
+	targets, _ := resolveHostTargets(cfg, nil) // Never fails without host names
+	versions := make([]runner.ComposeVersion, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			versions[i] = runner.GetComposeVersion(target)
+		}()
+	}
+	wg.Wait()
+
+	fmt.Println("Versions:")
+	for i, target := range targets {
+		if versions[i].Error != nil {
+			errorColor.Printf("  %s: %v\n", target.ServerName, versions[i].Error)
+			continue
+		}
+		fmt.Printf("  %s: %s\n", identifierColor.Sprint(target.ServerName), versions[i])
+	}
+	fmt.Println()
+}
+
 func init() {
+	// Add validation command
+	configCmd.AddCommand(configValidateCmd)
+	configValidateCmd.Flags().Bool("skip-versions", false, "Don't connect to the hosts to list their runtime and compose versions")
+
 	// Add local root commands
 	configCmd.AddCommand(configSetLocalRootCmd)
 	configCmd.AddCommand(configGetLocalRootCmd)
@@ -10,19 +10,24 @@ package cli
 import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/logger"
+	"bucket-manager/internal/runner"
 	"bufio"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 // hostsCmd is the parent command for SSH-specific configuration subcommands
 var hostsCmd = &cobra.Command{
-	Use:   "hosts",
-	Short: "Manage SSH host configurations",
+	Use:     "hosts",
+	Aliases: []string{"ssh"},
+	Short:   "Manage SSH host configurations",
 	Long: `Add, list, edit, remove, or import SSH host configurations used by bucket-manager.
 These configurations are used to connect to remote hosts for stack discovery and management.`,
 }
@@ -44,11 +49,7 @@ var hostsListCmd = &cobra.Command{
 
 		statusColor.Println("Configured SSH Hosts:")
 		for i, host := range cfg.SSHHosts {
-			details := fmt.Sprintf("%s@%s", host.User, host.Hostname)
-			if host.Port != 0 && host.Port != 22 {
-				details += fmt.Sprintf(":%d", host.Port)
-			}
-			fmt.Printf("%d: %s (%s)\n", i+1, identifierColor.Sprint(host.Name), details)
+			fmt.Printf("%d: %s (%s)\n", i+1, identifierColor.Sprint(host.Name), host.DisplayAddress())
 			if host.RemoteRoot != "" {
 				fmt.Printf("   Remote Root: %s\n", host.RemoteRoot)
 			} else {
@@ -57,9 +58,24 @@ var hostsListCmd = &cobra.Command{
 			if host.KeyPath != "" {
 				fmt.Printf("   Key Path:    %s\n", host.KeyPath)
 			}
+			if host.KeyPassphrase != "" {
+				fmt.Printf("   Passphrase:  %s\n", errorColor.Sprint("[set, stored insecurely]"))
+			}
+			if host.KeyPassphraseCommand != "" {
+				fmt.Printf("   Passphrase:  from command '%s'\n", host.KeyPassphraseCommand)
+			}
 			if host.Password != "" {
 				fmt.Printf("   Password:    %s\n", errorColor.Sprint("[set, stored insecurely]"))
 			}
+			if host.PasswordFile != "" {
+				fmt.Printf("   Password:    from file %s\n", host.PasswordFile)
+			}
+			if host.PasswordCommand != "" {
+				fmt.Printf("   Password:    from command '%s'\n", host.PasswordCommand)
+			}
+			if host.ForwardAgent {
+				fmt.Println("   SSH Agent:   forwarded")
+			}
 			if host.Disabled {
 				fmt.Printf("   Status:      %s\n", errorColor.Sprint("Disabled"))
 			}
@@ -92,13 +108,10 @@ func promptForNewHostDetails(existingHosts []config.SSHHost) (config.SSHHost, er
 		return newHost, fmt.Errorf("error reading username: %w", err)
 	}
 
-	newHost.Port, err = promptOptionalInt("SSH Port", 22)
+	newHost.Port, err = promptPort(fmt.Sprintf("SSH Port (default: %d):", config.DefaultSSHPort), 0)
 	if err != nil {
 		return newHost, fmt.Errorf("error reading port: %w", err)
 	}
-	if newHost.Port == 22 {
-		newHost.Port = 0 // Store 0 for default
-	}
 
 	prompt := "Remote Root Path (optional, defaults to ~/bucket or ~/compose-bucket):"
 	newHost.RemoteRoot, err = promptString(prompt, false)
@@ -111,6 +124,11 @@ func promptForNewHostDetails(existingHosts []config.SSHHost) (config.SSHHost, er
 		return newHost, fmt.Errorf("error getting authentication details: %w", err)
 	}
 
+	newHost.ForwardAgent, err = promptConfirm("Forward the local SSH agent to this host? (y/N):")
+	if err != nil {
+		return newHost, fmt.Errorf("error reading agent forwarding choice: %w", err)
+	}
+
 	newHost.Disabled = false
 	return newHost, nil
 }
@@ -119,6 +137,7 @@ var hostsAddCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new SSH host configuration interactively",
 	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("changing the configuration")
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			logger.Errorf("Error loading configuration: %v", err)
@@ -133,8 +152,16 @@ var hostsAddCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		cfg.SSHHosts = append(cfg.SSHHosts, newHost)
-		err = config.SaveConfig(cfg)
+		// Re-read under the config lock so hosts added elsewhere while prompting are kept.
+		err = config.UpdateConfig(func(latest *config.Config) error {
+			for _, h := range latest.SSHHosts {
+				if h.Name == newHost.Name {
+					return fmt.Errorf("SSH host with name '%s' already exists", newHost.Name)
+				}
+			}
+			latest.SSHHosts = append(latest.SSHHosts, newHost)
+			return nil
+		})
 		if err != nil {
 			logger.Errorf("Error saving configuration: %v", err)
 			os.Exit(1)
@@ -181,17 +208,14 @@ func promptForEditedHostDetails(originalHost config.SSHHost, allHosts []config.S
 		editedHost.User = originalHost.User
 	}
 
-	portDefault := originalHost.Port
-	if portDefault == 0 {
-		portDefault = 22
+	portPrompt := fmt.Sprintf("SSH Port [%d] (0 for the default):", originalHost.Port)
+	if originalHost.Port == 0 {
+		portPrompt = fmt.Sprintf("SSH Port [default: %d]:", config.DefaultSSHPort)
 	}
-	editedHost.Port, err = promptOptionalInt(fmt.Sprintf("SSH Port [%d]", portDefault), portDefault)
+	editedHost.Port, err = promptPort(portPrompt, originalHost.Port)
 	if err != nil {
 		return editedHost, fmt.Errorf("error reading port: %w", err)
 	}
-	if editedHost.Port == 22 {
-		editedHost.Port = 0 // Store 0 if it's the default 22
-	}
 
 	remoteRootPrompt := "Remote Root Path (leave blank to use default: ~/bucket or ~/compose-bucket)"
 	currentRemoteRootDisplay := originalHost.RemoteRoot
@@ -209,6 +233,12 @@ func promptForEditedHostDetails(originalHost config.SSHHost, allHosts []config.S
 		return editedHost, fmt.Errorf("error getting authentication details: %w", err)
 	}
 
+	forwardPrompt := fmt.Sprintf("Forward the local SSH agent to this host? (Currently: %t) (y/N):", originalHost.ForwardAgent)
+	editedHost.ForwardAgent, err = promptConfirm(forwardPrompt)
+	if err != nil {
+		return editedHost, fmt.Errorf("error reading agent forwarding choice: %w", err)
+	}
+
 	disablePrompt := fmt.Sprintf("Disable this host? (Currently: %t) (y/N):", originalHost.Disabled)
 	disableChoice, err := promptConfirm(disablePrompt)
 	if err != nil {
@@ -223,6 +253,7 @@ var hostsEditCmd = &cobra.Command{
 	Use:   "edit",
 	Short: "Edit an existing SSH host configuration interactively",
 	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("changing the configuration")
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			logger.Errorf("Error loading configuration: %v", err)
@@ -257,8 +288,21 @@ var hostsEditCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		cfg.SSHHosts[hostIndex] = editedHost
-		err = config.SaveConfig(cfg)
+		err = config.UpdateConfig(func(latest *config.Config) error {
+			index := -1
+			for i, h := range latest.SSHHosts {
+				if h.Name == originalHost.Name {
+					index = i
+				} else if h.Name == editedHost.Name {
+					return fmt.Errorf("SSH host with name '%s' already exists", editedHost.Name)
+				}
+			}
+			if index == -1 {
+				return fmt.Errorf("SSH host '%s' no longer exists", originalHost.Name)
+			}
+			latest.SSHHosts[index] = editedHost
+			return nil
+		})
 		if err != nil {
 			logger.Errorf("Error saving configuration: %v", err)
 			os.Exit(1)
@@ -272,6 +316,7 @@ var hostsRemoveCmd = &cobra.Command{
 	Use:   "remove",
 	Short: "Remove an SSH host configuration interactively",
 	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("changing the configuration")
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			logger.Errorf("Error loading configuration: %v", err)
@@ -313,9 +358,15 @@ var hostsRemoveCmd = &cobra.Command{
 			return
 		}
 
-		cfg.SSHHosts = append(cfg.SSHHosts[:choice-1], cfg.SSHHosts[choice:]...)
-
-		err = config.SaveConfig(cfg)
+		err = config.UpdateConfig(func(latest *config.Config) error {
+			for i, h := range latest.SSHHosts {
+				if h.Name == hostToRemove.Name {
+					latest.SSHHosts = append(latest.SSHHosts[:i], latest.SSHHosts[i+1:]...)
+					return nil
+				}
+			}
+			return fmt.Errorf("SSH host '%s' no longer exists", hostToRemove.Name)
+		})
 		if err != nil {
 			logger.Errorf("Error saving configuration: %v", err)
 			os.Exit(1)
@@ -325,6 +376,45 @@ var hostsRemoveCmd = &cobra.Command{
 	},
 }
 
+var hostsRenameCmd = &cobra.Command{
+	Use:   "rename <old-name> <new-name>",
+	Short: "Rename an SSH host configuration",
+	Long:  `Renames an SSH host, keeping the rest of its configuration. Groups, pinned stacks, per-stack settings and saved state that refer to the host by name are updated to the new name.`,
+	Args:  cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return sshHostCompletionFunc(cmd, args, toComplete)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("changing the configuration")
+		oldName, newName := args[0], args[1]
+
+		err := config.UpdateConfig(func(latest *config.Config) error {
+			return latest.RenameSSHHost(oldName, newName)
+		})
+		if err != nil {
+			logger.Errorf("Error renaming SSH host: %v", err)
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// State kept outside the configuration only affects previews and
+		// --resume, so failing to move it doesn't undo the rename
+		if err := runner.RenameAppliedConfigs(oldName, newName); err != nil {
+			logger.Warn("Could not move the applied configs of the renamed host", "host", newName, "error", err)
+			errorColor.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if err := renameBatchHost(oldName, newName); err != nil {
+			logger.Warn("Could not update the batch state of the renamed host", "host", newName, "error", err)
+			errorColor.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		successColor.Printf("Successfully renamed SSH host '%s' to '%s'.\n", oldName, newName)
+	},
+}
+
 // filterAndDisplayPotentialHosts filters hosts from ssh_config against existing bm config and displays them.
 // Returns the list of hosts that are actually importable.
 func filterAndDisplayPotentialHosts(potentialHosts []config.PotentialHost, currentConfigHosts []config.SSHHost) []config.PotentialHost {
@@ -340,7 +430,7 @@ func filterAndDisplayPotentialHosts(potentialHosts []config.PotentialHost, curre
 			fmt.Printf("  %d: %s (Alias: %s) - %s\n", i+1, identifierColor.Sprint(pHost.Alias), pHost.Hostname, errorColor.Sprint("[Skipped: Name already exists in bm config]"))
 			continue
 		}
-		fmt.Printf("  %d: %s (Hostname: %s, User: %s, Port: %d)\n", i+1, identifierColor.Sprint(pHost.Alias), pHost.Hostname, pHost.User, pHost.Port)
+		fmt.Printf("  %d: %s (%s)\n", i+1, identifierColor.Sprint(pHost.Alias), pHost.DisplayAddress())
 		if pHost.KeyPath != "" {
 			fmt.Printf("     Key: %s\n", pHost.KeyPath)
 		}
@@ -436,6 +526,7 @@ var hostsImportCmd = &cobra.Command{
 	Use:   "import",
 	Short: "Import hosts from ~/.ssh/config interactively",
 	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("changing the configuration")
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			logger.Errorf("Error loading current configuration: %v", err)
@@ -488,8 +579,17 @@ var hostsImportCmd = &cobra.Command{
 			return
 		}
 
-		cfg.SSHHosts = append(cfg.SSHHosts, successfullyConfiguredHosts...)
-		err = config.SaveConfig(cfg)
+		err = config.UpdateConfig(func(latest *config.Config) error {
+			for _, h := range latest.SSHHosts {
+				for _, imported := range successfullyConfiguredHosts {
+					if h.Name == imported.Name {
+						return fmt.Errorf("SSH host with name '%s' was added while importing", imported.Name)
+					}
+				}
+			}
+			latest.SSHHosts = append(latest.SSHHosts, successfullyConfiguredHosts...)
+			return nil
+		})
 		if err != nil {
 			logger.Errorf("\nError saving configuration: %v", err)
 			os.Exit(1)
@@ -499,12 +599,115 @@ var hostsImportCmd = &cobra.Command{
 	},
 }
 
+var hostsTestCmd = &cobra.Command{
+	Use:   "test [host-name...]",
+	Short: "Test the connection to SSH hosts",
+	Long: `Connects to the named hosts (or every enabled host with --all) on a new
+connection, runs a trivial command and reports the authentication method used,
+how long connecting took, whether the container runtime is installed and the
+resolved remote root. Failures say whether the network, the host key or
+authentication is at fault. Disabled hosts can be tested by name.
+
+Exits with a non-zero status if any host can't be connected to.`,
+	Example:           "  bm config hosts test server1\n  bm config hosts test --all",
+	ValidArgsFunction: sshHostCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) > 0) {
+			errorColor.Fprintln(os.Stderr, "Error: give host names or --all")
+			os.Exit(1)
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+			os.Exit(1)
+		}
+
+		var hosts []config.SSHHost
+		if all {
+			for _, host := range cfg.SSHHosts {
+				if !host.Disabled {
+					hosts = append(hosts, host)
+				}
+			}
+			if len(hosts) == 0 {
+				fmt.Println("No enabled SSH hosts configured.")
+				return
+			}
+		}
+		for _, name := range args {
+			index := slices.IndexFunc(cfg.SSHHosts, func(host config.SSHHost) bool { return host.Name == name })
+			if index == -1 {
+				errorColor.Fprintf(os.Stderr, "Error: host '%s' not found in configuration\n", name)
+				os.Exit(1)
+			}
+			hosts = append(hosts, cfg.SSHHosts[index])
+		}
+
+		results := make([]runner.HostTest, len(hosts))
+		var wg sync.WaitGroup
+		for i, host := range hosts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = runner.TestHost(host)
+			}()
+		}
+		wg.Wait()
+
+		failed := 0
+		for i, result := range results {
+			if i > 0 {
+				fmt.Println()
+			}
+			if !printHostTest(hosts[i], result) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// printHostTest prints the result of testing a host, returning false if the
+// host couldn't be connected to.
+func printHostTest(host config.SSHHost, result runner.HostTest) bool {
+	fmt.Printf("%s (%s)\n", identifierColor.Sprint(host.Name), host.DisplayAddress())
+	if result.Error != nil {
+		errorColor.Printf("  ✗ %v\n", result.Error)
+		return false
+	}
+
+	successColor.Printf("  ✓ connected in %s using %s authentication\n", result.Latency.Round(time.Millisecond), result.AuthMethod)
+	switch {
+	case result.RuntimePath != "" && result.RuntimeDetected:
+		successColor.Printf("  ✓ %s found at %s (detected)\n", result.Runtime, result.RuntimePath)
+	case result.RuntimePath != "":
+		successColor.Printf("  ✓ %s found at %s\n", result.Runtime, result.RuntimePath)
+	case result.RuntimeDetected:
+		statusPartialColor.Printf("  ✗ neither podman nor docker with a working compose found in the PATH of %s\n", host.User)
+	default:
+		statusPartialColor.Printf("  ✗ %s not found in the PATH of %s\n", result.Runtime, host.User)
+	}
+	if result.RemoteRootError != nil {
+		statusPartialColor.Printf("  ✗ %v\n", result.RemoteRootError)
+	} else {
+		successColor.Printf("  ✓ remote root: %s\n", result.RemoteRoot)
+	}
+	return true
+}
+
 func init() {
 	hostsCmd.AddCommand(hostsListCmd)
 	hostsCmd.AddCommand(hostsAddCmd)
 	hostsCmd.AddCommand(hostsEditCmd)
 	hostsCmd.AddCommand(hostsRemoveCmd)
+	hostsCmd.AddCommand(hostsRenameCmd)
 	hostsCmd.AddCommand(hostsImportCmd)
+	hostsCmd.AddCommand(hostsTestCmd)
+	hostsTestCmd.Flags().Bool("all", false, "Test every enabled host")
 
 	configCmd.AddCommand(hostsCmd)
 }
@@ -524,19 +727,22 @@ func promptString(prompt string, required bool) (string, error) {
 	return input, nil
 }
 
-func promptOptionalInt(prompt string, defaultValue int) (int, error) {
-	fmt.Printf("%s (default: %d): ", prompt, defaultValue)
+// promptPort prompts for an SSH port, returning current if the input is
+// empty. 0 stands for config.DefaultSSHPort; any other port, even 22, is
+// returned as typed so that it is stored explicitly.
+func promptPort(prompt string, current int) (int, error) {
+	fmt.Print(prompt + " ")
 	input, err := reader.ReadString('\n')
 	if err != nil {
-		return defaultValue, err
+		return current, err
 	}
 	input = strings.TrimSpace(input)
 	if input == "" {
-		return defaultValue, nil
+		return current, nil
 	}
 	val, err := strconv.Atoi(input)
-	if err != nil {
-		return defaultValue, fmt.Errorf("invalid integer input: %w", err)
+	if err != nil || val < 0 || val > 65535 {
+		return current, fmt.Errorf("invalid port number: %s", input)
 	}
 	return val, nil
 }
@@ -696,6 +902,8 @@ func promptForAuthDetails(host *config.SSHHost, isEditing bool, originalPassword
 			return passErr
 		}
 		host.Password = password
+		host.PasswordFile = "" // A plaintext password replaces the other sources
+		host.PasswordCommand = ""
 		host.KeyPath = ""
 	case 2:
 		host.KeyPath = ""
@@ -10,16 +10,21 @@ package cli
 import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
+	"errors"
 	"fmt"
+	"path"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
 )
 
 // findStackByIdentifier finds a specific stack based on its identifier.
 // Identifier can be "stackName" (implies local preference) or "serverName:stackName".
-// Returns an error if not found or if "stackName" is ambiguous.
+// Returns an error if not found, suggesting the closest names among stacks, or
+// if "stackName" is ambiguous.
 //
 // The function uses a preference system:
 // 1. If serverName is specified, it looks for an exact match
@@ -59,11 +64,17 @@ func findStackByIdentifier(stacks []discovery.Stack, identifier string) (discove
 		if exactMatch != nil {
 			return *exactMatch, nil
 		}
-		return discovery.Stack{}, fmt.Errorf("stack '%s:%s' not found", targetServer, targetName)
+		if err := ambiguousNameError(stacks, targetServer, targetName); err != nil {
+			return discovery.Stack{}, err
+		}
+		return discovery.Stack{}, fmt.Errorf("stack '%s:%s' not found%s", targetServer, targetName, didYouMean(suggestStacks(stacks, targetServer, targetName)))
 	}
 
 	if len(potentialMatches) == 0 {
-		return discovery.Stack{}, fmt.Errorf("stack '%s' not found", targetName)
+		if err := ambiguousNameError(stacks, "", targetName); err != nil {
+			return discovery.Stack{}, err
+		}
+		return discovery.Stack{}, fmt.Errorf("stack '%s' not found%s", targetName, didYouMean(suggestStacks(stacks, "", targetName)))
 	}
 
 	if len(potentialMatches) == 1 {
@@ -94,6 +105,63 @@ func findStackByIdentifier(stacks []discovery.Stack, identifier string) (discove
 	return discovery.Stack{}, fmt.Errorf("stack name '%s' is ambiguous, please specify one of: %s", targetName, strings.Join(options, ", "))
 }
 
+// ambiguousNameError returns an error listing the stacks that were named after
+// their paths because several stacks on their host share the name targetName,
+// on targetServer ("" for any host), or nil if there are none.
+func ambiguousNameError(stacks []discovery.Stack, targetServer, targetName string) error {
+	var options []string
+	for _, s := range discovery.DisambiguatedStacks(stacks, targetName) {
+		if targetServer == "" || s.ServerName == targetServer {
+			options = append(options, s.Identifier())
+		}
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return fmt.Errorf("stack name '%s' is ambiguous, please specify one of: %s", targetName, strings.Join(options, ", "))
+}
+
+// isStackPattern reports whether the stack name of an identifier ("name" or
+// "server:name") is a shell-style glob pattern such as "web-*".
+func isStackPattern(identifier string) bool {
+	name := identifier
+	if _, after, found := strings.Cut(identifier, ":"); found {
+		name = after
+	}
+	return strings.ContainsAny(name, "*?[")
+}
+
+// findStacksByPattern returns the stacks whose names match the glob pattern of
+// an identifier, "pattern" matching on every host and "server:pattern" on that
+// host only. Patterns use path.Match syntax. Stacks found on reachable hosts
+// are returned with the errors of the others; matching nothing is an error.
+func findStacksByPattern(identifier string, overrides discovery.RootOverrides) ([]discovery.Stack, []error) {
+	serverName, pattern, hasServer := strings.Cut(strings.TrimSpace(identifier), ":")
+	if !hasServer {
+		pattern, serverName = serverName, ""
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, []error{fmt.Errorf("invalid pattern '%s': %w", pattern, err)}
+	}
+
+	discoveryIdentifier := "" // Every host
+	if hasServer {
+		discoveryIdentifier = serverName + ":"
+	}
+	stacks, collectedErrors := discoverTargetStacks(discoveryIdentifier, nil, overrides)
+
+	var matches []discovery.Stack
+	for _, stack := range stacks {
+		if matched, _ := path.Match(pattern, stack.Name); matched {
+			matches = append(matches, stack)
+		}
+	}
+	if len(matches) == 0 && len(collectedErrors) == 0 {
+		return nil, []error{fmt.Errorf("no stacks match '%s'", identifier)}
+	}
+	return matches, collectedErrors
+}
+
 // discoverTargetStacks finds stacks based on an identifier, handling local/remote discovery.
 // identifier: The stack identifier (e.g., "my-app", "server1:my-app", "local:my-app").
 //
@@ -101,7 +169,8 @@ func findStackByIdentifier(stacks []discovery.Stack, identifier string) (discove
 //	If empty, discovers all stacks.
 //
 // s: Optional spinner for feedback during remote discovery.
-func discoverTargetStacks(identifier string, s *spinner.Spinner) ([]discovery.Stack, []error) {
+// overrides: Optional root directories that replace the configured ones for this call.
+func discoverTargetStacks(identifier string, s *spinner.Spinner, overrides discovery.RootOverrides) ([]discovery.Stack, []error) {
 	var stacksToCheck []discovery.Stack
 	var collectedErrors []error
 	targetStackName := ""
@@ -132,16 +201,11 @@ func discoverTargetStacks(identifier string, s *spinner.Spinner) ([]discovery.St
 	discoverAllRemotes := targetServerName == "" // Only if ambiguous and not found locally
 
 	if discoverLocal {
-		localRootDir, err := discovery.GetComposeRootDirectory()
-		if err == nil {
-			localStacks, err := discovery.FindLocalStacks(localRootDir)
-			if err != nil {
-				collectedErrors = append(collectedErrors, fmt.Errorf("local discovery failed: %w", err))
-			} else {
-				stacksToCheck = append(stacksToCheck, localStacks...)
-			}
-		} else if !strings.Contains(err.Error(), "could not find") {
-			collectedErrors = append(collectedErrors, fmt.Errorf("local root check failed: %w", err))
+		localStacks, err := discovery.DiscoverLocalStacks(overrides.LocalRoot)
+		if err != nil {
+			collectedErrors = append(collectedErrors, &discovery.HostError{Host: "local", Err: fmt.Errorf("local discovery failed: %w", err)})
+		} else {
+			stacksToCheck = append(stacksToCheck, localStacks...)
 		}
 	}
 
@@ -157,27 +221,23 @@ func discoverTargetStacks(identifier string, s *spinner.Spinner) ([]discovery.St
 			}
 		}
 		if targetHost == nil {
-			collectedErrors = append(collectedErrors, fmt.Errorf("remote host '%s' not found in configuration", targetServerName))
+			hostNames := []string{"local"}
+			for _, host := range cfg.SSHHosts {
+				hostNames = append(hostNames, host.Name)
+			}
+			collectedErrors = append(collectedErrors, fmt.Errorf("remote host '%s' not found in configuration%s", targetServerName, didYouMean(closestMatches(hostNames, targetServerName, nil))))
 		} else {
 			if s != nil {
 				originalSuffix := s.Suffix
 				s.Suffix = fmt.Sprintf(" Discovering on %s...", identifierColor.Sprint(targetServerName))
 				defer func() { s.Suffix = originalSuffix }()
 			}
-			remoteStacks, err := discovery.FindRemoteStacks(targetHost)
-			if err != nil {
-				collectedErrors = append(collectedErrors, fmt.Errorf("remote discovery failed for %s: %w", targetHost.Name, err))
+			remoteStacks, err := discovery.FindRemoteStacks(targetHost, overrides.RemoteRoot)
+			if err != nil && !errors.Is(err, discovery.ErrRootNotFound) {
+				collectedErrors = append(collectedErrors, &discovery.HostError{Host: targetHost.Name, Err: fmt.Errorf("remote discovery failed for %s: %w", targetHost.Name, err)})
 			} else {
-				if targetStackName == "" {
-					stacksToCheck = append(stacksToCheck, remoteStacks...)
-				} else {
-					for _, rs := range remoteStacks {
-						if rs.Name == targetStackName {
-							stacksToCheck = append(stacksToCheck, rs)
-							break
-						}
-					}
-				}
+				// Every stack is kept for "did you mean" suggestions; the target is filtered below
+				stacksToCheck = append(stacksToCheck, remoteStacks...)
 			}
 		}
 	}
@@ -212,15 +272,12 @@ func discoverTargetStacks(identifier string, s *spinner.Spinner) ([]discovery.St
 					hostConfig := cfg.SSHHosts[i]
 					go func(hc config.SSHHost) {
 						defer remoteWg.Done()
-						remoteStacks, err := discovery.FindRemoteStacks(&hc)
-						if err != nil {
-							remoteErrorChan <- fmt.Errorf("remote discovery failed for %s: %w", hc.Name, err)
+						remoteStacks, err := discovery.FindRemoteStacks(&hc, overrides.RemoteRoot)
+						if err != nil && !errors.Is(err, discovery.ErrRootNotFound) {
+							remoteErrorChan <- &discovery.HostError{Host: hc.Name, Err: fmt.Errorf("remote discovery failed for %s: %w", hc.Name, err)}
 						} else {
 							for _, rs := range remoteStacks {
-								if rs.Name == targetStackName {
-									remoteStackChan <- rs
-									break
-								}
+								remoteStackChan <- rs
 							}
 						}
 					}(hostConfig)
@@ -246,7 +303,7 @@ func discoverTargetStacks(identifier string, s *spinner.Spinner) ([]discovery.St
 				defer func() { s.Suffix = originalSuffix }()
 			}
 			stacksToCheck = nil
-			stackChan, errorChan, _ := discovery.FindStacks()
+			stackChan, errorChan, _ := discovery.FindStacks(overrides)
 			var wg sync.WaitGroup
 			wg.Add(2)
 			go func() {
@@ -287,14 +344,95 @@ func discoverTargetStacks(identifier string, s *spinner.Spinner) ([]discovery.St
 			} else {
 				return nil, append(collectedErrors, resolveErr)
 			}
-		} else if len(finalStacks) == 0 && len(collectedErrors) == 0 {
+		} else if len(finalStacks) == 0 && targetStackName == "" && len(collectedErrors) == 0 {
+			return nil, []error{fmt.Errorf("no stacks found on host '%s'", targetServerName)}
+		} else if len(finalStacks) == 0 && targetStackName != "" {
+			// Reported along with any discovery errors, so that the hosts that
+			// could be searched still give "did you mean" suggestions
 			_, notFoundErr := findStackByIdentifier(stacksToCheck, identifier)
-			if notFoundErr != nil {
-				return nil, []error{notFoundErr}
+			if notFoundErr == nil {
+				notFoundErr = fmt.Errorf("no stacks found matching identifier '%s'", identifier)
 			}
-			return nil, []error{fmt.Errorf("no stacks found matching identifier '%s'", identifier)}
+			return nil, append(collectedErrors, notFoundErr)
 		}
 	}
 
+	slices.SortFunc(finalStacks, discovery.CompareStacks)
 	return finalStacks, collectedErrors
 }
+
+// addRootOverrideFlags registers the --local-root and --remote-root discovery flags on cmd.
+func addRootOverrideFlags(cmd *cobra.Command) {
+	cmd.Flags().String("local-root", "", "Scan this local directory for stacks instead of the configured local_root")
+	cmd.Flags().String("remote-root", "", "Scan this directory on remote hosts instead of each host's remote_root")
+}
+
+// rootOverridesFromFlags reads the discovery root override flags registered by addRootOverrideFlags.
+func rootOverridesFromFlags(cmd *cobra.Command) discovery.RootOverrides {
+	localRoot, _ := cmd.Flags().GetString("local-root")
+	remoteRoot, _ := cmd.Flags().GetString("remote-root")
+	return discovery.RootOverrides{LocalRoot: localRoot, RemoteRoot: remoteRoot}
+}
+
+// addHostFilterFlags registers the --host and --local-only discovery flags on cmd.
+func addHostFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("host", nil, "Only discover stacks on this host ('local', a remote name or '@group'); can be repeated")
+	cmd.Flags().Bool("local-only", false, "Only discover local stacks, without connecting to remote hosts")
+	cmd.MarkFlagsMutuallyExclusive("host", "local-only")
+	cmd.RegisterFlagCompletionFunc("host", hostCompletionFunc)
+}
+
+// hostFilterFromFlags returns the hosts discovery is limited to by the flags
+// registered by addHostFilterFlags, for discovery.FindStacksOnHosts, or nil
+// for every host. Groups are expanded, and unknown hosts are an error rather
+// than silently finding nothing.
+func hostFilterFromFlags(cmd *cobra.Command) ([]string, error) {
+	if localOnly, _ := cmd.Flags().GetBool("local-only"); localOnly {
+		return []string{"local"}, nil
+	}
+	names, _ := cmd.Flags().GetStringArray("host")
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+	hosts, err := cfg.ExpandHostGroups(names)
+	if err != nil {
+		return nil, err
+	}
+	hostNames := []string{"local"}
+	for _, host := range cfg.SSHHosts {
+		hostNames = append(hostNames, host.Name)
+	}
+	for _, host := range hosts {
+		if !slices.Contains(hostNames, host) {
+			return nil, fmt.Errorf("host '%s' not found in configuration%s", host, didYouMean(closestMatches(hostNames, host, nil)))
+		}
+	}
+	return hosts, nil
+}
+
+// discoverStacksOnHosts discovers the stacks on the given hosts (nil for every
+// host), waiting for discovery to finish.
+func discoverStacksOnHosts(overrides discovery.RootOverrides, hosts []string) ([]discovery.Stack, []error) {
+	var stacks []discovery.Stack
+	var errs []error
+	stackChan, errorChan, _ := discovery.FindStacksOnHosts(overrides, hosts)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for err := range errorChan {
+			errs = append(errs, err)
+		}
+	}()
+	for stack := range stackChan {
+		stacks = append(stacks, stack)
+	}
+	wg.Wait()
+	slices.SortFunc(stacks, discovery.CompareStacks)
+	return stacks, errs
+}
@@ -0,0 +1,371 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's doctor.go implements `bm doctor`, which checks everything a
+// working setup needs (the config, the local runtime and stack root, the SSH
+// agent and every host) and prints what is wrong with a hint on fixing it.
+
+package cli
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/runner"
+	"bucket-manager/internal/ssh"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the setup for problems",
+	Long: `Runs a series of checks over the setup and prints a checklist, with a hint
+on fixing each problem found:
+
+  - the config file loads and passes 'bm config validate'
+  - the config directory is writable
+  - the local container runtime and compose are installed
+  - the local root exists and has stacks
+  - the SSH agent is running if a host authenticates with it
+  - every enabled host can be connected to, has the container runtime and a
+    remote root
+
+Use --skip-hosts to stay offline. Exits with a non-zero status if any check fails.`,
+	Example: "  bm doctor\n  bm doctor --skip-hosts",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		skipHosts, _ := cmd.Flags().GetBool("skip-hosts")
+
+		s := newSpinner(" Checking the setup...")
+		s.Start()
+		sections := runDoctorChecks(skipHosts)
+		s.Stop()
+
+		failed, warned := 0, 0
+		for _, section := range sections {
+			fmt.Println(section.title)
+			for _, check := range section.checks {
+				printDoctorCheck(check)
+				switch check.status {
+				case doctorFail:
+					failed++
+				case doctorWarn:
+					warned++
+				}
+			}
+			fmt.Println()
+		}
+
+		switch {
+		case failed > 0:
+			errorColor.Printf("%d problem(s) and %d warning(s) found.\n", failed, warned)
+			os.Exit(1)
+		case warned > 0:
+			statusPartialColor.Printf("No problems found, %d warning(s).\n", warned)
+		default:
+			successColor.Println("Everything looks good.")
+		}
+	},
+}
+
+// doctorStatus is the outcome of one check of `bm doctor`.
+type doctorStatus int
+
+const (
+	doctorOK doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+// doctorCheck is one line of the `bm doctor` checklist.
+type doctorCheck struct {
+	status  doctorStatus
+	message string
+	hint    string // How to fix a failure or warning, if there is anything to say
+}
+
+// doctorSection is a titled group of checks.
+type doctorSection struct {
+	title  string
+	checks []doctorCheck
+}
+
+// runDoctorChecks runs every check, the host checks concurrently with the
+// local ones, and returns their results by section.
+func runDoctorChecks(skipHosts bool) []doctorSection {
+	cfg, cfgErr := config.LoadConfig()
+
+	var hostChecks []doctorCheck
+	var wg sync.WaitGroup
+	if cfgErr == nil && !skipHosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hostChecks = checkDoctorHosts(cfg)
+		}()
+	}
+
+	configSection := doctorSection{title: "Configuration", checks: checkDoctorConfig(cfg, cfgErr)}
+	configSection.checks = append(configSection.checks, checkDoctorConfigDir())
+	localSection := doctorSection{title: "Local", checks: []doctorCheck{
+		checkDoctorLocalRuntime(),
+		checkDoctorLocalRoot(cfg),
+	}}
+	sections := []doctorSection{configSection, localSection}
+	if cfgErr != nil {
+		return sections
+	}
+
+	if agentCheck, needed := checkDoctorAgent(cfg); needed {
+		sections = append(sections, doctorSection{title: "SSH agent", checks: []doctorCheck{agentCheck}})
+	}
+	wg.Wait()
+	if len(hostChecks) > 0 {
+		sections = append(sections, doctorSection{title: "Hosts", checks: hostChecks})
+	}
+	return sections
+}
+
+// checkDoctorConfig reports whether the config file loads, and the errors
+// and warnings of Config.Validate.
+func checkDoctorConfig(cfg config.Config, loadErr error) []doctorCheck {
+	configPath, _ := config.DefaultConfigPath()
+	if loadErr != nil {
+		return []doctorCheck{{
+			status:  doctorFail,
+			message: fmt.Sprintf("config file could not be loaded: %v", loadErr),
+			hint:    fmt.Sprintf("fix or move away %s; a new one is created with the defaults", configPath),
+		}}
+	}
+
+	var checks []doctorCheck
+	errorCount := 0
+	for _, issue := range cfg.Validate() {
+		check := doctorCheck{status: doctorWarn, message: issue.String()}
+		if !issue.Warning {
+			check.status = doctorFail
+			errorCount++
+		}
+		checks = append(checks, check)
+	}
+	if errorCount > 0 {
+		checks[len(checks)-1].hint = fmt.Sprintf("edit %s, then run 'bm config validate'", configPath)
+		return checks
+	}
+	if _, err := os.Stat(configPath); errors.Is(err, os.ErrNotExist) {
+		return append([]doctorCheck{{status: doctorOK, message: "no config file yet, the defaults are used"}}, checks...)
+	}
+	return append([]doctorCheck{{status: doctorOK, message: fmt.Sprintf("config file %s is valid", configPath)}}, checks...)
+}
+
+// checkDoctorConfigDir checks that files can be created in the config
+// directory, where the config, pins and operation state are saved.
+func checkDoctorConfigDir() doctorCheck {
+	configPath, err := config.DefaultConfigPath()
+	if err != nil {
+		return doctorCheck{status: doctorFail, message: fmt.Sprintf("config directory not found: %v", err), hint: "set HOME or XDG_CONFIG_HOME"}
+	}
+	configDir := filepath.Dir(configPath)
+	if err := config.EnsureConfigDir(); err != nil {
+		return doctorCheck{status: doctorFail, message: err.Error(), hint: fmt.Sprintf("create %s and make it writable", configDir)}
+	}
+	probe, err := os.CreateTemp(configDir, ".doctor-*")
+	if err != nil {
+		return doctorCheck{status: doctorFail, message: fmt.Sprintf("config directory %s is not writable: %v", configDir, err), hint: fmt.Sprintf("check the owner and permissions of %s", configDir)}
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return doctorCheck{status: doctorOK, message: fmt.Sprintf("config directory %s is writable", configDir)}
+}
+
+// checkDoctorLocalRuntime checks that the local container runtime and its
+// compose work.
+func checkDoctorLocalRuntime() doctorCheck {
+	version := runner.GetComposeVersion(runner.HostTarget{ServerName: "local"})
+	if version.Error != nil {
+		return doctorCheck{
+			status:  doctorFail,
+			message: fmt.Sprintf("container runtime: %v", version.Error),
+			hint:    "install podman with podman-compose or docker-compose, or docker with its compose plugin, or pick the installed one with 'bm config set-runtime'",
+		}
+	}
+	if version.Version == "" {
+		return doctorCheck{
+			status:  doctorFail,
+			message: fmt.Sprintf("%s %s is installed, but its compose isn't working", version.Runtime, version.RuntimeVersion),
+			hint:    fmt.Sprintf("run '%s compose version' to see why", version.Runtime),
+		}
+	}
+	return doctorCheck{status: doctorOK, message: version.String()}
+}
+
+// checkDoctorLocalRoot checks that the local root exists and has stacks. A
+// missing local root is only a warning if remote hosts are configured, as
+// they may be all that is used.
+func checkDoctorLocalRoot(cfg config.Config) doctorCheck {
+	root, err := discovery.GetComposeRootDirectory()
+	if err != nil {
+		check := doctorCheck{
+			status:  doctorFail,
+			message: fmt.Sprintf("local root: %v", err),
+			hint:    "create ~/bucket or ~/compose-bucket with a directory per stack, or set another with 'bm config set-local-root'",
+		}
+		if cfg.LocalRoot == "" && len(cfg.SSHHosts) > 0 {
+			check.status = doctorWarn
+			check.hint += "; not needed if all your stacks are on remote hosts"
+		}
+		return check
+	}
+	stacks, err := discovery.FindLocalStacks(root)
+	switch {
+	case err != nil:
+		return doctorCheck{status: doctorFail, message: fmt.Sprintf("local root %s could not be searched: %v", root, err)}
+	case len(stacks) == 0:
+		return doctorCheck{
+			status:  doctorWarn,
+			message: fmt.Sprintf("local root %s has no stacks", root),
+			hint:    fmt.Sprintf("add a directory with a compose.yaml to %s for each stack", root),
+		}
+	}
+	return doctorCheck{status: doctorOK, message: fmt.Sprintf("local root %s has %d stack(s)", root, len(stacks))}
+}
+
+// checkDoctorAgent checks that the SSH agent runs and holds keys, if any
+// enabled host has neither a key nor a password and so needs the agent. needed
+// is false if no host does.
+func checkDoctorAgent(cfg config.Config) (check doctorCheck, needed bool) {
+	var hosts []string
+	for _, host := range cfg.SSHHosts {
+		if !host.Disabled && host.KeyPath == "" && !host.HasPassword() {
+			hosts = append(hosts, host.Name)
+		}
+	}
+	if len(hosts) == 0 {
+		return doctorCheck{}, false
+	}
+	usedBy := fmt.Sprintf("used by %s", strings.Join(hosts, ", "))
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return doctorCheck{
+			status:  doctorFail,
+			message: fmt.Sprintf("SSH agent is not running (SSH_AUTH_SOCK is not set), %s", usedBy),
+			hint:    "start it with 'eval $(ssh-agent)' and add your key with 'ssh-add', or set a key_path for these hosts",
+		}, true
+	}
+	conn, err := net.DialTimeout("unix", socket, 5*time.Second)
+	if err != nil {
+		return doctorCheck{
+			status:  doctorFail,
+			message: fmt.Sprintf("SSH agent at %s is unreachable: %v", socket, err),
+			hint:    "restart the agent, or set a key_path for these hosts",
+		}, true
+	}
+	defer conn.Close()
+	keys, err := agent.NewClient(conn).List()
+	switch {
+	case err != nil:
+		return doctorCheck{status: doctorFail, message: fmt.Sprintf("SSH agent keys could not be listed: %v", err)}, true
+	case len(keys) == 0:
+		return doctorCheck{
+			status:  doctorFail,
+			message: fmt.Sprintf("SSH agent is running but holds no keys, %s", usedBy),
+			hint:    "add your key with 'ssh-add'",
+		}, true
+	}
+	return doctorCheck{status: doctorOK, message: fmt.Sprintf("SSH agent is running with %d key(s), %s", len(keys), usedBy)}, true
+}
+
+// checkDoctorHosts tests the connection to every enabled host concurrently,
+// as `bm config ssh test` does, returning a check per host in config order.
+func checkDoctorHosts(cfg config.Config) []doctorCheck {
+	var hosts []config.SSHHost
+	for _, host := range cfg.SSHHosts {
+		if !host.Disabled {
+			hosts = append(hosts, host)
+		}
+	}
+	checks := make([]doctorCheck, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = doctorHostCheck(host, runner.TestHost(host))
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// doctorHostCheck turns the connection test of a host into a check.
+func doctorHostCheck(host config.SSHHost, result runner.HostTest) doctorCheck {
+	name := identifierColor.Sprint(host.Name)
+	if result.Error != nil {
+		check := doctorCheck{status: doctorFail, message: fmt.Sprintf("%s: %v", name, result.Error)}
+		switch {
+		case errors.Is(result.Error, ssh.ErrNetwork):
+			check.hint = fmt.Sprintf("check that %s is up and reachable from here, or disable the host with 'bm config ssh edit'", host.DisplayAddress())
+		case errors.Is(result.Error, ssh.ErrHostKey):
+			check.hint = fmt.Sprintf("run 'ssh -p %d %s' once to check and accept its host key", host.SSHPort(), host.User+"@"+host.Hostname)
+		case errors.Is(result.Error, ssh.ErrAuth):
+			check.hint = "check the user, key_path, password or the keys in the SSH agent with 'bm config ssh edit'"
+		case errors.Is(result.Error, ssh.ErrAlgorithm):
+			check.hint = "set the algorithms the host supports in ssh_algorithms"
+		default:
+			check.hint = "check the host's settings with 'bm config ssh edit'"
+		}
+		return check
+	}
+
+	connected := fmt.Sprintf("%s: connected in %s using %s authentication", name, result.Latency.Round(time.Millisecond), result.AuthMethod)
+	switch {
+	case result.RuntimePath == "" && result.RuntimeDetected:
+		return doctorCheck{
+			status:  doctorFail,
+			message: connected + ", but neither podman nor docker with a working compose is in the PATH of " + host.User,
+			hint:    "install podman or docker with compose on the host, or pick the installed one with container_runtime",
+		}
+	case result.RuntimePath == "":
+		return doctorCheck{
+			status:  doctorFail,
+			message: fmt.Sprintf("%s, but %s is not in the PATH of %s", connected, result.Runtime, host.User),
+			hint:    fmt.Sprintf("install %s on the host, or set the host's container_runtime to the installed one", result.Runtime),
+		}
+	case result.RemoteRootError != nil:
+		return doctorCheck{
+			status:  doctorFail,
+			message: fmt.Sprintf("%s, but %v", connected, result.RemoteRootError),
+			hint:    "create ~/bucket or ~/compose-bucket on the host, or set its remote_root with 'bm config ssh edit'",
+		}
+	}
+	return doctorCheck{
+		status:  doctorOK,
+		message: fmt.Sprintf("%s, %s at %s, remote root %s", connected, result.Runtime, result.RuntimePath, result.RemoteRoot),
+	}
+}
+
+// printDoctorCheck prints a check as a line of the checklist, followed by
+// its hint for failures and warnings.
+func printDoctorCheck(check doctorCheck) {
+	switch check.status {
+	case doctorOK:
+		fmt.Printf("  %s %s\n", successColor.Sprint("✓"), check.message)
+	case doctorWarn:
+		fmt.Printf("  %s %s\n", statusPartialColor.Sprint("!"), check.message)
+	case doctorFail:
+		fmt.Printf("  %s %s\n", errorColor.Sprint("✗"), check.message)
+	}
+	if check.hint != "" && check.status != doctorOK {
+		fmt.Printf("    %s\n", dimColor.Sprint("→ "+check.hint))
+	}
+}
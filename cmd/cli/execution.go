@@ -8,18 +8,60 @@
 package cli
 
 import (
+	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
 	"bucket-manager/internal/logger"
 	"bucket-manager/internal/runner"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	xansi "github.com/charmbracelet/x/ansi"
+	"github.com/spf13/cobra"
 )
 
+// stackActionOptions holds the flags of a stack action command, read once by
+// stackActionOptionsFromFlags.
+type stackActionOptions struct {
+	env         []string      // Extra KEY=VALUE variables passed to every compose command
+	profiles    []string      // Compose profiles to enable, nil for each stack's configured defaults
+	timeout     time.Duration // Limit on each stack's sequence, zero for none
+	stopTimeout int           // Seconds containers get to stop gracefully
+	logFile     io.Writer     // Also receives the output, unless nil
+	preview     configPreview
+	resume      bool // Skip the stacks done by the last interrupted run
+	smart       bool // Skip pulling pinned images that are present (up only)
+	detach      bool // Only start the action on each (remote) stack, running on in the background there
+}
+
+// stackActionOptionsFromFlags reads the stack action flags of cmd, exiting on
+// invalid values. Flags cmd doesn't register keep their defaults.
+func stackActionOptionsFromFlags(cmd *cobra.Command) stackActionOptions {
+	opts := stackActionOptions{
+		env:         envFromFlags(cmd),
+		profiles:    profilesFromFlags(cmd),
+		timeout:     timeoutFromFlags(cmd),
+		stopTimeout: stopTimeoutFromFlags(cmd),
+		logFile:     logFileFromFlags(cmd),
+		preview:     configPreviewFromFlags(cmd),
+		resume:      resumeFromFlags(cmd),
+		detach:      detachFromFlags(cmd),
+	}
+	if cmd.Flags().Lookup("smart") != nil {
+		opts.smart = smartFromFlags(cmd)
+	}
+	return opts
+}
+
 // runStackAction locates the target stacks and executes a predefined sequence of runner steps.
 // It handles parsing multiple stack identifiers, discovering the stacks, and executing the
-// specified action (up, down, refresh, or pull) on each stack.
-func runStackAction(action string, args []string) {
+// specified action (up, down, refresh, or pull) on each stack with the given options.
+func runStackAction(action string, args []string, opts stackActionOptions) {
 	if len(args) == 0 {
 		errorColor.Fprintf(os.Stderr, "Error: requires at least one stack identifier argument.\n")
 		os.Exit(1)
@@ -39,10 +81,34 @@ func runStackAction(action string, args []string) {
 
 	var targetStacks []discovery.Stack
 	var allErrors []error
+	seen := make(map[string]bool) // Identifiers already targeted, e.g. by both "server1:" and "server1:app"
+	addTarget := func(stack discovery.Stack) {
+		if !seen[stack.Identifier()] {
+			seen[stack.Identifier()] = true
+			targetStacks = append(targetStacks, stack)
+		}
+	}
 
 	// Discover each stack individually
 	for _, stackIdentifier := range args {
-		stacksToCheck, collectedErrors := discoverTargetStacks(stackIdentifier, nil)
+		// Glob patterns ("web-*", "server1:api-*") target every matching stack
+		if isStackPattern(stackIdentifier) {
+			matches, collectedErrors := findStacksByPattern(stackIdentifier, discovery.RootOverrides{})
+			for _, err := range collectedErrors {
+				allErrors = append(allErrors, fmt.Errorf("stack '%s': %w", stackIdentifier, err))
+			}
+			for _, stack := range matches {
+				addTarget(stack)
+			}
+			logger.Info("Stack pattern matched",
+				"action", action,
+				"stack_pattern", stackIdentifier,
+				"stack_count", len(matches),
+				"error_count", len(collectedErrors))
+			continue
+		}
+
+		stacksToCheck, collectedErrors := discoverTargetStacks(stackIdentifier, nil, discovery.RootOverrides{})
 
 		if len(collectedErrors) > 0 {
 			logger.Error("Stack discovery failed",
@@ -55,6 +121,18 @@ func runStackAction(action string, args []string) {
 			continue
 		}
 
+		// "host:" targets every stack discovered on that host
+		if strings.HasSuffix(stackIdentifier, ":") {
+			for _, stack := range stacksToCheck {
+				addTarget(stack)
+			}
+			logger.Info("Host stacks located successfully",
+				"action", action,
+				"host_identifier", stackIdentifier,
+				"stack_count", len(stacksToCheck))
+			continue
+		}
+
 		targetStack, err := findStackByIdentifier(stacksToCheck, stackIdentifier)
 		if err != nil {
 			logger.Error("Stack not found",
@@ -65,7 +143,7 @@ func runStackAction(action string, args []string) {
 			continue
 		}
 
-		targetStacks = append(targetStacks, targetStack)
+		addTarget(targetStack)
 		logger.Info("Stack located successfully",
 			"action", action,
 			"stack_name", targetStack.Name,
@@ -91,27 +169,106 @@ func runStackAction(action string, args []string) {
 		os.Exit(1)
 	}
 
+	// Detached runs keep going on the stack's host, which a local stack doesn't have
+	if opts.detach {
+		for _, stack := range targetStacks {
+			if !stack.IsRemote {
+				errorColor.Fprintf(os.Stderr, "Error: --detach only works for remote stacks, not %s.\n", plainStackLabel(stack))
+				os.Exit(1)
+			}
+		}
+	}
+	outcome := "completed successfully"
+	if opts.detach {
+		outcome = "started in the background"
+	}
+
+	// Record the progress of batches, and skip what an interrupted one did.
+	// Detached runs are only started, so there is no progress to record.
+	var batch *batchTracker
+	var alreadyDone []string
+	if !opts.detach && (len(targetStacks) > 1 || opts.resume) {
+		var resumed *batchRun
+		batch, resumed = startBatch(action, args, opts.resume)
+		switch {
+		case resumed != nil:
+			alreadyDone = resumed.Succeeded
+			logger.Info("Resuming batch",
+				"action", action,
+				"stack_identifiers", args,
+				"started", resumed.Started,
+				"succeeded_count", len(resumed.Succeeded))
+			statusColor.Printf("Resuming the '%s' run started %s: %d stack(s) already done.\n",
+				action, resumed.Started.Local().Format(time.DateTime), len(resumed.Succeeded))
+		case opts.resume:
+			statusColor.Printf("No interrupted '%s' run of the same stacks to resume, running all of them.\n", action)
+		}
+	}
+
 	// Execute action on each stack
 	var executionErrors []error
+	skipped := 0 // Stacks whose config preview wasn't confirmed
+	resumedSkips := 0
 	for i, targetStack := range targetStacks {
+		if slices.Contains(alreadyDone, targetStack.Identifier()) {
+			statusColor.Printf("Skipping %s, already done.\n", stackLabel(targetStack))
+			resumedSkips++
+			continue
+		}
+
 		if len(targetStacks) > 1 {
-			statusColor.Printf("\n[%d/%d] Executing '%s' action for stack: %s (%s)\n",
-				i+1, len(targetStacks), action, targetStack.Name, identifierColor.Sprint(targetStack.ServerName))
+			statusColor.Printf("\n[%d/%d] Executing '%s' action for stack: %s\n",
+				i+1, len(targetStacks), action, stackLabel(targetStack))
 		} else {
-			statusColor.Printf("Executing '%s' action for stack: %s (%s)\n",
-				action, targetStack.Name, identifierColor.Sprint(targetStack.ServerName))
+			statusColor.Printf("Executing '%s' action for stack: %s\n",
+				action, stackLabel(targetStack))
+		}
+
+		var appliedConfig string
+		if opts.preview.show && (action == "up" || action == "refresh") {
+			cfg, proceed, err := previewStackConfig(targetStack, action, opts.profiles, opts.env, opts.preview)
+			if err != nil {
+				logger.Error("Compose config preview failed",
+					"action", action,
+					"stack_name", targetStack.Name,
+					"server_name", targetStack.ServerName,
+					"error", err)
+				executionErrors = append(executionErrors, fmt.Errorf("'%s' action failed for %s: %w",
+					action, plainStackLabel(targetStack), err))
+				continue
+			}
+			if !proceed {
+				logger.Info("Stack action cancelled after config preview",
+					"action", action,
+					"stack_name", targetStack.Name,
+					"server_name", targetStack.ServerName)
+				statusColor.Printf("Skipped '%s' for %s.\n", action, stackLabel(targetStack))
+				skipped++
+				continue
+			}
+			appliedConfig = cfg
 		}
 
 		var sequence []runner.CommandStep
 		switch action {
 		case "up":
-			sequence = runner.UpSequence(targetStack)
+			if opts.smart {
+				sequence = runner.SmartUpSequence(targetStack, opts.profiles)
+			} else {
+				sequence = runner.UpSequence(targetStack, opts.profiles)
+			}
 		case "down":
-			sequence = runner.DownSequence(targetStack)
+			sequence = runner.DownSequence(targetStack, opts.profiles, opts.stopTimeout)
 		case "refresh":
-			sequence = runner.RefreshSequence(targetStack)
+			sequence = runner.RefreshSequence(targetStack, opts.profiles, opts.stopTimeout)
 		case "pull":
-			sequence = runner.PullSequence(targetStack)
+			sequence = runner.PullSequence(targetStack, opts.profiles)
+		case "create":
+			sequence = runner.CreateSequence(targetStack, opts.profiles)
+		case "stop":
+			sequence = runner.StopSequence(targetStack, opts.profiles, opts.stopTimeout)
+		case "start":
+			sequence = runner.StartSequence(targetStack, opts.profiles)
 		default:
 			logger.Error("Invalid action requested",
 				"action", action,
@@ -120,20 +277,29 @@ func runStackAction(action string, args []string) {
 			os.Exit(1)
 		}
 
+		for i := range sequence {
+			sequence[i].Env = opts.env
+		}
+
 		logger.Debug("Action sequence prepared",
 			"action", action,
 			"stack_name", targetStack.Name,
 			"step_count", len(sequence))
 
-		err := runSequence(targetStack, sequence)
+		var err error
+		if opts.detach {
+			err = runner.RunDetached(targetStack, action, sequence)
+		} else {
+			err = runSequence(targetStack, sequence, opts.timeout, opts.logFile)
+		}
 		if err != nil {
 			logger.Error("Stack action failed",
 				"action", action,
 				"stack_name", targetStack.Name,
 				"server_name", targetStack.ServerName,
 				"error", err)
-			executionErrors = append(executionErrors, fmt.Errorf("'%s' action failed for %s (%s): %w",
-				action, targetStack.Name, targetStack.ServerName, err))
+			executionErrors = append(executionErrors, fmt.Errorf("'%s' action failed for %s: %w",
+				action, plainStackLabel(targetStack), err))
 			continue
 		}
 
@@ -141,36 +307,92 @@ func runStackAction(action string, args []string) {
 			"action", action,
 			"stack_name", targetStack.Name,
 			"server_name", targetStack.ServerName)
-		successColor.Printf("'%s' action completed successfully for %s (%s).\n",
-			action, targetStack.Name, identifierColor.Sprint(targetStack.ServerName))
+		successColor.Printf("'%s' action %s for %s.\n",
+			action, outcome, stackLabel(targetStack))
+		if opts.detach {
+			statusColor.Printf("Its output is written to ~/%s on %s; follow it with: bm logs %s --detached -f\n",
+				runner.DetachedLogPath(targetStack), targetStack.ServerName, targetStack.Identifier())
+		}
+
+		if batch != nil {
+			batch.succeeded(targetStack.Identifier())
+		}
+
+		// A detached run hasn't applied the config yet, and may still fail
+		if appliedConfig != "" && !opts.detach {
+			if err := runner.SaveAppliedConfig(targetStack, appliedConfig); err != nil {
+				logger.Warn("Could not save the applied compose config", "stack", targetStack.Identifier(), "error", err)
+				errorColor.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	// A batch is done once no stack is left to run
+	if batch != nil && len(executionErrors) == 0 && skipped == 0 {
+		batch.finish()
 	}
 
 	// Report execution summary
+	ran := len(targetStacks) - resumedSkips
 	if len(executionErrors) > 0 {
 		errorColor.Fprintf(os.Stderr, "\n%d stack(s) failed:\n", len(executionErrors))
 		for _, err := range executionErrors {
 			errorColor.Fprintf(os.Stderr, "- %v\n", err)
 		}
 
-		if len(executionErrors) < len(targetStacks) {
-			successColor.Printf("\n%d stack(s) completed successfully.\n", len(targetStacks)-len(executionErrors))
+		if completed := ran - len(executionErrors) - skipped; completed > 0 {
+			successColor.Printf("\n%d stack(s) %s.\n", completed, outcome)
+		}
+		if batch != nil {
+			statusColor.Println("Run the same command with --resume to skip the stacks that succeeded.")
+		}
+		// A single failed stack exits with its command's status, so scripts can tell failures apart
+		var exitErr *runner.ExitError
+		if len(targetStacks) == 1 && errors.As(executionErrors[0], &exitErr) && exitErr.Status > 0 && exitErr.Status < 256 {
+			os.Exit(exitErr.Status)
 		}
 		os.Exit(1)
+	} else if skipped > 0 {
+		if completed := ran - skipped; completed > 0 {
+			successColor.Printf("\n%d stack(s) %s, %d skipped.\n", completed, outcome, skipped)
+		}
+	} else if resumedSkips > 0 {
+		successColor.Printf("\nAll %d remaining stack(s) %s (%d done before).\n", ran, outcome, resumedSkips)
 	} else {
 		if len(targetStacks) > 1 {
-			successColor.Printf("\nAll %d stack(s) completed successfully.\n", len(targetStacks))
+			successColor.Printf("\nAll %d stack(s) %s.\n", len(targetStacks), outcome)
 		}
 	}
 }
 
-// runSequence executes a series of command steps for a given stack.
-func runSequence(stack discovery.Stack, sequence []runner.CommandStep) error {
+// runSequence executes a series of command steps for a given stack. A non-zero
+// timeout bounds the whole sequence, stopping the step running when it expires.
+// If logFile isn't nil, the steps' output is also written to it without ANSI
+// escape sequences, and is then no longer sent straight to the terminal.
+func runSequence(stack discovery.Stack, sequence []runner.CommandStep, timeout time.Duration, logFile io.Writer) error {
 	logger.Debug("Command sequence started",
 		"stack_name", stack.Name,
 		"server_name", stack.ServerName,
 		"step_count", len(sequence))
 
+	// Fail fast if another operation is running on the stack
+	if runner.SequenceNeedsLock(sequence) {
+		lock, err := runner.LockStack(stack)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+	}
+
+	deadline := time.Now().Add(timeout)
 	for i, step := range sequence {
+		if timeout > 0 {
+			step.Timeout = time.Until(deadline)
+			if step.Timeout <= 0 {
+				return &runner.TimeoutError{Timeout: timeout}
+			}
+		}
+
 		logger.Debug("Step starting",
 			"step_index", i+1,
 			"step_name", step.Name,
@@ -179,14 +401,16 @@ func runSequence(stack discovery.Stack, sequence []runner.CommandStep) error {
 			"command", step.Command,
 			"args", step.Args)
 
-		stepColor.Printf("\n--- Running Step: %s for %s (%s) ---\n", step.Name, stack.Name, identifierColor.Sprint(stack.ServerName))
+		stepColor.Printf("\n--- Running Step: %s for %s ---\n", step.Name, stackLabel(stack))
+		writeLogFile(logFile, "\n--- Running Step: %s for %s ---\n", step.Name, plainStackLabel(stack))
 
-		outChan, errChan := runner.StreamCommand(step, true)
+		cliMode := logFile == nil
+		outChan, errChan := runner.StreamCommand(step, cliMode)
 
 		var stepErr error
 		var wg sync.WaitGroup
 
-		if !step.Stack.IsRemote {
+		if cliMode && !step.Stack.IsRemote {
 			stepErr = <-errChan
 			fmt.Println()
 		} else {
@@ -194,7 +418,12 @@ func runSequence(stack discovery.Stack, sequence []runner.CommandStep) error {
 			go func() {
 				defer wg.Done()
 				for outputLine := range outChan {
-					fmt.Fprint(os.Stdout, outputLine.Line)
+					if outputLine.IsError {
+						fmt.Fprint(os.Stderr, outputLine.Line)
+					} else {
+						fmt.Fprint(os.Stdout, outputLine.Line)
+					}
+					writeLogFile(logFile, "%s", xansi.Strip(outputLine.Line))
 				}
 			}()
 
@@ -210,7 +439,18 @@ func runSequence(stack discovery.Stack, sequence []runner.CommandStep) error {
 				"stack_name", stack.Name,
 				"server_name", stack.ServerName,
 				"error", stepErr)
-			return fmt.Errorf("step '%s' failed", step.Name)
+			writeLogFile(logFile, "\n--- Step '%s' failed for %s (%s): %v ---\n", step.Name, stack.Name, stack.ServerName, stepErr)
+			// The step description is already in the output above, so only
+			// the exit status or timeout is kept when the command itself failed
+			var timeoutErr *runner.TimeoutError
+			if errors.As(stepErr, &timeoutErr) {
+				return fmt.Errorf("step '%s' stopped: %w", step.Name, &runner.TimeoutError{Timeout: timeout})
+			}
+			var exitErr *runner.ExitError
+			if errors.As(stepErr, &exitErr) {
+				return fmt.Errorf("step '%s' failed: %w", step.Name, exitErr)
+			}
+			return fmt.Errorf("step '%s' failed: %w", step.Name, stepErr)
 		}
 
 		logger.Debug("Step completed successfully",
@@ -218,7 +458,8 @@ func runSequence(stack discovery.Stack, sequence []runner.CommandStep) error {
 			"step_name", step.Name,
 			"stack_name", stack.Name,
 			"server_name", stack.ServerName)
-		successColor.Printf("--- Step '%s' completed successfully for %s (%s) ---\n", step.Name, stack.Name, identifierColor.Sprint(stack.ServerName))
+		successColor.Printf("--- Step '%s' completed successfully for %s ---\n", step.Name, stackLabel(stack))
+		writeLogFile(logFile, "\n--- Step '%s' completed successfully for %s ---\n", step.Name, plainStackLabel(stack))
 	}
 
 	logger.Debug("Command sequence completed",
@@ -228,8 +469,187 @@ func runSequence(stack discovery.Stack, sequence []runner.CommandStep) error {
 	return nil
 }
 
-// runHostAction executes a host-level action (like prune) on one or more targets.
-func runHostAction(actionName string, targets []runner.HostTarget) error {
+// addEnvFlag registers the repeatable -e/--env flag on a stack action command.
+func addEnvFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayP("env", "e", nil, "Set an environment variable for the compose commands (KEY=VALUE, repeatable)")
+}
+
+// addStopTimeoutFlag registers the --stop-timeout flag on commands that stop containers.
+func addStopTimeoutFlag(cmd *cobra.Command) {
+	cmd.Flags().Int("stop-timeout", 0, "Seconds containers get to stop gracefully before they are killed (default: compose's 10)")
+}
+
+// stopTimeoutFromFlags returns the --stop-timeout value, or
+// runner.DefaultStopTimeout if the flag isn't given (or isn't registered).
+func stopTimeoutFromFlags(cmd *cobra.Command) int {
+	if !cmd.Flags().Changed("stop-timeout") {
+		return runner.DefaultStopTimeout
+	}
+	stopTimeout, _ := cmd.Flags().GetInt("stop-timeout")
+	if stopTimeout < 0 {
+		errorColor.Fprintf(os.Stderr, "Error: --stop-timeout must not be negative\n")
+		os.Exit(1)
+	}
+	return stopTimeout
+}
+
+// requireWritable exits with a read-only mode error if read-only mode is
+// enabled, naming the refused action (e.g. "stopping stacks").
+func requireWritable(action string) {
+	if err := config.CheckWritable(action); err != nil {
+		logger.Warn("Action refused in read-only mode", "action", action)
+		errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// addLogToFlag registers the --log-to flag on a command that streams stack output.
+func addLogToFlag(cmd *cobra.Command) {
+	cmd.Flags().String("log-to", "", "Also write the output to this file, without colors (appended to if it exists)")
+}
+
+// logFileFromFlags opens the --log-to file for appending, creating it if
+// needed, or returns nil if the flag isn't given. The file stays open until
+// the command exits.
+func logFileFromFlags(cmd *cobra.Command) io.Writer {
+	path, _ := cmd.Flags().GetString("log-to")
+	if path == "" {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		errorColor.Fprintf(os.Stderr, "Error: could not open --log-to file: %v\n", err)
+		os.Exit(1)
+	}
+	logger.Info("Writing operation output to file", "command", cmd.Name(), "path", path)
+	fmt.Fprintf(file, "=== bm %s, %s ===\n", strings.Join(redactEnvArgs(os.Args[1:]), " "), time.Now().Format(time.RFC3339))
+	return file
+}
+
+// redactEnvArgs returns args with the values of -e/--env replaced by "***", so
+// that secrets passed to compose aren't written to the --log-to file.
+func redactEnvArgs(args []string) []string {
+	redacted := slices.Clone(args)
+	redact := func(assignment string) string {
+		if key, _, ok := strings.Cut(assignment, "="); ok {
+			return key + "=***"
+		}
+		return assignment // KEY alone takes the value from bm's environment
+	}
+	for i := 0; i < len(redacted); i++ {
+		switch arg := redacted[i]; {
+		case arg == "--":
+			return redacted
+		case arg == "-e" || arg == "--env":
+			if i+1 < len(redacted) {
+				i++
+				redacted[i] = redact(redacted[i])
+			}
+		case strings.HasPrefix(arg, "--env="):
+			redacted[i] = "--env=" + redact(strings.TrimPrefix(arg, "--env="))
+		case strings.HasPrefix(arg, "-e") && !strings.HasPrefix(arg, "--"):
+			redacted[i] = "-e" + redact(strings.TrimPrefix(strings.TrimPrefix(arg, "-e"), "="))
+		}
+	}
+	return redacted
+}
+
+// writeLogFile writes formatted output to the --log-to file, if there is one.
+// Write errors are logged, not reported: they shouldn't stop the operation.
+func writeLogFile(logFile io.Writer, format string, args ...any) {
+	if logFile == nil {
+		return
+	}
+	if _, err := fmt.Fprintf(logFile, format, args...); err != nil {
+		logger.Warn("Could not write to --log-to file", "error", err)
+	}
+}
+
+// addProfileFlag registers the repeatable --compose-profile flag on a stack action command.
+func addProfileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("compose-profile", nil, "Enable a compose profile, replacing the stack's default profiles (repeatable)")
+}
+
+// profilesFromFlags returns the --compose-profile values, or nil (the stacks'
+// configured default profiles) if the flag isn't given.
+func profilesFromFlags(cmd *cobra.Command) []string {
+	if !cmd.Flags().Changed("compose-profile") {
+		return nil
+	}
+	profiles, _ := cmd.Flags().GetStringArray("compose-profile")
+	for _, profile := range profiles {
+		if strings.TrimSpace(profile) == "" || strings.HasPrefix(profile, "-") {
+			errorColor.Fprintf(os.Stderr, "Error: invalid compose profile name '%s'\n", profile)
+			os.Exit(1)
+		}
+	}
+	return profiles
+}
+
+// addSmartFlag registers the --smart flag on a command that brings stacks up.
+func addSmartFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("smart", false, "Skip pulling images that are pinned by digest and already present; images referenced by tag are always pulled (default: smart_up from the config)")
+}
+
+// smartFromFlags returns whether --smart is given, on a command registered with addSmartFlag.
+func smartFromFlags(cmd *cobra.Command) bool {
+	smart, _ := cmd.Flags().GetBool("smart")
+	return smart
+}
+
+// addDetachFlag registers the --detach flag on a stack action command. Detached
+// runs have no local output to time out or log, and their outcome isn't known
+// when bm exits, so it excludes --timeout, --log-to and --resume, which must be
+// registered first.
+func addDetachFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("detach", false, "Start the action in the background on each remote stack's host, so that it survives the SSH connection closing; follow it with 'bm logs --detached'")
+	cmd.MarkFlagsMutuallyExclusive("detach", "timeout")
+	cmd.MarkFlagsMutuallyExclusive("detach", "log-to")
+	cmd.MarkFlagsMutuallyExclusive("detach", "resume")
+}
+
+// detachFromFlags returns whether --detach is given, false if it isn't registered.
+func detachFromFlags(cmd *cobra.Command) bool {
+	detach, _ := cmd.Flags().GetBool("detach")
+	return detach
+}
+
+// addTimeoutFlag registers the --timeout flag bounding a single operation.
+func addTimeoutFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration("timeout", 0, "Stop an operation that runs longer than this on a stack or host (e.g. 10m; default: operation_timeout from the config, or no limit)")
+}
+
+// timeoutFromFlags returns the --timeout value, or the configured
+// operation_timeout if the flag isn't given. Zero means no limit.
+func timeoutFromFlags(cmd *cobra.Command) time.Duration {
+	if cmd.Flags().Changed("timeout") {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if timeout < 0 {
+			errorColor.Fprintf(os.Stderr, "Error: --timeout must not be negative\n")
+			os.Exit(1)
+		}
+		return timeout
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Warn("Could not load config to check operation_timeout", "error", err)
+	}
+	return cfg.GetOperationTimeout()
+}
+
+// envFromFlags returns the validated --env assignments, exiting on invalid input.
+func envFromFlags(cmd *cobra.Command) []string {
+	env, _ := cmd.Flags().GetStringArray("env")
+	if err := runner.ValidateEnv(env); err != nil {
+		errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return env
+}
+
+// runHostAction executes a host-level action (like prune) on one or more targets,
+// using buildStep to create the command step for each target.
+func runHostAction(actionName string, targets []runner.HostTarget, timeout time.Duration, buildStep func(runner.HostTarget) runner.HostCommandStep) error {
 	logger.Info("Host action started",
 		"action", actionName,
 		"target_count", len(targets))
@@ -247,19 +667,8 @@ func runHostAction(actionName string, targets []runner.HostTarget) error {
 				"server_name", t.ServerName,
 				"is_remote", t.IsRemote)
 
-			var step runner.HostCommandStep
-			switch actionName {
-			case "prune":
-				step = runner.PruneHostStep(t)
-			default:
-				err := fmt.Errorf("internal error: unknown host action '%s'", actionName)
-				logger.Error("Unknown host action",
-					"action", actionName,
-					"server_name", t.ServerName,
-					"error", err)
-				errChan <- err
-				return
-			}
+			step := buildStep(t)
+			step.Timeout = timeout
 
 			stepColor.Printf("\n--- Running Step: %s for host %s ---\n", step.Name, identifierColor.Sprint(t.ServerName))
 			outChan, stepErrChan := runner.RunHostCommand(step, true)
@@ -286,6 +695,10 @@ func runHostAction(actionName string, targets []runner.HostTarget) error {
 
 			if stepErr != nil {
 				err := fmt.Errorf("step '%s' failed for host %s", step.Name, t.ServerName)
+				var timeoutErr *runner.TimeoutError
+				if errors.As(stepErr, &timeoutErr) {
+					err = fmt.Errorf("%w: %w", err, timeoutErr)
+				}
 				logger.Error("Host action step failed",
 					"action", actionName,
 					"step_name", step.Name,
@@ -293,6 +706,7 @@ func runHostAction(actionName string, targets []runner.HostTarget) error {
 					"is_remote", t.IsRemote,
 					"error", stepErr)
 				logger.Errorf("%v", err)
+				errorColor.Fprintf(os.Stderr, "--- %v ---\n", err)
 				errChan <- err
 				return
 			}
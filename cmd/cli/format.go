@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's format.go implements the --format option of `bm list` and
+// `bm status`, which renders each stack through a Go text/template in the
+// style of `docker ps --format`.
+
+package cli
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/runner"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// stackFormatData holds the fields available to --format templates.
+type stackFormatData struct {
+	Name           string // Stack name
+	ServerName     string // "local" or the remote host name
+	Identifier     string // Full identifier, e.g. "server1:app"
+	DisplayName    string // The stack as shown with identifier_format, or Identifier
+	Path           string // Stack directory (relative to the remote root for remote stacks)
+	IsRemote       bool   // True for stacks on remote hosts
+	ProjectName    string // Compose project name; empty if only compose can resolve it
+	Status         string // Overall status (UP, DOWN, PARTIAL, ERROR); empty for `bm list`
+	ContainerCount int    // Number of containers; 0 for `bm list`
+	RunningCount   int    // Number of running containers; 0 for `bm list`
+	Stale          bool   // True if the compose files changed since the containers were created
+	Error          string // Status check error, if any
+}
+
+// formatFlagUsage is the help text shared by the --format flags.
+const formatFlagUsage = "Print each stack using a Go template, e.g. '{{.Identifier}} {{.Status}}' " +
+	"(fields: Name, ServerName, Identifier, DisplayName, Path, IsRemote, ProjectName, Status, ContainerCount, RunningCount, Stale, Error)"
+
+// parseFormatTemplate compiles a --format template. It returns nil if format is
+// empty, so callers can fall back to the default output.
+func parseFormatTemplate(format string) (*template.Template, error) {
+	if format == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("format").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// newStackFormatData builds template data for a stack. statusInfo may be nil
+// when only discovery information is available.
+func newStackFormatData(stack discovery.Stack, statusInfo *runner.StackRuntimeInfo) stackFormatData {
+	data := stackFormatData{
+		Name:        stack.Name,
+		ServerName:  stack.ServerName,
+		Identifier:  stack.Identifier(),
+		DisplayName: stack.DisplayName(),
+		Path:        stack.Path,
+		IsRemote:    stack.IsRemote,
+		ProjectName: stack.ProjectName,
+	}
+	if statusInfo != nil {
+		data.Status = string(statusInfo.OverallStatus)
+		data.ContainerCount = len(statusInfo.Containers)
+		for _, c := range statusInfo.Containers {
+			if isContainerUp(c.Status) {
+				data.RunningCount++
+			}
+		}
+		data.Stale = statusInfo.IsStale()
+		if statusInfo.Error != nil {
+			data.Error = statusInfo.Error.Error()
+		}
+	}
+	return data
+}
+
+// stackLabel names a stack in command output: "name (server)", or the stack as
+// shown with identifier_format if one is set.
+func stackLabel(stack discovery.Stack) string {
+	if discovery.HasIdentifierFormat() {
+		return identifierColor.Sprint(stack.DisplayName())
+	}
+	return fmt.Sprintf("%s (%s)", stack.Name, identifierColor.Sprint(stack.ServerName))
+}
+
+// plainStackLabel is stackLabel without color, for error messages.
+func plainStackLabel(stack discovery.Stack) string {
+	if discovery.HasIdentifierFormat() {
+		return stack.DisplayName()
+	}
+	return fmt.Sprintf("%s (%s)", stack.Name, stack.ServerName)
+}
+
+// printFormatted renders data with tmpl on its own line. Execution errors (such as
+// an unknown field) are reported to stderr and make the command exit with status 1.
+func printFormatted(tmpl *template.Template, data stackFormatData) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		errorColor.Fprintf(os.Stderr, "Error executing --format template: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(b.String())
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's host.go implements `bm host`, which groups actions run on
+// hosts themselves rather than on stacks, such as restarting the container runtime.
+
+package cli
+
+import (
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/runner"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var hostCmd = &cobra.Command{
+	Use:   "host",
+	Short: "Run actions on hosts",
+}
+
+var hostRestartRuntimeCmd = &cobra.Command{
+	Use:     "restart-podman <host-identifier...>",
+	Aliases: []string{"restart-runtime"},
+	Short:   "Restart the container runtime on hosts",
+	Long: `Restarts the container runtime on the specified hosts ('local', remote host
+names or '@group') by running their runtime restart command, after asking for
+confirmation unless --yes is given. The command defaults to
+'systemctl --user restart podman' (or 'systemctl restart docker' with the docker
+runtime) and can be changed with runtime_restart_command, globally or per host,
+e.g. 'systemctl restart podman' for rootful podman.`,
+	Example: `  bm host restart-podman server1
+  bm host restart-podman local server2 -y
+  bm host restart-podman @prod`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: hostCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("restarting the container runtime")
+		targets := loadHostTargets(args)
+
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+			confirmed, err := promptConfirm(fmt.Sprintf("Restart the container runtime on %d host(s)?", len(targets)))
+			if err != nil {
+				errorColor.Fprintf(os.Stderr, "\nCould not read confirmation (%v); use --yes to restart without prompting.\n", err)
+				os.Exit(1)
+			}
+			if !confirmed {
+				fmt.Println("Restart cancelled.")
+				return
+			}
+		}
+
+		err := runHostAction("runtime restart", targets, timeoutFromFlags(cmd), runner.RestartRuntimeHostStep)
+		if err != nil {
+			logger.Errorf("\nRuntime restart failed for one or more hosts: %v", err)
+			os.Exit(1)
+		}
+
+		successColor.Println("\nContainer runtime restarted on all targeted hosts.")
+	},
+}
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's host_status.go implements `bm status --hosts`, which reports
+// disk usage on the local machine and configured remote hosts.
+
+package cli
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/runner"
+	"bucket-manager/internal/util"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// resolveHostTargets builds host targets from host names. With no names, it returns
+// "local" plus every enabled remote host. A trailing ':' on a name is ignored so
+// identifiers like "server1:" can be used as well, and "@group" stands for the
+// members of a host group, leaving out its disabled hosts.
+func resolveHostTargets(cfg config.Config, hostNames []string) ([]runner.HostTarget, error) {
+	var targets []runner.HostTarget
+	if len(hostNames) == 0 {
+		targets = append(targets, runner.HostTarget{IsRemote: false, ServerName: "local"})
+		for i := range cfg.SSHHosts {
+			host := cfg.SSHHosts[i]
+			if !host.Disabled {
+				targets = append(targets, runner.HostTarget{IsRemote: true, HostConfig: &host, ServerName: host.Name})
+			}
+		}
+		return targets, nil
+	}
+
+	var names []string
+	groupMembers := make(map[string]bool) // Hosts named through a group only
+	for _, name := range hostNames {
+		name = strings.TrimSuffix(name, ":")
+		if !strings.HasPrefix(name, "@") {
+			names = append(names, name)
+			groupMembers[name] = false
+			continue
+		}
+		members, err := cfg.ExpandHostGroups([]string{name})
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			if _, named := groupMembers[member]; !named {
+				groupMembers[member] = true
+			}
+		}
+		names = append(names, members...)
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if name == "local" {
+			targets = append(targets, runner.HostTarget{IsRemote: false, ServerName: "local"})
+			continue
+		}
+		found := false
+		for i := range cfg.SSHHosts {
+			host := cfg.SSHHosts[i]
+			if host.Name == name {
+				if host.Disabled && groupMembers[name] {
+					found = true
+					break
+				}
+				if host.Disabled {
+					return nil, fmt.Errorf("host '%s' is disabled", name)
+				}
+				targets = append(targets, runner.HostTarget{IsRemote: true, HostConfig: &host, ServerName: host.Name})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("host identifier '%s' not found in configuration", name)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("every host in %s is disabled", strings.Join(hostNames, ", "))
+	}
+	return targets, nil
+}
+
+// runHostStatus prints disk usage for the given hosts (or all hosts if none are given),
+// warning when free space is below the configured threshold.
+func runHostStatus(hostNames []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		errorColor.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	targets, err := resolveHostTargets(cfg, hostNames)
+	if err != nil {
+		errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	warnBelow := float64(cfg.GetDiskWarnFreePercent())
+
+	s := newSpinner(" Checking host disk usage...")
+	s.Start()
+
+	results := make([]runner.HostDiskUsage, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(idx int, t runner.HostTarget) {
+			defer wg.Done()
+			results[idx] = runner.GetHostDiskUsage(t)
+		}(i, target)
+	}
+	wg.Wait()
+	s.Stop()
+
+	failed := false
+	for _, usage := range results {
+		fmt.Printf("\nHost: %s\n", identifierColor.Sprint(usage.Target.ServerName))
+		if usage.Error != nil {
+			failed = true
+			errorColor.Fprintf(os.Stderr, "  Error checking disk usage: %v\n", usage.Error)
+			continue
+		}
+		for _, fs := range usage.Filesystems {
+			line := fmt.Sprintf("  %-30s %8s free of %-8s (%.0f%% free)",
+				fs.MountPoint, util.FormatKiB(fs.AvailableKiB), util.FormatKiB(fs.TotalKiB), fs.FreePercent())
+			if fs.FreePercent() < warnBelow {
+				statusPartialColor.Println(line + " ⚠")
+			} else {
+				fmt.Println(line)
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's images.go implements `bm images`, which lists container images
+// on hosts, and `bm images prune`, which removes only selected unused images.
+
+package cli
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/runner"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images [host-identifier...]",
+	Short: "List container images on hosts",
+	Long: `Lists container images with their size and age on the specified hosts.
+Targets can be 'local', remote host names, '@group' for the hosts of a group, or
+left empty to list images on ALL enabled hosts. Use 'bm images prune' to remove selected images.`,
+	Example: `  bm images            # List images on every host
+  bm images server1    # List images only on 'server1'
+  bm images @prod      # List images on the hosts of the 'prod' group`,
+	ValidArgsFunction: hostCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		targets := loadHostTargets(args)
+
+		s := newSpinner(" Listing images...")
+		s.Start()
+
+		results := make([]runner.HostImages, len(targets))
+		var wg sync.WaitGroup
+		for i, target := range targets {
+			wg.Add(1)
+			go func(idx int, t runner.HostTarget) {
+				defer wg.Done()
+				results[idx] = runner.GetHostImages(t)
+			}(i, target)
+		}
+		wg.Wait()
+		s.Stop()
+
+		failed := false
+		for _, result := range results {
+			fmt.Printf("\nHost: %s\n", identifierColor.Sprint(result.Target.ServerName))
+			if result.Error != nil {
+				failed = true
+				errorColor.Fprintf(os.Stderr, "  Error listing images: %v\n", result.Error)
+				continue
+			}
+			printImageTable(result.Images)
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var imagesPruneCmd = &cobra.Command{
+	Use:   "prune [host-identifier...]",
+	Short: "Remove selected unused images on hosts",
+	Long: `Removes unused images on the specified hosts, without touching containers,
+networks or volumes. At least one of --dangling or --until is required:
+--dangling limits removal to untagged images, and --until limits it to images
+older than the given duration. Without --dangling, any image not used by a
+container can be removed.`,
+	Example: `  bm images prune server1 --dangling        # Remove untagged images on 'server1'
+  bm images prune local --until 168h        # Remove unused images older than a week
+  bm images prune --dangling --until 72h    # Both filters, on every host`,
+	ValidArgsFunction: hostCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		danglingOnly, _ := cmd.Flags().GetBool("dangling")
+		until, _ := cmd.Flags().GetDuration("until")
+		if !danglingOnly && until <= 0 {
+			errorColor.Fprintln(os.Stderr, "Error: specify --dangling and/or --until to select the images to remove (use 'bm prune' for a full cleanup).")
+			os.Exit(1)
+		}
+
+		requireWritable("pruning images")
+		targets := loadHostTargets(args)
+		opts := runner.ImagePruneOptions{DanglingOnly: danglingOnly, Until: until}
+		err := runHostAction("image prune", targets, timeoutFromFlags(cmd), func(t runner.HostTarget) runner.HostCommandStep {
+			return runner.PruneImagesHostStep(t, opts)
+		})
+		if err != nil {
+			logger.Errorf("\nImage prune failed for one or more hosts: %v", err)
+			os.Exit(1)
+		}
+
+		successColor.Println("\nImage prune completed for all targeted hosts.")
+	},
+}
+
+// loadHostTargets loads the configuration and resolves the given host names,
+// exiting on failure.
+func loadHostTargets(hostNames []string) []runner.HostTarget {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		errorColor.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	targets, err := resolveHostTargets(cfg, hostNames)
+	if err != nil {
+		errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return targets
+}
+
+// printImageTable prints images as an indented table.
+func printImageTable(images []runner.ImageInfo) {
+	if len(images) == 0 {
+		fmt.Println("  No images found.")
+		return
+	}
+
+	repoWidth := len("REPOSITORY")
+	tagWidth := len("TAG")
+	for _, img := range images {
+		repoWidth = max(repoWidth, len(img.Repository))
+		tagWidth = max(tagWidth, len(img.Tag))
+	}
+
+	format := fmt.Sprintf("  %%-%ds  %%-%ds  %%-12s  %%8s  %%6s\n", repoWidth, tagWidth)
+	fmt.Printf(format, "REPOSITORY", "TAG", "IMAGE ID", "SIZE", "AGE")
+	fmt.Println("  " + strings.Repeat("-", repoWidth+tagWidth+12+8+6+8))
+	for _, img := range images {
+		fmt.Printf(format, img.Repository, img.Tag, img.ID, img.Size, img.Age)
+	}
+}
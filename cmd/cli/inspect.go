@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's inspect.go implements `bm inspect`, which shows everything
+// bucket-manager knows about one stack: where it was discovered, its current
+// status and its resolved compose configuration.
+
+package cli
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/runner"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <stack-identifier>",
+	Short: "Show discovery, status and compose details of a stack",
+	Long: `Shows everything bucket-manager knows about a stack, for debugging: where it
+was discovered (path, host and remote root), the user its commands run as, its
+custom actions, its status and containers, the URLs of its published ports, and
+the project name and services of its compose configuration as resolved by
+'compose config'.
+
+With -o json, the same information is printed as JSON. SSH passwords are never
+included.`,
+	Example: `  bm inspect my-app
+  bm inspect server1:api
+  bm inspect server1:api -o json | jq .compose.services`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: stackCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "json" {
+			errorColor.Fprintf(os.Stderr, "Error: invalid --output '%s', expected text or json\n", output)
+			os.Exit(1)
+		}
+		if strings.HasSuffix(args[0], ":") {
+			errorColor.Fprintf(os.Stderr, "Error: inspect shows a single stack, not every stack on '%s'.\n", args[0])
+			os.Exit(1)
+		}
+
+		s := newSpinner(fmt.Sprintf(" Inspecting %s...", identifierColor.Sprint(args[0])))
+		if output == "text" {
+			s.Start()
+		}
+		inspection, err := inspectStack(args[0])
+		s.Stop()
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if output == "json" {
+			data, err := json.MarshalIndent(inspection, "", "  ")
+			if err != nil {
+				errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			return
+		}
+		printStackInspection(inspection)
+	},
+}
+
+// stackInspection is what `bm inspect` shows about a stack. Its JSON form
+// matches the stacks of the web API, with the compose configuration added.
+type stackInspection struct {
+	discovery.Stack                         // Redacted with Stack.Redacted
+	Identifier      string                  `json:"identifier"`
+	FullPath        string                  `json:"fullPath"`               // Path on the stack's host, see Stack.FullPath
+	RunAsUser       string                  `json:"runAsUser,omitempty"`    // User its commands run as on a remote host, if not the SSH user
+	Status          runner.StackStatus      `json:"status"`                 // Current running status of the stack
+	Containers      []runner.ContainerState `json:"containers,omitempty"`   // Containers reported by compose ps
+	StatusError     string                  `json:"statusError,omitempty"`  // Why the status couldn't be determined, if it couldn't
+	Stale           bool                    `json:"stale,omitempty"`        // The compose files changed since the containers were created
+	URLs            []runner.StackURL       `json:"urls,omitempty"`         // Where its published TCP ports may be reached
+	Compose         *runner.ComposeConfig   `json:"compose,omitempty"`      // Resolved compose configuration; nil for quadlets or if it couldn't be read
+	ComposeError    string                  `json:"composeError,omitempty"` // Why the compose configuration couldn't be read, if it couldn't
+}
+
+// inspectStack discovers the stack with the given identifier and gathers its
+// status and compose configuration, which are read at the same time.
+func inspectStack(identifier string) (stackInspection, error) {
+	stack, err := findSingleStack(identifier)
+	if err != nil {
+		return stackInspection{}, err
+	}
+
+	var statusInfo runner.StackRuntimeInfo
+	var composeConfig runner.ComposeConfig
+	var composeErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		statusInfo = runner.GetStackStatus(stack)
+	}()
+	if stack.Quadlet == nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			composeConfig, composeErr = runner.GetStackComposeConfig(stack)
+		}()
+	}
+	wg.Wait()
+
+	inspection := stackInspection{
+		Stack:      stack.Redacted(),
+		Identifier: stack.Identifier(),
+		FullPath:   stack.FullPath(),
+		Status:     statusInfo.OverallStatus,
+		Containers: statusInfo.Containers,
+		Stale:      statusInfo.IsStale(),
+		URLs:       runner.StackURLs(statusInfo),
+	}
+	if stack.HostConfig != nil && stack.Quadlet == nil {
+		inspection.RunAsUser = stack.HostConfig.RunAsUserFor(stack.Name)
+	}
+	if statusInfo.Error != nil {
+		inspection.StatusError = statusInfo.Error.Error()
+	}
+	switch {
+	case composeErr != nil:
+		inspection.ComposeError = composeErr.Error()
+	case stack.Quadlet == nil:
+		inspection.Compose = &composeConfig
+	}
+	return inspection, nil
+}
+
+// printStackInspection prints a stack inspection as text.
+func printStackInspection(in stackInspection) {
+	field := func(label, value string) {
+		fmt.Printf("  %-14s %s\n", label+":", value)
+	}
+
+	fmt.Printf("Stack: %s\n", stackLabel(in.Stack))
+	field("Identifier", in.Identifier)
+	field("Path", in.FullPath)
+	if in.IsRemote {
+		field("Remote root", in.AbsoluteRemoteRoot)
+		if in.HostConfig != nil {
+			field("Host", fmt.Sprintf("%s (%s)", in.HostConfig.Name, in.HostConfig.DisplayAddress()))
+		}
+		if in.RunAsUser != "" {
+			field("Run as", in.RunAsUser)
+		}
+	}
+	if in.ProjectName != "" {
+		field("Project name", in.ProjectName)
+	}
+	if in.Quadlet != nil {
+		quadlet := in.Quadlet.Unit
+		if in.Quadlet.Image != "" {
+			quadlet += " (" + in.Quadlet.Image + ")"
+		}
+		field("Quadlet", quadlet)
+	}
+	if len(in.Actions) > 0 {
+		field("Actions", strings.Join(slices.Sorted(maps.Keys(in.Actions)), ", "))
+	}
+
+	fmt.Println("\nStatus:")
+	color := statusErrorColor
+	switch in.Status {
+	case runner.StatusUp:
+		color = statusUpColor
+	case runner.StatusPartial:
+		color = statusPartialColor
+	case runner.StatusDown:
+		color = statusDownColor
+	}
+	status := color.Sprint(in.Status)
+	if in.Stale {
+		status += statusPartialColor.Sprint(" [stale: compose files changed since the containers were created]")
+	}
+	field("Status", status)
+	if in.StatusError != "" {
+		field("Error", errorColor.Sprint(in.StatusError))
+	}
+	for i, url := range in.URLs {
+		label := ""
+		if i == 0 {
+			label = "URLs:"
+		}
+		fmt.Printf("  %-14s %s (%s)\n", label, url.URL, url.Service)
+	}
+	if len(in.Containers) > 0 {
+		printContainerTable(in.Containers, true)
+	}
+
+	if in.Compose != nil || in.ComposeError != "" {
+		fmt.Println("\nCompose:")
+		if in.ComposeError != "" {
+			field("Error", errorColor.Sprint(in.ComposeError))
+			return
+		}
+		field("Project name", in.Compose.ProjectName)
+		for i, service := range in.Compose.Services {
+			label := ""
+			if i == 0 {
+				label = "Services:"
+			}
+			image := service.Image
+			if image == "" {
+				image = dimColor.Sprint("(built)")
+			}
+			fmt.Printf("  %-14s %s %s\n", label, padRight(service.Name, 24), image)
+		}
+	}
+}
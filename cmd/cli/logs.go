@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's logs.go implements `bm logs`, which shows (and optionally
+// follows) the logs of a stack, or of its last detached run with --detached,
+// filtered by a regular expression with --grep.
+
+package cli
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/runner"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <stack-identifier> [service...]",
+	Short: "Show the logs of a stack",
+	Long: `Shows the most recent logs of a stack's services, or only of the given services.
+With --follow, new log lines are streamed until interrupted.
+
+--grep only prints the lines matching a regular expression (Go syntax, e.g.
+'(?i)error|warn' for a case-insensitive match). The lines are filtered as they
+arrive, for local and remote stacks alike.
+
+--detached shows the output of the last action started on a remote stack with
+--detach instead, which ends with a "bm: finished" line once the action is done.`,
+	Example: `  bm logs my-app
+  bm logs server1:api web -f
+  bm logs app --grep ERROR -f
+  bm logs app --grep '(?i)warn|error' --tail 1000
+  bm logs server1:api --detached -f`,
+	Args: cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp // Service names aren't completed
+		}
+		return stackCompletionFunc(cmd, args, toComplete)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		follow, _ := cmd.Flags().GetBool("follow")
+		tail, _ := cmd.Flags().GetInt("tail")
+		grep, _ := cmd.Flags().GetString("grep")
+		detached, _ := cmd.Flags().GetBool("detached")
+		if detached && len(args) > 1 {
+			errorColor.Fprintln(os.Stderr, "Error: --detached shows the whole output of a detached run and takes no services.")
+			os.Exit(1)
+		}
+
+		var pattern *regexp.Regexp
+		if grep != "" {
+			var err error
+			pattern, err = regexp.Compile(grep)
+			if err != nil {
+				errorColor.Fprintf(os.Stderr, "Error: invalid --grep pattern: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		stackIdentifier := args[0]
+		if strings.HasSuffix(stackIdentifier, ":") {
+			errorColor.Fprintf(os.Stderr, "Error: logs are shown for a single stack, not every stack on '%s'.\n", stackIdentifier)
+			os.Exit(1)
+		}
+		stacksToCheck, collectedErrors := discoverTargetStacks(stackIdentifier, nil, discovery.RootOverrides{})
+		if len(collectedErrors) > 0 {
+			for _, err := range collectedErrors {
+				errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		stack, err := findStackByIdentifier(stacksToCheck, stackIdentifier)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		start := func(cliMode bool) (<-chan runner.OutputLine, <-chan error) {
+			step := runner.LogsStep(stack, runner.LogsOptions{Services: args[1:], Tail: tail, Follow: follow})
+			return runner.StreamCommand(step, cliMode)
+		}
+		if detached {
+			if tail == 0 {
+				tail = -1 // A detached run's output is shown in full by default
+			}
+			start = func(cliMode bool) (<-chan runner.OutputLine, <-chan error) {
+				return runner.StreamDetachedLog(stack, tail, follow, cliMode)
+			}
+		}
+		if err := streamLogs(start, pattern); err != nil {
+			errorColor.Fprintf(os.Stderr, "Error showing logs for %s: %v\n", stack.DisplayName(), err)
+			var exitErr *runner.ExitError
+			if errors.As(err, &exitErr) && exitErr.Status > 0 && exitErr.Status < 256 {
+				os.Exit(exitErr.Status)
+			}
+			os.Exit(1)
+		}
+	},
+}
+
+// streamLogs starts streaming logs with start, printing them as they arrive.
+// With a pattern, output is split into lines and only matching lines are
+// printed; without one, the output is passed through untouched (keeping
+// compose's colors).
+func streamLogs(start func(cliMode bool) (<-chan runner.OutputLine, <-chan error), pattern *regexp.Regexp) error {
+	if pattern == nil {
+		outChan, errChan := start(true)
+		for outputLine := range outChan { // Only used by remote stacks in CLI mode
+			fmt.Fprint(os.Stdout, outputLine.Line)
+		}
+		return <-errChan
+	}
+
+	outChan, errChan := start(false)
+	var partial [2]string // Unfinished last line of stdout and stderr
+	for chunk := range outChan {
+		stream, out := 0, io.Writer(os.Stdout)
+		if chunk.IsError {
+			stream, out = 1, os.Stderr
+		}
+		lines := strings.SplitAfter(partial[stream]+chunk.Line, "\n")
+		partial[stream] = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			printIfMatches(out, line, pattern)
+		}
+	}
+	printIfMatches(os.Stdout, partial[0], pattern)
+	printIfMatches(os.Stderr, partial[1], pattern)
+	return <-errChan
+}
+
+// printIfMatches prints line to out if it matches pattern, ignoring its line
+// ending. Only the text after the last carriage return is kept, as that is
+// what a terminal would end up showing for redrawn lines.
+func printIfMatches(out io.Writer, line string, pattern *regexp.Regexp) {
+	text := strings.TrimRight(line, "\r\n")
+	if i := strings.LastIndex(text, "\r"); i >= 0 {
+		text = text[i+1:]
+	}
+	if text != "" && pattern.MatchString(text) {
+		fmt.Fprintln(out, text)
+	}
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's passphrase.go file asks for the passphrases of encrypted SSH
+// keys on the terminal, pausing the spinners shown while hosts are contacted
+// so that they don't draw over the prompt.
+
+package cli
+
+import (
+	"bucket-manager/internal/config"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"golang.org/x/term"
+)
+
+// spinners are the spinners created with newSpinner, paused while a
+// passphrase is asked for.
+var spinners struct {
+	sync.Mutex
+	list []*spinner.Spinner
+}
+
+// newSpinner returns a cyan spinner with the given suffix, not yet started.
+func newSpinner(suffix string) *spinner.Spinner {
+	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+	s.Color("cyan")
+	s.Suffix = suffix
+	spinners.Lock()
+	spinners.list = append(spinners.list, s)
+	spinners.Unlock()
+	return s
+}
+
+// promptKeyPassphrase asks for the passphrase of an encrypted SSH key on the
+// terminal, without echoing it. It implements ssh.PassphrasePrompt.
+func promptKeyPassphrase(host config.SSHHost, keyPath string, retry bool) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("it is passphrase-protected and stdin is not a terminal to ask for the passphrase; set key_passphrase_command or add the key to the SSH agent")
+	}
+
+	spinners.Lock()
+	var paused []*spinner.Spinner
+	for _, s := range spinners.list {
+		if s.Active() {
+			s.Stop()
+			paused = append(paused, s)
+		}
+	}
+	defer func() {
+		for _, s := range paused {
+			s.Start()
+		}
+		spinners.Unlock()
+	}()
+
+	if retry {
+		errorColor.Fprintln(os.Stderr, "Wrong passphrase, try again.")
+	}
+	fmt.Fprintf(os.Stderr, "Enter passphrase for key '%s' (host %s): ", keyPath, identifierColor.Sprint(host.Name))
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return "", errors.New("no passphrase was entered")
+	}
+	return string(passphrase), nil
+}
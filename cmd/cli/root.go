@@ -14,9 +14,9 @@ import (
 	"bucket-manager/internal/ssh"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
@@ -50,7 +50,10 @@ var rootCmd = &cobra.Command{
 
 Discovers stacks in standard local directories (~/bucket, ~/compose-bucket)
 and on remote hosts configured via SSH (~/.config/bucket-manager/config.yaml).
+Without a command, starts the terminal user interface (see 'bm tui').
 Use 'bm serve' to start the web interface.`,
+	Args: cobra.NoArgs,
+	RunE: runTUI,
 
 	// PersistentPreRunE is executed before any subcommand runs
 	// It sets up the required environment and connections
@@ -59,16 +62,57 @@ Use 'bm serve' to start the web interface.`,
 		verbose, _ := cmd.Flags().GetBool("verbose")
 		silent, _ := cmd.Flags().GetBool("silent")
 
+		// --config and --profile select the config file before anything reads it
+		configPath, _ := cmd.Flags().GetString("config")
+		profile, _ := cmd.Flags().GetString("profile")
+		if configPath != "" {
+			config.SetConfigPath(configPath)
+		}
+		if profile != "" {
+			if err := config.SetProfile(profile); err != nil {
+				return err
+			}
+		}
+
+		// The log file options were read from the default config before the
+		// flags were parsed; a --log-file flag overrides the configured path
+		logFile, _ := cmd.Flags().GetString("log-file")
+		if configPath != "" || profile != "" || logFile != "" {
+			opts := config.ReadLogFileOptions()
+			if logFile != "" {
+				opts.Path = logFile
+			}
+			logger.SetFileOptions(opts)
+		}
+
 		// Re-initialize logger with correct verbosity settings
 		logger.InitCLI(verbose, silent)
 
+		colorMode, _ := cmd.Flags().GetString("color")
+		if err := applyColorMode(colorMode); err != nil {
+			return err
+		}
+
+		if readOnly, _ := cmd.Flags().GetBool("read-only"); readOnly {
+			config.SetReadOnly()
+		}
+
 		// Ensure config directory exists
 		if err := config.EnsureConfigDir(); err != nil {
 			return fmt.Errorf("failed to ensure config directory: %w", err)
 		}
 
+		// Show stacks with the configured identifier_format; an invalid one is
+		// reported by `bm config validate` and `bm doctor`
+		if cfg, err := config.LoadConfig(); err == nil {
+			if err := discovery.SetIdentifierFormat(cfg.IdentifierFormat); err != nil {
+				logger.Warn("Ignoring identifier_format", "error", err)
+			}
+		}
+
 		// Initialize SSH connection manager
 		sshManager = ssh.NewManager()
+		ssh.SetPassphrasePrompt(promptKeyPassphrase)
 
 		// Share SSH manager with other packages that need it
 		discovery.InitSSHManager(sshManager)
@@ -87,6 +131,22 @@ Use 'bm serve' to start the web interface.`,
 	},
 }
 
+// applyColorMode sets whether the CLI output is colored: "always", "never",
+// or "auto", which keeps fatih/color's detection (no color if NO_COLOR is set,
+// TERM is "dumb" or stdout isn't a terminal, e.g. when piped).
+func applyColorMode(mode string) error {
+	switch mode {
+	case "auto":
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	default:
+		return fmt.Errorf("invalid --color '%s', expected auto, always or never", mode)
+	}
+	return nil
+}
+
 func RunCLI() {
 	err := rootCmd.Execute()
 	if err != nil {
@@ -99,6 +159,13 @@ func init() {
 	// Add persistent flags that apply to all commands
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose logging to stderr")
 	rootCmd.PersistentFlags().BoolP("silent", "s", false, "Suppress all output to stderr (file logging only)")
+	rootCmd.PersistentFlags().String("log-file", "", "Write the log to this file instead of the configured or default location")
+	rootCmd.PersistentFlags().String("color", "auto", "Color the output: auto (only if stdout is a terminal and NO_COLOR is unset), always or never")
+	rootCmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.PersistentFlags().Bool("read-only", false, "Disable stopping, refreshing and pruning, and changes to the configuration (also: read_only in the config)")
+	rootCmd.PersistentFlags().String("config", "", "Read and save the configuration in this file instead of the default location (also: BM_CONFIG)")
+	rootCmd.PersistentFlags().String("profile", "", "Use the configuration of this profile, kept in profiles/<name>.yaml next to the default config file (also: BM_PROFILE)")
+	rootCmd.MarkFlagsMutuallyExclusive("config", "profile")
 
 	// Stack discovery command
 	rootCmd.AddCommand(listCmd)
@@ -108,21 +175,147 @@ func init() {
 	rootCmd.AddCommand(downCmd)    // Stop stacks
 	rootCmd.AddCommand(refreshCmd) // Restart stacks
 	rootCmd.AddCommand(statusCmd)  // Get stack status
+	rootCmd.AddCommand(inspectCmd) // Show everything known about a stack
 	rootCmd.AddCommand(pullCmd)    // Pull latest container images
+	rootCmd.AddCommand(createCmd)  // Create containers without starting them
+	rootCmd.AddCommand(stopCmd)    // Stop containers without removing them
+	rootCmd.AddCommand(startCmd)   // Start stopped containers
+	rootCmd.AddCommand(logsCmd)    // Show stack logs
+	rootCmd.AddCommand(runCmd)     // Run a stack's custom actions
+	rootCmd.AddCommand(watchCmd)   // Report stacks that go down
 
 	// Host operation commands
-	rootCmd.AddCommand(pruneCmd) // Clean up unused containers/images
+	rootCmd.AddCommand(pruneCmd)  // Clean up unused containers/images
+	rootCmd.AddCommand(imagesCmd) // List and selectively prune images
+	imagesCmd.AddCommand(imagesPruneCmd)
+	rootCmd.AddCommand(hostCmd) // Host-level actions
+	hostCmd.AddCommand(hostRestartRuntimeCmd)
+
+	// Setup diagnostics
+	rootCmd.AddCommand(doctorCmd) // Check the setup for problems
+
+	// Command-specific flags
+	statusCmd.Flags().Bool("hosts", false, "Show host disk usage instead of stack status")
+	statusCmd.Flags().BoolP("wide", "w", false, "Also show stack paths and container ports and commands")
+	statusCmd.Flags().Bool("exit-code", false, "Exit with a code for the worst stack status: 0 UP, 2 PARTIAL, 3 DOWN, 4 ERROR")
+	listCmd.Flags().String("format", "", formatFlagUsage)
+	listCmd.Flags().String("group", "", "Only list the stacks on the hosts of this host group")
+	listCmd.RegisterFlagCompletionFunc("group", groupCompletionFunc)
+	statusCmd.Flags().String("format", "", formatFlagUsage)
+	inspectCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	inspectCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	doctorCmd.Flags().Bool("skip-hosts", false, "Don't connect to the remote hosts")
+	for _, cmd := range []*cobra.Command{listCmd, statusCmd} {
+		cmd.Flags().Bool("strict", false, "Exit with status 1 if discovery failed on any host, even if stacks were found elsewhere")
+	}
+	pruneCmd.Flags().BoolP("yes", "y", false, "Prune without asking for confirmation")
+	pruneCmd.Flags().Bool("dry-run", false, "Only list the hosts that would be pruned")
+	pruneCmd.Flags().Duration("older-than", 0, "Only remove resources created more than this long ago (e.g. 720h)")
+	pruneCmd.Flags().Int("keep-latest", 0, "Keep the N newest images of each repository (implies --images-only)")
+	pruneCmd.Flags().Bool("images-only", false, "Only remove unused images")
+	pruneCmd.Flags().Bool("containers-only", false, "Only remove stopped containers")
+	pruneCmd.Flags().Bool("networks-only", false, "Only remove unused networks")
+	pruneCmd.MarkFlagsMutuallyExclusive("images-only", "containers-only", "networks-only")
+	imagesPruneCmd.Flags().Bool("dangling", false, "Only remove untagged (dangling) images")
+	imagesPruneCmd.Flags().Duration("until", 0, "Only remove images created more than this long ago (e.g. 168h)")
+	hostRestartRuntimeCmd.Flags().BoolP("yes", "y", false, "Restart without asking for confirmation")
+	addEnvFlag(upCmd)
+	addEnvFlag(downCmd)
+	addEnvFlag(refreshCmd)
+	addEnvFlag(pullCmd)
+	addEnvFlag(createCmd)
+	addEnvFlag(stopCmd)
+	addEnvFlag(startCmd)
+	addEnvFlag(runCmd)
+	addProfileFlag(upCmd)
+	addProfileFlag(downCmd)
+	addProfileFlag(refreshCmd)
+	addProfileFlag(pullCmd)
+	addProfileFlag(createCmd)
+	addProfileFlag(stopCmd)
+	addProfileFlag(startCmd)
+	addStopTimeoutFlag(downCmd)
+	addStopTimeoutFlag(refreshCmd)
+	addStopTimeoutFlag(stopCmd)
+	addShowConfigFlags(upCmd)
+	addSmartFlag(upCmd)
+	addShowConfigFlags(refreshCmd)
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep streaming new log lines until interrupted")
+	logsCmd.Flags().Int("tail", 0, "Number of recent lines shown per service (default 200, -1 for all)")
+	logsCmd.Flags().String("grep", "", "Only show lines matching this regular expression")
+	logsCmd.Flags().Bool("detached", false, "Show the output of the last action started on the remote stack with --detach")
+	watchCmd.Flags().Duration("interval", defaultWatchInterval, "How often to check the stack statuses")
+	watchCmd.Flags().Bool("notify", false, "Send a desktop notification for each change (notify-send or terminal-notifier)")
+	watchCmd.Flags().String("notify-command", "", "Run this shell command for each change, with BM_STACK, BM_PREVIOUS_STATUS, BM_STATUS and BM_MESSAGE set")
+	for _, cmd := range []*cobra.Command{upCmd, downCmd, refreshCmd, pullCmd, createCmd, stopCmd, startCmd, runCmd, pruneCmd, imagesPruneCmd, hostRestartRuntimeCmd} {
+		addTimeoutFlag(cmd)
+	}
+	for _, cmd := range []*cobra.Command{upCmd, downCmd, refreshCmd, pullCmd, createCmd, stopCmd, startCmd, runCmd} {
+		addLogToFlag(cmd)
+	}
+	for _, cmd := range []*cobra.Command{upCmd, downCmd, refreshCmd, pullCmd, createCmd, stopCmd, startCmd} {
+		addResumeFlag(cmd)
+		addDetachFlag(cmd)
+	}
+	addRootOverrideFlags(listCmd)
+	addRootOverrideFlags(statusCmd)
+	addHostFilterFlags(listCmd)
+	addHostFilterFlags(statusCmd)
+	listCmd.MarkFlagsMutuallyExclusive("local-only", "group")
+	statusCmd.MarkFlagsMutuallyExclusive("hosts", "host")
+	statusCmd.MarkFlagsMutuallyExclusive("hosts", "local-only")
 }
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List discovered compose stacks (local and remote)",
+	Long: `Lists compose stacks discovered locally and on all enabled remote hosts.
+--host (repeatable) limits discovery to the named hosts and --local-only to the
+local stacks, so that checking one host doesn't wait on the others. --group
+limits discovery to the hosts of a group in the config's groups section.
+--local-root and --remote-root replace the configured stack roots for this
+invocation only.
+
+Hosts that can't be searched are reported as warnings, and the command still
+succeeds if any stack was found; with --strict it exits with status 1.`,
+	Example: "  bm list\n  bm list --host server1\n  bm list --local-only\n  bm list --group prod\n  bm list --remote-root ~/staging\n  bm list --format '{{.Identifier}}'",
 	Run: func(cmd *cobra.Command, args []string) {
-		statusColor.Println("Discovering stacks...")
-		stackChan, errorChan, _ := discovery.FindStacks()
+		format, _ := cmd.Flags().GetString("format")
+		tmpl, err := parseFormatTemplate(format)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		hosts, err := hostFilterFromFlags(cmd) // Nil for every host
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if group, _ := cmd.Flags().GetString("group"); group != "" {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				errorColor.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+				os.Exit(1)
+			}
+			members, err := cfg.GroupMembers(group)
+			if err != nil {
+				errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, member := range members {
+				if !slices.Contains(hosts, member) {
+					hosts = append(hosts, member)
+				}
+			}
+		}
+
+		if tmpl == nil {
+			statusColor.Println("Discovering stacks...")
+		}
+		stackChan, errorChan, _ := discovery.FindStacksOnHosts(rootOverridesFromFlags(cmd), hosts)
 
 		var collectedErrors []error
-		var stacksFound bool
 		var wg sync.WaitGroup
 		wg.Add(1)
 
@@ -130,58 +323,92 @@ var listCmd = &cobra.Command{
 			defer wg.Done()
 			for err := range errorChan {
 				collectedErrors = append(collectedErrors, err)
-				errorColor.Fprintf(os.Stderr, "Error during discovery: %v\n", err)
+				errorColor.Fprintf(os.Stderr, "Warning: discovery failed: %v\n", err)
 			}
 		}()
 
-		fmt.Println("\nDiscovered stacks:")
-
-		s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-		s.Color("cyan")
-		s.Suffix = " Loading remote stacks..."
-		s.Start()
+		var s *spinner.Spinner
+		if tmpl == nil {
+			s = newSpinner(" Loading remote stacks...")
+			s.Start()
+		}
 
+		// Stacks arrive in whatever order their hosts answer, so they are
+		// sorted before anything is printed.
+		var stacks []discovery.Stack
 		for stack := range stackChan {
-			s.Stop()
-			stacksFound = true
-			fmt.Printf("- %s (%s)\n", stack.Name, identifierColor.Sprint(stack.ServerName))
-			s.Restart()
+			stacks = append(stacks, stack)
 		}
-		s.Stop()
-
 		wg.Wait()
+		slices.SortFunc(stacks, discovery.CompareStacks)
 
+		if tmpl != nil {
+			for _, stack := range stacks {
+				printFormatted(tmpl, newStackFormatData(stack, nil))
+			}
+			if discoveryFailed(cmd, len(stacks), collectedErrors) {
+				os.Exit(1)
+			}
+			return
+		}
+
+		s.Stop()
+		fmt.Println("\nDiscovered stacks:")
+		for _, stack := range stacks {
+			if stack.Quadlet != nil {
+				fmt.Printf("- %s quadlet\n", stackLabel(stack))
+			} else {
+				fmt.Printf("- %s\n", stackLabel(stack))
+			}
+		}
+
+		stacksFound := len(stacks) > 0
 		if !stacksFound && len(collectedErrors) == 0 {
 			fmt.Println("\nNo compose stacks found locally or on configured remote hosts.")
 		} else if !stacksFound && len(collectedErrors) > 0 {
 			fmt.Println("\nNo stacks discovered successfully.")
 		}
 
-		if len(collectedErrors) > 0 {
+		if discoveryFailed(cmd, len(stacks), collectedErrors) {
 			os.Exit(1)
 		}
 	},
 }
 
+// discoveryFailed reports whether `bm list` or `bm status` should exit with
+// status 1 because of discovery errors: only if no stack was found at all,
+// unless --strict makes any error count. Unreachable hosts are otherwise
+// just warnings, so scripts still get the stacks that were found.
+func discoveryFailed(cmd *cobra.Command, stacksFound int, errs []error) bool {
+	if len(errs) == 0 {
+		return false
+	}
+	strict, _ := cmd.Flags().GetBool("strict")
+	return strict || stacksFound == 0
+}
+
 var upCmd = &cobra.Command{
 	Use:               "up <stack-identifier> [stack-identifier...]",
 	Short:             "Start one or more stacks",
-	Example:           "  bm up my-local-app\n  bm up server1:remote-app\n  bm up app1 app2 server1:app3",
+	Long:              `Starts the given stacks. A host followed by a colon (e.g. 'server1:') targets every stack on that host, and a quoted glob pattern (e.g. 'web-*' or 'server1:api-*') every matching stack.`,
+	Example:           "  bm up my-local-app\n  bm up server1:remote-app\n  bm up app1 app2 server1:app3\n  bm up server1:\n  bm up 'server1:api-*'\n  bm up app -e TAG=v2 -e DEBUG=1\n  bm up app --compose-profile monitoring\n  bm up app --show-config\n  bm up app --smart",
 	Args:              cobra.MinimumNArgs(1),
 	ValidArgsFunction: stackCompletionFunc,
 	Run: func(cmd *cobra.Command, args []string) {
-		runStackAction("up", args)
+		runStackAction("up", args, stackActionOptionsFromFlags(cmd))
 	},
 }
 
 var downCmd = &cobra.Command{
 	Use:               "down <stack-identifier> [stack-identifier...]",
 	Short:             "Stop one or more stacks",
-	Example:           "  bm down my-local-app\n  bm down server1:remote-app\n  bm down app1 app2 server1:app3",
+	Long:              `Stops the given stacks. A host followed by a colon (e.g. 'server1:') targets every stack on that host, and a quoted glob pattern (e.g. 'web-*' or 'server1:api-*') every matching stack.`,
+	Example:           "  bm down my-local-app\n  bm down server1:remote-app\n  bm down app1 app2 server1:app3\n  bm down server1:\n  bm down 'web-*'",
 	Args:              cobra.MinimumNArgs(1),
 	ValidArgsFunction: stackCompletionFunc,
 	Run: func(cmd *cobra.Command, args []string) {
-		runStackAction("down", args)
+		requireWritable("stopping stacks")
+		runStackAction("down", args, stackActionOptionsFromFlags(cmd))
 	},
 }
 
@@ -189,23 +416,62 @@ var refreshCmd = &cobra.Command{
 	Use:               "refresh <stack-identifier> [stack-identifier...]",
 	Aliases:           []string{"re"},
 	Short:             "Fully refresh one or more stacks (alias: re)",
-	Long:              `Pulls latest images, stops the stack, and starts it again. Also cleans up unused resources on local stacks.`,
-	Example:           "  bm refresh my-local-app\n  bm re server1:remote-app\n  bm refresh app1 app2 server1:app3",
+	Long:              `Pulls latest images, stops the stack, and starts it again. Also cleans up unused resources on local stacks. A host followed by a colon (e.g. 'server1:') targets every stack on that host, and a quoted glob pattern (e.g. 'web-*' or 'server1:api-*') every matching stack.`,
+	Example:           "  bm refresh my-local-app\n  bm re server1:remote-app\n  bm refresh app1 app2 server1:app3\n  bm refresh server1:\n  bm refresh 'server1:api-*'\n  bm refresh server1:remote-app --show-config\n  bm refresh server1:remote-app --detach",
 	Args:              cobra.MinimumNArgs(1),
 	ValidArgsFunction: stackCompletionFunc,
 	Run: func(cmd *cobra.Command, args []string) {
-		runStackAction("refresh", args)
+		requireWritable("refreshing stacks (which stops them)")
+		runStackAction("refresh", args, stackActionOptionsFromFlags(cmd))
 	},
 }
 
 var pullCmd = &cobra.Command{
 	Use:               "pull <stack-identifier> [stack-identifier...]",
 	Short:             "Pull latest images for one or more stacks",
-	Example:           "  bm pull my-local-app\n  bm pull server1:remote-app\n  bm pull app1 app2 server1:app3",
+	Long:              `Pulls the latest images for the given stacks. A host followed by a colon (e.g. 'server1:') targets every stack on that host, and a quoted glob pattern (e.g. 'web-*' or 'server1:api-*') every matching stack.`,
+	Example:           "  bm pull my-local-app\n  bm pull server1:remote-app\n  bm pull app1 app2 server1:app3\n  bm pull server1:\n  bm pull 'web-*'\n  bm pull server1: --detach",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: stackCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		runStackAction("pull", args, stackActionOptionsFromFlags(cmd))
+	},
+}
+
+var createCmd = &cobra.Command{
+	Use:               "create <stack-identifier> [stack-identifier...]",
+	Short:             "Create the containers of one or more stacks without starting them",
+	Long:              `Pulls the images of the given stacks and creates their containers without starting them, so that a later 'bm up' starts them right away. A host followed by a colon (e.g. 'server1:') targets every stack on that host, and a quoted glob pattern (e.g. 'web-*' or 'server1:api-*') every matching stack.`,
+	Example:           "  bm create my-local-app\n  bm create server1:remote-app\n  bm create app1 app2 server1:app3\n  bm create server1:",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: stackCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		runStackAction("create", args, stackActionOptionsFromFlags(cmd))
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:               "stop <stack-identifier> [stack-identifier...]",
+	Short:             "Stop one or more stacks, keeping their containers",
+	Long:              `Stops the containers of the given stacks without removing them, unlike 'bm down', so that 'bm start' starts the same containers again with their state intact. A host followed by a colon (e.g. 'server1:') targets every stack on that host, and a quoted glob pattern (e.g. 'web-*' or 'server1:api-*') every matching stack.`,
+	Example:           "  bm stop my-local-app\n  bm stop server1:remote-app\n  bm stop app1 app2 server1:app3\n  bm stop server1:",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: stackCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("stopping stacks")
+		runStackAction("stop", args, stackActionOptionsFromFlags(cmd))
+	},
+}
+
+var startCmd = &cobra.Command{
+	Use:               "start <stack-identifier> [stack-identifier...]",
+	Short:             "Start the existing containers of one or more stacks",
+	Long:              `Starts the stopped containers of the given stacks, e.g. after 'bm stop', without pulling images or recreating containers like 'bm up' may. Containers that don't exist yet aren't created; use 'bm up' for those. A host followed by a colon (e.g. 'server1:') targets every stack on that host, and a quoted glob pattern (e.g. 'web-*' or 'server1:api-*') every matching stack.`,
+	Example:           "  bm start my-local-app\n  bm start server1:remote-app\n  bm start app1 app2 server1:app3\n  bm start server1:",
 	Args:              cobra.MinimumNArgs(1),
 	ValidArgsFunction: stackCompletionFunc,
 	Run: func(cmd *cobra.Command, args []string) {
-		runStackAction("pull", args)
+		runStackAction("start", args, stackActionOptionsFromFlags(cmd))
 	},
 }
 
@@ -215,86 +481,151 @@ var statusCmd = &cobra.Command{
 	Long: `Shows the status of compose containers for local and remote stacks.
 If a stack identifier (e.g., my-app or server1:remote-app) is provided, shows status for that specific stack.
 If a remote identifier ending with ':' (e.g., server1:) is provided, shows status for all stacks on that remote.
-Otherwise, shows status for all discovered stacks.`,
-	Example:           "  bm status\n  bm status my-local-app\n  bm status server1:remote-app\n  bm status server1:",
+Otherwise, shows status for all discovered stacks.
+
+--local-root and --remote-root replace the configured stack roots for this
+invocation only. --host (repeatable) only shows the stacks of the named hosts
+and --local-only those of the local host, without waiting on the others. Hosts
+that can't be searched are reported as warnings, and the command still succeeds
+if any stack was found; with --strict it exits with status 1.
+
+With --hosts, shows disk usage of the root filesystem and container storage for
+the local machine and all enabled remote hosts (or only the named host, or the
+hosts of an '@group') instead.
+
+With --exit-code, the exit status reflects the worst status among the checked
+stacks, for use in monitoring checks: 0 if all are UP, 2 if any is PARTIAL,
+3 if any is DOWN and 4 if any is ERROR or couldn't be checked. 1 still means
+bm itself failed, e.g. no stack matched the identifier.`,
+	Example:           "  bm status\n  bm status my-local-app\n  bm status server1:remote-app\n  bm status server1:\n  bm status --host server1 --host server2\n  bm status app --wide\n  bm status --format '{{.Identifier}} {{.Status}}'\n  bm status --hosts\n  bm status --hosts server1\n  bm status app --exit-code",
 	Args:              cobra.MaximumNArgs(1),
 	ValidArgsFunction: stackCompletionFunc,
 	Run: func(cmd *cobra.Command, args []string) {
+		exitCode, _ := cmd.Flags().GetBool("exit-code")
+		if showHosts, _ := cmd.Flags().GetBool("hosts"); showHosts {
+			if exitCode {
+				errorColor.Fprintln(os.Stderr, "Error: --exit-code can't be used with --hosts")
+				os.Exit(1)
+			}
+			runHostStatus(args)
+			return
+		}
+
+		wide, _ := cmd.Flags().GetBool("wide")
+		format, _ := cmd.Flags().GetString("format")
+		tmpl, err := parseFormatTemplate(format)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		hosts, err := hostFilterFromFlags(cmd) // Nil for every host
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if hosts != nil && len(args) > 0 {
+			errorColor.Fprintln(os.Stderr, "Error: --host and --local-only can't be used with a stack identifier")
+			os.Exit(1)
+		}
 		var collectedErrors []error
 		scanAll := len(args) == 0
 
-		s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-		s.Color("cyan")
+		s := newSpinner("")
 
 		discoveryIdentifier := ""
 		if !scanAll {
 			discoveryIdentifier = args[0]
-			statusColor.Printf("Checking status for %s...\n", identifierColor.Sprint(discoveryIdentifier))
 			s.Suffix = fmt.Sprintf(" Discovering %s...", identifierColor.Sprint(discoveryIdentifier))
 		} else {
-			statusColor.Println("Discovering all stacks and checking status...")
 			s.Suffix = " Discovering stacks..."
 		}
-		s.Start()
+		// Templated output is meant for piping, so skip the progress messages.
+		if tmpl == nil {
+			if !scanAll {
+				statusColor.Printf("Checking status for %s...\n", identifierColor.Sprint(discoveryIdentifier))
+			} else {
+				statusColor.Println("Discovering all stacks and checking status...")
+			}
+			s.Start()
+		}
 
-		stacksToProcess, collectedErrors := discoverTargetStacks(discoveryIdentifier, s)
+		var stacksToProcess []discovery.Stack
+		var discoveryErrors []error
+		if hosts != nil {
+			stacksToProcess, discoveryErrors = discoverStacksOnHosts(rootOverridesFromFlags(cmd), hosts)
+		} else {
+			stacksToProcess, discoveryErrors = discoverTargetStacks(discoveryIdentifier, s, rootOverridesFromFlags(cmd))
+		}
 		s.Stop()
 
-		if len(collectedErrors) > 0 {
-			logger.Error("\nErrors during stack discovery:")
-			for _, err := range collectedErrors {
-				logger.Errorf("- %v", err)
-			}
+		if len(discoveryErrors) > 0 {
 			if len(stacksToProcess) == 0 {
+				for _, err := range discoveryErrors {
+					errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
 				os.Exit(1)
 			}
+			for _, err := range discoveryErrors {
+				errorColor.Fprintf(os.Stderr, "Warning: discovery failed: %v\n", err)
+			}
 			errorColor.Fprintln(os.Stderr, "Continuing with successfully discovered stacks...")
 		}
 
 		if len(stacksToProcess) == 0 {
-			if scanAll {
+			if scanAll && tmpl == nil {
 				fmt.Println("\nNo compose stacks found locally or on configured remote hosts.")
 			}
-			if len(collectedErrors) == 0 {
-				os.Exit(1)
-			}
+			os.Exit(1)
 		}
 
-		if len(stacksToProcess) > 0 {
-			statusChan := make(chan runner.StackRuntimeInfo, len(stacksToProcess))
-			var statusWg sync.WaitGroup
-			statusWg.Add(len(stacksToProcess))
+		// Stacks that failed discovery couldn't be checked
+		worstCode := 0
+		if len(discoveryErrors) > 0 {
+			worstCode = statusExitCodeError
+		}
 
+		if len(stacksToProcess) > 0 {
 			s.Suffix = " Checking stack status..."
-			s.Start()
+			if tmpl == nil {
+				s.Start()
+			}
 
-			for _, stack := range stacksToProcess {
-				go func(s discovery.Stack) {
-					defer statusWg.Done()
-					statusInfo := runner.GetStackStatus(s)
-					statusChan <- statusInfo
-				}(stack)
+			// Statuses arrive as the checks finish; print them in the stacks' order
+			var statuses []runner.StackRuntimeInfo
+			for statusInfo := range runner.GetStackStatuses(stacksToProcess) {
+				statuses = append(statuses, statusInfo)
 			}
+			s.Stop()
+			slices.SortFunc(statuses, func(a, b runner.StackRuntimeInfo) int { return discovery.CompareStacks(a.Stack, b.Stack) })
 
-			go func() {
-				statusWg.Wait()
-				close(statusChan)
-			}()
+			for _, statusInfo := range statuses {
+				worstCode = max(worstCode, statusExitCode(statusInfo.OverallStatus))
 
-			for statusInfo := range statusChan {
-				s.Stop()
+				if tmpl != nil {
+					if statusInfo.OverallStatus == runner.StatusError {
+						collectedErrors = append(collectedErrors, fmt.Errorf("status check for %s failed: %w", statusInfo.Stack.DisplayName(), statusInfo.Error))
+					}
+					printFormatted(tmpl, newStackFormatData(statusInfo.Stack, &statusInfo))
+					continue
+				}
 
-				fmt.Printf("\nStack: %s (%s) ", statusInfo.Stack.Name, identifierColor.Sprint(statusInfo.Stack.ServerName))
+				fmt.Printf("\nStack: %s ", stackLabel(statusInfo.Stack))
+				stale := ""
+				if statusInfo.IsStale() {
+					stale = statusPartialColor.Sprint(" [stale]")
+				}
 				switch statusInfo.OverallStatus {
 				case runner.StatusUp:
-					statusUpColor.Printf("[%s]\n", statusInfo.OverallStatus)
+					statusUpColor.Printf("[%s]", statusInfo.OverallStatus)
+					fmt.Println(stale)
 				case runner.StatusDown:
 					statusDownColor.Printf("[%s]\n", statusInfo.OverallStatus)
 				case runner.StatusPartial:
-					statusPartialColor.Printf("[%s]\n", statusInfo.OverallStatus)
+					statusPartialColor.Printf("[%s]", statusInfo.OverallStatus)
+					fmt.Println(stale)
 				case runner.StatusError:
 					statusErrorColor.Printf("[%s]\n", statusInfo.OverallStatus)
-					err := fmt.Errorf("status check for %s failed: %w", statusInfo.Stack.Identifier(), statusInfo.Error)
+					err := fmt.Errorf("status check for %s failed: %w", statusInfo.Stack.DisplayName(), statusInfo.Error)
 					collectedErrors = append(collectedErrors, err)
 					if statusInfo.Error != nil {
 						logger.Errorf("  Error checking status: %v", statusInfo.Error)
@@ -305,44 +636,78 @@ Otherwise, shows status for all discovered stacks.`,
 					fmt.Printf("[%s]\n", statusInfo.OverallStatus)
 				}
 
-				if statusInfo.OverallStatus != runner.StatusDown && len(statusInfo.Containers) > 0 {
-					fmt.Println("  Containers:")
-					fmt.Printf("    %-25s %-35s %s\n", "SERVICE", "CONTAINER NAME", "STATUS")
-					fmt.Printf("    %-25s %-35s %s\n", strings.Repeat("-", 25), strings.Repeat("-", 35), strings.Repeat("-", 6))
-					for _, c := range statusInfo.Containers {
-						isUp := strings.Contains(strings.ToLower(c.Status), "running") ||
-							strings.Contains(strings.ToLower(c.Status), "healthy") ||
-							strings.HasPrefix(c.Status, "Up")
-
-						statusPrinter := statusDownColor
-						if isUp {
-							statusPrinter = statusUpColor
-						}
-						fmt.Printf("    %-25s %-35s %s\n", c.Service, c.Name, statusPrinter.Sprint(c.Status))
+				if wide {
+					if statusInfo.Stack.IsRemote {
+						fmt.Printf("  Remote path: %s\n", statusInfo.Stack.FullPath())
+					} else {
+						fmt.Printf("  Path: %s\n", statusInfo.Stack.FullPath())
 					}
 				}
-				s.Restart()
+				if statusInfo.OverallStatus != runner.StatusDown && len(statusInfo.Containers) > 0 {
+					printContainerTable(statusInfo.Containers, wide)
+				}
 			}
-			s.Stop()
 		}
 
-		if len(collectedErrors) > 0 {
+		if exitCode {
+			os.Exit(worstCode)
+		}
+		if len(collectedErrors) > 0 || discoveryFailed(cmd, len(stacksToProcess), discoveryErrors) {
 			os.Exit(1)
 		}
 	},
 }
 
+// Exit codes of `bm status --exit-code`, ordered by severity. 1 is left for
+// failures of bm itself.
+const (
+	statusExitCodePartial = 2
+	statusExitCodeDown    = 3
+	statusExitCodeError   = 4
+)
+
+// statusExitCode returns the `bm status --exit-code` exit code for a stack status.
+func statusExitCode(status runner.StackStatus) int {
+	switch status {
+	case runner.StatusUp:
+		return 0
+	case runner.StatusPartial:
+		return statusExitCodePartial
+	case runner.StatusDown:
+		return statusExitCodeDown
+	default: // ERROR and UNKNOWN
+		return statusExitCodeError
+	}
+}
+
 var pruneCmd = &cobra.Command{
 	Use:   "prune [host-identifier...]",
 	Short: "Clean up unused resources on specified hosts",
-	Long: `Removes unused containers, networks, images, and volumes on the specified hosts.
-Targets can be 'local', specific remote host names, or left empty to target ALL configured hosts (local + remotes).`,
+	Long: `Removes unused containers, networks, images, and build cache on the specified hosts.
+Targets can be 'local', specific remote host names, '@group' for the hosts of a
+group in the config's groups section, or left empty to target ALL configured hosts (local + remotes).
+The resolved hosts are listed and confirmation is asked for before anything is removed, unless --yes is given.
+
+--images-only, --containers-only and --networks-only limit the prune to one kind of
+resource, and --older-than to resources created before the given duration ago.
+--keep-latest N removes unused images except the N newest of each repository.`,
 	Example: `  bm prune          # Clean up local system AND all configured remote hosts
 	 bm prune local       # Clean up only the local system
 	 bm prune server1     # Clean up only the remote host 'server1'
-	 bm prune local server1 server2 # Clean up local, server1, and server2`,
+	 bm prune local server1 server2 # Clean up local, server1, and server2
+	 bm prune @prod       # Clean up the hosts of the 'prod' group
+	 bm prune --dry-run   # Only list the hosts that would be pruned
+	 bm prune server1 -y  # Prune without asking for confirmation
+	 bm prune local --images-only --older-than 720h # Remove unused images older than 30 days
+	 bm prune server1 --keep-latest 2               # Keep the 2 newest images of each repository`,
 	ValidArgsFunction: hostCompletionFunc,
 	Run: func(cmd *cobra.Command, args []string) {
+		opts, err := pruneOptionsFromFlags(cmd)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			errorColor.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
@@ -352,6 +717,8 @@ Targets can be 'local', specific remote host names, or left empty to target ALL
 		targetsToPrune := []runner.HostTarget{}
 		targetMap := make(map[string]bool)
 
+		var skippedDisabled []string
+
 		if len(args) == 0 {
 			statusColor.Println("Targeting local host and all configured remote hosts for prune...")
 			targetsToPrune = append(targetsToPrune, runner.HostTarget{IsRemote: false, ServerName: "local"})
@@ -360,11 +727,18 @@ Targets can be 'local', specific remote host names, or left empty to target ALL
 				if !host.Disabled {
 					targetsToPrune = append(targetsToPrune, runner.HostTarget{IsRemote: true, HostConfig: &host, ServerName: host.Name})
 					targetMap[host.Name] = true
+				} else {
+					skippedDisabled = append(skippedDisabled, host.Name)
 				}
 			}
 		} else {
 			statusColor.Printf("Targeting specified hosts for prune: %s...\n", strings.Join(args, ", "))
-			for _, targetName := range args {
+			targetNames, err := cfg.ExpandHostGroups(args)
+			if err != nil {
+				errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, targetName := range targetNames {
 				if targetMap[targetName] {
 					continue
 				}
@@ -400,7 +774,26 @@ Targets can be 'local', specific remote host names, or left empty to target ALL
 			os.Exit(1)
 		}
 
-		err = runHostAction("prune", targetsToPrune)
+		printPruneTargets(targetsToPrune, skippedDisabled, opts)
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			return
+		}
+		requireWritable("pruning hosts")
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+			confirmed, err := promptConfirm(fmt.Sprintf("Prune %d host(s)?", len(targetsToPrune)))
+			if err != nil {
+				errorColor.Fprintf(os.Stderr, "\nCould not read confirmation (%v); use --yes to prune without prompting.\n", err)
+				os.Exit(1)
+			}
+			if !confirmed {
+				fmt.Println("Prune cancelled.")
+				return
+			}
+		}
+
+		err = runHostAction("prune", targetsToPrune, timeoutFromFlags(cmd), func(t runner.HostTarget) runner.HostCommandStep {
+			return runner.PruneHostStep(t, opts)
+		})
 		if err != nil {
 			logger.Errorf("\nPrune action failed for one or more hosts: %v", err)
 			os.Exit(1)
@@ -409,3 +802,58 @@ Targets can be 'local', specific remote host names, or left empty to target ALL
 		successColor.Println("\nPrune action completed for all targeted hosts.")
 	},
 }
+
+// pruneOptionsFromFlags reads the prune filter flags.
+func pruneOptionsFromFlags(cmd *cobra.Command) (runner.PruneOptions, error) {
+	var opts runner.PruneOptions
+	opts.OlderThan, _ = cmd.Flags().GetDuration("older-than")
+	opts.KeepLatest, _ = cmd.Flags().GetInt("keep-latest")
+	for _, scope := range runner.PruneScopes {
+		if only, _ := cmd.Flags().GetBool(string(scope) + "-only"); only {
+			opts.Scope = scope
+		}
+	}
+	if opts.KeepLatest > 0 && opts.Scope == runner.PruneAll {
+		opts.Scope = runner.PruneImages
+	}
+	return opts, opts.Validate()
+}
+
+// describePrune says what a prune with opts removes, for the confirmation prompt.
+func describePrune(opts runner.PruneOptions) string {
+	var what string
+	switch opts.Scope {
+	case runner.PruneImages:
+		what = "unused images"
+		if opts.KeepLatest > 0 {
+			what += fmt.Sprintf(" except the %d newest of each repository", opts.KeepLatest)
+		}
+	case runner.PruneContainers:
+		what = "stopped containers"
+	case runner.PruneNetworks:
+		what = "unused networks"
+	default:
+		what = "unused containers, networks, images and build cache"
+	}
+	if opts.OlderThan > 0 {
+		what += fmt.Sprintf(", if older than %s,", opts.OlderThan)
+	}
+	return what + " will be removed"
+}
+
+// printPruneTargets lists the hosts a prune will run on, and any disabled hosts
+// that were left out, so the scope can be checked before confirming.
+func printPruneTargets(targets []runner.HostTarget, skippedDisabled []string, opts runner.PruneOptions) {
+	fmt.Printf("\nHosts to prune (%s):\n", describePrune(opts))
+	for _, target := range targets {
+		if target.IsRemote && target.HostConfig != nil {
+			fmt.Printf("  - %s (%s@%s)\n", target.ServerName, target.HostConfig.User, target.HostConfig.Hostname)
+		} else {
+			fmt.Printf("  - %s\n", target.ServerName)
+		}
+	}
+	if len(skippedDisabled) > 0 {
+		fmt.Printf("Skipping disabled hosts: %s\n", strings.Join(skippedDisabled, ", "))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's run.go implements `bm run`, which runs one of the custom
+// actions a stack defines in its .bm.yaml, or lists them.
+
+package cli
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/runner"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run <stack-identifier> [action]",
+	Short: "Run a custom action defined by a stack",
+	Long: `Runs one of the custom actions defined in the stack's .bm.yaml file, next to
+its compose file. Each action is a compose subcommand:
+
+  actions:
+    backup: exec db pg_dump -U app app
+    migrate: exec app ./migrate
+
+Without an action, the stack's actions are listed.`,
+	Example: `  bm run my-app
+  bm run my-app backup
+  bm run server1:api migrate -e DRY_RUN=1`,
+	Args: cobra.RangeArgs(1, 2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		switch len(args) {
+		case 0:
+			return stackCompletionFunc(cmd, args, toComplete)
+		case 1:
+			return actionCompletionFunc(args[0], toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		stackIdentifier := args[0]
+		if strings.HasSuffix(stackIdentifier, ":") {
+			errorColor.Fprintf(os.Stderr, "Error: actions are run on a single stack, not every stack on '%s'.\n", stackIdentifier)
+			os.Exit(1)
+		}
+		stack, err := findSingleStack(stackIdentifier)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(args) == 1 {
+			if len(stack.Actions) == 0 {
+				statusColor.Printf("Stack %s defines no actions.\n", stack.DisplayName())
+				return
+			}
+			for _, name := range slices.Sorted(maps.Keys(stack.Actions)) {
+				fmt.Printf("%s\t%s\n", identifierColor.Sprint(name), stack.Actions[name])
+			}
+			return
+		}
+
+		action := args[1]
+		sequence, err := runner.ActionSequence(stack, action)
+		if err != nil {
+			errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		env := envFromFlags(cmd)
+		for i := range sequence {
+			sequence[i].Env = env
+		}
+
+		logger.Info("Stack custom action started",
+			"action", action,
+			"stack_name", stack.Name,
+			"server_name", stack.ServerName)
+		statusColor.Printf("Running action '%s' for stack: %s\n", action, stackLabel(stack))
+
+		if err := runSequence(stack, sequence, timeoutFromFlags(cmd), logFileFromFlags(cmd)); err != nil {
+			logger.Error("Stack custom action failed",
+				"action", action,
+				"stack_name", stack.Name,
+				"server_name", stack.ServerName,
+				"error", err)
+			errorColor.Fprintf(os.Stderr, "Action '%s' failed for %s: %v\n", action, plainStackLabel(stack), err)
+			var exitErr *runner.ExitError
+			if errors.As(err, &exitErr) && exitErr.Status > 0 && exitErr.Status < 256 {
+				os.Exit(exitErr.Status)
+			}
+			os.Exit(1)
+		}
+		successColor.Printf("Action '%s' completed successfully for %s.\n", action, stackLabel(stack))
+	},
+}
+
+// findSingleStack discovers the stack with the given identifier, e.g. to run
+// one of the actions it defines.
+func findSingleStack(stackIdentifier string) (discovery.Stack, error) {
+	stacksToCheck, collectedErrors := discoverTargetStacks(stackIdentifier, nil, discovery.RootOverrides{})
+	if len(collectedErrors) > 0 {
+		return discovery.Stack{}, errors.Join(collectedErrors...)
+	}
+	return findStackByIdentifier(stacksToCheck, stackIdentifier)
+}
+
+// actionCompletionFunc completes the names of the custom actions defined by
+// the stack with the given identifier.
+func actionCompletionFunc(stackIdentifier, toComplete string) ([]string, cobra.ShellCompDirective) {
+	stack, err := findSingleStack(stackIdentifier)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var completions []string
+	for _, name := range slices.Sorted(maps.Keys(stack.Actions)) {
+		if strings.HasPrefix(name, toComplete) {
+			completions = append(completions, name+"\t"+stack.Actions[name])
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
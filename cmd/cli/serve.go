@@ -4,11 +4,21 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"bucket-manager/internal/api"
 	"bucket-manager/internal/logger"
@@ -27,18 +37,42 @@ This provides a modern web interface for managing all your compose stacks
 from any browser. The server runs on localhost by default and can be accessed
 at http://localhost:8080.
 
+Use --listen to serve on another address, or on a Unix socket (e.g.
+--listen unix:/run/bm.sock) for a reverse proxy in front of it. The socket gets
+the permissions of --socket-mode and is removed when the server stops.
+
 Use --dev flag for development mode, which proxies frontend requests to the Next.js
 dev server running on localhost:3000 for live reloading.`,
+	Example: "  bm serve\n  bm serve --listen 127.0.0.1:9000\n  bm serve --listen unix:/run/bm.sock --socket-mode 0660",
 	Run: func(cmd *cobra.Command, args []string) {
 		devMode, _ := cmd.Flags().GetBool("dev")
-		runWebServer(devMode)
+		address, _ := cmd.Flags().GetString("listen")
+		socketModeFlag, _ := cmd.Flags().GetString("socket-mode")
+		socketMode, err := strconv.ParseUint(socketModeFlag, 8, 32)
+		if err != nil || socketMode > 0o777 {
+			errorColor.Fprintf(os.Stderr, "Error: invalid --socket-mode '%s': expected octal permissions like 0660\n", socketModeFlag)
+			os.Exit(1)
+		}
+		runWebServer(devMode, address, fs.FileMode(socketMode))
 	},
 }
 
-// runWebServer starts the HTTP server for the web UI.
+// defaultListenAddress is the address the web server listens on by default.
+const defaultListenAddress = ":8080"
+
+// unixSocketPrefix marks a --listen address as the path of a Unix socket.
+const unixSocketPrefix = "unix:"
+
+// serverShutdownTimeout is how long open requests, like status streams, get to
+// finish when the server is stopped before their connections are closed.
+const serverShutdownTimeout = 5 * time.Second
+
+// runWebServer starts the HTTP server for the web UI on address, a TCP address
+// or "unix:" followed by a socket path (created with socketMode permissions).
 // It initializes the router, registers API endpoints, and serves either the embedded
 // Next.js web application or proxies to the dev server based on devMode.
-func runWebServer(devMode bool) {
+// It returns once the server is stopped with SIGINT or SIGTERM.
+func runWebServer(devMode bool, address string, socketMode fs.FileMode) {
 	// Initialize logger for web interface
 	logger.InitWeb(logger.LevelInfo)
 
@@ -49,6 +83,7 @@ func runWebServer(devMode bool) {
 	// Register API routes
 	api.RegisterStackRoutes(router)
 	api.RegisterSSHRoutes(router)
+	api.RegisterHostRoutes(router)
 	api.RegisterRunnerRoutes(router)
 
 	// Serve frontend - either embedded files or proxy to dev server
@@ -68,12 +103,84 @@ func runWebServer(devMode bool) {
 		router.PathPrefix("/").Handler(staticFileServer)
 	}
 
-	port := "8080" // TODO: Make this configurable via --port flag and in config.yaml under server.port
-	fmt.Printf("Starting web server on :%s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, router))
+	listener, err := listenWeb(address, socketMode)
+	if err != nil {
+		logger.Error("Failed to start web server", "address", address, "error", err)
+		errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	logger.Info("Web server started", "address", address)
+	fmt.Printf("Starting web server on %s\n", address)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Handler: router}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	// Closing the listener also removes a Unix socket
+	fmt.Println("Stopping web server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Web server requests did not finish in time", "error", err)
+		server.Close()
+	}
+	logger.Info("Web server stopped", "address", address)
+}
+
+// listenWeb creates the web server's listener on a TCP address, or on a Unix
+// socket for a "unix:" address. A socket file left behind by a server that
+// didn't stop cleanly is replaced, but not one that is still being served.
+func listenWeb(address string, socketMode fs.FileMode) (net.Listener, error) {
+	path, isSocket := strings.CutPrefix(address, unixSocketPrefix)
+	if !isSocket {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("could not listen on %s: %w", address, err)
+		}
+		return listener, nil
+	}
+	if path == "" {
+		return nil, errors.New("no socket path after 'unix:' in --listen")
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is already in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("could not remove stale socket %s: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("could not set the permissions of socket %s: %w", path, err)
+	}
+	return listener, nil
 }
 
 func init() {
 	serveCmd.Flags().Bool("dev", false, "Enable development mode (proxy to Next.js dev server on localhost:3000)")
+	serveCmd.Flags().String("listen", defaultListenAddress, "Address to listen on: [host]:port, or unix:<path> for a Unix socket")
+	serveCmd.Flags().String("socket-mode", "0660", "Permissions of the Unix socket created for a unix: --listen address, in octal")
 	rootCmd.AddCommand(serveCmd)
 }
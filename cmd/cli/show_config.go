@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's show_config.go file implements --show-config for up and
+// refresh: before a stack is brought up, its effective compose configuration
+// (or what changed in it since it was last brought up this way) is shown and
+// confirmed.
+
+package cli
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/runner"
+	"bucket-manager/internal/util"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// configDiffContext is the number of unchanged lines shown around each change
+// of a config diff.
+const configDiffContext = 3
+
+// configPreview holds the --show-config options of a stack action.
+type configPreview struct {
+	show bool // Show the effective config and confirm before bringing stacks up
+	yes  bool // Don't ask for confirmation
+}
+
+// addShowConfigFlags registers the --show-config and --yes flags on a command
+// that brings stacks up.
+func addShowConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("show-config", false, "Show the effective compose config (or its changes since it was last applied) and confirm before bringing each stack up")
+	cmd.Flags().BoolP("yes", "y", false, "With --show-config, only show the config without asking for confirmation")
+}
+
+// configPreviewFromFlags returns the --show-config options, disabled if the
+// flags aren't given (or aren't registered).
+func configPreviewFromFlags(cmd *cobra.Command) configPreview {
+	if cmd.Flags().Lookup("show-config") == nil {
+		return configPreview{}
+	}
+	show, _ := cmd.Flags().GetBool("show-config")
+	yes, _ := cmd.Flags().GetBool("yes")
+	return configPreview{show: show, yes: yes}
+}
+
+// previewStackConfig shows the effective compose config of a stack before the
+// action brings it up: in full the first time, then as a diff against the
+// config last applied with --show-config. It returns the config, to be saved
+// with runner.SaveAppliedConfig once the action succeeds, and whether to go
+// ahead. Quadlets have no compose config and are let through unchanged.
+func previewStackConfig(stack discovery.Stack, action string, profiles []string, env []string, preview configPreview) (string, bool, error) {
+	if stack.Quadlet != nil {
+		statusColor.Printf("%s is a quadlet, which has no compose config to show.\n", stackLabel(stack))
+		return "", true, nil
+	}
+
+	cfg, err := runner.GetStackEffectiveConfig(stack, profiles, env)
+	if err != nil {
+		return "", false, fmt.Errorf("could not get the effective compose config: %w", err)
+	}
+	last, found, err := runner.LoadAppliedConfig(stack)
+	if err != nil {
+		logger.Warn("Could not load the last applied compose config", "stack", stack.Identifier(), "error", err)
+		errorColor.Fprintf(os.Stderr, "Warning: %v; showing the full config.\n", err)
+	}
+
+	switch {
+	case !found:
+		statusColor.Printf("Effective compose config for %s:\n", stackLabel(stack))
+		fmt.Print(cfg)
+	case last == cfg:
+		statusColor.Printf("The compose config for %s is unchanged since it was last applied.\n", stackLabel(stack))
+	default:
+		statusColor.Printf("Changes to the compose config for %s since it was last applied:\n", stackLabel(stack))
+		printConfigDiff(last, cfg)
+	}
+
+	if preview.yes {
+		return cfg, true, nil
+	}
+	confirmed, err := promptConfirm(fmt.Sprintf("Continue with '%s' for %s?", action, plainStackLabel(stack)))
+	if err != nil {
+		return "", false, fmt.Errorf("could not read confirmation (%v); use --yes to skip it", err)
+	}
+	return cfg, confirmed, nil
+}
+
+// printConfigDiff prints the lines changed from oldConfig to newConfig, with
+// configDiffContext unchanged lines around them and "..." for those skipped.
+func printConfigDiff(oldConfig, newConfig string) {
+	diff := util.LineDiff(oldConfig, newConfig)
+
+	// Mark the unchanged lines close enough to a change to be shown
+	shown := make([]bool, len(diff))
+	for i, line := range diff {
+		if line.Op == util.DiffEqual {
+			continue
+		}
+		for j := max(0, i-configDiffContext); j <= min(len(diff)-1, i+configDiffContext); j++ {
+			shown[j] = true
+		}
+	}
+
+	skipped := false
+	for i, line := range diff {
+		if !shown[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			dimColor.Println("  ...")
+			skipped = false
+		}
+		switch line.Op {
+		case util.DiffDelete:
+			statusDownColor.Printf("- %s\n", line.Text)
+		case util.DiffInsert:
+			statusUpColor.Printf("+ %s\n", line.Text)
+		default:
+			fmt.Printf("  %s\n", line.Text)
+		}
+	}
+	if skipped {
+		dimColor.Println("  ...")
+	}
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's status_table.go renders the container table printed by
+// `bm status`, including the wider Ports/Command layout used by --wide.
+
+package cli
+
+import (
+	"bucket-manager/internal/runner"
+	"bucket-manager/internal/util"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Column widths of the container table. Ports and Command share whatever space is left.
+const (
+	serviceColumnWidth   = 25
+	nameColumnWidth      = 35
+	statusColumnWidth    = 20
+	minWideColumnWidth   = 12 // Narrowest Ports/Command column before they are dropped to fit
+	containerTableIndent = "    "
+)
+
+// isContainerUp reports whether a container status string describes a running container.
+func isContainerUp(status string) bool {
+	return strings.Contains(strings.ToLower(status), "running") ||
+		strings.Contains(strings.ToLower(status), "healthy") ||
+		strings.HasPrefix(status, "Up")
+}
+
+// terminalWidth returns the width of stdout, or 0 if stdout is not a terminal.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// printContainerTable prints the containers of a stack. With wide set, Ports and
+// Command columns are added and truncated to fit the terminal; when output is not
+// a terminal they are printed in full.
+func printContainerTable(containers []runner.ContainerState, wide bool) {
+	fmt.Println("  Containers:")
+	if !wide {
+		fmt.Printf("%s%-*s %-*s %s\n", containerTableIndent, serviceColumnWidth, "SERVICE", nameColumnWidth, "CONTAINER NAME", "STATUS")
+		fmt.Printf("%s%-*s %-*s %s\n", containerTableIndent, serviceColumnWidth, strings.Repeat("-", serviceColumnWidth), nameColumnWidth, strings.Repeat("-", nameColumnWidth), strings.Repeat("-", 6))
+		for _, c := range containers {
+			statusPrinter := statusDownColor
+			if isContainerUp(c.Status) {
+				statusPrinter = statusUpColor
+			}
+			fmt.Printf("%s%-*s %-*s %s\n", containerTableIndent, serviceColumnWidth, c.Service, nameColumnWidth, c.Name, statusPrinter.Sprint(c.Status))
+		}
+		return
+	}
+
+	portsWidth, commandWidth := wideColumnWidths(containers, terminalWidth())
+
+	fmt.Printf("%s%-*s %-*s %-*s %-*s %s\n", containerTableIndent,
+		serviceColumnWidth, "SERVICE", nameColumnWidth, "CONTAINER NAME", statusColumnWidth, "STATUS", portsWidth, "PORTS", "COMMAND")
+	fmt.Printf("%s%s %s %s %s %s\n", containerTableIndent,
+		strings.Repeat("-", serviceColumnWidth), strings.Repeat("-", nameColumnWidth), strings.Repeat("-", statusColumnWidth),
+		strings.Repeat("-", portsWidth), strings.Repeat("-", max(commandWidth, len("COMMAND"))))
+	for _, c := range containers {
+		statusPrinter := statusDownColor
+		if isContainerUp(c.Status) {
+			statusPrinter = statusUpColor
+		}
+		// Pad before colouring so escape codes don't throw off the alignment.
+		status := statusPrinter.Sprint(padRight(util.Truncate(c.Status, statusColumnWidth), statusColumnWidth))
+		ports := c.Ports
+		if ports == "" {
+			ports = "-"
+		}
+		fmt.Printf("%s%-*s %-*s %s %s %s\n", containerTableIndent,
+			serviceColumnWidth, util.Truncate(c.Service, serviceColumnWidth),
+			nameColumnWidth, util.Truncate(c.Name, nameColumnWidth),
+			status,
+			padRight(util.Truncate(ports, portsWidth), portsWidth),
+			util.Truncate(c.Command, commandWidth))
+	}
+}
+
+// wideColumnWidths splits the space left after the fixed columns between Ports and
+// Command. Ports is given up to its longest value so mappings stay readable; Command
+// gets the rest. A termWidth of 0 means unlimited, so nothing is truncated.
+func wideColumnWidths(containers []runner.ContainerState, termWidth int) (portsWidth, commandWidth int) {
+	portsWidth = len("PORTS")
+	for _, c := range containers {
+		portsWidth = max(portsWidth, utf8.RuneCountInString(c.Ports))
+	}
+	if termWidth <= 0 {
+		return portsWidth, 0
+	}
+
+	fixed := len(containerTableIndent) + serviceColumnWidth + nameColumnWidth + statusColumnWidth + 4 // 4 column gaps
+	remaining := termWidth - fixed
+	if remaining < 2*minWideColumnWidth {
+		// Too narrow to fit both; keep them at the minimum and let the terminal wrap.
+		return min(portsWidth, minWideColumnWidth), minWideColumnWidth
+	}
+	portsWidth = min(portsWidth, remaining-minWideColumnWidth)
+	return portsWidth, remaining - portsWidth
+}
+
+// padRight pads s with spaces to width runes.
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
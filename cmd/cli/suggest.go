@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's suggest.go file suggests the stacks or hosts a mistyped
+// identifier may have meant, by edit distance, for "did you mean" hints.
+
+package cli
+
+import (
+	"bucket-manager/internal/discovery"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxSuggestions is how many suggestions a "did you mean" hint lists at most.
+const maxSuggestions = 3
+
+// suggestStacks returns the identifiers of the stacks whose names are closest
+// to name, nearest first: plain names when serverName is empty, otherwise
+// "server:name" for the stacks on that host.
+func suggestStacks(stacks []discovery.Stack, serverName, name string) []string {
+	var candidates []string
+	for _, s := range stacks {
+		switch {
+		case serverName == "":
+			candidates = append(candidates, s.Name)
+		case s.ServerName == serverName:
+			candidates = append(candidates, s.Identifier())
+		}
+	}
+	return closestMatches(candidates, name, func(candidate string) string {
+		_, candidateName, found := strings.Cut(candidate, ":")
+		if !found {
+			return candidate
+		}
+		return candidateName
+	})
+}
+
+// closestMatches returns the candidates within a few edits of target
+// (ignoring case), nearest first and at most maxSuggestions of them. key picks
+// the part of a candidate that is compared, nil for the whole candidate.
+func closestMatches(candidates []string, target string, key func(string) string) []string {
+	type match struct {
+		candidate string
+		distance  int
+	}
+	maxDistance := max(1, min(3, len(target)/2))
+	target = strings.ToLower(target)
+
+	var matches []match
+	for _, candidate := range candidates {
+		compared := candidate
+		if key != nil {
+			compared = key(candidate)
+		}
+		distance := levenshtein(strings.ToLower(compared), target)
+		if distance <= maxDistance && !slices.ContainsFunc(matches, func(m match) bool { return m.candidate == candidate }) {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	slices.SortFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.candidate, b.candidate)
+	})
+
+	var suggestions []string
+	for _, m := range matches[:min(len(matches), maxSuggestions)] {
+		suggestions = append(suggestions, m.candidate)
+	}
+	return suggestions
+}
+
+// levenshtein returns the number of single-character insertions, deletions
+// and substitutions needed to turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// didYouMean formats suggestions as a hint appended to a "not found" error,
+// e.g. "; did you mean: myapp, myapp2?", or "" if there are none.
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("; did you mean: %s?", strings.Join(suggestions, ", "))
+}
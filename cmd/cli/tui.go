@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+package cli
+
+import (
+	"bucket-manager/cmd/tui"
+	"bucket-manager/internal/logger"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// tuiCmd represents the command to start the terminal user interface, which
+// `bm` also starts when run without a command.
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Start the terminal user interface",
+	Long: `Starts the interactive terminal user interface, as running 'bm' without
+a command does. Both accept the global flags, e.g. --profile, --config,
+--read-only or --log-file. The TUI only logs to its log file, so --verbose and
+--silent are refused.`,
+	Example: "  bm tui\n  bm --read-only\n  bm tui --profile work\n  bm --log-file /tmp/bm-tui.log",
+	Args:    cobra.NoArgs,
+	RunE:    runTUI,
+}
+
+// runTUI starts the TUI, for both `bm tui` and `bm` without a command.
+func runTUI(cmd *cobra.Command, args []string) error {
+	for _, flag := range []string{"verbose", "silent"} {
+		if cmd.Flags().Changed(flag) {
+			return fmt.Errorf("--%s has no effect on the TUI, which only logs to its log file (see --log-file)", flag)
+		}
+	}
+	// Initialize logger for the TUI, replacing the CLI logger (file only)
+	logger.InitTUI()
+	tui.RunTUI()
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,281 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package cli's watch.go implements `bm watch`, which checks stack statuses
+// periodically and reports stacks that go down (leave UP) or come back up,
+// optionally as desktop notifications or through a notifier command.
+
+package cli
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/runner"
+	"bucket-manager/internal/util"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultWatchInterval is how often `bm watch` checks statuses by default.
+const defaultWatchInterval = 30 * time.Second
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [stack-identifier]",
+	Short: "Watch stack statuses and report stacks that go down",
+	Long: `Checks the status of all stacks (or of one stack, or of every stack on a
+host with 'server1:') every --interval until interrupted with Ctrl+C, printing
+a line whenever a stack that was UP goes PARTIAL, DOWN or ERROR, and when it is
+UP again. Stacks are discovered again before each check, so new stacks are
+watched too, and the stacks of a host that can no longer be reached are
+reported as ERROR. The first check only records the statuses.
+
+With --notify, each change is also sent as a desktop notification through
+notify-send (Linux) or terminal-notifier (macOS). --notify-command runs a
+shell command for each change as well, with BM_STACK, BM_PREVIOUS_STATUS,
+BM_STATUS and BM_MESSAGE set, e.g. to post to a chat webhook.`,
+	Example: `  bm watch --notify
+  bm watch server1: --interval 1m
+  bm watch --notify-command 'curl -d "$BM_MESSAGE" https://ntfy.sh/my-homelab'`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: stackCompletionFunc,
+	Run: func(cmd *cobra.Command, args []string) {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		notify, _ := cmd.Flags().GetBool("notify")
+		notifyCommand, _ := cmd.Flags().GetString("notify-command")
+		if interval <= 0 {
+			errorColor.Fprintln(os.Stderr, "Error: --interval must be positive")
+			os.Exit(1)
+		}
+
+		var notifiers []func(statusChange) error
+		if notify {
+			notifier, err := desktopNotifier()
+			if err != nil {
+				errorColor.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			notifiers = append(notifiers, notifier)
+		}
+		if notifyCommand != "" {
+			notifiers = append(notifiers, commandNotifier(notifyCommand))
+		}
+
+		identifier := ""
+		if len(args) > 0 {
+			identifier = args[0]
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		statusColor.Printf("Watching stack statuses every %s (Ctrl+C to stop)...\n", interval)
+		logger.Info("Status watch started", "stack_identifier", identifier, "interval", interval, "notify", notify)
+
+		var previous map[string]runner.StackStatus
+		var previousFailed map[string]error
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			// The check runs aside so that Ctrl+C doesn't wait for it to finish
+			checkDone := make(chan watchCheck, 1)
+			go func() {
+				checkDone <- watchStatuses(identifier)
+			}()
+			var check watchCheck
+			select {
+			case <-ctx.Done():
+				fmt.Println()
+				statusColor.Println("Stopped watching.")
+				return
+			case check = <-checkDone:
+			}
+			current := check.statuses
+
+			for _, host := range slices.Sorted(maps.Keys(check.failedHosts)) {
+				if _, failedBefore := previousFailed[host]; !failedBefore {
+					errorColor.Fprintf(os.Stderr, "%s Warning: %v\n", time.Now().Format(time.TimeOnly), check.failedHosts[host])
+				}
+			}
+			// The stacks of a host that couldn't be searched can't be checked
+			for id := range previous {
+				host, _, _ := strings.Cut(id, ":")
+				if _, checked := current[id]; !checked && check.failedHosts[host] != nil {
+					current[id] = runner.StatusError
+				}
+			}
+
+			if previous == nil {
+				statusColor.Printf("%s %s\n", time.Now().Format(time.TimeOnly), summarizeStatuses(current))
+			}
+			for _, change := range statusChanges(previous, current) {
+				printStatusChange(change)
+				for _, notifier := range notifiers {
+					if err := notifier(change); err != nil {
+						logger.Warn("Status change notification failed", "stack_identifier", change.Identifier, "error", err)
+						errorColor.Fprintf(os.Stderr, "Warning: notification failed: %v\n", err)
+					}
+				}
+			}
+			if previous == nil {
+				previous = current
+			} else {
+				maps.Copy(previous, current) // Stacks missing from a check keep their last status
+			}
+			previousFailed = check.failedHosts
+
+			select {
+			case <-ctx.Done():
+				fmt.Println()
+				statusColor.Println("Stopped watching.")
+				return
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+// watchCheck is the result of one check of a watch.
+type watchCheck struct {
+	statuses    map[string]runner.StackStatus // Status of each stack found, by identifier
+	failedHosts map[string]error              // Discovery error of each host that couldn't be searched
+}
+
+// watchStatuses discovers the stacks matching identifier ("" for all) and
+// returns their statuses by identifier. Discovery errors are logged and
+// returned by host: the stacks that were found are still checked.
+func watchStatuses(identifier string) watchCheck {
+	var stacks []discovery.Stack
+	var errs []error
+	if identifier != "" && !strings.HasSuffix(identifier, ":") {
+		stack, err := findSingleStack(identifier)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			stacks = []discovery.Stack{stack}
+		}
+	} else {
+		stacks, errs = discoverTargetStacks(identifier, nil, discovery.RootOverrides{})
+	}
+	check := watchCheck{
+		statuses:    make(map[string]runner.StackStatus, len(stacks)),
+		failedHosts: make(map[string]error),
+	}
+	for _, err := range errs {
+		logger.Warn("Stack discovery failed during watch", "error", err)
+		var hostErr *discovery.HostError
+		if errors.As(err, &hostErr) {
+			check.failedHosts[hostErr.Host] = err
+		}
+	}
+
+	for info := range runner.GetStackStatuses(stacks) {
+		check.statuses[info.Stack.Identifier()] = info.OverallStatus
+	}
+	return check
+}
+
+// statusChange is a stack that left UP or came back to it between two checks.
+type statusChange struct {
+	Identifier string
+	Previous   runner.StackStatus
+	Current    runner.StackStatus
+}
+
+// Message describes the change for a notification, e.g. "server1:api is DOWN (was UP)".
+func (c statusChange) Message() string {
+	return fmt.Sprintf("%s is %s (was %s)", c.Identifier, c.Current, c.Previous)
+}
+
+// statusChanges compares two checks and returns the stacks that were UP and
+// no longer are, or weren't UP and now are, sorted by identifier. Stacks
+// missing from either check are skipped; the caller reports those on a host
+// whose discovery failed as ERROR instead.
+func statusChanges(previous, current map[string]runner.StackStatus) []statusChange {
+	var changes []statusChange
+	for identifier, status := range current {
+		before, ok := previous[identifier]
+		if !ok || before == status || (before != runner.StatusUp && status != runner.StatusUp) {
+			continue
+		}
+		changes = append(changes, statusChange{Identifier: identifier, Previous: before, Current: status})
+	}
+	slices.SortFunc(changes, func(a, b statusChange) int { return strings.Compare(a.Identifier, b.Identifier) })
+	return changes
+}
+
+// summarizeStatuses describes the first check of a watch, e.g. "3 stacks: 2 UP, 1 DOWN".
+func summarizeStatuses(statuses map[string]runner.StackStatus) string {
+	counts := make(map[runner.StackStatus]int)
+	for _, status := range statuses {
+		counts[status]++
+	}
+	var parts []string
+	for _, status := range []runner.StackStatus{runner.StatusUp, runner.StatusPartial, runner.StatusDown, runner.StatusError, runner.StatusUnknown} {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	if len(parts) == 0 {
+		return "0 stacks"
+	}
+	return fmt.Sprintf("%d stacks: %s", len(statuses), strings.Join(parts, ", "))
+}
+
+// printStatusChange prints a status change with the time it was noticed.
+func printStatusChange(change statusChange) {
+	logger.Info("Stack status changed",
+		"stack_identifier", change.Identifier,
+		"previous_status", change.Previous,
+		"status", change.Current)
+	color := statusErrorColor
+	switch change.Current {
+	case runner.StatusUp:
+		color = statusUpColor
+	case runner.StatusPartial:
+		color = statusPartialColor
+	case runner.StatusDown:
+		color = statusDownColor
+	}
+	fmt.Printf("%s %s %s\n", time.Now().Format(time.TimeOnly), identifierColor.Sprint(discovery.DisplayIdentifier(change.Identifier)), color.Sprintf("%s -> %s", change.Previous, change.Current))
+}
+
+// desktopNotifier returns a notifier sending desktop notifications through
+// notify-send or terminal-notifier, whichever is installed.
+func desktopNotifier() (func(statusChange) error, error) {
+	if err := util.CheckDesktopNotifications(); err != nil {
+		return nil, fmt.Errorf("--notify needs notify-send or terminal-notifier to be installed")
+	}
+	return func(change statusChange) error {
+		return util.SendDesktopNotification("bm: "+change.Identifier, change.Message(), change.Current != runner.StatusUp)
+	}, nil
+}
+
+// commandNotifier returns a notifier running command with `sh -c` for each
+// change, with the change in its environment.
+func commandNotifier(command string) func(statusChange) error {
+	return func(change statusChange) error {
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Env = append(os.Environ(),
+			"BM_STACK="+change.Identifier,
+			"BM_PREVIOUS_STATUS="+string(change.Previous),
+			"BM_STATUS="+string(change.Current),
+			"BM_MESSAGE="+change.Message())
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("--notify-command failed: %w", err)
+		}
+		return nil
+	}
+}
@@ -9,6 +9,7 @@ package tui
 import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
 	"bucket-manager/internal/runner"
 	"bucket-manager/internal/ssh"
 	"bucket-manager/internal/ui"
@@ -27,6 +28,14 @@ func RunTUI() {
 		os.Exit(1)
 	}
 
+	// Show stacks with the configured identifier_format; an invalid one is
+	// reported by `bm config validate` and `bm doctor`
+	if cfg, err := config.LoadConfig(); err == nil {
+		if err := discovery.SetIdentifierFormat(cfg.IdentifierFormat); err != nil {
+			logger.Warn("Ignoring identifier_format", "error", err)
+		}
+	}
+
 	// Initialize SSH connection manager
 	sshManager := ssh.NewManager()
 	defer sshManager.CloseAll() // Ensure all SSH connections are closed on exit
@@ -35,6 +44,9 @@ func RunTUI() {
 	discovery.InitSSHManager(sshManager)
 	runner.InitSSHManager(sshManager)
 
+	// Encrypted SSH keys are unlocked with a prompt in the TUI
+	ssh.SetPassphrasePrompt(ui.PromptKeyPassphrase)
+
 	m := ui.InitialModel()
 	p := tea.NewProgram(&m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	ui.BubbleProgram = p
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package api's hosts.go file implements the host summary endpoint, which returns
+// everything about one host (its stacks with their containers, and disk usage)
+// in a single response, e.g. for embedding in a dashboard.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/runner"
+
+	"github.com/gorilla/mux"
+)
+
+// HostSummary is the response of the host summary endpoint.
+type HostSummary struct {
+	Name      string         `json:"name"`               // "local" or the SSH host name
+	IsRemote  bool           `json:"isRemote"`           // Whether the host is a remote SSH host
+	Hostname  string         `json:"hostname,omitempty"` // Address of a remote host
+	Reachable bool           `json:"reachable"`          // Whether stack discovery could reach the host
+	Error     string         `json:"error,omitempty"`    // Why the host couldn't be reached, if it couldn't
+	Disk      []DiskUsage    `json:"disk,omitempty"`     // Usage of the root and container storage filesystems, if available
+	Stacks    []StackSummary `json:"stacks"`             // The host's stacks
+	Counts    map[string]int `json:"counts"`             // Number of stacks per status
+}
+
+// DiskUsage describes one filesystem in a host summary.
+type DiskUsage struct {
+	MountPoint   string  `json:"mountPoint"`
+	TotalKiB     uint64  `json:"totalKiB"`
+	AvailableKiB uint64  `json:"availableKiB"`
+	FreePercent  float64 `json:"freePercent"`
+}
+
+// StackSummary is a stack in a host summary, with its status and containers.
+type StackSummary struct {
+	Name              string                  `json:"name"`
+	Identifier        string                  `json:"identifier"`
+	ProjectName       string                  `json:"projectName,omitempty"`
+	Status            runner.StackStatus      `json:"status"`
+	Error             string                  `json:"error,omitempty"`
+	RunningContainers int                     `json:"runningContainers"`
+	Containers        []runner.ContainerState `json:"containers"`
+}
+
+// RegisterHostRoutes registers the API routes for host-level information.
+func RegisterHostRoutes(router *mux.Router) {
+	router.HandleFunc("/api/hosts/{hostName}/summary", getHostSummaryHandler).Methods("GET")
+}
+
+// getHostSummaryHandler serves the GET /api/hosts/{hostName}/summary endpoint,
+// which discovers a host's stacks and returns them with their statuses and
+// containers, along with the host's disk usage.
+//
+// URL Parameters:
+// - hostName: "local" or the name of a configured SSH host
+//
+// Response:
+//   - 200 OK: Returns a host summary. An unreachable host is reported with
+//     "reachable": false and an error rather than as a failed request.
+//   - 404 Not Found: If the specified host is not configured
+func getHostSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	hostName := mux.Vars(r)["hostName"]
+
+	logger.Info("API request received",
+		"endpoint", "/api/hosts/summary",
+		"method", r.Method,
+		"host_name", hostName,
+		"remote_addr", r.RemoteAddr,
+		"user_agent", r.UserAgent())
+
+	summary := HostSummary{Name: hostName, Stacks: []StackSummary{}, Counts: map[string]int{}}
+	target := runner.HostTarget{ServerName: "local"}
+	if hostName != "local" {
+		targetHost, err := findSSHHost(hostName)
+		if err != nil {
+			logger.Error("SSH host not found",
+				"host_name", hostName,
+				"error", err,
+				"duration", time.Since(startTime))
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		target = runner.HostTarget{ServerName: hostName, IsRemote: true, HostConfig: targetHost}
+		summary.IsRemote = true
+		summary.Hostname = targetHost.Hostname
+	}
+
+	stacks, err := discoverHostStacks(target)
+	if err != nil {
+		logger.Warn("Host summary discovery failed",
+			"host_name", hostName,
+			"error", err,
+			"duration", time.Since(startTime))
+		summary.Error = err.Error()
+		writeJSONResponse(w, summary)
+		return
+	}
+	summary.Reachable = true
+
+	// Disk usage is checked alongside the stack statuses
+	var diskUsage runner.HostDiskUsage
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		diskUsage = runner.GetHostDiskUsage(target)
+	}()
+
+	for _, stack := range collectStacksWithStatus(stacks) {
+		stackSummary := StackSummary{
+			Name:        stack.Name,
+			Identifier:  stack.Identifier(),
+			ProjectName: stack.ProjectName,
+			Status:      stack.Status,
+			Error:       stack.StatusError,
+			Containers:  stack.Containers,
+		}
+		if stackSummary.Containers == nil {
+			stackSummary.Containers = []runner.ContainerState{}
+		}
+		for _, container := range stack.Containers {
+			if container.IsRunning() {
+				stackSummary.RunningContainers++
+			}
+		}
+		summary.Stacks = append(summary.Stacks, stackSummary)
+		summary.Counts[string(stack.Status)]++
+	}
+
+	wg.Wait()
+	for _, fs := range diskUsage.Filesystems {
+		summary.Disk = append(summary.Disk, DiskUsage{
+			MountPoint:   fs.MountPoint,
+			TotalKiB:     fs.TotalKiB,
+			AvailableKiB: fs.AvailableKiB,
+			FreePercent:  fs.FreePercent(),
+		})
+	}
+
+	writeJSONResponse(w, summary)
+
+	logger.Info("API request completed successfully",
+		"endpoint", "/api/hosts/summary",
+		"host_name", hostName,
+		"stack_count", len(stacks),
+		"duration", time.Since(startTime))
+}
+
+// discoverHostStacks finds the stacks on a local or remote host. A host without
+// a stack root has no stacks rather than an error.
+func discoverHostStacks(target runner.HostTarget) ([]discovery.Stack, error) {
+	var stacks []discovery.Stack
+	var err error
+	if target.IsRemote {
+		stacks, err = discovery.FindRemoteStacks(target.HostConfig, "")
+	} else {
+		stacks, err = discovery.DiscoverLocalStacks("")
+	}
+	if err != nil {
+		if errors.Is(err, discovery.ErrRootNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error finding stacks on host %s: %w", target.ServerName, err)
+	}
+	return stacks, nil
+}
@@ -0,0 +1,315 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package api's operations.go file implements the operation registry. Every
+// stack sequence and host command started through the API runs as an operation
+// that keeps its recent output server-side, so a client that lost its stream
+// (e.g. by reloading the page) can fetch the output so far and resume following
+// it with GET /api/run/result/{opID}. With operation_log_dir configured, each
+// operation's output is also written to a file there.
+
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/logger"
+
+	xansi "github.com/charmbracelet/x/ansi"
+	"github.com/gorilla/mux"
+)
+
+// operationRetention is how long a finished operation's output stays available.
+const operationRetention = 30 * time.Minute
+
+// OperationEvent is one server-sent event of an operation, e.g. a line of output.
+type OperationEvent struct {
+	Seq   int    `json:"seq"`   // Position of the event in the operation, starting at 0
+	Event string `json:"event"` // SSE event name: "step", "stdout", "stderr", "error" or "done"
+	Data  string `json:"data"`  // Event data, with newlines escaped as "\n"
+}
+
+// OperationResult is the response of the operation result endpoint.
+type OperationResult struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`              // The stacks or host the operation runs on, e.g. "local:app"
+	StartedAt time.Time        `json:"startedAt"`         // When the operation started
+	Done      bool             `json:"done"`              // Whether the operation has finished
+	Dropped   int              `json:"dropped"`           // Number of early events no longer kept
+	Events    []OperationEvent `json:"events"`            // The kept events, oldest first
+	LogFile   string           `json:"logFile,omitempty"` // File the full output is written to, if operation_log_dir is set
+}
+
+// operation is a running or recently finished operation and its output.
+type operation struct {
+	id        string
+	name      string
+	startedAt time.Time
+
+	mu        sync.Mutex
+	events    []OperationEvent // At most maxEvents, oldest first
+	maxEvents int              // output_max_lines, or zero to keep all events
+	nextSeq   int
+	done      bool
+	changed   chan struct{} // Closed (and replaced) whenever an event is added or the operation finishes
+	logFile   *os.File      // Output file in operation_log_dir, or nil
+	logPath   string
+}
+
+var (
+	operationsMu sync.Mutex
+	operations   = make(map[string]*operation)
+)
+
+// startOperation registers a new operation and runs it in the background. run
+// emits the operation's events; the operation finishes when run returns. The
+// operation keeps running if the client that started it goes away.
+func startOperation(name string, run func(op *operation)) *operation {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Warn("Could not load config for operation output settings, using defaults", "error", err)
+	}
+	op := &operation{
+		id:        hex.EncodeToString(idBytes),
+		name:      name,
+		startedAt: time.Now(),
+		maxEvents: cfg.GetOutputMaxLines(),
+		changed:   make(chan struct{}),
+	}
+	op.openLogFile(cfg.GetOperationLogDir())
+
+	operationsMu.Lock()
+	operations[op.id] = op
+	operationsMu.Unlock()
+	logger.Debug("Operation started", "operation_id", op.id, "name", name)
+
+	go func() {
+		defer op.finish()
+		run(op)
+	}()
+	return op
+}
+
+// openLogFile creates the operation's output file in dir (operation_log_dir),
+// named after its start time and ID, if the directory is configured. Failing to
+// create it is logged: the operation still runs, with its output kept in memory only.
+func (op *operation) openLogFile(dir string) {
+	if dir == "" {
+		return
+	}
+	path := filepath.Join(dir, op.startedAt.Format("20060102-150405")+"-"+op.id+".log")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logger.Error("Could not create operation log directory", "path", dir, "error", err)
+		return
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		logger.Error("Could not create operation log file", "path", path, "error", err)
+		return
+	}
+	op.logFile = file
+	op.logPath = path
+	op.writeLog(fmt.Sprintf("=== %s, started %s ===", op.name, op.startedAt.Format(time.RFC3339)))
+}
+
+// writeLog appends a line to the operation's output file, if it has one. The
+// file is closed on the first write error. Must be called with op.mu held,
+// or before the operation starts.
+func (op *operation) writeLog(line string) {
+	if op.logFile == nil {
+		return
+	}
+	if _, err := fmt.Fprintln(op.logFile, line); err != nil {
+		logger.Error("Could not write operation log file, no longer writing it", "path", op.logPath, "error", err)
+		op.logFile.Close()
+		op.logFile = nil
+	}
+}
+
+// logLine formats an event for the operation's output file: output without
+// ANSI escape sequences, and step, error and done events set apart.
+func logLine(event, data string) string {
+	data = xansi.Strip(strings.ReplaceAll(data, "\\n", "\n"))
+	switch event {
+	case "step", "done":
+		return "--- " + data + " ---"
+	case "error":
+		return "!!! " + data
+	}
+	return data
+}
+
+// findOperation returns a registered operation by ID, or nil.
+func findOperation(id string) *operation {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	return operations[id]
+}
+
+// emit adds an event to the operation, dropping the oldest event once
+// maxEvents (output_max_lines) is reached.
+func (op *operation) emit(event, data string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.events = append(op.events, OperationEvent{Seq: op.nextSeq, Event: event, Data: data})
+	op.nextSeq++
+	op.writeLog(logLine(event, data))
+	if op.maxEvents > 0 && len(op.events) > op.maxEvents {
+		op.events = op.events[len(op.events)-op.maxEvents:]
+	}
+	close(op.changed)
+	op.changed = make(chan struct{})
+}
+
+// finish marks the operation as finished and schedules its removal from the registry.
+func (op *operation) finish() {
+	op.mu.Lock()
+	op.done = true
+	if op.logFile != nil {
+		if err := op.logFile.Close(); err != nil {
+			logger.Error("Could not close operation log file", "path", op.logPath, "error", err)
+		}
+		op.logFile = nil
+	}
+	close(op.changed)
+	op.changed = make(chan struct{})
+	op.mu.Unlock()
+	logger.Debug("Operation finished", "operation_id", op.id, "duration", time.Since(op.startedAt))
+
+	time.AfterFunc(operationRetention, func() {
+		operationsMu.Lock()
+		delete(operations, op.id)
+		operationsMu.Unlock()
+	})
+}
+
+// eventsSince returns the kept events from seq on, the number of events before
+// seq that were dropped, whether the operation has finished, and a channel
+// that is closed on the next change.
+func (op *operation) eventsSince(seq int) (events []OperationEvent, dropped int, done bool, changed <-chan struct{}) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	first := op.nextSeq - len(op.events)
+	if seq < first {
+		dropped = first - seq
+		seq = first
+	}
+	if seq < op.nextSeq {
+		events = append(events, op.events[seq-first:]...)
+	}
+	return events, dropped, op.done, op.changed
+}
+
+// result returns the output of the operation so far.
+func (op *operation) result() OperationResult {
+	events, dropped, done, _ := op.eventsSince(0)
+	if events == nil {
+		events = []OperationEvent{}
+	}
+	return OperationResult{ID: op.id, Name: op.name, StartedAt: op.startedAt, Done: done, Dropped: dropped, Events: events, LogFile: op.logPath}
+}
+
+// streamOperation streams an operation's events from seq on using Server-Sent
+// Events, until the operation finishes or the client disconnects. The first
+// event, "operation", carries the operation ID for resuming the stream later;
+// every other event carries its sequence number as the SSE event ID.
+func streamOperation(w http.ResponseWriter, r *http.Request, op *operation, seq int) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*") // Allow cross-origin for development
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.Error("HTTP response writer does not support flushing for operation SSE stream")
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprintf(w, "event: operation\ndata: %s\n\n", op.id)
+	flusher.Flush()
+
+	for {
+		events, dropped, done, changed := op.eventsSince(seq)
+		if dropped > 0 {
+			fmt.Fprintf(w, "event: stdout\ndata: [%d earlier lines are no longer available]\n\n", dropped)
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Event, event.Data)
+			seq = event.Seq + 1
+		}
+		flusher.Flush()
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			logger.Debug("Operation stream closed by client", "operation_id", op.id)
+			return
+		}
+	}
+}
+
+// getOperationResultHandler serves GET /api/run/result/{opID}, returning the
+// output an operation has kept so far as JSON.
+//
+// Response:
+// - 200 OK with an OperationResult
+// - 404 Not Found if there is no such operation, or it finished too long ago
+func getOperationResultHandler(w http.ResponseWriter, r *http.Request) {
+	op := findOperation(mux.Vars(r)["opID"])
+	if op == nil {
+		http.Error(w, "Operation not found", http.StatusNotFound)
+		return
+	}
+	writeJSONResponse(w, op.result())
+}
+
+// streamOperationResultHandler serves GET /api/run/result/{opID}/stream, which
+// replays an operation's output and then follows it live, like the stream the
+// operation was started with.
+//
+// Query Parameters:
+//   - since: Sequence number of the first event to send (optional, default 0). The
+//     Last-Event-ID header sent by a reconnecting EventSource is used if it's absent.
+//
+// Response:
+// - 200 OK with text/event-stream content type
+// - 400 Bad Request if since is invalid
+// - 404 Not Found if there is no such operation, or it finished too long ago
+func streamOperationResultHandler(w http.ResponseWriter, r *http.Request) {
+	op := findOperation(mux.Vars(r)["opID"])
+	if op == nil {
+		http.Error(w, "Operation not found", http.StatusNotFound)
+		return
+	}
+
+	seq := 0
+	if since := r.URL.Query().Get("since"); since != "" {
+		n, err := strconv.Atoi(since)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("Invalid 'since' query parameter: %q", since), http.StatusBadRequest)
+			return
+		}
+		seq = n
+	} else if lastID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		seq = lastID + 1
+	}
+
+	logger.Info("Resuming operation stream", "operation_id", op.id, "since", seq, "remote_addr", r.RemoteAddr)
+	streamOperation(w, r, op, seq)
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package api's readonly.go file refuses the endpoints that stop, prune or
+// reconfigure anything while read-only mode is enabled.
+
+package api
+
+import (
+	"net/http"
+
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/logger"
+)
+
+// writable wraps a handler so that it responds with 403 Forbidden instead of
+// running in read-only mode. action describes what the handler does, for the
+// error message (e.g. "stopping stacks").
+func writable(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := config.CheckWritable(action); err != nil {
+			logger.Warn("Refused request in read-only mode", "method", r.Method, "path", r.URL.Path)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
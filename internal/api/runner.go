@@ -8,9 +8,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -32,7 +34,26 @@ type StackRunRequest struct {
 // HostRunRequest represents the expected JSON body for host runner endpoints.
 // It specifies which server should execute host-level operations like pruning.
 type HostRunRequest struct {
-	ServerName string `json:"serverName"` // Server to run the command on ("local" or SSH host name)
+	ServerName string `json:"serverName"`           // Server to run the command on ("local" or SSH host name)
+	Scope      string `json:"scope,omitempty"`      // Prune only "images", "containers" or "networks" (optional)
+	OlderThan  string `json:"olderThan,omitempty"`  // Prune only resources older than this duration, e.g. "720h" (optional)
+	KeepLatest int    `json:"keepLatest,omitempty"` // Keep the N newest images of each repository (optional)
+}
+
+// pruneOptions converts the prune fields of the request into runner.PruneOptions.
+func (req HostRunRequest) pruneOptions() (runner.PruneOptions, error) {
+	opts := runner.PruneOptions{Scope: runner.PruneScope(req.Scope), KeepLatest: req.KeepLatest}
+	if req.OlderThan != "" {
+		olderThan, err := time.ParseDuration(req.OlderThan)
+		if err != nil {
+			return opts, fmt.Errorf("invalid olderThan %q: %w", req.OlderThan, err)
+		}
+		opts.OlderThan = olderThan
+	}
+	if opts.KeepLatest > 0 && opts.Scope == runner.PruneAll {
+		opts.Scope = runner.PruneImages
+	}
+	return opts, opts.Validate()
 }
 
 // RunOutput represents the output of a command execution.
@@ -49,17 +70,41 @@ func RegisterRunnerRoutes(router *mux.Router) {
 	// Synchronous stack operation endpoints (return output all at once)
 	router.HandleFunc("/api/run/stack/up", runStackUpHandler).Methods("POST")
 	router.HandleFunc("/api/run/stack/pull", runStackPullHandler).Methods("POST")
-	router.HandleFunc("/api/run/stack/down", runStackDownHandler).Methods("POST")
-	router.HandleFunc("/api/run/stack/refresh", runStackRefreshHandler).Methods("POST")
+	router.HandleFunc("/api/run/stack/create", runStackCreateHandler).Methods("POST")
+	router.HandleFunc("/api/run/stack/stop", writable("stopping stacks", runStackStopHandler)).Methods("POST")
+	router.HandleFunc("/api/run/stack/start", runStackStartHandler).Methods("POST")
+	router.HandleFunc("/api/run/stack/down", writable("stopping stacks", runStackDownHandler)).Methods("POST")
+	router.HandleFunc("/api/run/stack/refresh", writable("refreshing stacks", runStackRefreshHandler)).Methods("POST")
 
 	// Streaming endpoints (return output as it's generated using Server-Sent Events)
-	router.HandleFunc("/api/run/stack/refresh/stream", streamStackRefreshHandler).Methods("GET")
-	router.HandleFunc("/api/run/stack/up/stream", streamStackUpHandler).Methods("GET")
-	router.HandleFunc("/api/run/stack/down/stream", streamStackDownHandler).Methods("GET")
-	router.HandleFunc("/api/run/stack/pull/stream", streamStackPullHandler).Methods("GET")
+	router.HandleFunc("/api/run/stack/refresh/stream", writable("refreshing stacks", streamStackHandler("refresh", func(stack discovery.Stack) []runner.CommandStep {
+		return runner.RefreshSequence(stack, nil, runner.DefaultStopTimeout)
+	}))).Methods("GET")
+	router.HandleFunc("/api/run/stack/up/stream", streamStackHandler("up", func(stack discovery.Stack) []runner.CommandStep {
+		return runner.UpSequence(stack, nil)
+	})).Methods("GET")
+	router.HandleFunc("/api/run/stack/down/stream", writable("stopping stacks", streamStackHandler("down", func(stack discovery.Stack) []runner.CommandStep {
+		return runner.DownSequence(stack, nil, runner.DefaultStopTimeout)
+	}))).Methods("GET")
+	router.HandleFunc("/api/run/stack/pull/stream", streamStackHandler("pull", func(stack discovery.Stack) []runner.CommandStep {
+		return runner.PullSequence(stack, nil)
+	})).Methods("GET")
+	router.HandleFunc("/api/run/stack/create/stream", streamStackHandler("create", func(stack discovery.Stack) []runner.CommandStep {
+		return runner.CreateSequence(stack, nil)
+	})).Methods("GET")
+	router.HandleFunc("/api/run/stack/stop/stream", writable("stopping stacks", streamStackHandler("stop", func(stack discovery.Stack) []runner.CommandStep {
+		return runner.StopSequence(stack, nil, runner.DefaultStopTimeout)
+	}))).Methods("GET")
+	router.HandleFunc("/api/run/stack/start/stream", streamStackHandler("start", func(stack discovery.Stack) []runner.CommandStep {
+		return runner.StartSequence(stack, nil)
+	})).Methods("GET")
 
 	// Host-level operation endpoints
-	router.HandleFunc("/api/run/host/prune", runHostPruneHandler).Methods("POST")
+	router.HandleFunc("/api/run/host/prune", writable("pruning hosts", runHostPruneHandler)).Methods("POST")
+
+	// Output of operations started above, for clients that lost their stream
+	router.HandleFunc("/api/run/result/{opID}", getOperationResultHandler).Methods("GET")
+	router.HandleFunc("/api/run/result/{opID}/stream", streamOperationResultHandler).Methods("GET")
 	// TODO: Add routes for running arbitrary commands or sequences
 	//  - POST /api/run/stack/custom for executing custom sequences on stacks
 	//  - POST /api/run/host/custom for executing arbitrary commands on hosts
@@ -97,26 +142,20 @@ func getStackFromRequest(r *http.Request) (discovery.Stack, error) {
 		"duration", time.Since(startTime))
 
 	if req.ServerName == "local" {
-		rootDir, err := discovery.GetComposeRootDirectory()
+		stack, err := findLocalStackByName(req.Name)
 		if err != nil {
 			logger.Error("Failed to get local compose root directory for stack request",
 				"stack_name", req.Name,
 				"error", err)
-			return discovery.Stack{}, fmt.Errorf("error getting local root directory: %w", err)
+			return discovery.Stack{}, err
 		}
-		stackPath := rootDir + "/" + req.Name
 
 		logger.Info("Created local stack from request",
 			"stack_name", req.Name,
-			"stack_path", stackPath,
+			"stack_path", stack.Path,
 			"duration", time.Since(startTime))
 
-		return discovery.Stack{
-			Name:       req.Name,
-			Path:       stackPath,
-			ServerName: "local",
-			IsRemote:   false,
-		}, nil
+		return stack, nil
 	} else {
 		// Get complete remote stack with AbsoluteRemoteRoot properly populated
 		logger.Debug("Looking up remote stack",
@@ -142,8 +181,36 @@ func getStackFromRequest(r *http.Request) (discovery.Stack, error) {
 	}
 }
 
-// getHostTargetFromRequest reads the request body and retrieves the corresponding runner.HostTarget.
-func getHostTargetFromRequest(r *http.Request) (runner.HostTarget, error) {
+// findLocalStackByName returns the local stack in the directory name below the
+// local root. If there is no such compose stack, the local quadlet of that name
+// is returned instead, so quadlets can be targeted by name like compose stacks,
+// even when there is no local root.
+func findLocalStackByName(name string) (discovery.Stack, error) {
+	rootDir, err := discovery.GetComposeRootDirectory()
+	if err != nil {
+		if quadlet, ok := discovery.FindLocalQuadlet(name); ok && errors.Is(err, discovery.ErrRootNotFound) {
+			return quadlet, nil
+		}
+		return discovery.Stack{}, fmt.Errorf("error getting local root directory: %w", err)
+	}
+	stackPath := rootDir + "/" + name
+	if _, err := os.Stat(stackPath); err != nil {
+		if quadlet, ok := discovery.FindLocalQuadlet(name); ok {
+			return quadlet, nil
+		}
+	}
+	return discovery.Stack{
+		Name:        name,
+		Path:        stackPath,
+		ServerName:  "local",
+		IsRemote:    false,
+		ProjectName: discovery.LocalProjectName(stackPath),
+	}, nil
+}
+
+// getHostTargetFromRequest reads the request body and retrieves the corresponding runner.HostTarget,
+// returning the parsed request along with it.
+func getHostTargetFromRequest(r *http.Request) (runner.HostTarget, HostRunRequest, error) {
 	startTime := time.Now()
 
 	logger.Debug("Processing host target request from request body",
@@ -155,7 +222,7 @@ func getHostTargetFromRequest(r *http.Request) (runner.HostTarget, error) {
 		logger.Error("Failed to read request body for host target request",
 			"error", err,
 			"remote_addr", r.RemoteAddr)
-		return runner.HostTarget{}, fmt.Errorf("error reading request body: %w", err)
+		return runner.HostTarget{}, HostRunRequest{}, fmt.Errorf("error reading request body: %w", err)
 	}
 	defer r.Body.Close()
 
@@ -165,7 +232,7 @@ func getHostTargetFromRequest(r *http.Request) (runner.HostTarget, error) {
 			"error", err,
 			"body_length", len(body),
 			"remote_addr", r.RemoteAddr)
-		return runner.HostTarget{}, fmt.Errorf("invalid request body: %w", err)
+		return runner.HostTarget{}, req, fmt.Errorf("invalid request body: %w", err)
 	}
 
 	logger.Debug("Parsed host target request",
@@ -176,7 +243,7 @@ func getHostTargetFromRequest(r *http.Request) (runner.HostTarget, error) {
 		logger.Info("Created local host target from request",
 			"server_name", req.ServerName,
 			"duration", time.Since(startTime))
-		return runner.HostTarget{ServerName: "local", IsRemote: false}, nil
+		return runner.HostTarget{ServerName: "local", IsRemote: false}, req, nil
 	} else {
 		// For remote hosts, find the host config
 		logger.Debug("Loading config for remote host target",
@@ -187,7 +254,7 @@ func getHostTargetFromRequest(r *http.Request) (runner.HostTarget, error) {
 			logger.Error("Failed to load config for remote host target",
 				"server_name", req.ServerName,
 				"error", err)
-			return runner.HostTarget{}, fmt.Errorf("error loading config: %w", err)
+			return runner.HostTarget{}, req, fmt.Errorf("error loading config: %w", err)
 		}
 
 		var targetHost *config.SSHHost
@@ -202,7 +269,7 @@ func getHostTargetFromRequest(r *http.Request) (runner.HostTarget, error) {
 			logger.Error("SSH host not found for host target request",
 				"server_name", req.ServerName,
 				"available_hosts", len(cfg.SSHHosts))
-			return runner.HostTarget{}, fmt.Errorf("SSH host '%s' not found", req.ServerName)
+			return runner.HostTarget{}, req, fmt.Errorf("SSH host '%s' not found", req.ServerName)
 		}
 
 		logger.Info("Created remote host target from request",
@@ -210,28 +277,27 @@ func getHostTargetFromRequest(r *http.Request) (runner.HostTarget, error) {
 			"host_address", targetHost.Hostname,
 			"duration", time.Since(startTime))
 
-		return runner.HostTarget{ServerName: req.ServerName, IsRemote: true, HostConfig: targetHost}, nil
+		return runner.HostTarget{ServerName: req.ServerName, IsRemote: true, HostConfig: targetHost}, req, nil
 	}
 }
 
-// runStackSequence streams the output of a given stack command sequence using Server-Sent Events.
-// runStackSequence executes a sequence of commands and streams the output
-// to the client using Server-Sent Events (SSE). This function is used by
-// all streaming API endpoints to provide real-time command execution updates.
+// runStackSequence starts a stack command sequence as an operation and streams
+// its output to the client using Server-Sent Events (SSE). This function is used
+// by all streaming API endpoints to provide real-time command execution updates.
 //
 // The function:
-// 1. Sets up proper headers for SSE communication
-// 2. Executes each command in the sequence sequentially
-// 3. Streams command outputs, errors, and step transitions as events
-// 4. Handles flushing the response buffer to ensure timely updates
-// 5. Terminates the stream when all commands complete or an error occurs
+// 1. Registers an operation that executes each command in the sequence sequentially
+// 2. Records command outputs, errors, and step transitions as events of the operation
+// 3. Streams the operation's events until all commands complete or the client disconnects
+//
+// The sequence keeps running if the client disconnects; its output can be
+// fetched and followed again through the operation result endpoints.
 //
 // Parameters:
 //   - w: HTTP response writer to send the SSE stream
+//   - r: The request, whose context ends the stream when the client disconnects
 //   - sequence: Ordered list of commands to execute
-func runStackSequence(w http.ResponseWriter, sequence []runner.CommandStep) {
-	startTime := time.Now()
-
+func runStackSequence(w http.ResponseWriter, r *http.Request, sequence []runner.CommandStep) {
 	logger.Info("Starting stack command sequence stream",
 		"sequence_length", len(sequence),
 		"steps", func() []string {
@@ -242,22 +308,34 @@ func runStackSequence(w http.ResponseWriter, sequence []runner.CommandStep) {
 			return steps
 		}())
 
-	// Set headers for Server-Sent Events
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*") // Allow cross-origin for development
-
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		logger.Error("HTTP response writer does not support flushing for SSE stream")
-		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
-		return
+	var identifiers []string
+	for _, stack := range runner.SequenceStacks(sequence) {
+		identifiers = append(identifiers, stack.Identifier())
 	}
+	op := startOperation(strings.Join(identifiers, ", "), func(op *operation) {
+		runSequenceOperation(op, sequence)
+	})
+	streamOperation(w, r, op, 0)
+}
 
-	logger.Debug("SSE headers set, starting command sequence execution")
+// runSequenceOperation runs a stack command sequence, recording its output as
+// events of op.
+func runSequenceOperation(op *operation, sequence []runner.CommandStep) {
+	startTime := time.Now()
 
-	// For simplicity, run steps sequentially and stream output
+	// Fail fast if another operation is running on one of the stacks
+	if runner.SequenceNeedsLock(sequence) {
+		locks, err := runner.LockStacks(runner.SequenceStacks(sequence))
+		if err != nil {
+			logger.Error("Failed to lock stacks for command sequence", "error", err)
+			op.emit("error", strings.ReplaceAll(err.Error(), "\n", "\\n"))
+			op.emit("done", "Sequence finished")
+			return
+		}
+		defer runner.ReleaseStackLocks(locks)
+	}
+
+	// For simplicity, run steps sequentially and record their output
 	for i, step := range sequence {
 		stepStartTime := time.Now()
 
@@ -267,15 +345,14 @@ func runStackSequence(w http.ResponseWriter, sequence []runner.CommandStep) {
 			"total_steps", len(sequence))
 
 		// Send step name as an event
-		fmt.Fprintf(w, "event: step\ndata: %s\n\n", step.Name)
-		flusher.Flush()
+		op.emit("step", step.Name)
 
 		outChan, errChan := runner.StreamCommand(step, false) // Use cliMode false for channel output
 
 		outputLines := 0
 		errorLines := 0
 
-		// Collect output and errors from channels and stream them
+		// Collect output and errors from channels and record them
 		for outputLine := range outChan {
 			// Escape newlines in data to ensure proper SSE formatting
 			// Remove extra spaces before newlines and normalize line endings
@@ -285,13 +362,12 @@ func runStackSequence(w http.ResponseWriter, sequence []runner.CommandStep) {
 			}
 			escapedLine := strings.ReplaceAll(line, "\n", "\\n")
 			if outputLine.IsError {
-				fmt.Fprintf(w, "event: stderr\ndata: %s\n\n", escapedLine)
+				op.emit("stderr", escapedLine)
 				errorLines++
 			} else {
-				fmt.Fprintf(w, "event: stdout\ndata: %s\n\n", escapedLine)
+				op.emit("stdout", escapedLine)
 				outputLines++
 			}
-			flusher.Flush()
 		}
 
 		// Check for errors after the command finishes
@@ -304,8 +380,7 @@ func runStackSequence(w http.ResponseWriter, sequence []runner.CommandStep) {
 
 			errMsg := strings.TrimRight(err.Error(), " \t\r\n")
 			escapedError := strings.ReplaceAll(errMsg, "\n", "\\n")
-			fmt.Fprintf(w, "event: error\ndata: Error during step '%s': %s\n\n", step.Name, escapedError)
-			flusher.Flush()
+			op.emit("error", fmt.Sprintf("Error during step '%s': %s", step.Name, escapedError))
 		} else {
 			logger.Debug("Completed sequence step successfully",
 				"step_index", i+1,
@@ -317,64 +392,54 @@ func runStackSequence(w http.ResponseWriter, sequence []runner.CommandStep) {
 	}
 
 	// Send a done event when the sequence is finished
-	fmt.Fprintf(w, "event: done\ndata: Sequence finished\n\n")
-	flusher.Flush()
+	op.emit("done", "Sequence finished")
 
-	logger.Info("Completed stack command sequence stream",
+	logger.Info("Completed stack command sequence",
 		"total_steps", len(sequence),
 		"total_duration", time.Since(startTime))
 }
 
-// runHostCommand streams the output of a given host command using Server-Sent Events.
-func runHostCommand(w http.ResponseWriter, step runner.HostCommandStep) {
-	startTime := time.Now()
-
+// runHostCommand starts a host command as an operation and streams its output
+// using Server-Sent Events, like runStackSequence.
+func runHostCommand(w http.ResponseWriter, r *http.Request, step runner.HostCommandStep) {
 	logger.Info("Starting host command stream",
 		"command_name", step.Name,
 		"server_name", step.Target.ServerName,
 		"is_remote", step.Target.IsRemote)
 
-	// Set headers for Server-Sent Events
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*") // Allow cross-origin for development
-
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		logger.Error("HTTP response writer does not support flushing for host command SSE stream")
-		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
-		return
-	}
+	op := startOperation(step.Target.ServerName, func(op *operation) {
+		runHostCommandOperation(op, step)
+	})
+	streamOperation(w, r, op, 0)
+}
 
-	logger.Debug("SSE headers set, starting host command execution",
-		"command_name", step.Name)
+// runHostCommandOperation runs a host command, recording its output as events of op.
+func runHostCommandOperation(op *operation, step runner.HostCommandStep) {
+	startTime := time.Now()
 
 	// Send step name as an event
-	fmt.Fprintf(w, "event: step\ndata: %s\n\n", step.Name)
-	flusher.Flush()
+	op.emit("step", step.Name)
 
 	outChan, errChan := runner.RunHostCommand(step, false) // Use cliMode false for channel output
 
 	outputLines := 0
 	errorLines := 0
 
-	// Collect output and errors from channels and stream them
+	// Collect output and errors from channels and record them
 	for outputLine := range outChan { // Normalize line endings
 		lines := strings.Split(strings.TrimRight(outputLine.Line, " \t\r\n"), "\n")
 		for _, line := range lines {
 			if trimmed := strings.TrimRight(line, " \t\r"); trimmed != "" {
 				escapedLine := strings.ReplaceAll(trimmed, "\n", "\\n")
 				if outputLine.IsError {
-					fmt.Fprintf(w, "event: stderr\ndata: %s\n\n", escapedLine)
+					op.emit("stderr", escapedLine)
 					errorLines++
 				} else {
-					fmt.Fprintf(w, "event: stdout\ndata: %s\n\n", escapedLine)
+					op.emit("stdout", escapedLine)
 					outputLines++
 				}
 			}
 		}
-		flusher.Flush()
 	}
 
 	// Check for errors after the command finishes
@@ -386,8 +451,7 @@ func runHostCommand(w http.ResponseWriter, step runner.HostCommandStep) {
 			"duration", time.Since(startTime))
 
 		escapedError := strings.ReplaceAll(err.Error(), "\n", "\\n")
-		fmt.Fprintf(w, "event: error\ndata: Error during step '%s': %s\n\n", step.Name, escapedError)
-		flusher.Flush()
+		op.emit("error", fmt.Sprintf("Error during step '%s': %s", step.Name, escapedError))
 	} else {
 		logger.Info("Completed host command successfully",
 			"command_name", step.Name,
@@ -398,8 +462,7 @@ func runHostCommand(w http.ResponseWriter, step runner.HostCommandStep) {
 	}
 
 	// Send a done event when the command is finished
-	fmt.Fprintf(w, "event: done\ndata: Command finished\n\n")
-	flusher.Flush()
+	op.emit("done", "Command finished")
 }
 
 // runStackUpHandler handles requests to start a stack.
@@ -425,14 +488,14 @@ func runStackUpHandler(w http.ResponseWriter, r *http.Request) {
 		"is_remote", stack.IsRemote,
 		"stack_path", stack.Path)
 
-	sequence := runner.UpSequence(stack)
+	sequence := runner.UpSequence(stack, nil)
 
 	logger.Debug("Generated stack up sequence",
 		"stack_name", stack.Name,
 		"sequence_length", len(sequence),
 		"preparation_duration", time.Since(startTime))
 
-	runStackSequence(w, sequence) // Stream output
+	runStackSequence(w, r, sequence) // Stream output
 }
 
 // runStackPullHandler handles requests to pull images for a stack.
@@ -458,389 +521,193 @@ func runStackPullHandler(w http.ResponseWriter, r *http.Request) {
 		"is_remote", stack.IsRemote,
 		"stack_path", stack.Path)
 
-	sequence := runner.PullSequence(stack)
+	sequence := runner.PullSequence(stack, nil)
 
 	logger.Debug("Generated stack pull sequence",
 		"stack_name", stack.Name,
 		"sequence_length", len(sequence),
 		"preparation_duration", time.Since(startTime))
 
-	runStackSequence(w, sequence) // Stream output
+	runStackSequence(w, r, sequence) // Stream output
 }
 
-// runStackDownHandler handles requests to stop a stack.
-func runStackDownHandler(w http.ResponseWriter, r *http.Request) {
+// runStackCreateHandler handles requests to create a stack's containers without starting them.
+func runStackCreateHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
-	logger.Info("Received stack down request",
+	logger.Info("Received stack create request",
 		"remote_addr", r.RemoteAddr,
 		"user_agent", r.Header.Get("User-Agent"))
 
 	stack, err := getStackFromRequest(r)
 	if err != nil {
-		logger.Error("Failed to get stack info for stack down request",
+		logger.Error("Failed to get stack info for stack create request",
 			"error", err,
 			"remote_addr", r.RemoteAddr)
 		http.Error(w, fmt.Sprintf("Error getting stack info: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	logger.Info("Starting stack down operation",
+	logger.Info("Starting stack create operation",
 		"stack_name", stack.Name,
 		"server_name", stack.ServerName,
 		"is_remote", stack.IsRemote,
 		"stack_path", stack.Path)
 
-	sequence := runner.DownSequence(stack)
+	sequence := runner.CreateSequence(stack, nil)
 
-	logger.Debug("Generated stack down sequence",
+	logger.Debug("Generated stack create sequence",
 		"stack_name", stack.Name,
 		"sequence_length", len(sequence),
 		"preparation_duration", time.Since(startTime))
 
-	runStackSequence(w, sequence) // Stream output
+	runStackSequence(w, r, sequence) // Stream output
 }
 
-// runStackRefreshHandler handles requests to run the 'refresh' sequence on a stack.
-func runStackRefreshHandler(w http.ResponseWriter, r *http.Request) {
+// runStackStopHandler handles requests to stop a stack's containers without removing them.
+func runStackStopHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
-	logger.Info("Received stack refresh request",
+	logger.Info("Received stack stop request",
 		"remote_addr", r.RemoteAddr,
 		"user_agent", r.Header.Get("User-Agent"))
 
 	stack, err := getStackFromRequest(r)
 	if err != nil {
-		logger.Error("Failed to get stack info for stack refresh request",
+		logger.Error("Failed to get stack info for stack stop request",
 			"error", err,
 			"remote_addr", r.RemoteAddr)
 		http.Error(w, fmt.Sprintf("Error getting stack info: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	logger.Info("Starting stack refresh operation",
+	logger.Info("Starting stack stop operation",
 		"stack_name", stack.Name,
 		"server_name", stack.ServerName,
 		"is_remote", stack.IsRemote,
 		"stack_path", stack.Path)
 
-	sequence := runner.RefreshSequence(stack)
+	sequence := runner.StopSequence(stack, nil, runner.DefaultStopTimeout)
 
-	logger.Debug("Generated stack refresh sequence",
+	logger.Debug("Generated stack stop sequence",
 		"stack_name", stack.Name,
 		"sequence_length", len(sequence),
 		"preparation_duration", time.Since(startTime))
 
-	runStackSequence(w, sequence) // Stream output
+	runStackSequence(w, r, sequence) // Stream output
 }
 
-// streamStackRefreshHandler handles GET requests to stream the 'refresh' sequence output on a stack.
-// streamStackRefreshHandler serves the GET /api/stream/stack/refresh endpoint, which
-// streams real-time output from the `compose ps` command to check stack status.
-//
-// This handler uses Server-Sent Events (SSE) to provide a continuous stream of
-// command execution updates to the client, including command output and error messages.
-// The connection remains open until the command completes or an error occurs.
-//
-// Query Parameters:
-// - name: The name of the stack to refresh
-// - serverName: The server name where the stack is located ("local" or an SSH host name)
-//
-// Response:
-// - 200 OK with text/event-stream content type for successful connections
-// - 400 Bad Request if required parameters are missing
-// - 404 Not Found if the stack or host doesn't exist
-// - 500 Internal Server Error if command execution fails
-func streamStackRefreshHandler(w http.ResponseWriter, r *http.Request) {
+// runStackStartHandler handles requests to start a stack's existing containers.
+func runStackStartHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
-	logger.Info("Received stream stack refresh request",
+	logger.Info("Received stack start request",
 		"remote_addr", r.RemoteAddr,
 		"user_agent", r.Header.Get("User-Agent"))
 
-	query := r.URL.Query()
-	stackName := query.Get("name")
-	serverName := query.Get("serverName")
-
-	logger.Debug("Parsing stream stack refresh query parameters",
-		"stack_name", stackName,
-		"server_name", serverName)
-
-	if stackName == "" || serverName == "" {
-		logger.Error("Missing required query parameters for stream stack refresh",
-			"stack_name", stackName,
-			"server_name", serverName,
+	stack, err := getStackFromRequest(r)
+	if err != nil {
+		logger.Error("Failed to get stack info for stack start request",
+			"error", err,
 			"remote_addr", r.RemoteAddr)
-		http.Error(w, "Missing 'name' or 'serverName' query parameter", http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Error getting stack info: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Adapted logic from getStackFromRequest to get stack details from query params
-	var stack discovery.Stack
-
-	if serverName == "local" {
-		rootDir, err := discovery.GetComposeRootDirectory()
-		if err != nil {
-			logger.Error("Failed to get local root directory for stream stack refresh",
-				"stack_name", stackName,
-				"error", err)
-			http.Error(w, fmt.Sprintf("Error getting local root directory: %v", err), http.StatusInternalServerError)
-			return
-		}
-		stackPath := rootDir + "/" + stackName
-		stack = discovery.Stack{
-			Name:       stackName,
-			Path:       stackPath,
-			ServerName: "local",
-			IsRemote:   false,
-		}
-
-		logger.Debug("Created local stack for stream refresh",
-			"stack_name", stackName,
-			"stack_path", stackPath)
-	} else {
-		// Get complete remote stack with AbsoluteRemoteRoot properly populated
-		logger.Debug("Looking up remote stack for stream refresh",
-			"stack_name", stackName,
-			"server_name", serverName)
-
-		completeStack, err := findRemoteStackByNameAndServer(stackName, serverName)
-		if err != nil {
-			logger.Error("Failed to find remote stack for stream refresh",
-				"stack_name", stackName,
-				"server_name", serverName,
-				"error", err)
-			http.Error(w, fmt.Sprintf("Error finding stack: %v", err), http.StatusNotFound)
-			return
-		}
-
-		stack = completeStack
-	}
-
-	logger.Info("Starting stream stack refresh operation",
+	logger.Info("Starting stack start operation",
 		"stack_name", stack.Name,
 		"server_name", stack.ServerName,
 		"is_remote", stack.IsRemote,
-		"stack_path", stack.Path,
+		"stack_path", stack.Path)
+
+	sequence := runner.StartSequence(stack, nil)
+
+	logger.Debug("Generated stack start sequence",
+		"stack_name", stack.Name,
+		"sequence_length", len(sequence),
 		"preparation_duration", time.Since(startTime))
 
-	sequence := runner.RefreshSequence(stack)
-	runStackSequence(w, sequence) // Stream output
+	runStackSequence(w, r, sequence) // Stream output
 }
 
-// streamStackUpHandler serves the GET /api/stream/stack/up endpoint, which
-// streams real-time output from the sequence of commands used to start a stack.
-//
-// This handler uses Server-Sent Events (SSE) to provide a continuous stream of
-// command execution updates to the client as the stack is being started. The stream
-// includes all output from the stack startup process.
-//
-// The connection remains open until:
-// - All commands complete successfully
-// - An error occurs during execution
-// - The client disconnects
-//
-// Query Parameters:
-// - name: The name of the stack to start
-// - serverName: The server name where the stack is located ("local" or an SSH host name)
-//
-// Response:
-// - 200 OK with text/event-stream content type for successful connections
-// - 400 Bad Request if required parameters are missing
-// - 404 Not Found if the stack or host doesn't exist
-// - 500 Internal Server Error if command execution fails
-func streamStackUpHandler(w http.ResponseWriter, r *http.Request) {
+// runStackDownHandler handles requests to stop a stack.
+func runStackDownHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
-	logger.Info("Received stream stack up request",
+	logger.Info("Received stack down request",
 		"remote_addr", r.RemoteAddr,
 		"user_agent", r.Header.Get("User-Agent"))
 
-	query := r.URL.Query()
-	stackName := query.Get("name")
-	serverName := query.Get("serverName")
-
-	logger.Debug("Parsing stream stack up query parameters",
-		"stack_name", stackName,
-		"server_name", serverName)
-
-	if stackName == "" || serverName == "" {
-		logger.Error("Missing required query parameters for stream stack up",
-			"stack_name", stackName,
-			"server_name", serverName,
+	stack, err := getStackFromRequest(r)
+	if err != nil {
+		logger.Error("Failed to get stack info for stack down request",
+			"error", err,
 			"remote_addr", r.RemoteAddr)
-		http.Error(w, "Missing 'name' or 'serverName' query parameter", http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Error getting stack info: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Adapted logic from getStackFromRequest to get stack details from query params
-	var stack discovery.Stack
-
-	if serverName == "local" {
-		rootDir, err := discovery.GetComposeRootDirectory()
-		if err != nil {
-			logger.Error("Failed to get local root directory for stream stack up",
-				"stack_name", stackName,
-				"error", err)
-			http.Error(w, fmt.Sprintf("Error getting local root directory: %v", err), http.StatusInternalServerError)
-			return
-		}
-		stackPath := rootDir + "/" + stackName
-		stack = discovery.Stack{
-			Name:       stackName,
-			Path:       stackPath,
-			ServerName: "local",
-			IsRemote:   false,
-		}
-
-		logger.Debug("Created local stack for stream up",
-			"stack_name", stackName,
-			"stack_path", stackPath)
-	} else {
-		// Get complete remote stack with AbsoluteRemoteRoot properly populated
-		logger.Debug("Looking up remote stack for stream up",
-			"stack_name", stackName,
-			"server_name", serverName)
-
-		completeStack, err := findRemoteStackByNameAndServer(stackName, serverName)
-		if err != nil {
-			logger.Error("Failed to find remote stack for stream up",
-				"stack_name", stackName,
-				"server_name", serverName,
-				"error", err)
-			http.Error(w, fmt.Sprintf("Error finding stack: %v", err), http.StatusNotFound)
-			return
-		}
-
-		stack = completeStack
-	}
-
-	logger.Info("Starting stream stack up operation",
+	logger.Info("Starting stack down operation",
 		"stack_name", stack.Name,
 		"server_name", stack.ServerName,
 		"is_remote", stack.IsRemote,
-		"stack_path", stack.Path,
+		"stack_path", stack.Path)
+
+	sequence := runner.DownSequence(stack, nil, runner.DefaultStopTimeout)
+
+	logger.Debug("Generated stack down sequence",
+		"stack_name", stack.Name,
+		"sequence_length", len(sequence),
 		"preparation_duration", time.Since(startTime))
 
-	sequence := runner.UpSequence(stack)
-	runStackSequence(w, sequence) // Stream output
+	runStackSequence(w, r, sequence) // Stream output
 }
 
-// streamStackDownHandler handles GET requests to stream output from stopping a stack.
-// streamStackDownHandler serves the GET /api/stream/stack/down endpoint, which
-// streams real-time output from the sequence of commands used to stop a stack.
-//
-// This handler uses Server-Sent Events (SSE) to provide a continuous stream of
-// command execution updates to the client as the stack is being stopped. The stream
-// includes all output from the stack shutdown process.
-//
-// The connection remains open until:
-// - All commands complete successfully
-// - An error occurs during execution
-// - The client disconnects
-//
-// Query Parameters:
-// - name: The name of the stack to stop
-// - serverName: The server name where the stack is located ("local" or an SSH host name)
-//
-// Response:
-// - 200 OK with text/event-stream content type for successful connections
-// - 400 Bad Request if required parameters are missing
-// - 404 Not Found if the stack or host doesn't exist
-// - 500 Internal Server Error if command execution fails
-func streamStackDownHandler(w http.ResponseWriter, r *http.Request) {
+// runStackRefreshHandler handles requests to run the 'refresh' sequence on a stack.
+func runStackRefreshHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
-	logger.Info("Received stream stack down request",
+	logger.Info("Received stack refresh request",
 		"remote_addr", r.RemoteAddr,
 		"user_agent", r.Header.Get("User-Agent"))
 
-	query := r.URL.Query()
-	stackName := query.Get("name")
-	serverName := query.Get("serverName")
-
-	logger.Debug("Parsing stream stack down query parameters",
-		"stack_name", stackName,
-		"server_name", serverName)
-
-	if stackName == "" || serverName == "" {
-		logger.Error("Missing required query parameters for stream stack down",
-			"stack_name", stackName,
-			"server_name", serverName,
+	stack, err := getStackFromRequest(r)
+	if err != nil {
+		logger.Error("Failed to get stack info for stack refresh request",
+			"error", err,
 			"remote_addr", r.RemoteAddr)
-		http.Error(w, "Missing 'name' or 'serverName' query parameter", http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("Error getting stack info: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Adapted logic from getStackFromRequest to get stack details from query params
-	var stack discovery.Stack
-
-	if serverName == "local" {
-		rootDir, err := discovery.GetComposeRootDirectory()
-		if err != nil {
-			logger.Error("Failed to get local root directory for stream stack down",
-				"stack_name", stackName,
-				"error", err)
-			http.Error(w, fmt.Sprintf("Error getting local root directory: %v", err), http.StatusInternalServerError)
-			return
-		}
-		stackPath := rootDir + "/" + stackName
-		stack = discovery.Stack{
-			Name:       stackName,
-			Path:       stackPath,
-			ServerName: "local",
-			IsRemote:   false,
-		}
-
-		logger.Debug("Created local stack for stream down",
-			"stack_name", stackName,
-			"stack_path", stackPath)
-	} else {
-		// Get complete remote stack with AbsoluteRemoteRoot properly populated
-		logger.Debug("Looking up remote stack for stream down",
-			"stack_name", stackName,
-			"server_name", serverName)
-
-		completeStack, err := findRemoteStackByNameAndServer(stackName, serverName)
-		if err != nil {
-			logger.Error("Failed to find remote stack for stream down",
-				"stack_name", stackName,
-				"server_name", serverName,
-				"error", err)
-			http.Error(w, fmt.Sprintf("Error finding stack: %v", err), http.StatusNotFound)
-			return
-		}
-
-		stack = completeStack
-	}
-
-	logger.Info("Starting stream stack down operation",
+	logger.Info("Starting stack refresh operation",
 		"stack_name", stack.Name,
 		"server_name", stack.ServerName,
 		"is_remote", stack.IsRemote,
-		"stack_path", stack.Path,
+		"stack_path", stack.Path)
+
+	sequence := runner.RefreshSequence(stack, nil, runner.DefaultStopTimeout)
+
+	logger.Debug("Generated stack refresh sequence",
+		"stack_name", stack.Name,
+		"sequence_length", len(sequence),
 		"preparation_duration", time.Since(startTime))
 
-	sequence := runner.DownSequence(stack)
-	runStackSequence(w, sequence) // Stream output
+	runStackSequence(w, r, sequence) // Stream output
 }
 
-// streamStackPullHandler handles GET requests to stream output from pulling images for a stack.
-// streamStackPullHandler serves the GET /api/stream/stack/pull endpoint, which
-// streams real-time output from the sequence of commands used to pull updated
-// container images for a stack.
+// streamStackHandler returns the handler of a GET /api/run/stack/<action>/stream
+// endpoint, which streams real-time output from the sequence of commands
+// buildSequence returns for the stack, such as `compose pull` and `compose up`.
 //
-// This handler uses Server-Sent Events (SSE) to provide a continuous stream of
-// command execution updates to the client as images are being pulled. The stream
-// includes all output from `compose pull` and any related commands.
-//
-// The connection remains open until:
+// The handler uses Server-Sent Events (SSE) to provide a continuous stream of
+// command execution updates to the client. The connection remains open until:
 // - All commands complete successfully
 // - An error occurs during execution
 // - The client disconnects
 //
 // Query Parameters:
-// - name: The name of the stack to pull images for
+// - name: The name of the stack
 // - serverName: The server name where the stack is located ("local" or an SSH host name)
 //
 // Response:
@@ -848,81 +715,84 @@ func streamStackDownHandler(w http.ResponseWriter, r *http.Request) {
 // - 400 Bad Request if required parameters are missing
 // - 404 Not Found if the stack or host doesn't exist
 // - 500 Internal Server Error if command execution fails
-func streamStackPullHandler(w http.ResponseWriter, r *http.Request) {
-	startTime := time.Now()
+func streamStackHandler(action string, buildSequence func(stack discovery.Stack) []runner.CommandStep) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
 
-	logger.Info("Received stream stack pull request",
-		"remote_addr", r.RemoteAddr,
-		"user_agent", r.Header.Get("User-Agent"))
+		logger.Info("Received stream stack request",
+			"action", action,
+			"remote_addr", r.RemoteAddr,
+			"user_agent", r.Header.Get("User-Agent"))
 
-	query := r.URL.Query()
-	stackName := query.Get("name")
-	serverName := query.Get("serverName")
+		query := r.URL.Query()
+		stackName := query.Get("name")
+		serverName := query.Get("serverName")
 
-	logger.Debug("Parsing stream stack pull query parameters",
-		"stack_name", stackName,
-		"server_name", serverName)
-
-	if stackName == "" || serverName == "" {
-		logger.Error("Missing required query parameters for stream stack pull",
+		logger.Debug("Parsing stream stack query parameters",
+			"action", action,
 			"stack_name", stackName,
-			"server_name", serverName,
-			"remote_addr", r.RemoteAddr)
-		http.Error(w, "Missing 'name' or 'serverName' query parameter", http.StatusBadRequest)
-		return
-	}
-
-	// Adapted logic from getStackFromRequest to get stack details from query params
-	var stack discovery.Stack
+			"server_name", serverName)
 
-	if serverName == "local" {
-		rootDir, err := discovery.GetComposeRootDirectory()
-		if err != nil {
-			logger.Error("Failed to get local root directory for stream stack pull",
+		if stackName == "" || serverName == "" {
+			logger.Error("Missing required query parameters for stream stack request",
+				"action", action,
 				"stack_name", stackName,
-				"error", err)
-			http.Error(w, fmt.Sprintf("Error getting local root directory: %v", err), http.StatusInternalServerError)
+				"server_name", serverName,
+				"remote_addr", r.RemoteAddr)
+			http.Error(w, "Missing 'name' or 'serverName' query parameter", http.StatusBadRequest)
 			return
 		}
-		stackPath := rootDir + "/" + stackName
-		stack = discovery.Stack{
-			Name:       stackName,
-			Path:       stackPath,
-			ServerName: "local",
-			IsRemote:   false,
-		}
 
-		logger.Debug("Created local stack for stream pull",
-			"stack_name", stackName,
-			"stack_path", stackPath)
-	} else {
-		// Get complete remote stack with AbsoluteRemoteRoot properly populated
-		logger.Debug("Looking up remote stack for stream pull",
-			"stack_name", stackName,
-			"server_name", serverName)
+		// Adapted logic from getStackFromRequest to get stack details from query params
+		var stack discovery.Stack
+
+		if serverName == "local" {
+			localStack, err := findLocalStackByName(stackName)
+			if err != nil {
+				logger.Error("Failed to get local root directory for stream stack request",
+					"action", action,
+					"stack_name", stackName,
+					"error", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			stack = localStack
 
-		completeStack, err := findRemoteStackByNameAndServer(stackName, serverName)
-		if err != nil {
-			logger.Error("Failed to find remote stack for stream pull",
+			logger.Debug("Created local stack for stream request",
+				"action", action,
 				"stack_name", stackName,
-				"server_name", serverName,
-				"error", err)
-			http.Error(w, fmt.Sprintf("Error finding stack: %v", err), http.StatusNotFound)
-			return
-		}
+				"stack_path", stack.Path)
+		} else {
+			// Get complete remote stack with AbsoluteRemoteRoot properly populated
+			logger.Debug("Looking up remote stack for stream request",
+				"action", action,
+				"stack_name", stackName,
+				"server_name", serverName)
+
+			completeStack, err := findRemoteStackByNameAndServer(stackName, serverName)
+			if err != nil {
+				logger.Error("Failed to find remote stack for stream request",
+					"action", action,
+					"stack_name", stackName,
+					"server_name", serverName,
+					"error", err)
+				http.Error(w, fmt.Sprintf("Error finding stack: %v", err), http.StatusNotFound)
+				return
+			}
 
-		stack = completeStack
-	}
+			stack = completeStack
+		}
 
-	logger.Info("Starting stream stack pull operation",
-		"stack_name", stack.Name,
-		"server_name", stack.ServerName,
-		"is_remote", stack.IsRemote,
-		"stack_path", stack.Path,
-		"preparation_duration", time.Since(startTime))
+		logger.Info("Starting stream stack operation",
+			"action", action,
+			"stack_name", stack.Name,
+			"server_name", stack.ServerName,
+			"is_remote", stack.IsRemote,
+			"stack_path", stack.Path,
+			"preparation_duration", time.Since(startTime))
 
-	sequence := runner.PullSequence(stack)
-	runStackSequence(w, sequence) // Stream output
+		runStackSequence(w, r, buildSequence(stack)) // Stream output
+	}
 }
 
 // runHostPruneHandler handles requests to clean up unused resources on a host.
@@ -935,11 +805,13 @@ func streamStackPullHandler(w http.ResponseWriter, r *http.Request) {
 //
 // Request Body (JSON):
 // - serverName: The name of the server to prune ("local" or an SSH host name)
-// - pruneVolumes: Boolean flag indicating whether to prune volumes as well (optional)
+// - scope: "images", "containers" or "networks" to prune only that kind of resource (optional)
+// - olderThan: Only prune resources created more than this long ago, e.g. "720h" (optional)
+// - keepLatest: Keep the N newest images of each repository; implies scope "images" (optional)
 //
 // Response:
 // - 200 OK with JSON containing command output and success status
-// - 400 Bad Request if the serverName is missing or invalid
+// - 400 Bad Request if the serverName or prune options are missing or invalid
 // - 404 Not Found if the host doesn't exist
 // - 500 Internal Server Error if command execution fails
 func runHostPruneHandler(w http.ResponseWriter, r *http.Request) {
@@ -949,7 +821,7 @@ func runHostPruneHandler(w http.ResponseWriter, r *http.Request) {
 		"remote_addr", r.RemoteAddr,
 		"user_agent", r.Header.Get("User-Agent"))
 
-	target, err := getHostTargetFromRequest(r)
+	target, req, err := getHostTargetFromRequest(r)
 	if err != nil {
 		logger.Error("Failed to get host info for host prune request",
 			"error", err,
@@ -958,18 +830,27 @@ func runHostPruneHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts, err := req.pruneOptions()
+	if err != nil {
+		logger.Error("Invalid prune options in host prune request",
+			"error", err,
+			"server_name", target.ServerName)
+		http.Error(w, fmt.Sprintf("Invalid prune options: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	logger.Info("Starting host prune operation",
 		"server_name", target.ServerName,
 		"is_remote", target.IsRemote,
 		"preparation_duration", time.Since(startTime))
 
-	step := runner.PruneHostStep(target)
+	step := runner.PruneHostStep(target, opts)
 
 	logger.Debug("Generated host prune step",
 		"server_name", target.ServerName,
 		"command_name", step.Name)
 
-	runHostCommand(w, step) // Stream output
+	runHostCommand(w, r, step) // Stream output
 }
 
 // TODO: Implement handlers for running arbitrary commands or sequences.
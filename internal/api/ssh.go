@@ -21,15 +21,16 @@ import (
 // These endpoints enable the web UI to manage SSH host configurations.
 func RegisterSSHRoutes(router *mux.Router) {
 	router.HandleFunc("/api/ssh/hosts", listSSHHostsHandler).Methods("GET")
-	router.HandleFunc("/api/ssh/hosts", addSSHHostHandler).Methods("POST")
+	router.HandleFunc("/api/ssh/hosts", writable("changing the configuration", addSSHHostHandler)).Methods("POST")
 	router.HandleFunc("/api/ssh/hosts/{name}", getSSHHostHandler).Methods("GET")
-	router.HandleFunc("/api/ssh/hosts/{name}", updateSSHHostHandler).Methods("PUT")
-	router.HandleFunc("/api/ssh/hosts/{name}", deleteSSHHostHandler).Methods("DELETE")
-	router.HandleFunc("/api/ssh/import", importSSHHostsHandler).Methods("POST")
+	router.HandleFunc("/api/ssh/hosts/{name}", writable("changing the configuration", updateSSHHostHandler)).Methods("PUT")
+	router.HandleFunc("/api/ssh/hosts/{name}", writable("changing the configuration", deleteSSHHostHandler)).Methods("DELETE")
+	router.HandleFunc("/api/ssh/import", writable("changing the configuration", importSSHHostsHandler)).Methods("POST")
 }
 
 // listSSHHostsHandler handles requests to list all SSH hosts.
-// GET /api/ssh/hosts - Returns a JSON array of all configured SSH hosts
+// GET /api/ssh/hosts - Returns a JSON array of all configured SSH hosts,
+// without their passwords
 func listSSHHostsHandler(w http.ResponseWriter, r *http.Request) {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -37,8 +38,13 @@ func listSSHHostsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hosts := make([]config.SSHHost, len(cfg.SSHHosts))
+	for i, host := range cfg.SSHHosts {
+		hosts[i] = host.Redacted()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(cfg.SSHHosts)
+	json.NewEncoder(w).Encode(hosts)
 }
 
 // addSSHHostHandler handles requests to add a new SSH host.
@@ -50,6 +56,13 @@ func addSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	unlock, err := config.LockConfig()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error locking config: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer unlock()
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error loading config: %v", err), http.StatusInternalServerError)
@@ -70,7 +83,7 @@ func addSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(newHost)
+	json.NewEncoder(w).Encode(newHost.Redacted())
 }
 
 // getSSHHostHandler handles requests to get details of a specific SSH host.
@@ -87,7 +100,7 @@ func getSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 	for _, host := range cfg.SSHHosts {
 		if host.Name == hostName {
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(host)
+			json.NewEncoder(w).Encode(host.Redacted())
 			return
 		}
 	}
@@ -96,6 +109,9 @@ func getSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // updateSSHHostHandler handles requests to update an existing SSH host.
+// An empty Password keeps the stored one, since responses never include it;
+// switching a host to key authentication clears it. Likewise, an empty
+// KeyPassphrase keeps the stored one as long as KeyPath doesn't change.
 func updateSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	hostName := vars["name"]
@@ -106,6 +122,13 @@ func updateSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	unlock, err := config.LockConfig()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error locking config: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer unlock()
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error loading config: %v", err), http.StatusInternalServerError)
@@ -119,6 +142,12 @@ func updateSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 			//  - Check for valid hostname, port, and authentication details
 			//  - Validate that remoteRoot exists on the remote system
 			//  - Consider adding a validation endpoint that checks connectivity
+			if !updatedHost.HasPassword() && updatedHost.KeyPath == "" {
+				updatedHost.Password = host.Password
+			}
+			if !updatedHost.HasKeyPassphrase() && updatedHost.KeyPath == host.KeyPath {
+				updatedHost.KeyPassphrase = host.KeyPassphrase
+			}
 			cfg.SSHHosts[i] = updatedHost
 			found = true
 			break
@@ -136,7 +165,7 @@ func updateSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedHost)
+	json.NewEncoder(w).Encode(updatedHost.Redacted())
 }
 
 // deleteSSHHostHandler handles requests to delete an SSH host.
@@ -144,6 +173,13 @@ func deleteSSHHostHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	hostName := vars["name"]
 
+	unlock, err := config.LockConfig()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error locking config: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer unlock()
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error loading config: %v", err), http.StatusInternalServerError)
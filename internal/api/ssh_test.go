@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/logger"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	testPassword   = "s3cret-password"
+	testPassphrase = "s3cret-passphrase"
+)
+
+// TestSSHHostsRedactSecrets checks that the SSH host endpoints never return a
+// stored password or key passphrase, nor log them, and that an update without
+// them keeps the stored ones.
+func TestSSHHostsRedactSecrets(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	configPath := filepath.Join(dir, "config", "bucket-manager", "config.yaml")
+	config.SetConfigPath(configPath)
+	t.Cleanup(func() { config.SetConfigPath("") })
+
+	var logs bytes.Buffer
+	logger.InitWeb(logger.LevelSilent)
+	logger.SetLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	RegisterSSHRoutes(router)
+
+	var responses []string
+	request := func(method, path, body string, wantStatus int) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if recorder.Code != wantStatus {
+			t.Fatalf("%s %s: status %d, want %d: %s", method, path, recorder.Code, wantStatus, recorder.Body)
+		}
+		responses = append(responses, method+" "+path+": "+recorder.Body.String())
+	}
+
+	request("POST", "/api/ssh/hosts",
+		`{"name":"pw","hostname":"pw.example","user":"me","password":"`+testPassword+`"}`, http.StatusCreated)
+	request("POST", "/api/ssh/hosts",
+		`{"name":"key","hostname":"key.example","user":"me","keyPath":"`+keyPath+`","keyPassphrase":"`+testPassphrase+`"}`, http.StatusCreated)
+	request("GET", "/api/ssh/hosts", "", http.StatusOK)
+	request("GET", "/api/ssh/hosts/pw", "", http.StatusOK)
+	request("GET", "/api/ssh/hosts/key", "", http.StatusOK)
+	request("PUT", "/api/ssh/hosts/pw", `{"name":"pw","hostname":"pw2.example","user":"me"}`, http.StatusOK)
+	request("PUT", "/api/ssh/hosts/key", `{"name":"key","hostname":"key2.example","user":"me","keyPath":"`+keyPath+`"}`, http.StatusOK)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range cfg.SSHHosts {
+		logger.Info("Loaded SSH host", "host", host)
+	}
+
+	for _, secret := range []string{testPassword, testPassphrase} {
+		for _, response := range responses {
+			if strings.Contains(response, secret) {
+				t.Errorf("response contains a secret: %s", response)
+			}
+		}
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("log contains a secret:\n%s", logs.String())
+		}
+	}
+
+	stored := map[string]config.SSHHost{}
+	for _, host := range cfg.SSHHosts {
+		stored[host.Name] = host
+	}
+	if got := stored["pw"].Password; got != testPassword {
+		t.Errorf("password after update = %q, want it kept", got)
+	}
+	if got := stored["key"].KeyPassphrase; got != testPassphrase {
+		t.Errorf("key passphrase after update = %q, want it kept", got)
+	}
+}
@@ -8,10 +8,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"bucket-manager/internal/config"
@@ -25,18 +27,19 @@ import (
 // StackWithStatus combines Stack information with its runtime status
 // for presenting complete stack information to the web UI
 type StackWithStatus struct {
-	discovery.Stack                    // Embedded Stack struct with stack metadata
-	Status          runner.StackStatus `json:"status"` // Current running status of the stack
+	discovery.Stack                         // Embedded Stack struct with stack metadata, redacted with Stack.Redacted
+	Status          runner.StackStatus      `json:"status"`                // Current running status of the stack
+	Containers      []runner.ContainerState `json:"containers,omitempty"`  // Containers reported by compose ps
+	StatusError     string                  `json:"statusError,omitempty"` // Why the status couldn't be determined, if it couldn't
+	Stale           bool                    `json:"stale,omitempty"`       // The compose files changed since the containers were created
 }
 
-// collectStacksWithStatus retrieves status for a slice of stacks concurrently
-// using goroutines for efficient parallel processing
 // collectStacksWithStatus transforms a slice of Stack objects into StackWithStatus objects
 // by fetching the current status of each stack in parallel using goroutines.
 //
 // This function:
 // 1. Creates a result array to store status-enhanced stack information
-// 2. Launches a goroutine for each stack to fetch its status concurrently
+// 2. Fetches the statuses with runner.GetStackStatuses (one SSH command per remote host)
 // 3. Waits for all status checks to complete
 // 4. Returns the complete array with status information
 //
@@ -52,34 +55,26 @@ func collectStacksWithStatus(stacks []discovery.Stack) []StackWithStatus {
 		"stack_count", len(stacks))
 
 	stacksWithStatus := make([]StackWithStatus, len(stacks))
-	var wg sync.WaitGroup
-	wg.Add(len(stacks))
-
+	indexes := make(map[string]int, len(stacks))
 	for i, stack := range stacks {
-		go func(i int, s discovery.Stack) {
-			defer wg.Done()
-
-			stackStartTime := time.Now()
-			logger.Debug("Getting status for stack",
-				"stack_name", s.Name,
-				"server_name", s.ServerName,
-				"is_remote", s.IsRemote)
-
-			statusInfo := runner.GetStackStatus(s)
-			stacksWithStatus[i] = StackWithStatus{
-				Stack:  s,
-				Status: statusInfo.OverallStatus,
-			}
-
-			logger.Debug("Status retrieved for stack",
-				"stack_name", s.Name,
-				"server_name", s.ServerName,
-				"status", statusInfo.OverallStatus,
-				"duration", time.Since(stackStartTime))
-		}(i, stack)
+		indexes[stack.Identifier()] = i
+		stacksWithStatus[i] = StackWithStatus{Stack: stack.Redacted(), Status: runner.StatusUnknown}
 	}
 
-	wg.Wait()
+	for statusInfo := range runner.GetStackStatuses(stacks) {
+		i := indexes[statusInfo.Stack.Identifier()]
+		stacksWithStatus[i].Status = statusInfo.OverallStatus
+		stacksWithStatus[i].Containers = statusInfo.Containers
+		stacksWithStatus[i].Stale = statusInfo.IsStale()
+		if statusInfo.Error != nil {
+			stacksWithStatus[i].StatusError = statusInfo.Error.Error()
+		}
+
+		logger.Debug("Status retrieved for stack",
+			"stack_name", statusInfo.Stack.Name,
+			"server_name", statusInfo.Stack.ServerName,
+			"status", statusInfo.OverallStatus)
+	}
 
 	logger.Info("Status collection completed for all stacks",
 		"stack_count", len(stacks),
@@ -88,6 +83,187 @@ func collectStacksWithStatus(stacks []discovery.Stack) []StackWithStatus {
 	return stacksWithStatus
 }
 
+// stackStreamRecheckInterval is how often the stack status stream re-checks
+// every discovered stack after the initial statuses have been sent, unless the
+// client asks for another interval.
+const stackStreamRecheckInterval = 30 * time.Second
+
+// minStackStreamRecheckInterval is the shortest re-check interval a client may
+// request, so a single connection can't keep every host busy with status checks.
+const minStackStreamRecheckInterval = 2 * time.Second
+
+// parseStreamInterval parses the interval query parameter of the stack stream:
+// a Go duration such as "5s" or "1m", or a plain number of seconds. Intervals
+// below minStackStreamRecheckInterval are raised to it.
+func parseStreamInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return stackStreamRecheckInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid interval %q: use a duration like \"5s\" or a number of seconds", value)
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid interval %q: must be positive", value)
+	}
+	return max(interval, minStackStreamRecheckInterval), nil
+}
+
+// drainDiscovery discards whatever a discovery run still sends on stackChan and
+// errorChan until both are closed. Either may already be nil.
+func drainDiscovery(stackChan <-chan discovery.Stack, errorChan <-chan error) {
+	for stackChan != nil || errorChan != nil {
+		select {
+		case _, ok := <-stackChan:
+			if !ok {
+				stackChan = nil
+			}
+		case _, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+			}
+		}
+	}
+}
+
+// streamStacksHandler serves the GET /api/stacks/stream endpoint, which discovers
+// local and remote stacks and streams their statuses as Server-Sent Events as
+// soon as each one resolves, instead of waiting for every stack like the list
+// endpoints do. Stacks are re-checked every stackStreamRecheckInterval until the
+// client disconnects; the optional interval query parameter (e.g. ?interval=5s)
+// changes how often, down to minStackStreamRecheckInterval. A round of checks is
+// skipped while the previous one is still running. Every event's data is JSON:
+//
+// - stack: a discovered stack (StackWithStatus with status UNKNOWN)
+// - status: a stack whose status was resolved or has changed since the last check
+// - discovery-error: an error string from discovering a host's stacks
+// - discovered: the number of stacks found, sent once discovery has finished
+func streamStacksHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	logger.Info("API request received",
+		"endpoint", "/api/stacks/stream",
+		"method", r.Method,
+		"remote_addr", r.RemoteAddr,
+		"user_agent", r.UserAgent())
+
+	interval, err := parseStreamInterval(r.URL.Query().Get("interval"))
+	if err != nil {
+		logger.Error("Invalid interval for stack stream", "error", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.Error("HTTP response writer does not support flushing for SSE stream")
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx := r.Context()
+	sendEvent := func(event string, data any) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			logger.Error("Failed to encode stack stream event", "event", event, "error", err)
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		flusher.Flush()
+	}
+
+	// Newly discovered stacks are checked as they arrive, one goroutine per
+	// stack; results are written from this goroutine only.
+	statusResults := make(chan StackWithStatus)
+	checkStatus := func(s discovery.Stack) {
+		go func() {
+			result := StackWithStatus{Stack: s.Redacted(), Status: runner.GetStackStatus(s).OverallStatus}
+			select {
+			case statusResults <- result:
+			case <-ctx.Done():
+			}
+		}()
+	}
+	// Re-checks of every stack are batched, one SSH command per remote host
+	checkStatuses := func(stacks []discovery.Stack) {
+		go func() {
+			for info := range runner.GetStackStatuses(stacks) {
+				select {
+				case statusResults <- StackWithStatus{Stack: info.Stack.Redacted(), Status: info.OverallStatus}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	stackChan, errorChan, _ := discovery.FindStacks(discovery.RootOverrides{})
+	var stacks []discovery.Stack
+	lastStatus := make(map[string]runner.StackStatus)
+	pendingChecks := 0
+
+	logger.Debug("Stack stream re-check interval", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("API stream closed by client",
+				"endpoint", "/api/stacks/stream",
+				"stack_count", len(stacks),
+				"duration", time.Since(startTime))
+			// Discovery keeps sending after the client has gone; drain its
+			// channels so the host goroutines can finish and close their sessions
+			go drainDiscovery(stackChan, errorChan)
+			return
+		case s, ok := <-stackChan:
+			if !ok {
+				stackChan = nil
+				if errorChan == nil {
+					sendEvent("discovered", len(stacks))
+				}
+				continue
+			}
+			stacks = append(stacks, s)
+			sendEvent("stack", StackWithStatus{Stack: s.Redacted(), Status: runner.StatusUnknown})
+			pendingChecks++
+			checkStatus(s)
+		case err, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+				if stackChan == nil {
+					sendEvent("discovered", len(stacks))
+				}
+				continue
+			}
+			sendEvent("discovery-error", err.Error())
+		case result := <-statusResults:
+			pendingChecks--
+			id := result.Identifier()
+			if previous, seen := lastStatus[id]; !seen || previous != result.Status {
+				lastStatus[id] = result.Status
+				sendEvent("status", result)
+			}
+		case <-ticker.C:
+			if pendingChecks > 0 {
+				continue // The previous round of checks is still running
+			}
+			logger.Debug("Re-checking stack statuses for stream", "stack_count", len(stacks))
+			pendingChecks += len(stacks)
+			checkStatuses(slices.Clone(stacks))
+		}
+	}
+}
+
 // writeJSONResponse writes a JSON response with CORS headers
 func writeJSONResponse(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -145,12 +321,31 @@ func findStackByName(stacks []discovery.Stack, name string) (*discovery.Stack, e
 		}
 	}
 
+	if err := ambiguousStackError(stacks, name); err != nil {
+		return nil, err
+	}
+
 	logger.Debug("Stack not found",
 		"stack_name", name,
 		"searched_stacks", len(stacks))
 	return nil, fmt.Errorf("stack not found")
 }
 
+// ambiguousStackError returns an error listing the stacks of one host that were
+// named after their paths because several of them share the name name, or nil
+// if there are none.
+func ambiguousStackError(stacks []discovery.Stack, name string) error {
+	matches := discovery.DisambiguatedStacks(stacks, name)
+	if len(matches) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(matches))
+	for _, stack := range matches {
+		names = append(names, stack.Name)
+	}
+	return fmt.Errorf("stack name '%s' is ambiguous, use one of: %s", name, strings.Join(names, ", "))
+}
+
 // findRemoteStackByNameAndServer finds a remote stack on a specific host by name.
 //
 // This function:
@@ -193,7 +388,7 @@ func findRemoteStackByNameAndServer(stackName, serverName string) (discovery.Sta
 
 	// TODO: In a future improvement, we should cache discovered stacks to avoid
 	// rediscovery for every operation. For now, we'll fetch them each time.
-	stacks, err := discovery.FindRemoteStacks(targetHost)
+	stacks, err := discovery.FindRemoteStacks(targetHost, "")
 	if err != nil {
 		logger.Error("Failed to discover remote stacks for stack lookup",
 			"stack_name", stackName,
@@ -219,6 +414,10 @@ func findRemoteStackByNameAndServer(stackName, serverName string) (discovery.Sta
 		}
 	}
 
+	if err := ambiguousStackError(stacks, stackName); err != nil {
+		return discovery.Stack{}, err
+	}
+
 	logger.Error("Remote stack not found",
 		"stack_name", stackName,
 		"server_name", serverName,
@@ -228,10 +427,13 @@ func findRemoteStackByNameAndServer(stackName, serverName string) (discovery.Sta
 }
 
 func RegisterStackRoutes(router *mux.Router) {
+	router.HandleFunc("/api/stacks/stream", streamStacksHandler).Methods("GET")
 	router.HandleFunc("/api/stacks/local", listLocalStacksHandler).Methods("GET")
-	router.HandleFunc("/api/stacks/local/{name}/status", getLocalStackStatusHandler).Methods("GET")
+	// Stacks sharing a name on a host are named after their paths, e.g. "a/app"
+	router.HandleFunc("/api/stacks/local/{name:.+}/status", getLocalStackStatusHandler).Methods("GET")
 	router.HandleFunc("/api/ssh/hosts/{hostName}/stacks", listRemoteStacksHandler).Methods("GET")
-	router.HandleFunc("/api/ssh/hosts/{hostName}/stacks/{name}/status", getRemoteStackStatusHandler).Methods("GET")
+	router.HandleFunc("/api/ssh/hosts/{hostName}/stacks/{name:.+}/status", getRemoteStackStatusHandler).Methods("GET")
+	router.HandleFunc("/api/ssh/hosts/{hostName}/status", getRemoteHostStatusHandler).Methods("GET")
 }
 
 // listLocalStacksHandler serves the GET /api/stacks/local endpoint, which returns
@@ -242,8 +444,8 @@ func RegisterStackRoutes(router *mux.Router) {
 // - 200 OK: Returns an array of stack objects with their status information
 // - 500 Internal Server Error: If an error occurs during stack discovery
 //
-// If no local root directory is configured or found, an empty array is returned
-// rather than an error.
+// If no local root directory is configured or found, only the local quadlets
+// are returned (an empty array without quadlet_discovery) rather than an error.
 func listLocalStacksHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
@@ -253,37 +455,16 @@ func listLocalStacksHandler(w http.ResponseWriter, r *http.Request) {
 		"remote_addr", r.RemoteAddr,
 		"user_agent", r.UserAgent())
 
-	rootDir, err := discovery.GetComposeRootDirectory()
-	if err != nil {
-		// If no local root is found, return an empty list, not an error
-		if strings.Contains(err.Error(), "could not find") {
-			logger.Info("No local root directory found, returning empty stack list",
-				"duration", time.Since(startTime))
-			writeJSONResponse(w, []StackWithStatus{})
-			return
-		}
-		logger.Error("Failed to get local root directory",
-			"error", err,
-			"duration", time.Since(startTime))
-		http.Error(w, fmt.Sprintf("Error getting local root directory: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	logger.Debug("Local root directory found", "root_dir", rootDir)
-
-	stacks, err := discovery.FindLocalStacks(rootDir)
+	stacks, err := discovery.DiscoverLocalStacks("")
 	if err != nil {
 		logger.Error("Failed to find local stacks",
-			"root_dir", rootDir,
 			"error", err,
 			"duration", time.Since(startTime))
 		http.Error(w, fmt.Sprintf("Error finding local stacks: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	logger.Debug("Local stacks discovered",
-		"stack_count", len(stacks),
-		"root_dir", rootDir)
+	logger.Debug("Local stacks discovered", "stack_count", len(stacks))
 
 	stacksWithStatus := collectStacksWithStatus(stacks)
 	writeJSONResponse(w, stacksWithStatus)
@@ -334,10 +515,10 @@ func listRemoteStacksHandler(w http.ResponseWriter, r *http.Request) {
 		"user", targetHost.User,
 		"port", targetHost.Port)
 
-	stacks, err := discovery.FindRemoteStacks(targetHost)
+	stacks, err := discovery.FindRemoteStacks(targetHost, "")
 	if err != nil {
 		// If no remote root is found, return an empty list, not an error
-		if strings.Contains(err.Error(), "could not find") {
+		if errors.Is(err, discovery.ErrRootNotFound) {
 			logger.Info("No remote root directory found, returning empty stack list",
 				"host_name", hostName,
 				"duration", time.Since(startTime))
@@ -392,25 +573,10 @@ func getLocalStackStatusHandler(w http.ResponseWriter, r *http.Request) {
 		"remote_addr", r.RemoteAddr,
 		"user_agent", r.UserAgent())
 
-	rootDir, err := discovery.GetComposeRootDirectory()
-	if err != nil {
-		logger.Error("Failed to get local root directory",
-			"stack_name", stackName,
-			"error", err,
-			"duration", time.Since(startTime))
-		http.Error(w, fmt.Sprintf("Error getting local root directory: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	logger.Debug("Local root directory found",
-		"stack_name", stackName,
-		"root_dir", rootDir)
-
-	stacks, err := discovery.FindLocalStacks(rootDir)
+	stacks, err := discovery.DiscoverLocalStacks("")
 	if err != nil {
 		logger.Error("Failed to find local stacks",
 			"stack_name", stackName,
-			"root_dir", rootDir,
 			"error", err,
 			"duration", time.Since(startTime))
 		http.Error(w, fmt.Sprintf("Error finding local stacks: %v", err), http.StatusInternalServerError)
@@ -436,6 +602,7 @@ func getLocalStackStatusHandler(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"name":   targetStack.Name,
 		"status": statusInfo.OverallStatus,
+		"stale":  statusInfo.IsStale(),
 	}
 
 	writeJSONResponse(w, response)
@@ -495,7 +662,7 @@ func getRemoteStackStatusHandler(w http.ResponseWriter, r *http.Request) {
 		"user", targetHost.User,
 		"port", targetHost.Port)
 
-	stacks, err := discovery.FindRemoteStacks(targetHost)
+	stacks, err := discovery.FindRemoteStacks(targetHost, "")
 	if err != nil {
 		logger.Error("Failed to find remote stacks",
 			"host_name", hostName,
@@ -527,6 +694,7 @@ func getRemoteStackStatusHandler(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"name":   targetStack.Name,
 		"status": statusInfo.OverallStatus,
+		"stale":  statusInfo.IsStale(),
 	}
 
 	writeJSONResponse(w, response)
@@ -538,3 +706,74 @@ func getRemoteStackStatusHandler(w http.ResponseWriter, r *http.Request) {
 		"status", statusInfo.OverallStatus,
 		"duration", time.Since(startTime))
 }
+
+// HostStackStatus is the status of one stack in the response of the host
+// status endpoint, in the same shape as the single stack status endpoints.
+type HostStackStatus struct {
+	Name        string             `json:"name"`
+	Status      runner.StackStatus `json:"status"`
+	Stale       bool               `json:"stale"`
+	StatusError string             `json:"statusError,omitempty"` // Why the status couldn't be determined, if it couldn't
+}
+
+// getRemoteHostStatusHandler serves the GET /api/ssh/hosts/{hostName}/status
+// endpoint, which returns the statuses of every stack on a remote host. The
+// host's stacks are discovered once and checked together, instead of once per
+// stack as with the single stack status endpoint.
+//
+// URL Parameters:
+// - hostName: The name of the SSH host as configured in the application
+//
+// Response:
+// - 200 OK: Returns an array of stack status objects, empty if the host has no stack root
+// - 404 Not Found: If the specified host is not configured
+// - 500 Internal Server Error: If an error occurs during stack discovery or SSH connection
+func getRemoteHostStatusHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	hostName := mux.Vars(r)["hostName"]
+
+	logger.Info("API request received",
+		"endpoint", "/api/ssh/hosts/status",
+		"method", r.Method,
+		"host_name", hostName,
+		"remote_addr", r.RemoteAddr,
+		"user_agent", r.UserAgent())
+
+	targetHost, err := findSSHHost(hostName)
+	if err != nil {
+		logger.Error("SSH host not found",
+			"host_name", hostName,
+			"error", err,
+			"duration", time.Since(startTime))
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	stacks, err := discoverHostStacks(runner.HostTarget{ServerName: hostName, IsRemote: true, HostConfig: targetHost})
+	if err != nil {
+		logger.Error("Failed to find remote stacks",
+			"host_name", hostName,
+			"error", err,
+			"duration", time.Since(startTime))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	statuses := make([]HostStackStatus, 0, len(stacks))
+	for _, stack := range collectStacksWithStatus(stacks) {
+		statuses = append(statuses, HostStackStatus{
+			Name:        stack.Name,
+			Status:      stack.Status,
+			Stale:       stack.Stale,
+			StatusError: stack.StatusError,
+		})
+	}
+
+	writeJSONResponse(w, statuses)
+
+	logger.Info("API request completed successfully",
+		"endpoint", "/api/ssh/hosts/status",
+		"host_name", hostName,
+		"stack_count", len(stacks),
+		"duration", time.Since(startTime))
+}
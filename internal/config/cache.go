@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package config's cache.go file keeps the last loaded config for settings
+// read on every command, status check or request, such as the compose flags
+// or read-only mode, so that the file is only parsed again once it changes.
+
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// cachedConfig is the config returned by LoadConfigCached, with the path and
+// file state it was loaded from.
+var cachedConfig struct {
+	sync.Mutex
+	loaded  bool
+	path    string
+	exists  bool
+	modTime time.Time
+	size    int64
+	cfg     Config
+}
+
+// LoadConfigCached is LoadConfig for settings read often: the file is only
+// read again once its modification time or size has changed, another config
+// path is used, or SaveConfig wrote it. Failed loads aren't cached. The
+// returned Config is shared and must not be modified.
+func LoadConfigCached() (Config, error) {
+	configPath, err := DefaultConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
+	info, statErr := os.Stat(configPath)
+
+	cachedConfig.Lock()
+	defer cachedConfig.Unlock()
+	if cachedConfig.loaded && cachedConfig.path == configPath && cachedConfig.exists == (statErr == nil) &&
+		(statErr != nil || (info.ModTime().Equal(cachedConfig.modTime) && info.Size() == cachedConfig.size)) {
+		return cachedConfig.cfg, nil
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		cachedConfig.loaded = false
+		return Config{}, err
+	}
+	cachedConfig.loaded = true
+	cachedConfig.path = configPath
+	cachedConfig.exists = statErr == nil
+	if statErr == nil {
+		cachedConfig.modTime, cachedConfig.size = info.ModTime(), info.Size()
+	}
+	cachedConfig.cfg = cfg
+	return cfg, nil
+}
+
+// invalidateCachedConfig makes the next LoadConfigCached read the file again,
+// e.g. after it was written within the modification time's granularity.
+func invalidateCachedConfig() {
+	cachedConfig.Lock()
+	cachedConfig.loaded = false
+	cachedConfig.Unlock()
+}
@@ -8,14 +8,19 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
 )
 
 // SSHHost represents a remote SSH host configuration for connecting to
@@ -30,50 +35,428 @@ type SSHHost struct {
 	// User is the SSH username for authentication
 	User string `yaml:"user"`
 
-	// Port is the SSH port number (optional, defaults to standard SSH port)
+	// Port is the SSH port number (optional, 0 means DefaultSSHPort). A port
+	// set explicitly, even to 22, is kept as given.
 	Port int `yaml:"port,omitempty"`
 
 	// KeyPath is the path to the SSH private key file
 	KeyPath string `yaml:"key_path,omitempty"`
 
+	// KeyPassphrase decrypts KeyPath if it is passphrase-protected (plaintext,
+	// discouraged). Without it, or KeyPassphraseCommand, the passphrase is
+	// asked for interactively.
+	KeyPassphrase string `yaml:"key_passphrase,omitempty"`
+
+	// KeyPassphraseCommand is a shell command printing the passphrase of
+	// KeyPath on its first line, run when the key is first used. An
+	// alternative to KeyPassphrase.
+	KeyPassphraseCommand string `yaml:"key_passphrase_command,omitempty"`
+
 	// Password is an optional authentication method (plaintext, discouraged)
 	Password string `yaml:"password,omitempty"`
 
+	// PasswordFile is the path of a file whose first line is the password,
+	// read when connecting. An alternative to Password.
+	PasswordFile string `yaml:"password_file,omitempty"`
+
+	// PasswordCommand is a shell command printing the password on its first
+	// line (e.g. "pass show server1"), run when connecting. An alternative to Password.
+	PasswordCommand string `yaml:"password_command,omitempty"`
+
 	// RemoteRoot is the directory path to search for stacks on the remote host
 	RemoteRoot string `yaml:"remote_root,omitempty"`
 
 	// Disabled indicates whether this host should be skipped during discovery
 	Disabled bool `yaml:"disabled,omitempty"`
+
+	// RunAsUser runs container commands on this host as another user through
+	// passwordless sudo (e.g. "root" for rootful podman). Empty runs them as User.
+	RunAsUser string `yaml:"run_as_user,omitempty"`
+
+	// StackRunAsUsers overrides RunAsUser for individual stacks, keyed by stack name.
+	// An empty value runs that stack's commands as User.
+	StackRunAsUsers map[string]string `yaml:"stack_run_as_users,omitempty"`
+
+	// ForwardAgent forwards the local SSH agent (SSH_AUTH_SOCK) to commands run
+	// on this host, e.g. so compose can pull from registries that authenticate with it.
+	ForwardAgent bool `yaml:"forward_agent,omitempty"`
+
+	// SSHAlgorithms overrides the global ssh_algorithms for this host, list by list.
+	SSHAlgorithms SSHAlgorithms `yaml:"ssh_algorithms,omitempty"`
+
+	// RuntimeRestartCommand overrides the global runtime_restart_command for
+	// this host, e.g. "systemctl restart podman" for rootful podman.
+	RuntimeRestartCommand string `yaml:"runtime_restart_command,omitempty"`
+
+	// ContainerRuntime overrides the global container_runtime for this host
+	// ("podman", "docker" or "auto").
+	ContainerRuntime string `yaml:"container_runtime,omitempty"`
+}
+
+// SSHAlgorithms lists the algorithms offered when connecting to a host over
+// SSH, for legacy hosts that only support algorithms the SSH client no longer
+// offers by default. Each list that is set replaces the client's default list,
+// in order of preference. Weak algorithms like diffie-hellman-group1-sha1 or
+// 3des-cbc make the connections that negotiate them easier to break, so they
+// are best set only on the hosts that need them.
+type SSHAlgorithms struct {
+	// KexAlgorithms are the key exchange algorithms, e.g. diffie-hellman-group14-sha1.
+	KexAlgorithms []string `yaml:"kex_algorithms,omitempty"`
+
+	// Ciphers are the encryption algorithms, e.g. aes128-cbc.
+	Ciphers []string `yaml:"ciphers,omitempty"`
+
+	// HostKeyAlgorithms are the host key types accepted from the host, e.g. ssh-rsa.
+	HostKeyAlgorithms []string `yaml:"host_key_algorithms,omitempty"`
+}
+
+// DefaultSSHPort is the port connected to for hosts that don't set one.
+const DefaultSSHPort = 22
+
+// SSHPort returns the port to connect to: Port, or DefaultSSHPort if it isn't set.
+func (h SSHHost) SSHPort() int {
+	if h.Port == 0 {
+		return DefaultSSHPort
+	}
+	return h.Port
+}
+
+// Address returns the host:port address to connect to, with IPv6 addresses
+// in brackets (e.g. "[2001:db8::1]:22").
+func (h SSHHost) Address() string {
+	return net.JoinHostPort(strings.Trim(h.Hostname, "[]"), strconv.Itoa(h.SSHPort()))
+}
+
+// DisplayAddress returns user@hostname for showing the host, followed by the
+// port only if it is set explicitly (even to 22), e.g. "admin@[2001:db8::1]:2222".
+func (h SSHHost) DisplayAddress() string {
+	if h.Port == 0 {
+		return h.User + "@" + h.Hostname
+	}
+	return h.User + "@" + net.JoinHostPort(strings.Trim(h.Hostname, "[]"), strconv.Itoa(h.Port))
+}
+
+// RunAsUserFor returns the user that container commands for the named stack
+// should run as on this host, or "" to run them as the SSH user. An empty
+// stackName returns the host-wide setting used for host-level commands.
+func (h SSHHost) RunAsUserFor(stackName string) string {
+	if user, ok := h.StackRunAsUsers[stackName]; ok && stackName != "" {
+		return user
+	}
+	return h.RunAsUser
+}
+
+// Redacted returns a copy of the host with its password and key passphrase
+// cleared, for showing the host outside the config file, e.g. in API
+// responses. KeyPath is kept: it names the key without revealing it.
+func (h SSHHost) Redacted() SSHHost {
+	h.Password = ""
+	h.KeyPassphrase = ""
+	return h
+}
+
+// LogValue implements slog.LogValuer so that a logged host never includes its
+// password or key passphrase, only whether one is set.
+func (h SSHHost) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", h.Name),
+		slog.String("hostname", h.Hostname),
+		slog.String("user", h.User),
+		slog.Int("port", h.Port),
+		slog.String("key_path", h.KeyPath),
+		slog.Bool("has_key_passphrase", h.KeyPassphrase != ""),
+		slog.String("key_passphrase_command", h.KeyPassphraseCommand),
+		slog.Bool("has_password", h.Password != ""),
+		slog.String("password_file", h.PasswordFile),
+		slog.String("password_command", h.PasswordCommand),
+		slog.String("remote_root", h.RemoteRoot),
+		slog.Bool("disabled", h.Disabled))
 }
 
 // Config represents the top-level application configuration
 type Config struct {
+	// ConfigVersion is the version of the file's format, upgraded on load by
+	// the migrations in migrate.go. Unset means a file older than versioning.
+	ConfigVersion int `yaml:"config_version"`
+
 	// LocalRoot is the custom directory to search for stacks locally (optional)
 	LocalRoot string `yaml:"local_root,omitempty"`
 
-	// ContainerRuntime specifies which container runtime to use (podman or docker)
-	// Defaults to "podman" if not specified
+	// ContainerRuntime specifies which container runtime to use: "podman",
+	// "docker", or "auto" to use the first of them with a working compose on
+	// each host. Unset means "auto".
 	ContainerRuntime string `yaml:"container_runtime,omitempty"`
 
+	// RefreshAllStagger is the delay between stack starts during a TUI "refresh all"
+	// (Go duration string, e.g. "2s"). Defaults to DefaultRefreshAllStagger.
+	RefreshAllStagger string `yaml:"refresh_all_stagger,omitempty"`
+
+	// RefreshAllMaxConcurrent limits how many stacks are refreshed at the same time
+	// during a TUI "refresh all". Defaults to DefaultRefreshAllMaxConcurrent.
+	RefreshAllMaxConcurrent int `yaml:"refresh_all_max_concurrent,omitempty"`
+
+	// DiskWarnFreePercent is the free-space percentage below which host disk usage
+	// is highlighted as a warning. Defaults to DefaultDiskWarnFreePercent.
+	DiskWarnFreePercent int `yaml:"disk_warn_free_percent,omitempty"`
+
+	// CollapseStepOutput collapses the output of each successful step in the TUI
+	// sequence view to a single line. It can be toggled while viewing output.
+	CollapseStepOutput bool `yaml:"collapse_step_output,omitempty"`
+
+	// OutputMaxLines is how many lines of output the TUI keeps for each step
+	// of an action, and the web interface for each operation; older lines are
+	// dropped. Defaults to DefaultOutputMaxLines; negative keeps all output.
+	OutputMaxLines int `yaml:"output_max_lines,omitempty"`
+
+	// CompletionNotify alerts when an action run from the TUI finishes, for
+	// when the terminal isn't being watched: "bell" rings the terminal bell,
+	// "desktop" sends a desktop notification and "both" does both. Unset does
+	// neither.
+	CompletionNotify string `yaml:"completion_notify,omitempty"`
+
+	// DefaultAction is the action run when Enter is pressed on a single stack in
+	// the TUI stack list (one of DefaultActions). Defaults to DefaultStackAction.
+	DefaultAction string `yaml:"default_action,omitempty"`
+
+	// PullParallel limits how many images compose pulls at the same time when a
+	// stack's images are pulled (compose --parallel). Zero leaves compose's default.
+	PullParallel int `yaml:"pull_parallel,omitempty"`
+
+	// StackPullParallel overrides PullParallel for individual stacks, keyed by
+	// stack identifier (e.g. "server1:api") or name.
+	StackPullParallel map[string]int `yaml:"stack_pull_parallel,omitempty"`
+
+	// SmartUp makes up skip pulling a stack's images when every one of them is
+	// pinned by digest (image@sha256:...) and already present, so pulling can't
+	// change anything. The --smart flag enables it for one `bm up`.
+	SmartUp bool `yaml:"smart_up,omitempty"`
+
+	// StackComposeProfiles lists the compose profiles enabled by default when a
+	// stack is started, stopped or pulled, keyed by stack identifier (e.g.
+	// "server1:api") or name. --compose-profile replaces them for one command.
+	StackComposeProfiles map[string][]string `yaml:"stack_compose_profiles,omitempty"`
+
+	// ComposeGlobalArgs are runtime flags inserted before "compose" in every
+	// compose command, and before the subcommand of the runtime's own commands
+	// (prune, image listing, ...), e.g. ["--connection=remote"] to use a podman
+	// connection. Each must be a single flag, with its value after "=".
+	ComposeGlobalArgs []string `yaml:"compose_global_args,omitempty"`
+
+	// OperationTimeout bounds how long a CLI stack action or prune may run on one
+	// target before it is stopped (Go duration string, e.g. "30m"). The --timeout
+	// flag overrides it; unset means no limit.
+	OperationTimeout string `yaml:"operation_timeout,omitempty"`
+
+	// OperationLogDir is a directory where the web API writes the output of
+	// every operation it runs to a file, for later review. Unset keeps the
+	// output in memory only.
+	OperationLogDir string `yaml:"operation_log_dir,omitempty"`
+
+	// StatusCacheTTL is how long a checked stack status is reused before the
+	// stack is checked again (Go duration string, e.g. "10s"). "0" disables the
+	// cache. Defaults to DefaultStatusCacheTTL.
+	StatusCacheTTL string `yaml:"status_cache_ttl,omitempty"`
+
+	// ReadOnly disables actions that stop or remove anything (down, refresh,
+	// prune, service restarts) and changes to the configuration, in every
+	// interface. The --read-only flag enables it for one run.
+	ReadOnly bool `yaml:"read_only,omitempty"`
+
+	// DiscoveryMaxDepth is how many directory levels below a stack root are
+	// searched for compose files, for stacks laid out like app/docker/compose.yaml.
+	// Defaults to DefaultDiscoveryMaxDepth.
+	DiscoveryMaxDepth int `yaml:"discovery_max_depth,omitempty"`
+
+	// DiscoveryTimeout bounds how long the search for stacks on a remote host
+	// may take (Go duration string, e.g. "1m"), so a host with a hung mount
+	// fails on its own instead of holding up discovery. "0" disables it.
+	// Defaults to DefaultDiscoveryTimeout.
+	DiscoveryTimeout string `yaml:"discovery_timeout,omitempty"`
+
+	// DiscoveryXDev keeps the search for stacks on remote hosts on the
+	// filesystem of the remote root (find -xdev), skipping network and other
+	// mounts below it.
+	DiscoveryXDev bool `yaml:"discovery_xdev,omitempty"`
+
+	// RuntimeRestartCommand is the shell command run by the "restart runtime"
+	// host action, locally and on hosts that don't set their own. Defaults to
+	// restarting the runtime's systemd service (see RuntimeRestartCommandFor).
+	RuntimeRestartCommand string `yaml:"runtime_restart_command,omitempty"`
+
+	// QuadletDiscovery also lists the Podman quadlet .container units of the
+	// local user and of each host's SSH user as stacks, managed with systemctl --user.
+	QuadletDiscovery bool `yaml:"quadlet_discovery,omitempty"`
+
+	// IdentifierFormat is a Go template that stacks are shown with in CLI
+	// output and the TUI, e.g. "{{.Name}}@{{.ServerName}}", with the fields of
+	// IdentifierFields. Stacks are still targeted by identifier. Unset shows
+	// stacks as before.
+	IdentifierFormat string `yaml:"identifier_format,omitempty"`
+
+	// PinnedStacks lists the identifiers of the stacks pinned in the TUI (e.g.
+	// "server1:api"), which are listed before the others. Pins are toggled there.
+	PinnedStacks []string `yaml:"pinned_stacks,omitempty"`
+
+	// LogFile is the log file path, shared by all interfaces. Defaults to a
+	// per-interface file in $XDG_STATE_HOME/bucket-manager.
+	LogFile string `yaml:"log_file,omitempty"`
+
+	// LogMaxSizeMB is the size in megabytes at which the log file is rotated.
+	// Defaults to logger.DefaultMaxSizeMB.
+	LogMaxSizeMB int `yaml:"log_max_size_mb,omitempty"`
+
+	// LogMaxBackups is the number of rotated log files kept; 0 keeps none.
+	// Defaults to logger.DefaultMaxBackups if unset.
+	LogMaxBackups *int `yaml:"log_max_backups,omitempty"`
+
+	// Keybindings overrides TUI key bindings, mapping action names from the TUI
+	// KeyMap (e.g. "UpAction", "Down") to the keys that should trigger them.
+	Keybindings map[string][]string `yaml:"keybindings,omitempty"`
+
+	// Groups names sets of hosts ("local" or SSH host names) that CLI commands
+	// taking hosts accept as "@name", e.g. prod: [server1, server2].
+	Groups map[string][]string `yaml:"groups,omitempty"`
+
+	// SSHAlgorithms sets the SSH algorithms offered to every host, unless the
+	// host sets its own. Unset lists use the SSH client's defaults.
+	SSHAlgorithms SSHAlgorithms `yaml:"ssh_algorithms,omitempty"`
+
 	// SSHHosts is a list of remote SSH host configurations
 	SSHHosts []SSHHost `yaml:"ssh_hosts"`
+
+	// envFileValues holds the file's values of the options overridden from the
+	// environment, keyed by variable name (see applyEnvOverrides).
+	envFileValues map[string]any
 }
 
+// Defaults for the TUI "refresh all" queue.
+const (
+	DefaultRefreshAllStagger       = 2 * time.Second
+	DefaultRefreshAllMaxConcurrent = 2
+)
+
+// DefaultStatusCacheTTL is the default lifetime of a cached stack status.
+const DefaultStatusCacheTTL = 5 * time.Second
+
+// DefaultDiscoveryTimeout is the default limit on the search for stacks on a
+// remote host.
+const DefaultDiscoveryTimeout = 20 * time.Second
+
+// DefaultDiskWarnFreePercent is the default low disk space warning threshold.
+const DefaultDiskWarnFreePercent = 10
+
+// DefaultOutputMaxLines is the default number of output lines kept per step or
+// operation.
+const DefaultOutputMaxLines = 10000
+
+// DefaultDiscoveryMaxDepth is the default stack discovery depth: only the
+// directories directly under a stack root are checked for compose files.
+const DefaultDiscoveryMaxDepth = 1
+
+// DefaultStackAction is the default action for Enter in the TUI stack list,
+// which opens the stack details view.
+const DefaultStackAction = "details"
+
+// DefaultActions lists the accepted values of default_action.
+var DefaultActions = []string{DefaultStackAction, "up", "down", "refresh", "pull", "logs"}
+
+// CompletionNotifyModes lists the accepted values of completion_notify.
+var CompletionNotifyModes = []string{"bell", "desktop", "both"}
+
+// configPathEnv and profileEnv name the environment variables selecting the
+// config file, like --config and --profile. The flags take precedence.
+const (
+	configPathEnv = "BM_CONFIG"
+	profileEnv    = "BM_PROFILE"
+)
+
+// configPathOverride and profileOverride are set by SetConfigPath and
+// SetProfile, before anything loads the config.
+var configPathOverride, profileOverride string
+
+// SetConfigPath makes the config be read from and saved to path instead of
+// the default location, for --config.
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
+// SetProfile selects the config file of a named profile,
+// bucket-manager/profiles/<name>.yaml in the user config directory, for
+// --profile. Each profile has its own hosts and settings.
+func SetProfile(name string) error {
+	if err := checkProfileName(name); err != nil {
+		return err
+	}
+	profileOverride = name
+	return nil
+}
+
+// checkProfileName checks that a profile name can be used as a file name.
+func checkProfileName(name string) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid profile name '%s': it can't contain slashes or be '.' or '..'", name)
+	}
+	return nil
+}
+
+// DefaultConfigPath returns the path of the config file: the one given with
+// --config or BM_CONFIG, that of the --profile or BM_PROFILE profile, or
+// bucket-manager/config.yaml in the user config directory.
 func DefaultConfigPath() (string, error) {
+	configPath, err := configFilePath()
+	if err != nil {
+		logger.Error("Failed to determine config path", "error", err)
+		return "", err
+	}
+	logger.Debug("Determined default config path", "config_path", configPath)
+	return configPath, nil
+}
+
+// configFilePath is DefaultConfigPath without logging, for use before the
+// logger is initialized.
+func configFilePath() (string, error) {
+	if configPathOverride != "" {
+		return configPathOverride, nil
+	}
+	if path := os.Getenv(configPathEnv); path != "" {
+		return path, nil
+	}
+
 	configDir, err := os.UserConfigDir()
 	if err != nil {
-		logger.Error("Failed to get user config directory", "error", err)
 		return "", fmt.Errorf("failed to get user config directory: %w", err)
 	}
 
-	configPath := filepath.Join(configDir, "bucket-manager", "config.yaml")
-	logger.Debug("Determined default config path",
-		"config_dir", configDir,
-		"config_path", configPath)
+	profile := profileOverride
+	if profile == "" {
+		profile = os.Getenv(profileEnv)
+	}
+	if profile != "" {
+		if err := checkProfileName(profile); err != nil {
+			return "", err
+		}
+		return filepath.Join(configDir, "bucket-manager", "profiles", profile+".yaml"), nil
+	}
+	return filepath.Join(configDir, "bucket-manager", "config.yaml"), nil
+}
 
-	return configPath, nil
+// StateDir returns the directory bucket-manager keeps its state in, following
+// the XDG spec: $XDG_STATE_HOME/bucket-manager, ~/.local/state/bucket-manager
+// by default. The log files are written there too.
+func StateDir() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not get user home directory: %w", err)
+		}
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateDir, "bucket-manager"), nil
 }
 
+// LoadConfig reads the config file and applies the BM_* environment variable
+// overrides (see env.go) on top of it. A missing file is treated as empty.
 func LoadConfig() (Config, error) {
 	startTime := time.Now()
 
@@ -91,8 +474,13 @@ func LoadConfig() (Config, error) {
 			logger.Info("Configuration file not found, using defaults",
 				"config_path", configPath,
 				"duration", time.Since(startTime))
-			// Return empty config - defaults will be set below
-			return Config{}, nil
+			// Return empty config, apart from any environment overrides
+			cfg := Config{ConfigVersion: CurrentConfigVersion}
+			if err := applyEnvOverrides(&cfg); err != nil {
+				logger.Error("Invalid config override in environment", "error", err)
+				return Config{}, err
+			}
+			return cfg, nil
 		}
 		logger.Error("Failed to read config file",
 			"config_path", configPath,
@@ -115,10 +503,25 @@ func LoadConfig() (Config, error) {
 		return Config{}, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
 
-	// Set default container runtime if not specified
-	if cfg.ContainerRuntime == "" {
-		cfg.ContainerRuntime = "podman"
-		logger.Debug("Applied default container runtime", "runtime", "podman")
+	switch {
+	case cfg.ConfigVersion < CurrentConfigVersion:
+		cfg, err = upgradeConfigFile(configPath, data, cfg.ConfigVersion)
+		if err != nil {
+			logger.Error("Failed to upgrade config file",
+				"config_path", configPath,
+				"error", err)
+			return Config{}, fmt.Errorf("failed to upgrade config file %s: %w", configPath, err)
+		}
+	case cfg.ConfigVersion > CurrentConfigVersion:
+		logger.Warn("Config file written by a newer release, options it added are ignored",
+			"config_path", configPath,
+			"config_version", cfg.ConfigVersion,
+			"supported_version", CurrentConfigVersion)
+	}
+
+	if err := applyEnvOverrides(&cfg); err != nil {
+		logger.Error("Invalid config override in environment", "error", err)
+		return Config{}, err
 	}
 
 	logger.Info("Configuration loaded successfully",
@@ -172,6 +575,11 @@ func EnsureConfigDir() error {
 func SaveConfig(cfg Config) error {
 	startTime := time.Now()
 
+	if err := CheckWritable("changing the configuration"); err != nil {
+		logger.Warn("Refusing to save configuration", "error", err)
+		return err
+	}
+
 	configPath, err := DefaultConfigPath()
 	if err != nil {
 		logger.Error("Failed to get default config path for saving", "error", err)
@@ -190,7 +598,11 @@ func SaveConfig(cfg Config) error {
 		return err
 	}
 
-	data, err := yaml.Marshal(cfg)
+	// Whatever version the file had, it now only holds the options of this release
+	cfg.ConfigVersion = CurrentConfigVersion
+
+	// Overrides from the environment only last as long as the process
+	data, err := yaml.Marshal(cfg.withoutEnvOverrides())
 	if err != nil {
 		logger.Error("Failed to marshal config to YAML",
 			"error", err,
@@ -202,8 +614,9 @@ func SaveConfig(cfg Config) error {
 		"yaml_size", len(data),
 		"config_path", configPath)
 
-	// Write with permissions rw-r----- (0640)
-	err = os.WriteFile(configPath, data, 0640)
+	// Write to a temporary file in the same directory and rename it into place so
+	// that a crash mid-write never leaves a truncated config behind.
+	err = util.WriteFileAtomic(configPath, data, 0640)
 	if err != nil {
 		logger.Error("Failed to write config file",
 			"config_path", configPath,
@@ -212,6 +625,7 @@ func SaveConfig(cfg Config) error {
 			"duration", time.Since(startTime))
 		return fmt.Errorf("failed to write config file %s: %w", configPath, err)
 	}
+	invalidateCachedConfig()
 
 	logger.Info("Configuration saved successfully",
 		"config_path", configPath,
@@ -222,27 +636,67 @@ func SaveConfig(cfg Config) error {
 	return nil
 }
 
-// GetContainerRuntime returns the configured container runtime, defaulting to "podman"
-func GetContainerRuntime() string {
-	logger.Debug("Getting container runtime from configuration")
+// UpdateConfig loads the configuration, applies mutate to it, and saves the
+// result while holding the config lock, so changes made by other instances
+// in the meantime are not lost. Nothing is saved if mutate returns an error.
+func UpdateConfig(mutate func(cfg *Config) error) error {
+	unlock, err := LockConfig()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	cfg, err := LoadConfig()
 	if err != nil {
-		logger.Warn("Failed to load config for runtime check, using default",
-			"error", err,
-			"default_runtime", "podman")
-		// Fallback to podman if config can't be loaded
-		return "podman"
+		return err
+	}
+	if err := mutate(&cfg); err != nil {
+		return err
 	}
+	return SaveConfig(cfg)
+}
 
-	runtime := cfg.ContainerRuntime
-	if runtime == "" {
-		runtime = "podman"
-		logger.Debug("No runtime configured, using default", "runtime", runtime)
-	} else {
-		logger.Debug("Retrieved configured runtime", "runtime", runtime)
+// configLockPath returns the path of the lock file used by LockConfig.
+func configLockPath() (string, error) {
+	configPath, err := DefaultConfigPath()
+	if err != nil {
+		return "", err
 	}
+	return configPath + ".lock", nil
+}
+
+// ContainerRuntimeAuto is the container_runtime value that detects the runtime
+// of each host, like leaving it unset.
+const ContainerRuntimeAuto = "auto"
+
+// ContainerRuntimes lists the valid container_runtime values.
+var ContainerRuntimes = []string{ContainerRuntimeAuto, "podman", "docker"}
+
+// ContainerRuntimeFor returns the container runtime configured for host (nil
+// for the local machine): the host's container_runtime, otherwise the global
+// one. It returns "" if neither is set to "podman" or "docker", meaning the
+// runtime is detected on the host.
+func (c Config) ContainerRuntimeFor(host *SSHHost) string {
+	runtime := c.ContainerRuntime
+	if host != nil && host.ContainerRuntime != "" {
+		runtime = host.ContainerRuntime
+	}
+	if runtime == ContainerRuntimeAuto {
+		return ""
+	}
+	return runtime
+}
 
+// GetContainerRuntime returns the container runtime configured for host (nil
+// for the local machine), or "" if it is to be detected on the host.
+func GetContainerRuntime(host *SSHHost) string {
+	cfg, err := LoadConfig()
+	if err != nil {
+		logger.Warn("Failed to load config for runtime check, detecting the runtime", "error", err)
+		return ""
+	}
+	runtime := cfg.ContainerRuntimeFor(host)
+	logger.Debug("Retrieved configured runtime", "runtime", runtime)
 	return runtime
 }
 
@@ -270,3 +724,364 @@ func ResolvePath(path string) (string, error) {
 
 	return resolvedPath, nil
 }
+
+// GetRefreshAllStagger returns the parsed "refresh all" stagger delay,
+// falling back to DefaultRefreshAllStagger if unset or invalid.
+func (c Config) GetRefreshAllStagger() time.Duration {
+	if c.RefreshAllStagger == "" {
+		return DefaultRefreshAllStagger
+	}
+	d, err := time.ParseDuration(c.RefreshAllStagger)
+	if err != nil || d < 0 {
+		logger.Warn("Invalid refresh_all_stagger in config, using default",
+			"value", c.RefreshAllStagger,
+			"error", err,
+			"default", DefaultRefreshAllStagger)
+		return DefaultRefreshAllStagger
+	}
+	return d
+}
+
+// GetRefreshAllMaxConcurrent returns the "refresh all" concurrency limit,
+// falling back to DefaultRefreshAllMaxConcurrent if unset or invalid.
+func (c Config) GetRefreshAllMaxConcurrent() int {
+	if c.RefreshAllMaxConcurrent <= 0 {
+		return DefaultRefreshAllMaxConcurrent
+	}
+	return c.RefreshAllMaxConcurrent
+}
+
+// GetDiskWarnFreePercent returns the low disk space warning threshold,
+// falling back to DefaultDiskWarnFreePercent if unset or out of range.
+func (c Config) GetDiskWarnFreePercent() int {
+	if c.DiskWarnFreePercent <= 0 || c.DiskWarnFreePercent > 100 {
+		return DefaultDiskWarnFreePercent
+	}
+	return c.DiskWarnFreePercent
+}
+
+// GetOutputMaxLines returns how many lines of output are kept per step or
+// operation: DefaultOutputMaxLines if unset, or zero (no limit) if negative.
+func (c Config) GetOutputMaxLines() int {
+	switch {
+	case c.OutputMaxLines == 0:
+		return DefaultOutputMaxLines
+	case c.OutputMaxLines < 0:
+		return 0
+	}
+	return c.OutputMaxLines
+}
+
+// GetDefaultAction returns the action for Enter in the TUI stack list,
+// falling back to DefaultStackAction if unset or invalid.
+func (c Config) GetDefaultAction() string {
+	if c.DefaultAction == "" {
+		return DefaultStackAction
+	}
+	if !slices.Contains(DefaultActions, c.DefaultAction) {
+		logger.Warn("Invalid default_action in config, using default",
+			"value", c.DefaultAction,
+			"default", DefaultStackAction)
+		return DefaultStackAction
+	}
+	return c.DefaultAction
+}
+
+// CompletionBell reports whether completion_notify rings the terminal bell.
+func (c Config) CompletionBell() bool {
+	return c.CompletionNotify == "bell" || c.CompletionNotify == "both"
+}
+
+// CompletionDesktop reports whether completion_notify sends a desktop
+// notification.
+func (c Config) CompletionDesktop() bool {
+	return c.CompletionNotify == "desktop" || c.CompletionNotify == "both"
+}
+
+// GetPullParallel returns the image pull parallelism for a stack: its entry in
+// StackPullParallel (by identifier, then name), otherwise PullParallel. Zero
+// means compose's default is used.
+func (c Config) GetPullParallel(identifier, name string) int {
+	n, ok := c.StackPullParallel[identifier]
+	if !ok {
+		n, ok = c.StackPullParallel[name]
+	}
+	if !ok {
+		n = c.PullParallel
+	}
+	return max(n, 0)
+}
+
+// GetComposeProfiles returns the default compose profiles of a stack: its entry
+// in StackComposeProfiles, by identifier, then name.
+func (c Config) GetComposeProfiles(identifier, name string) []string {
+	if profiles, ok := c.StackComposeProfiles[identifier]; ok {
+		return profiles
+	}
+	return c.StackComposeProfiles[name]
+}
+
+// GetComposeGlobalArgs returns the runtime flags inserted before "compose" in
+// compose commands, or none if any of them is invalid (see
+// CheckComposeGlobalArgs).
+func (c Config) GetComposeGlobalArgs() []string {
+	if err := CheckComposeGlobalArgs(c.ComposeGlobalArgs); err != nil {
+		logger.Warn("Invalid compose_global_args in config, ignoring them",
+			"value", c.ComposeGlobalArgs,
+			"error", err)
+		return nil
+	}
+	return c.ComposeGlobalArgs
+}
+
+// CheckComposeGlobalArgs checks that each of args is a single flag such as
+// "--connection=remote". Separate values, "compose" and its subcommands are
+// refused, since they would change which command runs.
+func CheckComposeGlobalArgs(args []string) error {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			return fmt.Errorf("'%s' is not a flag; give flag values after '=', e.g. --connection=remote", arg)
+		}
+	}
+	return nil
+}
+
+// GroupMembers returns the hosts of a group in Groups, without duplicates.
+func (c Config) GroupMembers(group string) ([]string, error) {
+	members, ok := c.Groups[group]
+	if !ok {
+		return nil, fmt.Errorf("host group '%s' not found in configuration", group)
+	}
+	var hosts []string
+	for _, member := range members {
+		if !slices.Contains(hosts, member) {
+			hosts = append(hosts, member)
+		}
+	}
+	return hosts, nil
+}
+
+// ExpandHostGroups replaces each "@group" in names with the group's members,
+// keeping the order of the names and dropping duplicates. Other names are
+// returned as they are.
+func (c Config) ExpandHostGroups(names []string) ([]string, error) {
+	var expanded []string
+	for _, name := range names {
+		hosts := []string{name}
+		if group, ok := strings.CutPrefix(name, "@"); ok {
+			var err error
+			if hosts, err = c.GroupMembers(group); err != nil {
+				return nil, err
+			}
+		}
+		for _, host := range hosts {
+			if !slices.Contains(expanded, host) {
+				expanded = append(expanded, host)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// RenameSSHHost renames the SSH host oldName to newName, along with the
+// settings that refer to it: group members and the "oldName:stack"
+// identifiers in StackPullParallel, StackComposeProfiles and PinnedStacks.
+func (c *Config) RenameSSHHost(oldName, newName string) error {
+	switch {
+	case newName == "":
+		return fmt.Errorf("the new name can't be empty")
+	case newName == "local":
+		return fmt.Errorf("'local' is reserved for the local host")
+	case strings.ContainsAny(newName, ":@ \t"):
+		return fmt.Errorf("the new name '%s' can't contain ':', '@' or whitespace", newName)
+	}
+
+	index := -1
+	for i, h := range c.SSHHosts {
+		if h.Name == newName {
+			return fmt.Errorf("SSH host with name '%s' already exists", newName)
+		}
+		if h.Name == oldName {
+			index = i
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("SSH host '%s' not found", oldName)
+	}
+	c.SSHHosts[index].Name = newName
+
+	renameIdentifier := func(identifier string) string {
+		if stackName, ok := strings.CutPrefix(identifier, oldName+":"); ok {
+			return newName + ":" + stackName
+		}
+		return identifier
+	}
+	for key, n := range c.StackPullParallel {
+		if renamed := renameIdentifier(key); renamed != key {
+			delete(c.StackPullParallel, key)
+			c.StackPullParallel[renamed] = n
+		}
+	}
+	for key, profiles := range c.StackComposeProfiles {
+		if renamed := renameIdentifier(key); renamed != key {
+			delete(c.StackComposeProfiles, key)
+			c.StackComposeProfiles[renamed] = profiles
+		}
+	}
+	for i, identifier := range c.PinnedStacks {
+		c.PinnedStacks[i] = renameIdentifier(identifier)
+	}
+	for _, members := range c.Groups {
+		for i, member := range members {
+			if member == oldName {
+				members[i] = newName
+			}
+		}
+	}
+	return nil
+}
+
+// GetOperationTimeout returns the parsed CLI operation timeout, or zero (no
+// limit) if unset or invalid.
+func (c Config) GetOperationTimeout() time.Duration {
+	if c.OperationTimeout == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(c.OperationTimeout)
+	if err != nil || d < 0 {
+		logger.Warn("Invalid operation_timeout in config, not limiting operations",
+			"value", c.OperationTimeout,
+			"error", err)
+		return 0
+	}
+	return d
+}
+
+// GetOperationLogDir returns the directory operation output is written to,
+// with a leading "~/" expanded, or "" if it isn't set.
+func (c Config) GetOperationLogDir() string {
+	if c.OperationLogDir == "" {
+		return ""
+	}
+	dir, err := ResolvePath(c.OperationLogDir)
+	if err != nil {
+		logger.Warn("Could not resolve operation_log_dir, using it as-is", "value", c.OperationLogDir, "error", err)
+	}
+	return dir
+}
+
+// GetStatusCacheTTL returns how long stack statuses are cached, falling back
+// to DefaultStatusCacheTTL if unset or invalid. Zero disables the cache.
+func (c Config) GetStatusCacheTTL() time.Duration {
+	if c.StatusCacheTTL == "" {
+		return DefaultStatusCacheTTL
+	}
+	d, err := time.ParseDuration(c.StatusCacheTTL)
+	if err != nil || d < 0 {
+		logger.Warn("Invalid status_cache_ttl in config, using default",
+			"value", c.StatusCacheTTL,
+			"default", DefaultStatusCacheTTL,
+			"error", err)
+		return DefaultStatusCacheTTL
+	}
+	return d
+}
+
+// GetDiscoveryMaxDepth returns the stack discovery depth, falling back to
+// DefaultDiscoveryMaxDepth if unset or invalid.
+func (c Config) GetDiscoveryMaxDepth() int {
+	if c.DiscoveryMaxDepth <= 0 {
+		return DefaultDiscoveryMaxDepth
+	}
+	return c.DiscoveryMaxDepth
+}
+
+// GetDiscoveryTimeout returns the limit on the search for stacks on a remote
+// host, falling back to DefaultDiscoveryTimeout if unset or invalid. Zero
+// means no limit.
+func (c Config) GetDiscoveryTimeout() time.Duration {
+	if c.DiscoveryTimeout == "" {
+		return DefaultDiscoveryTimeout
+	}
+	d, err := time.ParseDuration(c.DiscoveryTimeout)
+	if err != nil || d < 0 {
+		logger.Warn("Invalid discovery_timeout in config, using default",
+			"value", c.DiscoveryTimeout,
+			"default", DefaultDiscoveryTimeout,
+			"error", err)
+		return DefaultDiscoveryTimeout
+	}
+	return d
+}
+
+// SSHAlgorithmsFor returns the SSH algorithms offered to host: each list the
+// host sets, otherwise the global one. Empty lists use the client's defaults.
+func (c Config) SSHAlgorithmsFor(host SSHHost) SSHAlgorithms {
+	algorithms := c.SSHAlgorithms
+	if len(host.SSHAlgorithms.KexAlgorithms) > 0 {
+		algorithms.KexAlgorithms = host.SSHAlgorithms.KexAlgorithms
+	}
+	if len(host.SSHAlgorithms.Ciphers) > 0 {
+		algorithms.Ciphers = host.SSHAlgorithms.Ciphers
+	}
+	if len(host.SSHAlgorithms.HostKeyAlgorithms) > 0 {
+		algorithms.HostKeyAlgorithms = host.SSHAlgorithms.HostKeyAlgorithms
+	}
+	return algorithms
+}
+
+// RuntimeRestartCommandFor returns the shell command restarting the container
+// runtime on host (nil for the local host): the host's runtime_restart_command,
+// otherwise the global one, otherwise `systemctl --user restart podman` for
+// rootless podman or `systemctl restart docker`, depending on runtime, the
+// runtime used on the host.
+func (c Config) RuntimeRestartCommandFor(host *SSHHost, runtime string) string {
+	if host != nil && host.RuntimeRestartCommand != "" {
+		return host.RuntimeRestartCommand
+	}
+	if c.RuntimeRestartCommand != "" {
+		return c.RuntimeRestartCommand
+	}
+	if runtime == "docker" {
+		return "systemctl restart docker"
+	}
+	return "systemctl --user restart podman"
+}
+
+// GetLogFileOptions returns the log file location and rotation settings,
+// falling back to the logger defaults for unset or invalid values.
+func (c Config) GetLogFileOptions() logger.FileOptions {
+	opts := logger.FileOptions{
+		MaxSizeMB:  c.LogMaxSizeMB,
+		MaxBackups: logger.DefaultMaxBackups,
+	}
+	if opts.MaxSizeMB <= 0 {
+		opts.MaxSizeMB = logger.DefaultMaxSizeMB
+	}
+	if c.LogMaxBackups != nil && *c.LogMaxBackups >= 0 {
+		opts.MaxBackups = *c.LogMaxBackups
+	}
+	opts.Path = c.LogFile
+	if rest, ok := strings.CutPrefix(c.LogFile, "~/"); ok {
+		// Expanded here rather than with ResolvePath, which logs
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			opts.Path = filepath.Join(homeDir, rest)
+		}
+	}
+	return opts
+}
+
+// ReadLogFileOptions returns the log file settings from the config file, with
+// the BM_LOG_* environment overrides. Unlike LoadConfig it doesn't log, so it
+// can be used before the logger is initialized. If the config can't be read,
+// the defaults are returned; an invalid override is left for LoadConfig to report.
+func ReadLogFileOptions() logger.FileOptions {
+	var cfg Config
+	if configPath, err := configFilePath(); err == nil {
+		if data, err := os.ReadFile(configPath); err == nil {
+			_ = yaml.Unmarshal(data, &cfg)
+		}
+	}
+	_, _ = setEnvOverrides(&cfg)
+	return cfg.GetLogFileOptions()
+}
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package config's env.go file lets environment variables override options of
+// the config file, e.g. to configure `bm serve` in a container without mounting
+// a config.yaml. Each option is read from BM_ followed by its YAML key in upper
+// case (local_root is BM_LOCAL_ROOT). Command-line flags still take precedence.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"bucket-manager/internal/logger"
+)
+
+// envPrefix is prepended to the upper-cased YAML key of an option to name its
+// environment variable.
+const envPrefix = "BM_"
+
+// envBinding ties an environment variable to the Config field it overrides.
+// field returns a pointer to the field: a *string, *int, **int (for an
+// optional integer, where 0 differs from unset) or *bool.
+type envBinding struct {
+	name  string
+	field func(cfg *Config) any
+}
+
+// envBindings lists the options that can be set from the environment. Host
+// definitions and other structured options can only be set in the file.
+var envBindings = []envBinding{
+	{envPrefix + "LOCAL_ROOT", func(cfg *Config) any { return &cfg.LocalRoot }},
+	{envPrefix + "CONTAINER_RUNTIME", func(cfg *Config) any { return &cfg.ContainerRuntime }},
+	{envPrefix + "REFRESH_ALL_STAGGER", func(cfg *Config) any { return &cfg.RefreshAllStagger }},
+	{envPrefix + "REFRESH_ALL_MAX_CONCURRENT", func(cfg *Config) any { return &cfg.RefreshAllMaxConcurrent }},
+	{envPrefix + "DISK_WARN_FREE_PERCENT", func(cfg *Config) any { return &cfg.DiskWarnFreePercent }},
+	{envPrefix + "COLLAPSE_STEP_OUTPUT", func(cfg *Config) any { return &cfg.CollapseStepOutput }},
+	{envPrefix + "OUTPUT_MAX_LINES", func(cfg *Config) any { return &cfg.OutputMaxLines }},
+	{envPrefix + "COMPLETION_NOTIFY", func(cfg *Config) any { return &cfg.CompletionNotify }},
+	{envPrefix + "DEFAULT_ACTION", func(cfg *Config) any { return &cfg.DefaultAction }},
+	{envPrefix + "IDENTIFIER_FORMAT", func(cfg *Config) any { return &cfg.IdentifierFormat }},
+	{envPrefix + "PULL_PARALLEL", func(cfg *Config) any { return &cfg.PullParallel }},
+	{envPrefix + "SMART_UP", func(cfg *Config) any { return &cfg.SmartUp }},
+	{envPrefix + "OPERATION_TIMEOUT", func(cfg *Config) any { return &cfg.OperationTimeout }},
+	{envPrefix + "OPERATION_LOG_DIR", func(cfg *Config) any { return &cfg.OperationLogDir }},
+	{envPrefix + "READ_ONLY", func(cfg *Config) any { return &cfg.ReadOnly }},
+	{envPrefix + "DISCOVERY_MAX_DEPTH", func(cfg *Config) any { return &cfg.DiscoveryMaxDepth }},
+	{envPrefix + "DISCOVERY_TIMEOUT", func(cfg *Config) any { return &cfg.DiscoveryTimeout }},
+	{envPrefix + "DISCOVERY_XDEV", func(cfg *Config) any { return &cfg.DiscoveryXDev }},
+	{envPrefix + "QUADLET_DISCOVERY", func(cfg *Config) any { return &cfg.QuadletDiscovery }},
+	{envPrefix + "LOG_FILE", func(cfg *Config) any { return &cfg.LogFile }},
+	{envPrefix + "LOG_MAX_SIZE_MB", func(cfg *Config) any { return &cfg.LogMaxSizeMB }},
+	{envPrefix + "LOG_MAX_BACKUPS", func(cfg *Config) any { return &cfg.LogMaxBackups }},
+}
+
+// EnvOverrides returns the names of the environment variables that overrode
+// options of the loaded config, in the order of envBindings.
+func (c Config) EnvOverrides() []string {
+	var names []string
+	for _, binding := range envBindings {
+		if _, ok := c.envFileValues[binding.name]; ok {
+			names = append(names, binding.name)
+		}
+	}
+	return names
+}
+
+// applyEnvOverrides sets the options whose environment variable is set (even
+// to an empty value) on cfg. The values they replace are remembered so that
+// SaveConfig writes the file's own values back instead of the overrides.
+func applyEnvOverrides(cfg *Config) error {
+	names, err := setEnvOverrides(cfg)
+	for _, name := range names {
+		logger.Debug("Applied config override from environment", "variable", name)
+	}
+	return err
+}
+
+// setEnvOverrides is applyEnvOverrides without logging, so that it can be used
+// before the logger is initialized. It returns the names of the variables applied.
+func setEnvOverrides(cfg *Config) ([]string, error) {
+	var names []string
+	for _, binding := range envBindings {
+		value, ok := os.LookupEnv(binding.name)
+		if !ok {
+			continue
+		}
+
+		var fileValue any
+		switch field := binding.field(cfg).(type) {
+		case *string:
+			fileValue = *field
+			*field = value
+		case *int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return names, fmt.Errorf("invalid value %q for %s: expected an integer", value, binding.name)
+			}
+			fileValue = *field
+			*field = n
+		case **int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return names, fmt.Errorf("invalid value %q for %s: expected an integer", value, binding.name)
+			}
+			fileValue = *field
+			*field = &n
+		case *bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return names, fmt.Errorf("invalid value %q for %s: expected true or false", value, binding.name)
+			}
+			fileValue = *field
+			*field = b
+		}
+
+		if cfg.envFileValues == nil {
+			cfg.envFileValues = make(map[string]any)
+		}
+		cfg.envFileValues[binding.name] = fileValue
+		names = append(names, binding.name)
+	}
+	return names, nil
+}
+
+// withoutEnvOverrides returns cfg with the options overridden from the
+// environment set back to their values in the file, unless they have been
+// changed since the config was loaded.
+func (c Config) withoutEnvOverrides() Config {
+	for _, binding := range envBindings {
+		fileValue, ok := c.envFileValues[binding.name]
+		if !ok {
+			continue
+		}
+		envValue := os.Getenv(binding.name)
+		switch field := binding.field(&c).(type) {
+		case *string:
+			if *field == envValue {
+				*field = fileValue.(string)
+			}
+		case *int:
+			if n, err := strconv.Atoi(envValue); err == nil && *field == n {
+				*field = fileValue.(int)
+			}
+		case **int:
+			if n, err := strconv.Atoi(envValue); err == nil && *field != nil && **field == n {
+				*field = fileValue.(*int)
+			}
+		case *bool:
+			if b, err := strconv.ParseBool(envValue); err == nil && *field == b {
+				*field = fileValue.(bool)
+			}
+		}
+	}
+	c.envFileValues = nil
+	return c
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package config's identifier.go file compiles identifier_format, the template
+// that stacks are shown with instead of their "server:name" identifier.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// IdentifierFields holds the fields available to identifier_format templates.
+type IdentifierFields struct {
+	Name       string // Stack name
+	ServerName string // "local" or the remote host name
+	Identifier string // Canonical identifier, e.g. "server1:app"
+}
+
+// ParseIdentifierFormat compiles an identifier_format template. It returns nil
+// if format is empty. Templates that fail on a stack, e.g. by using a field
+// that IdentifierFields doesn't have, or that show it as nothing at all are
+// rejected here rather than later.
+func ParseIdentifierFormat(format string) (*template.Template, error) {
+	if format == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("identifier_format").Option("missingkey=error").Parse(format)
+	if err == nil {
+		var b strings.Builder
+		err = tmpl.Execute(&b, IdentifierFields{Name: "app", ServerName: "local", Identifier: "local:app"})
+		if err == nil && strings.TrimSpace(b.String()) == "" {
+			err = errors.New("it shows stacks as empty text")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid identifier_format: %w", err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+//go:build unix
+
+// Package config's lock_unix.go file implements advisory config file locking
+// with flock(2).
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"bucket-manager/internal/logger"
+)
+
+// LockConfig takes an exclusive advisory lock on the configuration, blocking
+// until it is available. It must be held across read-modify-write sequences
+// (LoadConfig followed by SaveConfig) so that concurrent instances, such as the
+// CLI and the web server, don't overwrite each other's changes. The returned
+// function releases the lock.
+func LockConfig() (func(), error) {
+	lockPath, err := configLockPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := EnsureConfigDir(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		logger.Error("Failed to open config lock file", "lock_path", lockPath, "error", err)
+		return nil, fmt.Errorf("failed to open config lock file %s: %w", lockPath, err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		logger.Error("Failed to lock config", "lock_path", lockPath, "error", err)
+		return nil, fmt.Errorf("failed to lock config file %s: %w", lockPath, err)
+	}
+	logger.Debug("Config lock acquired", "lock_path", lockPath)
+
+	return func() {
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+			logger.Warn("Failed to unlock config", "lock_path", lockPath, "error", err)
+		}
+		f.Close()
+		logger.Debug("Config lock released", "lock_path", lockPath)
+	}, nil
+}
+
+// tryLockConfig is LockConfig without blocking: if the lock is held, by
+// another instance or by this one, it returns a nil function and no error.
+func tryLockConfig() (func(), error) {
+	lockPath, err := configLockPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := EnsureConfigDir(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config lock file %s: %w", lockPath, err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock config file %s: %w", lockPath, err)
+	}
+	logger.Debug("Config lock acquired", "lock_path", lockPath)
+
+	return func() {
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+			logger.Warn("Failed to unlock config", "lock_path", lockPath, "error", err)
+		}
+		f.Close()
+		logger.Debug("Config lock released", "lock_path", lockPath)
+	}, nil
+}
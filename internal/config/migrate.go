@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package config's migrate.go file versions the config file with
+// config_version and upgrades files written by older releases when they are
+// loaded, so that renamed or reinterpreted options keep their meaning. The
+// original file is kept next to the upgraded one as config.yaml.v<N>.bak.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+)
+
+// CurrentConfigVersion is the config_version written by this release. It
+// equals len(migrations).
+const CurrentConfigVersion = 1
+
+// migration upgrades the top-level mapping of a config file by one version,
+// editing it in place (filling defaults, renaming or converting options) and
+// describing each change. Editing the YAML nodes keeps the file's comments,
+// layout and options this release doesn't know.
+type migration func(root *yaml.Node) []string
+
+// migrations[i] upgrades a config file from version i to version i+1.
+var migrations = []migration{
+	migrateV0ToV1,
+}
+
+// migrateV0ToV1 upgrades files written before config_version existed. Their
+// container_runtime was run as the command itself, so a path or a capitalized
+// name like "/usr/bin/docker" worked; it is reduced to the runtime's name,
+// which is all that is accepted now. An unset container_runtime is left as it
+// is: it meant podman, which detection still picks wherever podman works.
+func migrateV0ToV1(root *yaml.Node) []string {
+	node := mappingValue(root, "container_runtime")
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return nil
+	}
+	runtime := node.Value
+	name := strings.ToLower(path.Base(strings.TrimSpace(runtime)))
+	if runtime == "" || runtime == name || !slices.Contains(ContainerRuntimes, name) {
+		return nil
+	}
+	node.Value = name
+	return []string{fmt.Sprintf("set container_runtime '%s' to '%s'", runtime, name)}
+}
+
+// mappingValue returns the value node of key in the mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingInt sets key in the mapping node to value, appending it if unset.
+func setMappingInt(mapping *yaml.Node, key string, value int) {
+	if node := mappingValue(mapping, key); node != nil {
+		node.Kind, node.Tag, node.Style, node.Value = yaml.ScalarNode, "!!int", 0, strconv.Itoa(value)
+		node.Content = nil
+		return
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(value)})
+}
+
+// migrateConfigData applies the migrations from version to
+// CurrentConfigVersion to the content of a config file, returning the upgraded
+// config, the upgraded file content and a description of each change.
+func migrateConfigData(data []byte, version int) (Config, []byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Config{}, nil, nil, fmt.Errorf("failed to parse config for migration: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		// Empty file, or only comments
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return Config{}, nil, nil, errors.New("failed to parse config for migration: not a mapping")
+	}
+
+	var changes []string
+	for v := version; v < CurrentConfigVersion; v++ {
+		for _, change := range migrations[v](root) {
+			changes = append(changes, fmt.Sprintf("v%d to v%d: %s", v, v+1, change))
+		}
+	}
+	setMappingInt(root, "config_version", CurrentConfigVersion)
+
+	var cfg Config
+	if err := root.Decode(&cfg); err != nil {
+		return Config{}, nil, nil, fmt.Errorf("failed to parse migrated config: %w", err)
+	}
+	var migrated bytes.Buffer
+	encoder := yaml.NewEncoder(&migrated)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return Config{}, nil, nil, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return Config{}, nil, nil, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	return cfg, migrated.Bytes(), changes, nil
+}
+
+// reportedUpgrades holds the config paths whose in-memory upgrade was already
+// logged, so that a file that can't be rewritten isn't reported on every load.
+var reportedUpgrades sync.Map
+
+// upgradeConfigFile migrates the config file at configPath, whose content is
+// data, from version to CurrentConfigVersion. If no migration changed it, the
+// new version is only recorded in memory. Otherwise, under the config lock,
+// the upgraded file replaces it after a backup of the original is written. In
+// read-only mode, while the lock is held elsewhere, if the file changed
+// meanwhile or if writing fails, the config is only migrated in memory,
+// which is logged once per file.
+func upgradeConfigFile(configPath string, data []byte, version int) (Config, error) {
+	cfg, migrated, changes, err := migrateConfigData(data, version)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(changes) == 0 {
+		logger.Debug("Configuration needs no upgrade",
+			"config_path", configPath,
+			"from_version", version,
+			"to_version", CurrentConfigVersion)
+		return cfg, nil
+	}
+
+	if err := writeUpgradedConfig(configPath, data, version, migrated, cfg.ReadOnly); err != nil {
+		if _, reported := reportedUpgrades.LoadOrStore(configPath, true); !reported {
+			for _, change := range changes {
+				logger.Info("Migrated configuration option",
+					"config_path", configPath,
+					"change", change)
+			}
+			logger.Warn("Configuration upgraded in memory only",
+				"config_path", configPath,
+				"from_version", version,
+				"to_version", CurrentConfigVersion,
+				"reason", err)
+		}
+		return cfg, nil
+	}
+
+	for _, change := range changes {
+		logger.Info("Migrated configuration option",
+			"config_path", configPath,
+			"change", change)
+	}
+	logger.Info("Configuration upgraded",
+		"config_path", configPath,
+		"backup_path", upgradeBackupPath(configPath, version),
+		"from_version", version,
+		"to_version", CurrentConfigVersion,
+		"changes", len(changes))
+	return cfg, nil
+}
+
+// upgradeBackupPath is where the original of a config file upgraded from version is kept.
+func upgradeBackupPath(configPath string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", configPath, version)
+}
+
+// writeUpgradedConfig backs up the config file at configPath, read as data,
+// and replaces it with migrated, returning why it didn't if it couldn't. The lock
+// isn't waited for, since LoadConfig may be called while this instance
+// already holds it; the holder then saves the upgraded config instead.
+func writeUpgradedConfig(configPath string, data []byte, version int, migrated []byte, readOnly bool) error {
+	// IsReadOnly would load the config again, so only the flag and the file are checked
+	if readOnlyForced.Load() || readOnly {
+		return errors.New("read-only mode is enabled")
+	}
+
+	unlock, err := tryLockConfig()
+	if err != nil {
+		return err
+	}
+	if unlock == nil {
+		return errors.New("the configuration is locked")
+	}
+	defer unlock()
+
+	current, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file again: %w", err)
+	}
+	if !bytes.Equal(current, data) {
+		return errors.New("the config file changed while it was upgraded")
+	}
+
+	backupPath := upgradeBackupPath(configPath, version)
+	if err := util.WriteFileAtomic(backupPath, data, 0640); err != nil {
+		return fmt.Errorf("failed to back up configuration: %w", err)
+	}
+	if err := util.WriteFileAtomic(configPath, migrated, 0640); err != nil {
+		return fmt.Errorf("failed to write upgraded configuration: %w", err)
+	}
+	return nil
+}
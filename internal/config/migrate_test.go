@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bucket-manager/internal/logger"
+)
+
+func TestMigrateConfigData(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantRuntime string
+		wantChanges int
+		wantKept    []string
+	}{
+		{
+			name:        "path-style runtime",
+			data:        "container_runtime: /usr/bin/Docker\n",
+			wantRuntime: "docker",
+			wantChanges: 1,
+		},
+		{
+			name:        "runtime name",
+			data:        "container_runtime: podman\n",
+			wantRuntime: "podman",
+		},
+		{
+			name: "unknown runtime",
+			data: "container_runtime: /opt/bin/nerdctl\n",
+			// Left for validation to report
+			wantRuntime: "/opt/bin/nerdctl",
+		},
+		{
+			name: "empty file",
+			data: "",
+		},
+		{
+			name:        "comments and unknown keys",
+			data:        "# My config\nfuture_option:\n  nested: true # kept\ncontainer_runtime: /usr/bin/podman\n",
+			wantRuntime: "podman",
+			wantChanges: 1,
+			wantKept:    []string{"# My config", "future_option:", "nested: true # kept"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, migrated, changes, err := migrateConfigData([]byte(tt.data), 0)
+			if err != nil {
+				t.Fatalf("migrateConfigData() error = %v", err)
+			}
+			if cfg.ConfigVersion != CurrentConfigVersion {
+				t.Errorf("ConfigVersion = %d, want %d", cfg.ConfigVersion, CurrentConfigVersion)
+			}
+			if cfg.ContainerRuntime != tt.wantRuntime {
+				t.Errorf("ContainerRuntime = %q, want %q", cfg.ContainerRuntime, tt.wantRuntime)
+			}
+			if len(changes) != tt.wantChanges {
+				t.Errorf("changes = %q, want %d", changes, tt.wantChanges)
+			}
+			if !strings.Contains(string(migrated), "config_version: 1") {
+				t.Errorf("migrated config lacks config_version:\n%s", migrated)
+			}
+			for _, kept := range tt.wantKept {
+				if !strings.Contains(string(migrated), kept) {
+					t.Errorf("migrated config lacks %q:\n%s", kept, migrated)
+				}
+			}
+		})
+	}
+}
+
+// setupConfigFile points the config at a file with data in a temporary
+// directory and captures the logs, returning the file's path and the logs.
+func setupConfigFile(t *testing.T, data string) (string, *bytes.Buffer) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	configPath := filepath.Join(dir, "config.yaml")
+	SetConfigPath(configPath)
+	t.Cleanup(func() { SetConfigPath("") })
+
+	var logs bytes.Buffer
+	logger.InitWeb(logger.LevelSilent)
+	logger.SetLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	if err := os.WriteFile(configPath, []byte(data), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return configPath, &logs
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLoadConfigUpgradesFile(t *testing.T) {
+	original := "# Runtime\ncontainer_runtime: /usr/bin/docker\nfuture_option: 1\n"
+	configPath, logs := setupConfigFile(t, original)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.ContainerRuntime != "docker" || cfg.ConfigVersion != CurrentConfigVersion {
+		t.Errorf("LoadConfig() = runtime %q version %d", cfg.ContainerRuntime, cfg.ConfigVersion)
+	}
+
+	if backup := readFile(t, upgradeBackupPath(configPath, 0)); backup != original {
+		t.Errorf("backup = %q, want %q", backup, original)
+	}
+	upgraded := readFile(t, configPath)
+	for _, want := range []string{"# Runtime", "container_runtime: docker", "future_option: 1", "config_version: 1"} {
+		if !strings.Contains(upgraded, want) {
+			t.Errorf("upgraded config lacks %q:\n%s", want, upgraded)
+		}
+	}
+	if !strings.Contains(logs.String(), "v0 to v1: set container_runtime '/usr/bin/docker' to 'docker'") {
+		t.Errorf("change not logged:\n%s", logs)
+	}
+}
+
+func TestLoadConfigWithoutChangesKeepsFile(t *testing.T) {
+	original := "container_runtime: podman\n"
+	configPath, _ := setupConfigFile(t, original)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.ConfigVersion != CurrentConfigVersion {
+		t.Errorf("ConfigVersion = %d, want %d", cfg.ConfigVersion, CurrentConfigVersion)
+	}
+	if got := readFile(t, configPath); got != original {
+		t.Errorf("config file rewritten: %q", got)
+	}
+	if _, err := os.Stat(upgradeBackupPath(configPath, 0)); !os.IsNotExist(err) {
+		t.Errorf("backup written: %v", err)
+	}
+}
+
+func TestLoadConfigNewerVersion(t *testing.T) {
+	original := "config_version: 99\ncontainer_runtime: /usr/bin/docker\n"
+	configPath, _ := setupConfigFile(t, original)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.ConfigVersion != 99 || cfg.ContainerRuntime != "/usr/bin/docker" {
+		t.Errorf("LoadConfig() = runtime %q version %d", cfg.ContainerRuntime, cfg.ConfigVersion)
+	}
+	if got := readFile(t, configPath); got != original {
+		t.Errorf("config file rewritten: %q", got)
+	}
+}
+
+func TestLoadConfigUpgradesInMemoryWhileLocked(t *testing.T) {
+	original := "container_runtime: /usr/bin/docker\n"
+	configPath, logs := setupConfigFile(t, original)
+
+	unlock, err := LockConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if tryUnlock, _ := tryLockConfig(); tryUnlock != nil {
+		tryUnlock()
+		t.Skip("the config lock isn't enforced on this platform")
+	}
+
+	for range 2 {
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		if cfg.ContainerRuntime != "docker" {
+			t.Errorf("ContainerRuntime = %q, want docker", cfg.ContainerRuntime)
+		}
+	}
+	if got := readFile(t, configPath); got != original {
+		t.Errorf("config file rewritten while locked: %q", got)
+	}
+	if _, err := os.Stat(upgradeBackupPath(configPath, 0)); !os.IsNotExist(err) {
+		t.Errorf("backup written while locked: %v", err)
+	}
+	if n := strings.Count(logs.String(), "Configuration upgraded in memory only"); n != 1 {
+		t.Errorf("in-memory upgrade logged %d times, want 1:\n%s", n, logs)
+	}
+}
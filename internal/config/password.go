@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package config's password.go file resolves a host's SSH password from where
+// the config says to find it: the plaintext password option, a file read at
+// connect time, or the output of a command such as `pass show server1`, so
+// that the secret itself never has to be stored in the config file. The
+// passphrase of an encrypted key is resolved the same way.
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// passwordCommandTimeout bounds how long a password_command or
+// key_passphrase_command may run, leaving time for a password manager to ask
+// for its own passphrase.
+const passwordCommandTimeout = time.Minute
+
+// HasPassword reports whether the host has a password source: a plaintext
+// password, a password file or a password command.
+func (h SSHHost) HasPassword() bool {
+	return h.Password != "" || h.PasswordFile != "" || h.PasswordCommand != ""
+}
+
+// passwordSources returns the names of the host's configured password options.
+func (h SSHHost) passwordSources() []string {
+	var sources []string
+	if h.Password != "" {
+		sources = append(sources, "password")
+	}
+	if h.PasswordFile != "" {
+		sources = append(sources, "password_file")
+	}
+	if h.PasswordCommand != "" {
+		sources = append(sources, "password_command")
+	}
+	return sources
+}
+
+// ResolvePassword returns the host's SSH password: Password if set, otherwise
+// the first line of PasswordFile, otherwise the first line printed by
+// PasswordCommand, run with `sh -c`. It returns "" if the host has no password.
+func (h SSHHost) ResolvePassword() (string, error) {
+	switch {
+	case h.Password != "":
+		return h.Password, nil
+	case h.PasswordFile != "":
+		path, err := ResolvePath(h.PasswordFile)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password_file: %w", err)
+		}
+		return firstLine(data), nil
+	case h.PasswordCommand != "":
+		return runSecretCommand("password_command", h.PasswordCommand)
+	}
+	return "", nil
+}
+
+// HasKeyPassphrase reports whether the host's config gives the passphrase of
+// its key, as key_passphrase or key_passphrase_command.
+func (h SSHHost) HasKeyPassphrase() bool {
+	return h.KeyPassphrase != "" || h.KeyPassphraseCommand != ""
+}
+
+// ResolveKeyPassphrase returns the passphrase of the host's key:
+// KeyPassphrase if set, otherwise the first line printed by
+// KeyPassphraseCommand, run with `sh -c`. It returns "" if the config doesn't
+// give one.
+func (h SSHHost) ResolveKeyPassphrase() (string, error) {
+	switch {
+	case h.KeyPassphrase != "":
+		return h.KeyPassphrase, nil
+	case h.KeyPassphraseCommand != "":
+		return runSecretCommand("key_passphrase_command", h.KeyPassphraseCommand)
+	}
+	return "", nil
+}
+
+// runSecretCommand runs command, the value of the named option, with `sh -c`
+// and returns the first line it prints.
+func runSecretCommand(option, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("%s timed out after %s", option, passwordCommandTimeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %w: %s", option, err, msg)
+		}
+		return "", fmt.Errorf("%s failed: %w", option, err)
+	}
+	secret := firstLine(output)
+	if secret == "" {
+		return "", fmt.Errorf("%s printed nothing", option)
+	}
+	return secret, nil
+}
+
+// firstLine returns data up to its first line break, the way password
+// managers like pass print the password on the first line.
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSuffix(line, "\r")
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package config's readonly.go file implements read-only mode, in which stacks
+// can be listed, inspected and started but nothing is stopped, pruned or
+// reconfigured. It is enabled with read_only in the config or --read-only.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"bucket-manager/internal/logger"
+)
+
+// ErrReadOnly is wrapped by the errors returned for actions refused in read-only mode.
+var ErrReadOnly = errors.New("read-only mode")
+
+// readOnlyForced is set by SetReadOnly, for the --read-only flag.
+var readOnlyForced atomic.Bool
+
+// SetReadOnly forces read-only mode for the rest of the process, whatever the config says.
+func SetReadOnly() {
+	readOnlyForced.Store(true)
+}
+
+// IsReadOnly reports whether read-only mode is enabled, by SetReadOnly or by
+// read_only in the config. The config is only read again once the file
+// changes (see LoadConfigCached), so a change still applies to a running web
+// server or TUI right away. If the config can't be loaded, whether read-only
+// mode was requested can't be told, so it is assumed to be.
+func IsReadOnly() bool {
+	if readOnlyForced.Load() {
+		return true
+	}
+	cfg, err := LoadConfigCached()
+	if err != nil {
+		logger.Warn("Failed to load config for read-only check, refusing changes", "error", err)
+		return true
+	}
+	return cfg.ReadOnly
+}
+
+// ReadOnlyError returns the error for an action refused in read-only mode, e.g.
+// "read-only mode: stopping stacks is disabled".
+func ReadOnlyError(action string) error {
+	return fmt.Errorf("%w: %s is disabled", ErrReadOnly, action)
+}
+
+// CheckWritable returns ReadOnlyError(action) in read-only mode, nil otherwise.
+func CheckWritable(action string) error {
+	if IsReadOnly() {
+		return ReadOnlyError(action)
+	}
+	return nil
+}
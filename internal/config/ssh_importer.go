@@ -9,10 +9,13 @@ package config
 
 import (
 	"bucket-manager/internal/logger"
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kevinburke/ssh_config"
@@ -24,8 +27,10 @@ type PotentialHost struct {
 	Alias    string // Host alias as defined in SSH config (e.g., "my-server")
 	Hostname string // Actual hostname or IP address to connect to
 	User     string // Username for SSH connection
-	Port     int    // Port number for SSH connection
+	Port     int    // Port number for SSH connection, 0 if the SSH config doesn't set one
 	KeyPath  string // Path to the identity file (private key)
+
+	ForwardAgent bool // Whether ForwardAgent is enabled for the host
 }
 
 // DefaultSSHConfigPath returns the standard location of the user's SSH config file.
@@ -58,7 +63,7 @@ func ParseSSHConfig() ([]PotentialHost, error) {
 
 	logger.Debug("Starting SSH config parsing", "ssh_config_path", sshConfigPath)
 
-	f, err := os.Open(sshConfigPath)
+	data, err := readSSHConfigWithIncludes(sshConfigPath, nil)
 	if err != nil {
 		if os.IsNotExist(err) {
 			logger.Info("SSH config file not found, returning empty host list",
@@ -67,17 +72,16 @@ func ParseSSHConfig() ([]PotentialHost, error) {
 			// Not an error if the file doesn't exist, just return empty results
 			return []PotentialHost{}, nil
 		}
-		logger.Error("Failed to open SSH config file",
+		logger.Error("Failed to read SSH config file",
 			"ssh_config_path", sshConfigPath,
 			"error", err,
 			"duration", time.Since(startTime))
-		return nil, fmt.Errorf("failed to open ssh config file %s: %w", sshConfigPath, err)
+		return nil, fmt.Errorf("failed to read ssh config file %s: %w", sshConfigPath, err)
 	}
-	defer f.Close()
 
-	logger.Debug("SSH config file opened successfully", "ssh_config_path", sshConfigPath)
+	logger.Debug("SSH config file read successfully", "ssh_config_path", sshConfigPath)
 
-	cfg, err := ssh_config.Decode(f)
+	cfg, err := ssh_config.Decode(bytes.NewReader(data))
 	if err != nil {
 		logger.Error("Failed to parse SSH config file",
 			"ssh_config_path", sshConfigPath,
@@ -112,14 +116,15 @@ func ParseSSHConfig() ([]PotentialHost, error) {
 		user, _ := cfg.Get(alias, "User")
 		portStr, _ := cfg.Get(alias, "Port")
 		keyPath, _ := cfg.Get(alias, "IdentityFile")
+		forwardAgent, _ := cfg.Get(alias, "ForwardAgent")
 
 		// If HostName is not specified, use the alias itself
 		if hostname == "" {
 			hostname = alias
 		}
 
-		// Default port is 22
-		port := 22
+		// Only a port set in the SSH config is kept; 0 uses DefaultSSHPort
+		port := 0
 		if portStr != "" {
 			p, err := strconv.Atoi(portStr)
 			if err == nil { // Only use parsed port if conversion is successful
@@ -128,9 +133,9 @@ func ParseSSHConfig() ([]PotentialHost, error) {
 				logger.Debug("Invalid port value, using default",
 					"alias", alias,
 					"port_string", portStr,
-					"default_port", 22)
+					"default_port", DefaultSSHPort)
 			}
-			// Ignore conversion errors, keep default port 22
+			// Ignore conversion errors, keep the default port
 		}
 
 		// Resolve ~ in IdentityFile path using the shared function
@@ -159,6 +164,8 @@ func ParseSSHConfig() ([]PotentialHost, error) {
 				User:     user,
 				Port:     port,
 				KeyPath:  keyPath,
+
+				ForwardAgent: strings.EqualFold(forwardAgent, "yes"),
 			}
 			potentialHosts = append(potentialHosts, potentialHost)
 			processedCount++
@@ -189,6 +196,99 @@ func ParseSSHConfig() ([]PotentialHost, error) {
 	return potentialHosts, nil
 }
 
+// readSSHConfigWithIncludes reads an SSH config file with the files named by its
+// Include directives inlined in their place, so hosts split across several files
+// are all parsed together. Include arguments may use globs and "~/", and relative
+// paths are resolved against ~/.ssh like ssh does. includeChain holds the files
+// currently being included, so a file including itself (directly or not) is
+// skipped instead of looping. Included files that don't exist are ignored.
+func readSSHConfigWithIncludes(path string, includeChain []string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	for line := range strings.Lines(string(data)) {
+		patterns, ok := parseSSHInclude(line)
+		if !ok {
+			out.WriteString(line)
+			continue
+		}
+		out.WriteString("# " + strings.TrimSpace(line) + "\n") // Commented out so the parser doesn't follow it again
+		for _, pattern := range patterns {
+			matches, err := filepath.Glob(resolveSSHIncludePath(pattern))
+			if err != nil {
+				logger.Warn("Invalid Include pattern in SSH config", "file", path, "pattern", pattern, "error", err)
+				continue
+			}
+			for _, match := range matches {
+				if match == path || slices.Contains(includeChain, match) {
+					logger.Warn("Skipping recursive Include in SSH config", "file", path, "include", match)
+					continue
+				}
+				if info, err := os.Stat(match); err != nil || info.IsDir() {
+					continue
+				}
+				included, err := readSSHConfigWithIncludes(match, append(includeChain, path))
+				if err != nil {
+					return nil, fmt.Errorf("failed to read included ssh config file %s: %w", match, err)
+				}
+				logger.Debug("Included SSH config file", "file", path, "include", match)
+				out.Write(included)
+				if len(included) > 0 && included[len(included)-1] != '\n' {
+					out.WriteByte('\n')
+				}
+			}
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// parseSSHInclude returns the arguments of an Include directive line (e.g.
+// "Include ~/.ssh/config.d/*" or "include=a b"), or false if the line isn't one.
+func parseSSHInclude(line string) ([]string, bool) {
+	line = strings.TrimSpace(line)
+	if len(line) < len("include") || !strings.EqualFold(line[:len("include")], "include") {
+		return nil, false
+	}
+	rest := line[len("include"):]
+	if rest == "" || (rest[0] != ' ' && rest[0] != '\t' && rest[0] != '=') {
+		return nil, false
+	}
+	rest = strings.TrimPrefix(strings.TrimSpace(rest), "=")
+	if i := strings.Index(rest, "#"); i >= 0 {
+		rest = rest[:i] // Trailing comment
+	}
+	var patterns []string
+	for _, field := range strings.Fields(rest) {
+		patterns = append(patterns, strings.Trim(field, `"`))
+	}
+	return patterns, len(patterns) > 0
+}
+
+// resolveSSHIncludePath expands "~/" in an Include argument and makes relative
+// paths relative to ~/.ssh, as ssh does for the user's config.
+func resolveSSHIncludePath(pattern string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return pattern
+	}
+	if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+		return filepath.Join(homeDir, rest)
+	}
+	if !filepath.IsAbs(pattern) {
+		return filepath.Join(homeDir, ".ssh", pattern)
+	}
+	return pattern
+}
+
+// DisplayAddress returns user@hostname for showing the host, followed by the
+// port if the SSH config sets one (see SSHHost.DisplayAddress).
+func (p PotentialHost) DisplayAddress() string {
+	return SSHHost{Hostname: p.Hostname, User: p.User, Port: p.Port}.DisplayAddress()
+}
+
 func ConvertToBucketManagerHost(p PotentialHost, uniqueName, remoteRoot string) (SSHHost, error) {
 	logger.Debug("Converting potential host to bucket manager host",
 		"alias", p.Alias,
@@ -217,6 +317,8 @@ func ConvertToBucketManagerHost(p PotentialHost, uniqueName, remoteRoot string)
 		Port:       p.Port,
 		KeyPath:    p.KeyPath,
 		RemoteRoot: remoteRoot,
+
+		ForwardAgent: p.ForwardAgent,
 	}
 
 	logger.Info("Successfully converted potential host to bucket manager host",
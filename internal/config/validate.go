@@ -0,0 +1,278 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package config's validate.go file implements sanity checks over a loaded
+// configuration, so mistakes can be reported up front instead of surfacing
+// as failures during discovery or command execution.
+
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ValidationIssue describes a single problem found by Config.Validate.
+type ValidationIssue struct {
+	Host    string // Name of the SSH host the issue belongs to, empty for global settings
+	Message string
+	Warning bool // True for issues that don't prevent bucket-manager from working
+}
+
+func (i ValidationIssue) String() string {
+	if i.Host != "" {
+		return fmt.Sprintf("host '%s': %s", i.Host, i.Message)
+	}
+	return i.Message
+}
+
+// Validate checks the configuration for duplicate or incomplete hosts, missing
+// key files, invalid ports, an inaccessible local root and other mistakes.
+// Plaintext passwords and settings that silently fall back to defaults are
+// reported as warnings.
+func (c Config) Validate() []ValidationIssue {
+	var issues []ValidationIssue
+	addError := func(host, format string, args ...any) {
+		issues = append(issues, ValidationIssue{Host: host, Message: fmt.Sprintf(format, args...)})
+	}
+	addWarning := func(host, format string, args ...any) {
+		issues = append(issues, ValidationIssue{Host: host, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	if c.ConfigVersion > CurrentConfigVersion {
+		addWarning("", "config_version %d is newer than this release supports (%d), options added since are ignored and dropped if the config is saved",
+			c.ConfigVersion, CurrentConfigVersion)
+	}
+
+	if c.ContainerRuntime != "" && !slices.Contains(ContainerRuntimes, c.ContainerRuntime) {
+		addError("", "container_runtime '%s' is not 'auto', 'podman' or 'docker'", c.ContainerRuntime)
+	}
+
+	if c.LocalRoot != "" {
+		if err := checkDirectory(c.LocalRoot); err != nil {
+			addError("", "local_root: %v", err)
+		}
+	}
+
+	if c.DefaultAction != "" && !slices.Contains(DefaultActions, c.DefaultAction) {
+		addWarning("", "default_action '%s' is not one of %s, the default '%s' is used",
+			c.DefaultAction, strings.Join(DefaultActions, ", "), DefaultStackAction)
+	}
+
+	if c.CompletionNotify != "" && !slices.Contains(CompletionNotifyModes, c.CompletionNotify) {
+		addWarning("", "completion_notify '%s' is not one of %s, no alert is given",
+			c.CompletionNotify, strings.Join(CompletionNotifyModes, ", "))
+	} else if c.CompletionDesktop() {
+		if err := util.CheckDesktopNotifications(); err != nil {
+			addWarning("", "completion_notify '%s': %v", c.CompletionNotify, err)
+		}
+	}
+
+	if _, err := ParseIdentifierFormat(c.IdentifierFormat); err != nil {
+		addWarning("", "%v, stacks are shown as usual", err)
+	}
+
+	if c.RefreshAllStagger != "" {
+		if d, err := time.ParseDuration(c.RefreshAllStagger); err != nil || d < 0 {
+			addWarning("", "refresh_all_stagger '%s' is not a valid duration, the default %s is used", c.RefreshAllStagger, DefaultRefreshAllStagger)
+		}
+	}
+	if c.RefreshAllMaxConcurrent < 0 {
+		addWarning("", "refresh_all_max_concurrent %d is negative, the default %d is used", c.RefreshAllMaxConcurrent, DefaultRefreshAllMaxConcurrent)
+	}
+	if c.DiskWarnFreePercent < 0 || c.DiskWarnFreePercent > 100 {
+		addWarning("", "disk_warn_free_percent %d is not between 1 and 100, the default %d is used", c.DiskWarnFreePercent, DefaultDiskWarnFreePercent)
+	}
+
+	if c.OperationTimeout != "" {
+		if d, err := time.ParseDuration(c.OperationTimeout); err != nil || d < 0 {
+			addWarning("", "operation_timeout '%s' is not a valid duration, operations are not limited", c.OperationTimeout)
+		}
+	}
+	if c.StatusCacheTTL != "" {
+		if d, err := time.ParseDuration(c.StatusCacheTTL); err != nil || d < 0 {
+			addWarning("", "status_cache_ttl '%s' is not a valid duration, the default %s is used", c.StatusCacheTTL, DefaultStatusCacheTTL)
+		}
+	}
+	if c.DiscoveryTimeout != "" {
+		if d, err := time.ParseDuration(c.DiscoveryTimeout); err != nil || d < 0 {
+			addWarning("", "discovery_timeout '%s' is not a valid duration, the default %s is used", c.DiscoveryTimeout, DefaultDiscoveryTimeout)
+		}
+	}
+	if c.DiscoveryMaxDepth < 0 {
+		addWarning("", "discovery_max_depth %d is negative, the default %d is used", c.DiscoveryMaxDepth, DefaultDiscoveryMaxDepth)
+	}
+	if c.PullParallel < 0 {
+		addWarning("", "pull_parallel %d is negative, compose's default is used", c.PullParallel)
+	}
+	for _, stack := range slices.Sorted(maps.Keys(c.StackPullParallel)) {
+		if n := c.StackPullParallel[stack]; n < 0 {
+			addWarning("", "stack_pull_parallel for '%s' is negative (%d), compose's default is used", stack, n)
+		}
+	}
+	for _, stack := range slices.Sorted(maps.Keys(c.StackComposeProfiles)) {
+		if slices.ContainsFunc(c.StackComposeProfiles[stack], func(p string) bool { return strings.TrimSpace(p) == "" }) {
+			addWarning("", "stack_compose_profiles for '%s' contains an empty profile name", stack)
+		}
+	}
+
+	for _, problem := range sshAlgorithmProblems(c.SSHAlgorithms) {
+		addError("", "ssh_algorithms: %s", problem)
+	}
+
+	if err := CheckComposeGlobalArgs(c.ComposeGlobalArgs); err != nil {
+		addError("", "compose_global_args: %v", err)
+	}
+
+	if c.LogMaxSizeMB < 0 {
+		addWarning("", "log_max_size_mb %d is negative, the default %d is used", c.LogMaxSizeMB, logger.DefaultMaxSizeMB)
+	}
+	if c.LogMaxBackups != nil && *c.LogMaxBackups < 0 {
+		addWarning("", "log_max_backups %d is negative, the default %d is used", *c.LogMaxBackups, logger.DefaultMaxBackups)
+	}
+
+	seenNames := make(map[string]bool)
+	for i, host := range c.SSHHosts {
+		name := host.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1) // Refer to unnamed hosts by position
+			addError(name, "name is required")
+		} else if seenNames[name] {
+			addError(name, "name is used by more than one host")
+		}
+		seenNames[host.Name] = true
+
+		if host.Hostname == "" {
+			addError(name, "hostname is required")
+		}
+		if host.User == "" {
+			addError(name, "user is required")
+		}
+		if host.Port < 0 || host.Port > 65535 {
+			addError(name, "port %d is not between 1 and 65535", host.Port)
+		}
+		if host.KeyPath != "" {
+			if err := checkFile(host.KeyPath); err != nil {
+				addError(name, "key_path: %v", err)
+			}
+		}
+		switch {
+		case host.KeyPassphrase != "" && host.KeyPassphraseCommand != "":
+			addError(name, "only one of key_passphrase, key_passphrase_command can be set")
+		case host.HasKeyPassphrase() && host.KeyPath == "":
+			addWarning(name, "a key passphrase is set without a key_path, so it is never used")
+		case host.KeyPassphrase != "":
+			addWarning(name, "key_passphrase is stored in plaintext; prefer key_passphrase_command or the SSH agent")
+		}
+		if sources := host.passwordSources(); len(sources) > 1 {
+			addError(name, "only one of %s can be set", strings.Join(sources, ", "))
+		}
+		if host.Password != "" {
+			addWarning(name, "password is stored in plaintext; prefer a key, the SSH agent, password_file or password_command")
+		}
+		if host.PasswordFile != "" {
+			if err := checkFile(host.PasswordFile); err != nil {
+				addError(name, "password_file: %v", err)
+			}
+		}
+		if host.ContainerRuntime != "" && !slices.Contains(ContainerRuntimes, host.ContainerRuntime) {
+			addError(name, "container_runtime '%s' is not 'auto', 'podman' or 'docker'", host.ContainerRuntime)
+		}
+		for _, problem := range sshAlgorithmProblems(host.SSHAlgorithms) {
+			addError(name, "ssh_algorithms: %s", problem)
+		}
+	}
+
+	for _, group := range slices.Sorted(maps.Keys(c.Groups)) {
+		if group == "" || strings.ContainsAny(group, ":@ ") {
+			addError("", "groups: '%s' is not a valid group name, it must be non-empty without ':', '@' or spaces", group)
+		}
+		members := c.Groups[group]
+		if len(members) == 0 {
+			addWarning("", "groups: '%s' has no hosts", group)
+		}
+		for i, member := range members {
+			if member == "" || (member != "local" && !seenNames[member]) {
+				addError("", "groups: '%s' lists host '%s', which is not configured", group, member)
+			} else if slices.Index(members, member) < i {
+				addWarning("", "groups: '%s' lists host '%s' more than once", group, member)
+			}
+		}
+	}
+
+	return issues
+}
+
+// sshAlgorithmProblems returns the empty names in a, and the key exchange
+// algorithms and ciphers the SSH client doesn't implement, which it would
+// silently leave out. Host key algorithms can't be checked up front.
+func sshAlgorithmProblems(a SSHAlgorithms) []string {
+	var problems []string
+	supported := ssh.Config{KeyExchanges: a.KexAlgorithms, Ciphers: a.Ciphers}
+	supported.SetDefaults()
+	for _, list := range []struct {
+		option     string
+		algorithms []string
+		checked    bool
+		supported  []string
+	}{
+		{"kex_algorithms", a.KexAlgorithms, true, supported.KeyExchanges},
+		{"ciphers", a.Ciphers, true, supported.Ciphers},
+		{"host_key_algorithms", a.HostKeyAlgorithms, false, nil},
+	} {
+		for _, algorithm := range list.algorithms {
+			switch {
+			case strings.TrimSpace(algorithm) == "":
+				problems = append(problems, fmt.Sprintf("%s contains an empty name", list.option))
+			case list.checked && !slices.Contains(list.supported, algorithm):
+				problems = append(problems, fmt.Sprintf("%s: '%s' is not supported", list.option, algorithm))
+			}
+		}
+	}
+	return problems
+}
+
+// checkDirectory checks that path (which may start with "~/") is an existing directory.
+func checkDirectory(path string) error {
+	resolved, err := ResolvePath(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(resolved)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("'%s' does not exist", resolved)
+	} else if err != nil {
+		return fmt.Errorf("cannot access '%s': %w", resolved, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", resolved)
+	}
+	return nil
+}
+
+// checkFile checks that path (which may start with "~/") is an existing regular file.
+func checkFile(path string) error {
+	resolved, err := ResolvePath(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(resolved)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("'%s' does not exist", resolved)
+	} else if err != nil {
+		return fmt.Errorf("cannot access '%s': %w", resolved, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("'%s' is a directory, not a file", resolved)
+	}
+	return nil
+}
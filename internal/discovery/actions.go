@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package discovery's actions.go file reads the custom actions a stack defines
+// in a .bm.yaml file next to its compose file: named compose subcommands such
+// as `backup: exec db pg_dump -U app app`, run like any other stack action.
+
+package discovery
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"bucket-manager/internal/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stackConfigFile is the per-stack file defining custom actions.
+const stackConfigFile = ".bm.yaml"
+
+// parseStackActions returns the actions defined in the content of a
+// .bm.yaml file, keyed by name. Actions with an empty name or command are
+// an error.
+func parseStackActions(data []byte) (map[string]string, error) {
+	var doc struct {
+		Actions map[string]string `yaml:"actions"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for name, command := range doc.Actions {
+		if strings.TrimSpace(name) == "" || strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("action '%s' needs a name and a command", name)
+		}
+	}
+	if len(doc.Actions) == 0 {
+		return nil, nil
+	}
+	return doc.Actions, nil
+}
+
+// localStackActions returns the actions defined in a local stack's .bm.yaml,
+// or none if it has no such file. An invalid file is logged and ignored.
+func localStackActions(stackPath string) map[string]string {
+	configPath := filepath.Join(stackPath, stackConfigFile)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Could not read stack actions", "path", configPath, "error", err)
+		}
+		return nil
+	}
+	actions, err := parseStackActions(data)
+	if err != nil {
+		logger.Warn("Invalid stack actions, ignoring them", "path", configPath, "error", err)
+		return nil
+	}
+	return actions
+}
+
+// remoteStackActions returns the actions of a remote stack from its .bm.yaml
+// as printed in base64 by the remote discovery command, or none if it was
+// empty. An invalid file is logged and ignored.
+func remoteStackActions(hostName, stackPath, encoded string) map[string]string {
+	if encoded == "" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err == nil {
+		var actions map[string]string
+		if actions, err = parseStackActions(data); err == nil {
+			return actions
+		}
+	}
+	logger.Warn("Invalid stack actions, ignoring them",
+		"host_name", hostName,
+		"path", stackPath+"/"+stackConfigFile,
+		"error", err)
+	return nil
+}
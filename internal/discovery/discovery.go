@@ -16,11 +16,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	gossh "golang.org/x/crypto/ssh"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -28,6 +33,18 @@ import (
 // to prevent overwhelming local or remote systems
 const maxConcurrentDiscoveries = 8
 
+// hostProbeTimeout returns the bound on the reachability check made before a
+// remote host is searched. It only needs the host's SSH port to accept a TCP
+// connection, so it is a third of the SSH dial timeout, or of discovery_timeout
+// if that is shorter.
+func hostProbeTimeout(cfg config.Config) time.Duration {
+	timeout := ssh.DialTimeout
+	if discoveryTimeout := cfg.GetDiscoveryTimeout(); discoveryTimeout > 0 && discoveryTimeout < timeout {
+		timeout = discoveryTimeout
+	}
+	return timeout / 3
+}
+
 // sshManager provides access to SSH connections for remote discovery operations
 var sshManager *ssh.Manager
 
@@ -42,14 +59,18 @@ func InitSSHManager(manager *ssh.Manager) {
 
 // Stack represents a discovered compose stack, which is a directory
 // containing compose files (compose.yaml, compose.yml, docker-compose.yaml, docker-compose.yml, etc.)
-// The Stack can be either local or on a remote SSH host.
+// The Stack can be either local or on a remote SSH host. With quadlet_discovery,
+// a Stack can also be a Podman quadlet, whose Path is the quadlet's directory.
 type Stack struct {
-	Name               string          // Name of the stack (derived from directory name)
-	Path               string          // Full local path OR path relative to AbsoluteRemoteRoot on SSH host
-	ServerName         string          // "local" or the Name field from SSHHost config
-	IsRemote           bool            // True if stack is on a remote server, false if local
-	HostConfig         *config.SSHHost // SSH host configuration (nil if local)
-	AbsoluteRemoteRoot string          // Root directory on remote host (empty if local)
+	Name               string            // Name of the stack (derived from directory name)
+	Path               string            // Full local path OR path relative to AbsoluteRemoteRoot on SSH host
+	ServerName         string            // "local" or the Name field from SSHHost config
+	IsRemote           bool              // True if stack is on a remote server, false if local
+	HostConfig         *config.SSHHost   // SSH host configuration (nil if local)
+	AbsoluteRemoteRoot string            // Root directory on remote host (empty if local)
+	ProjectName        string            // Compose project name (empty if only compose can resolve it)
+	Quadlet            *Quadlet          // Set if the stack is a Podman quadlet rather than a compose project
+	Actions            map[string]string // Custom compose subcommands from the stack's .bm.yaml, by name
 }
 
 // Identifier returns the unique string representation (e.g., "my-app" or "server1:my-app").
@@ -61,6 +82,94 @@ func (s Stack) Identifier() string {
 	return fmt.Sprintf("%s:%s", s.ServerName, s.Name)
 }
 
+// FullPath returns where the stack lives on its host: Path for local stacks,
+// and Path joined to AbsoluteRemoteRoot for remote ones.
+func (s Stack) FullPath() string {
+	if !s.IsRemote || s.AbsoluteRemoteRoot == "" {
+		return s.Path
+	}
+	return filepath.Join(s.AbsoluteRemoteRoot, s.Path)
+}
+
+// CompareStacks orders stacks by host, local stacks first, then by name, for
+// sorting with slices.SortFunc. Stacks are discovered concurrently, so this is
+// what gives listings a stable order from one run to the next.
+func CompareStacks(a, b Stack) int {
+	if a.IsRemote != b.IsRemote {
+		if !a.IsRemote {
+			return -1
+		}
+		return 1
+	}
+	if c := strings.Compare(a.ServerName, b.ServerName); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Path, b.Path)
+}
+
+// Redacted returns a copy of the stack whose HostConfig, if any, is redacted
+// with config.SSHHost.Redacted, for showing the stack in API responses.
+func (s Stack) Redacted() Stack {
+	if s.HostConfig != nil {
+		host := s.HostConfig.Redacted()
+		s.HostConfig = &host
+	}
+	return s
+}
+
+// HostError is a discovery error affecting a single host ("local" or a remote host
+// name). Discovery on other hosts is unaffected, so callers can treat it as a
+// warning; errors of any other type mean discovery as a whole failed.
+type HostError struct {
+	Host string
+	Err  error
+}
+
+func (e *HostError) Error() string { return e.Err.Error() }
+
+func (e *HostError) Unwrap() error { return e.Err }
+
+// ErrRootNotFound is returned, wrapped, when a host has no stack root: none is
+// configured and none of the default locations (~/bucket, ~/compose-bucket)
+// exists. Such a host has no stacks rather than a problem; a configured root
+// that doesn't exist is a different error.
+var ErrRootNotFound = errors.New("could not find a stack root directory")
+
+// RootOverrides holds per-invocation replacements for the stack root directories.
+// Empty fields fall back to the configured roots and the default locations.
+type RootOverrides struct {
+	LocalRoot  string // Replaces the configured local_root
+	RemoteRoot string // Replaces remote_root for every remote host
+}
+
+// ResolveLocalRoot returns the local stack root directory, using the given
+// override if it is non-empty and GetComposeRootDirectory otherwise.
+// An invalid override is an error; it never falls back to the defaults.
+func ResolveLocalRoot(override string) (string, error) {
+	if override == "" {
+		return GetComposeRootDirectory()
+	}
+
+	rootPath, err := config.ResolvePath(override)
+	if err != nil {
+		logger.Warn("Could not resolve local root override", "override", override, "error", err)
+		rootPath = override
+	}
+
+	info, statErr := os.Stat(rootPath)
+	if statErr != nil {
+		return "", fmt.Errorf("local root override '%s' is invalid: %w", override, statErr)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("local root override '%s' is not a directory", override)
+	}
+	logger.Info("Using local root override", "path", rootPath, "resolved_from", override)
+	return rootPath, nil
+}
+
 // GetComposeRootDirectory finds the root directory for local compose stacks,
 // checking config override first, then defaults.
 func GetComposeRootDirectory() (string, error) {
@@ -132,11 +241,22 @@ func GetComposeRootDirectory() (string, error) {
 	logger.Error("No valid local stack root directory found",
 		"checked_config", cfg.LocalRoot != "",
 		"checked_defaults", possibleDirs)
-	return "", fmt.Errorf("could not find a valid local stack root directory (checked config 'local_root' and defaults: ~/bucket, ~/compose-bucket)")
+	return "", fmt.Errorf("%w locally (checked config 'local_root' and defaults: ~/bucket, ~/compose-bucket)", ErrRootNotFound)
 }
 
-func FindStacks() (<-chan Stack, <-chan error, <-chan struct{}) {
-	logger.Info("Starting stack discovery")
+// FindStacks discovers local and remote stacks concurrently. Non-empty fields in
+// overrides replace the configured roots for this discovery run only.
+func FindStacks(overrides RootOverrides) (<-chan Stack, <-chan error, <-chan struct{}) {
+	return FindStacksOnHosts(overrides, nil)
+}
+
+// FindStacksOnHosts is FindStacks limited to the named hosts ("local" or SSH
+// host names), e.g. the members of a host group. Nil hosts searches every host.
+func FindStacksOnHosts(overrides RootOverrides, hosts []string) (<-chan Stack, <-chan error, <-chan struct{}) {
+	logger.Info("Starting stack discovery",
+		"local_root_override", overrides.LocalRoot,
+		"remote_root_override", overrides.RemoteRoot,
+		"hosts", hosts)
 
 	stackChan := make(chan Stack, 10)
 	errorChan := make(chan error, 5)
@@ -160,9 +280,19 @@ func FindStacks() (<-chan Stack, <-chan error, <-chan struct{}) {
 		}(),
 		"local_root_configured", cfg.LocalRoot != "")
 
-	numGoroutines := 1
+	discoverLocal := hosts == nil || slices.Contains(hosts, "local")
+	var remoteHosts []config.SSHHost
 	if configErr == nil {
-		numGoroutines += len(cfg.SSHHosts)
+		for _, host := range cfg.SSHHosts {
+			if hosts == nil || slices.Contains(hosts, host.Name) {
+				remoteHosts = append(remoteHosts, host)
+			}
+		}
+	}
+
+	numGoroutines := len(remoteHosts)
+	if discoverLocal {
+		numGoroutines++
 	}
 	wg.Add(numGoroutines)
 
@@ -173,43 +303,34 @@ func FindStacks() (<-chan Stack, <-chan error, <-chan struct{}) {
 		close(doneChan)
 	}()
 
-	go func() {
-		defer wg.Done()
-		logger.Debug("Starting local stack discovery")
-
-		localRootDir, err := GetComposeRootDirectory()
-		if err == nil {
-			logger.Debug("Local root directory found, searching for stacks", "root_dir", localRootDir)
+	if discoverLocal {
+		go func() {
+			defer wg.Done()
+			logger.Debug("Starting local stack discovery")
 
-			localStacks, err := FindLocalStacks(localRootDir)
+			localStacks, err := DiscoverLocalStacks(overrides.LocalRoot)
 			if err != nil {
-				logger.Error("Local stack discovery failed", "root_dir", localRootDir, "error", err)
-				errorChan <- fmt.Errorf("local discovery failed: %w", err)
-			} else {
-				logger.Info("Local stack discovery completed",
-					"root_dir", localRootDir,
-					"stack_count", len(localStacks))
-				for _, s := range localStacks {
-					logger.Debug("Local stack found", "stack_name", s.Name, "path", s.Path)
-					stackChan <- s
-				}
+				logger.Error("Local stack discovery failed", "error", err)
+				errorChan <- &HostError{Host: "local", Err: fmt.Errorf("local discovery failed: %w", err)}
+				return
 			}
-		} else if !strings.Contains(err.Error(), "could not find") {
-			logger.Error("Local root directory check failed", "error", err)
-			errorChan <- fmt.Errorf("local root check failed: %w", err)
-		} else {
-			logger.Debug("No local root directory configured or found")
-		}
-	}()
+			logger.Info("Local stack discovery completed", "stack_count", len(localStacks))
+			for _, s := range localStacks {
+				logger.Debug("Local stack found", "stack_name", s.Name, "path", s.Path)
+				stackChan <- s
+			}
+		}()
+	}
 
-	if configErr == nil && len(cfg.SSHHosts) > 0 {
-		logger.Debug("Starting remote stack discovery", "host_count", len(cfg.SSHHosts))
+	if len(remoteHosts) > 0 {
+		logger.Debug("Starting remote stack discovery", "host_count", len(remoteHosts))
+		probeTimeout := hostProbeTimeout(cfg)
 
 		sem := semaphore.NewWeighted(maxConcurrentDiscoveries)
 		ctx := context.Background()
 
-		for i := range cfg.SSHHosts {
-			hostConfig := cfg.SSHHosts[i] // Create copy for the goroutine closure
+		for i := range remoteHosts {
+			hostConfig := remoteHosts[i] // Create copy for the goroutine closure
 			go func(hc config.SSHHost) {
 				defer wg.Done()
 
@@ -224,21 +345,39 @@ func FindStacks() (<-chan Stack, <-chan error, <-chan struct{}) {
 					return
 				}
 
+				// Probe every host at once before waiting for a discovery slot, so that
+				// unreachable hosts are reported right away rather than each after the
+				// SSH dial timeout, and don't hold up the reachable ones
+				if sshManager != nil {
+					if err := sshManager.ProbeHost(hc, probeTimeout); err != nil {
+						logger.Warn("Remote host is unreachable, skipping discovery",
+							"host_name", hc.Name,
+							"address", hc.Address(),
+							"error", err)
+						errorChan <- &HostError{Host: hc.Name, Err: fmt.Errorf("host %s is unreachable: %w", hc.Name, err)}
+						return
+					}
+				}
+
 				if err := sem.Acquire(ctx, 1); err != nil {
 					logger.Error("Failed to acquire semaphore for remote discovery",
 						"host_name", hc.Name, "error", err)
-					errorChan <- fmt.Errorf("failed to acquire semaphore for %s: %w", hc.Name, err)
+					errorChan <- &HostError{Host: hc.Name, Err: fmt.Errorf("failed to acquire semaphore for %s: %w", hc.Name, err)}
 					return
 				}
 				defer sem.Release(1)
 
-				remoteStacks, err := FindRemoteStacks(&hc)
-				if err != nil {
+				remoteStacks, err := FindRemoteStacks(&hc, overrides.RemoteRoot)
+				if errors.Is(err, ErrRootNotFound) {
+					logger.Info("No stack root directory on remote host, it has no stacks",
+						"host_name", hc.Name,
+						"hostname", hc.Hostname)
+				} else if err != nil {
 					logger.Error("Remote stack discovery failed",
 						"host_name", hc.Name,
 						"hostname", hc.Hostname,
 						"error", err)
-					errorChan <- fmt.Errorf("remote discovery failed for %s: %w", hc.Name, err)
+					errorChan <- &HostError{Host: hc.Name, Err: fmt.Errorf("remote discovery failed for %s: %w", hc.Name, err)}
 				} else {
 					logger.Info("Remote stack discovery completed",
 						"host_name", hc.Name,
@@ -259,34 +398,39 @@ func FindStacks() (<-chan Stack, <-chan error, <-chan struct{}) {
 	return stackChan, errorChan, doneChan
 }
 
-func FindLocalStacks(rootDir string) ([]Stack, error) {
-	var stacks []Stack
-
-	entries, err := os.ReadDir(rootDir)
+// discoveryMaxDepth returns the configured stack discovery depth.
+func discoveryMaxDepth() int {
+	cfg, err := config.LoadConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read local root directory %s: %w", rootDir, err)
+		logger.Warn("Could not load config to check discovery_max_depth", "error", err)
 	}
+	return cfg.GetDiscoveryMaxDepth()
+}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		stackName := entry.Name()
-		stackPath := filepath.Join(rootDir, stackName)
+// FindLocalStacks discovers the stacks under rootDir: directories up to
+// discovery_max_depth levels deep that contain a compose file. A stack is named
+// after the directory holding its compose file, and directories inside a stack
+// aren't searched.
+func FindLocalStacks(rootDir string) ([]Stack, error) {
+	var stacks []Stack
+	maxDepth := discoveryMaxDepth()
 
-		// Check for common compose file names
-		composeFiles := []string{
-			"compose.yaml",
-			"compose.yml",
-			"docker-compose.yaml",
-			"docker-compose.yml",
+	err := filepath.WalkDir(rootDir, func(stackPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if stackPath == rootDir {
+				return err
+			}
+			logger.Errorf("Warning: could not read local directory %s: %v", stackPath, err)
+			return nil
+		}
+		if !entry.IsDir() || stackPath == rootDir {
+			return nil
 		}
 
 		hasComposeFile := false
 		var statErrors []error
 
-		for _, composeFile := range composeFiles {
+		for _, composeFile := range composeFileNames {
 			composePath := filepath.Join(stackPath, composeFile)
 			_, err := os.Stat(composePath)
 			if err == nil {
@@ -300,107 +444,213 @@ func FindLocalStacks(rootDir string) ([]Stack, error) {
 		// If any compose file exists, consider it a valid stack
 		if hasComposeFile {
 			stacks = append(stacks, Stack{
-				Name:       stackName,
+				Name:       entry.Name(),
 				Path:       stackPath,
 				ServerName: "local",
 				IsRemote:   false,
 				HostConfig: nil,
 				// AbsoluteRemoteRoot is empty for local stacks
+				ProjectName: LocalProjectName(stackPath),
+				Actions:     localStackActions(stackPath),
 			})
+			return filepath.SkipDir
 		} else if len(statErrors) > 0 {
 			// Only log warnings if there were non-NotExist errors
 			for _, statErr := range statErrors {
 				logger.Errorf("Warning: could not stat compose files in local stack %s: %v", stackPath, statErr)
 			}
 		}
+
+		relativePath, err := filepath.Rel(rootDir, stackPath)
+		if err == nil && strings.Count(relativePath, string(filepath.Separator))+1 >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local root directory %s: %w", rootDir, err)
 	}
 
+	stacks = append(stacks, localQuadletStacks()...)
+
+	disambiguateStackNames(stacks, rootDir)
 	return stacks, nil
 }
 
-func FindRemoteStacks(hostConfig *config.SSHHost) ([]Stack, error) {
-	var stacks []Stack
-
-	if sshManager == nil {
-		return nil, fmt.Errorf("ssh manager not initialized for discovery on %s", hostConfig.Name)
+// DiscoverLocalStacks finds the local stacks: those under the local root (see
+// ResolveLocalRoot) and, with quadlet_discovery, the quadlets of the local
+// user. Quadlets don't live under the root, so they are still found without
+// one; a missing root then only means there are no compose stacks.
+func DiscoverLocalStacks(override string) ([]Stack, error) {
+	rootDir, err := ResolveLocalRoot(override)
+	if errors.Is(err, ErrRootNotFound) {
+		logger.Debug("No local root directory configured or found")
+		return localQuadletStacks(), nil
 	}
-
-	client, err := sshManager.GetClient(*hostConfig)
 	if err != nil {
-		return nil, err // GetClient already provides context
+		return nil, fmt.Errorf("local root check failed: %w", err)
 	}
+	return FindLocalStacks(rootDir)
+}
 
-	var targetRemoteRoot string
-	var resolveErr error
-	var pwdOutput []byte
+// localQuadletStacks returns the local quadlets if quadlet_discovery is set.
+// A quadlet directory that can't be read is logged rather than failing discovery.
+func localQuadletStacks() []Stack {
+	if !quadletDiscoveryEnabled() {
+		return nil
+	}
+	quadlets, err := FindLocalQuadlets()
+	if err != nil {
+		logger.Errorf("Warning: %v", err)
+	}
+	return quadlets
+}
 
-	if hostConfig.RemoteRoot != "" {
-		targetRemoteRoot = hostConfig.RemoteRoot
-		session, err := client.NewSession()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create ssh session for discovery on %s: %w", hostConfig.Name, err)
-		}
-		resolveCmd := fmt.Sprintf("cd %s && pwd", util.QuoteArgForShell(targetRemoteRoot))
-		pwdOutput, resolveErr = session.CombinedOutput(resolveCmd)
-		if err := session.Close(); err != nil {
-			logger.Errorf("Error closing SSH session for %s (resolve path): %v", hostConfig.Name, err)
+// disambiguateStackNames renames the compose stacks that share a name on one
+// host, which can happen with nested layouts (e.g. a/docker and b/docker), after
+// their path below rootDir ("a/docker" and "b/docker"), so that each of them can
+// be targeted. rootDir is only used for local stacks, whose Path is absolute.
+func disambiguateStackNames(stacks []Stack, rootDir string) {
+	counts := make(map[string]int)
+	for _, stack := range stacks {
+		if stack.Quadlet == nil {
+			counts[stack.Name]++
 		}
-		if resolveErr != nil {
-			return nil, fmt.Errorf("failed to resolve configured remote root path '%s' on host %s: %w\nOutput: %s", targetRemoteRoot, hostConfig.Name, resolveErr, string(pwdOutput))
+	}
+	for i := range stacks {
+		stack := &stacks[i]
+		if stack.Quadlet != nil || counts[stack.Name] < 2 {
+			continue
 		}
-	} else {
-		// Configured root is empty, try fallbacks
-		fallbacks := []string{"~/bucket", "~/compose-bucket"}
-		foundFallback := false
-		for _, fallback := range fallbacks {
-			session, err := client.NewSession()
+		name := stack.Path
+		if !stack.IsRemote {
+			relativePath, err := filepath.Rel(rootDir, stack.Path)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create ssh session for fallback discovery on %s: %w", hostConfig.Name, err)
+				continue
 			}
-			resolveCmd := fmt.Sprintf("cd %s && pwd", util.QuoteArgForShell(fallback))
-			pwdOutput, resolveErr = session.CombinedOutput(resolveCmd)
+			name = filepath.ToSlash(relativePath)
+		}
+		logger.Warn("Multiple stacks share a name, naming them after their paths instead",
+			"stack", stack.Identifier(), "name", name)
+		stack.Name = name
+	}
+	warnDuplicateStackNames(stacks)
+}
 
-			if resolveErr == nil {
-				targetRemoteRoot = fallback
-				foundFallback = true
-				break
-			}
+// warnDuplicateStackNames logs a warning for stacks still found under the same
+// name on one host, such as a compose stack and a quadlet. Stacks are looked up
+// by name, so only the first of them can be targeted.
+func warnDuplicateStackNames(stacks []Stack) {
+	seen := make(map[string]string)
+	for _, stack := range stacks {
+		if first, ok := seen[stack.Name]; ok {
+			logger.Warn("Multiple stacks share a name, only the first can be targeted by name",
+				"stack", stack.Identifier(), "path", stack.Path, "first_path", first)
+			continue
 		}
+		seen[stack.Name] = stack.Path
+	}
+}
 
-		if !foundFallback {
-			return nil, fmt.Errorf("remote_root not configured for host %s, and default fallbacks ('~/bucket', '~/compose-bucket') could not be resolved", hostConfig.Name)
+// DisambiguatedStacks returns the stacks that were named after their paths
+// because they share the directory name name with another stack on their host,
+// e.g. "a/docker" and "b/docker" for "docker", to list them when name is used
+// to target a stack.
+func DisambiguatedStacks(stacks []Stack, name string) []Stack {
+	var matches []Stack
+	for _, stack := range stacks {
+		if stack.Quadlet == nil && stack.Name != name && path.Base(stack.Name) == name {
+			matches = append(matches, stack)
 		}
 	}
+	return matches
+}
 
-	absoluteRemoteRoot := strings.TrimSpace(string(pwdOutput))
-	if absoluteRemoteRoot == "" {
-		return nil, fmt.Errorf("resolved remote root path is empty for '%s' (resolved from '%s') on host %s", absoluteRemoteRoot, targetRemoteRoot, hostConfig.Name)
+// FindRemoteStacks discovers stacks on a remote host. If remoteRootOverride is
+// non-empty, it is searched instead of the host's configured remote_root.
+func FindRemoteStacks(hostConfig *config.SSHHost, remoteRootOverride string) ([]Stack, error) {
+	var stacks []Stack
+
+	if remoteRootOverride != "" {
+		overridden := *hostConfig // Copy so the caller's config is left untouched
+		overridden.RemoteRoot = remoteRootOverride
+		hostConfig = &overridden
+	}
+
+	if sshManager == nil {
+		return nil, fmt.Errorf("ssh manager not initialized for discovery on %s", hostConfig.Name)
+	}
+
+	client, err := sshManager.GetClient(*hostConfig)
+	if err != nil {
+		return nil, err // GetClient already provides context
+	}
+
+	absoluteRemoteRoot, err := ResolveRemoteRoot(client, hostConfig)
+	if errors.Is(err, ErrRootNotFound) && quadletDiscoveryEnabled() {
+		// Quadlets don't live under the root, so the host may still have some
+		logger.Debug("No stack root directory on remote host, only listing quadlets", "host_name", hostConfig.Name)
+		return remoteQuadletStacks(client, hostConfig), nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	findSession, err := client.NewSession()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create second ssh session for discovery on %s: %w", hostConfig.Name, err)
 	}
-	// CombinedOutput handles the session lifecycle for findSession.
+	// CombinedOutputContext handles the session lifecycle for findSession.
 
-	// Command to find directories containing any supported compose files one level deep using find (representing stack roots)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Warn("Could not load config to check discovery settings", "error", err)
+	}
+	findOptions := ""
+	if cfg.DiscoveryXDev {
+		findOptions = " -xdev"
+	}
+
+	// Command to find compose files up to discovery_max_depth directories deep (their
+	// directories are the stack roots), printing each directory with the lines that
+	// determine its project name and its custom actions: "<dir>\t<name: line>\t
+	// <COMPOSE_PROJECT_NAME= line from .env>\t<.bm.yaml in base64>". Sorting lists
+	// the compose file compose prefers first within each directory.
 	remoteFindCmd := fmt.Sprintf(
-		`find %s -maxdepth 2 \( -name 'compose.y*ml' -o -name 'docker-compose.y*ml' \) -printf '%%h\\n' | sort -u`,
-		util.QuoteArgForShell(absoluteRemoteRoot),
+		`find %s%s -mindepth 2 -maxdepth %d \( -name 'compose.y*ml' -o -name 'docker-compose.y*ml' \) -print | LC_ALL=C sort | `+
+			`while IFS= read -r f; do d="${f%%/*}"; `+
+			`printf '%%s\t%%s\t%%s\t%%s\n' "$d" "$(grep -m1 '^name:' "$f")" "$(grep -s -m1 '^COMPOSE_PROJECT_NAME=' "$d/.env")" `+
+			`"$([ -f "$d/%s" ] && base64 < "$d/%s" | tr -d '\n')"; done`,
+		util.QuoteArgForShell(absoluteRemoteRoot), findOptions, cfg.GetDiscoveryMaxDepth()+1, stackConfigFile, stackConfigFile,
 	)
 
-	output, err := findSession.CombinedOutput(remoteFindCmd)
+	// A find stuck on a hung mount would otherwise hold up the whole discovery
+	ctx := context.Background()
+	timeout := cfg.GetDiscoveryTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	output, err := ssh.CombinedOutputContext(ctx, findSession, remoteFindCmd)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("remote find command timed out after %s on host %s; raise discovery_timeout, or set discovery_xdev to skip mounts below the remote root", timeout, hostConfig.Name)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("remote find command failed for host %s: %w\nOutput: %s", hostConfig.Name, err, string(output))
 	}
 
 	scanner := bufio.NewScanner(bytes.NewReader(output))
+	seenDirs := make(map[string]bool)
 
 	for scanner.Scan() {
-		fullPath := scanner.Text()
-		if fullPath == "" {
-			continue
+		fullPath, rest, _ := strings.Cut(scanner.Text(), "\t")
+		if fullPath == "" || seenDirs[fullPath] {
+			continue // Only the preferred compose file of a directory counts
 		}
+		seenDirs[fullPath] = true
+		nameLine, rest, _ := strings.Cut(rest, "\t")
+		envLine, encodedActions, _ := strings.Cut(rest, "\t")
 
 		relativePath, err := filepath.Rel(absoluteRemoteRoot, fullPath)
 		if err != nil {
@@ -409,7 +659,7 @@ func FindRemoteStacks(hostConfig *config.SSHHost) ([]Stack, error) {
 		}
 		relativePath = filepath.ToSlash(relativePath) // Ensure forward slashes
 
-		stackName := filepath.Base(relativePath)
+		stackName := path.Base(relativePath)
 		if stackName == "." || stackName == "/" {
 			continue
 		}
@@ -421,11 +671,104 @@ func FindRemoteStacks(hostConfig *config.SSHHost) ([]Stack, error) {
 			IsRemote:           true,
 			HostConfig:         hostConfig,
 			AbsoluteRemoteRoot: absoluteRemoteRoot,
+			ProjectName:        projectName(fullPath, []byte(nameLine), []byte(envLine)),
+			Actions:            remoteStackActions(hostConfig.Name, fullPath, encodedActions),
 		})
 	}
 	if err := scanner.Err(); err != nil {
 		return stacks, fmt.Errorf("error reading ssh output for host %s: %w", hostConfig.Name, err)
 	}
 
-	return stacks, nil
+	// Like local discovery, directories inside a stack aren't stacks themselves
+	var topLevelStacks []Stack
+	for _, stack := range stacks {
+		if !insideStack(stacks, stack.Path) {
+			topLevelStacks = append(topLevelStacks, stack)
+		}
+	}
+
+	if quadletDiscoveryEnabled() {
+		topLevelStacks = append(topLevelStacks, remoteQuadletStacks(client, hostConfig)...)
+	}
+
+	disambiguateStackNames(topLevelStacks, absoluteRemoteRoot)
+	return topLevelStacks, nil
+}
+
+// remoteQuadletStacks returns the quadlets of the SSH user on a remote host. A
+// failure to list them is logged rather than failing discovery.
+func remoteQuadletStacks(client *gossh.Client, hostConfig *config.SSHHost) []Stack {
+	quadlets, err := findRemoteQuadlets(client, hostConfig)
+	if err != nil {
+		logger.Errorf("Warning: %v", err)
+	}
+	return quadlets
+}
+
+// ResolveRemoteRoot returns the absolute path of the directory searched for
+// stacks on a remote host: its remote_root, or else the first of ~/bucket and
+// ~/compose-bucket that exists.
+func ResolveRemoteRoot(client *gossh.Client, hostConfig *config.SSHHost) (string, error) {
+	var targetRemoteRoot string
+	var resolveErr error
+	var pwdOutput []byte
+
+	if hostConfig.RemoteRoot != "" {
+		targetRemoteRoot = hostConfig.RemoteRoot
+		session, err := client.NewSession()
+		if err != nil {
+			return "", fmt.Errorf("failed to create ssh session for discovery on %s: %w", hostConfig.Name, err)
+		}
+		resolveCmd := fmt.Sprintf("cd %s && pwd", util.QuoteArgForShell(targetRemoteRoot))
+		pwdOutput, resolveErr = session.CombinedOutput(resolveCmd)
+		if err := session.Close(); err != nil {
+			logger.Errorf("Error closing SSH session for %s (resolve path): %v", hostConfig.Name, err)
+		}
+		if resolveErr != nil {
+			return "", fmt.Errorf("failed to resolve configured remote root path '%s' on host %s: %w\nOutput: %s", targetRemoteRoot, hostConfig.Name, resolveErr, string(pwdOutput))
+		}
+	} else {
+		// Configured root is empty, try fallbacks
+		fallbacks := []string{"~/bucket", "~/compose-bucket"}
+		foundFallback := false
+		for _, fallback := range fallbacks {
+			session, err := client.NewSession()
+			if err != nil {
+				return "", fmt.Errorf("failed to create ssh session for fallback discovery on %s: %w", hostConfig.Name, err)
+			}
+			resolveCmd := fmt.Sprintf("cd %s && pwd", util.QuoteArgForShell(fallback))
+			pwdOutput, resolveErr = session.CombinedOutput(resolveCmd)
+			session.Close()
+
+			if resolveErr == nil {
+				targetRemoteRoot = fallback
+				foundFallback = true
+				break
+			}
+			// Only a failing cd means the fallback doesn't exist; anything else,
+			// such as a dropped connection, says nothing about the host's root
+			var exitErr *gossh.ExitError
+			if !errors.As(resolveErr, &exitErr) || exitErr.ExitStatus() == 0 {
+				return "", fmt.Errorf("failed to resolve fallback remote root path '%s' on host %s: %w", fallback, hostConfig.Name, resolveErr)
+			}
+		}
+
+		if !foundFallback {
+			return "", fmt.Errorf("%w on host %s (remote_root isn't configured, and neither '~/bucket' nor '~/compose-bucket' could be resolved)", ErrRootNotFound, hostConfig.Name)
+		}
+	}
+
+	absoluteRemoteRoot := strings.TrimSpace(string(pwdOutput))
+	if absoluteRemoteRoot == "" {
+		return "", fmt.Errorf("resolved remote root path is empty for '%s' (resolved from '%s') on host %s", absoluteRemoteRoot, targetRemoteRoot, hostConfig.Name)
+	}
+	return absoluteRemoteRoot, nil
+}
+
+// insideStack reports whether the remote directory relativePath is inside one of
+// the given stacks.
+func insideStack(stacks []Stack, relativePath string) bool {
+	return slices.ContainsFunc(stacks, func(s Stack) bool {
+		return strings.HasPrefix(relativePath, s.Path+"/")
+	})
 }
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package discovery's identifier.go file shows stacks with identifier_format,
+// e.g. as "app@server1" instead of "server1:app". Only the display changes;
+// stacks are still targeted, completed and keyed by their identifier.
+
+package discovery
+
+import (
+	"bucket-manager/internal/config"
+	"strings"
+	"sync/atomic"
+	"text/template"
+)
+
+// identifierFormat is the compiled identifier_format, nil if none is set.
+var identifierFormat atomic.Pointer[template.Template]
+
+// SetIdentifierFormat sets the identifier_format that stacks are shown with.
+// An empty format shows them by identifier. An invalid one is not set.
+func SetIdentifierFormat(format string) error {
+	tmpl, err := config.ParseIdentifierFormat(format)
+	if err != nil {
+		return err
+	}
+	identifierFormat.Store(tmpl)
+	return nil
+}
+
+// HasIdentifierFormat reports whether an identifier_format is set, for views
+// that show stacks differently from their identifier by default.
+func HasIdentifierFormat() bool {
+	return identifierFormat.Load() != nil
+}
+
+// DisplayName returns how the stack is shown: its identifier rendered with
+// identifier_format, or the identifier itself if none is set.
+func (s Stack) DisplayName() string {
+	return DisplayIdentifier(s.Identifier())
+}
+
+// DisplayIdentifier returns how the stack with the given identifier is shown,
+// like Stack.DisplayName, for places that only keep the identifier.
+func DisplayIdentifier(identifier string) string {
+	tmpl := identifierFormat.Load()
+	if tmpl == nil {
+		return identifier
+	}
+	serverName, name, ok := strings.Cut(identifier, ":")
+	if !ok {
+		return identifier
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, config.IdentifierFields{Name: name, ServerName: serverName, Identifier: identifier}); err != nil {
+		return identifier
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package discovery's project.go file determines the compose project name of a
+// stack, so that commands can pass it explicitly with `-p` instead of relying
+// on the directory they run in.
+
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeFileNames lists the supported compose file names in the order compose
+// itself prefers them.
+var composeFileNames = []string{
+	"compose.yaml",
+	"compose.yml",
+	"docker-compose.yaml",
+	"docker-compose.yml",
+}
+
+// parseComposeName returns the top-level `name:` of compose file content (a
+// whole file or just its `name:` line), or "" if there is none.
+func parseComposeName(data []byte) string {
+	var doc struct {
+		Name string `yaml:"name"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return ""
+	}
+	return doc.Name
+}
+
+// normalizeProjectName applies compose's project name rules: lowercase letters,
+// digits, '-' and '_' only, starting with a letter or digit.
+func normalizeProjectName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(b.String(), "-_")
+}
+
+// parseEnvProjectName returns the COMPOSE_PROJECT_NAME set in .env file content
+// (a whole file or just the matching line), or "" if it isn't set.
+func parseEnvProjectName(data []byte) string {
+	for line := range strings.Lines(string(data)) {
+		value, found := strings.CutPrefix(strings.TrimSpace(line), "COMPOSE_PROJECT_NAME=")
+		if found {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// projectName returns the project name for a stack directory, given its compose
+// file and .env content (or just the relevant lines, possibly empty), following
+// compose's precedence: COMPOSE_PROJECT_NAME from .env, then the top-level
+// `name:`, then the directory name. An empty result means the name uses
+// variable interpolation that only compose can resolve, so no `-p` should be passed.
+func projectName(stackDir string, composeData, envData []byte) string {
+	name := parseEnvProjectName(envData)
+	if name == "" {
+		name = parseComposeName(composeData)
+	}
+	switch {
+	case name == "":
+		return normalizeProjectName(filepath.Base(stackDir))
+	case strings.Contains(name, "$"):
+		return ""
+	default:
+		return normalizeProjectName(name)
+	}
+}
+
+// LocalProjectName returns the compose project name of the local stack in
+// stackPath, read from its .env and preferred compose files.
+func LocalProjectName(stackPath string) string {
+	envData, _ := os.ReadFile(filepath.Join(stackPath, ".env")) // Usually absent
+	for _, composeFile := range composeFileNames {
+		composeData, err := os.ReadFile(filepath.Join(stackPath, composeFile))
+		if err == nil {
+			return projectName(stackPath, composeData, envData)
+		}
+	}
+	return projectName(stackPath, nil, envData)
+}
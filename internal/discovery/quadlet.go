@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package discovery's quadlet.go file discovers Podman quadlets: .container
+// units that systemd turns into services. With quadlet_discovery enabled, each
+// one is listed as a stack next to the compose stacks of its host.
+
+package discovery
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/logger"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// quadletExtension is the extension of the quadlet files listed as stacks.
+const quadletExtension = ".container"
+
+// Quadlet describes a stack backed by a Podman quadlet instead of a compose
+// project. It is started and stopped with systemctl --user.
+type Quadlet struct {
+	Unit  string // Service generated from the quadlet, e.g. "web.service"
+	Image string // Image= of the .container file; empty if it has none
+}
+
+// quadletDiscoveryEnabled reports whether quadlet_discovery is set.
+func quadletDiscoveryEnabled() bool {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Warn("Could not load config to check quadlet_discovery", "error", err)
+	}
+	return cfg.QuadletDiscovery
+}
+
+// localQuadletDir returns the directory quadlets of the local user are read
+// from: $XDG_CONFIG_HOME/containers/systemd.
+func localQuadletDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "containers", "systemd"), nil
+}
+
+// FindLocalQuadlets returns the quadlets of the local user as stacks, searching
+// the quadlet directory and its subdirectories for .container files. A missing
+// directory means there are none.
+func FindLocalQuadlets() ([]Stack, error) {
+	quadletDir, err := localQuadletDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find local quadlet directory: %w", err)
+	}
+
+	var stacks []Stack
+	err = filepath.WalkDir(quadletDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == quadletDir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), quadletExtension) {
+			return nil
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			logger.Errorf("Warning: could not read quadlet %s: %v", filePath, err)
+			return nil
+		}
+		name := strings.TrimSuffix(entry.Name(), quadletExtension)
+		stacks = append(stacks, Stack{
+			Name:       name,
+			Path:       filepath.Dir(filePath),
+			ServerName: "local",
+			IsRemote:   false,
+			Quadlet:    parseQuadlet(name, content),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quadlet directory %s: %w", quadletDir, err)
+	}
+	return stacks, nil
+}
+
+// FindLocalQuadlet returns the local quadlet stack with the given name, if
+// quadlet_discovery is enabled and there is one.
+func FindLocalQuadlet(name string) (Stack, bool) {
+	if !quadletDiscoveryEnabled() {
+		return Stack{}, false
+	}
+	quadlets, err := FindLocalQuadlets()
+	if err != nil {
+		logger.Errorf("Warning: %v", err)
+	}
+	for _, quadlet := range quadlets {
+		if quadlet.Name == name {
+			return quadlet, true
+		}
+	}
+	return Stack{}, false
+}
+
+// remoteQuadletCmd lists the services of the SSH user that were generated from
+// a .container quadlet, printing "<unit>\t<quadlet file>\t<Image= value>".
+const remoteQuadletCmd = `systemctl --user list-units --all --type=service --plain --no-legend 2>/dev/null | ` +
+	`while read -r unit _; do src=$(systemctl --user show -p SourcePath --value "$unit"); ` +
+	`case "$src" in *.container) printf '%s\t%s\t%s\n' "$unit" "$src" "$(sed -n 's/^Image=//p' "$src" | tail -n 1)";; esac; done`
+
+// findRemoteQuadlets returns the quadlets of the SSH user on a remote host as
+// stacks, found among the services systemd has loaded.
+func findRemoteQuadlets(client *gossh.Client, hostConfig *config.SSHHost) ([]Stack, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ssh session for quadlet discovery on %s: %w", hostConfig.Name, err)
+	}
+	output, err := session.CombinedOutput(remoteQuadletCmd)
+	if err != nil {
+		return nil, fmt.Errorf("quadlet discovery failed on host %s: %w\nOutput: %s", hostConfig.Name, err, string(output))
+	}
+
+	var stacks []Stack
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		unit, sourcePath, image := fields[0], fields[1], fields[2]
+		stacks = append(stacks, Stack{
+			Name:               strings.TrimSuffix(path.Base(sourcePath), quadletExtension),
+			ServerName:         hostConfig.Name,
+			IsRemote:           true,
+			HostConfig:         hostConfig,
+			AbsoluteRemoteRoot: path.Dir(sourcePath), // Commands run in the quadlet's directory
+			Quadlet:            &Quadlet{Unit: unit, Image: quadletImage(image)},
+		})
+	}
+	return stacks, scanner.Err()
+}
+
+// parseQuadlet reads the service name and image of a .container file named name.
+func parseQuadlet(name string, content []byte) *Quadlet {
+	quadlet := &Quadlet{Unit: name + ".service"}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Image":
+			quadlet.Image = quadletImage(strings.TrimSpace(value))
+		case "ServiceName":
+			quadlet.Unit = strings.TrimSpace(value) + ".service"
+		}
+	}
+	return quadlet
+}
+
+// quadletImage returns the image reference of an Image= value, or "" if it
+// refers to another quadlet (e.g. "app.image"), which can't be pulled directly.
+func quadletImage(value string) string {
+	if strings.HasSuffix(value, ".image") || strings.HasSuffix(value, ".build") {
+		return ""
+	}
+	return value
+}
@@ -44,11 +44,48 @@ type Config struct {
 	Silent     bool // For CLI: disables all stderr output
 }
 
+// FileOptions controls where the log file is written and how it is rotated.
+type FileOptions struct {
+	Path       string // Log file path; empty for the per-interface file in the XDG state directory
+	MaxSizeMB  int    // Size in megabytes at which the file is rotated
+	MaxBackups int    // Number of rotated files to keep
+}
+
+// Defaults for log file rotation.
+const (
+	DefaultMaxSizeMB  = 10
+	DefaultMaxBackups = 3
+)
+
 // defaultLogger is the package-level logger instance used by all logging functions
 var defaultLogger *slog.Logger
 
-// getLogFilePath determines the path for the application log file based on XDG spec and interface type.
+// fileOptions holds the log file settings used by the Init functions.
+var fileOptions = FileOptions{MaxSizeMB: DefaultMaxSizeMB, MaxBackups: DefaultMaxBackups}
+
+// logFile is the currently open log file, closed when logging is set up again.
+var logFile *rotatingFile
+
+// SetFileOptions sets the log file location and rotation. It takes effect the
+// next time one of the Init functions is called. Non-positive sizes and
+// negative backup counts are replaced by the defaults.
+func SetFileOptions(opts FileOptions) {
+	if opts.MaxSizeMB <= 0 {
+		opts.MaxSizeMB = DefaultMaxSizeMB
+	}
+	if opts.MaxBackups < 0 {
+		opts.MaxBackups = DefaultMaxBackups
+	}
+	fileOptions = opts
+}
+
+// getLogFilePath determines the path for the application log file: the configured
+// path if set, otherwise a per-interface file following the XDG spec.
 func getLogFilePath(interfaceType InterfaceType) (string, error) {
+	if fileOptions.Path != "" {
+		return fileOptions.Path, nil
+	}
+
 	stateDir := os.Getenv("XDG_STATE_HOME")
 	if stateDir == "" {
 		homeDir, err := os.UserHomeDir()
@@ -105,11 +142,15 @@ func createFileWriter(interfaceType InterfaceType) (io.Writer, error) {
 		return nil, fmt.Errorf("error creating log directory %s: %w", logDir, err)
 	}
 
-	file, err := os.OpenFile(logFilePath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0640)
+	file, err := openRotatingFile(logFilePath, int64(fileOptions.MaxSizeMB)*1024*1024, fileOptions.MaxBackups)
 	if err != nil {
-		return nil, fmt.Errorf("error opening log file %s: %w", logFilePath, err)
+		return nil, err
 	}
 
+	if logFile != nil {
+		logFile.Close() // Left over from a previous Init call
+	}
+	logFile = file
 	return file, nil
 }
 
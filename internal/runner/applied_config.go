@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's applied_config.go file remembers the effective compose
+// configuration each stack was last brought up with, so that the next preview
+// can show what changed since.
+
+package runner
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/util"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// appliedConfigPath returns the file the stack's last applied configuration
+// is kept in, under the state directory.
+func appliedConfigPath(stack discovery.Stack) (string, error) {
+	stateDir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	// Stack names may contain slashes (see disambiguateStackNames), which
+	// become subdirectories
+	return filepath.Join(stateDir, "applied-configs", stack.ServerName, filepath.FromSlash(stack.Name)+".yaml"), nil
+}
+
+// LoadAppliedConfig returns the configuration saved with SaveAppliedConfig for
+// the stack, and false if none was saved yet.
+func LoadAppliedConfig(stack discovery.Stack) (string, bool, error) {
+	path, err := appliedConfigPath(stack)
+	if err != nil {
+		return "", false, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read the last applied config of %s: %w", stack.Identifier(), err)
+	}
+	return string(data), true, nil
+}
+
+// SaveAppliedConfig saves the configuration the stack was brought up with,
+// for LoadAppliedConfig.
+func SaveAppliedConfig(stack discovery.Stack, cfg string) error {
+	path, err := appliedConfigPath(stack)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create the applied config directory: %w", err)
+	}
+	if err := util.WriteFileAtomic(path, []byte(cfg), 0o600); err != nil {
+		return fmt.Errorf("failed to save the applied config of %s: %w", stack.Identifier(), err)
+	}
+	return nil
+}
+
+// RenameAppliedConfigs moves the configurations saved for the stacks of the
+// host oldServer to newServer, after the host is renamed.
+func RenameAppliedConfigs(oldServer, newServer string) error {
+	stateDir, err := config.StateDir()
+	if err != nil {
+		return err
+	}
+	oldDir := filepath.Join(stateDir, "applied-configs", oldServer)
+	newDir := filepath.Join(stateDir, "applied-configs", newServer)
+	if _, err := os.Stat(oldDir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err := os.Rename(oldDir, newDir); err != nil {
+		return fmt.Errorf("failed to move the applied configs of %s: %w", oldServer, err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's detach.go file runs the steps of a remote stack detached
+// from the SSH connection (`--detach`), so that a long pull or refresh keeps
+// going if the connection drops. The steps run in the background under nohup,
+// holding the stack's lock, with their output written to a log file on the
+// host that `bm logs --detached` shows.
+
+package runner
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// detachedLogDir is the directory, relative to the SSH user's home, that the
+// logs of detached runs are written to.
+const detachedLogDir = ".local/state/bucket-manager/detached"
+
+// detachedBusyStatus is the exit status of the remote detach script when the
+// stack's lock is held by another operation.
+const detachedBusyStatus = 75
+
+// detachedLockFD is the file descriptor on which the remote detach script holds
+// the stack's lock, inherited by the background run.
+const detachedLockFD = 9
+
+// DetachedLogPath returns the path of the log of a stack's detached runs on its
+// host, relative to the SSH user's home directory. Each run replaces the log
+// of the previous one.
+func DetachedLogPath(stack discovery.Stack) string {
+	// Stack names may contain slashes (see disambiguateStackNames)
+	return path.Join(detachedLogDir, strings.ReplaceAll(stack.Name, "/", "_")+".log")
+}
+
+// detachedLogWord returns the log path as a shell word expanded on the host.
+func detachedLogWord(stack discovery.Stack) string {
+	return `"$HOME"/` + util.QuoteArgForShell(DetachedLogPath(stack))
+}
+
+// RunDetached starts the steps of a remote stack in the background on its host,
+// returning without waiting for them. The steps run one after the other until one fails, and the log ends
+// with a "bm: finished" line giving the exit status (see DetachedLogPath). If another operation
+// holds the stack's lock, an error wrapping ErrStackBusy is returned.
+func RunDetached(stack discovery.Stack, action string, sequence []CommandStep) error {
+	if !stack.IsRemote {
+		return fmt.Errorf("%s is a local stack; only remote stacks can be run detached", stack.Identifier())
+	}
+	if stack.HostConfig == nil {
+		return fmt.Errorf("internal error: HostConfig is nil for remote stack %s", stack.Identifier())
+	}
+	if stack.AbsoluteRemoteRoot == "" {
+		return fmt.Errorf("internal error: AbsoluteRemoteRoot is empty for remote stack %s", stack.Identifier())
+	}
+	if err := CheckSequenceWritable(sequence); err != nil {
+		return err
+	}
+
+	// The steps are chained so that the first failing one ends the run
+	var steps []string
+	for _, step := range sequence {
+		resolveStepRuntime(&step)
+		if step.SkipIfPinned && pinnedImagesPresent(step) {
+			steps = append(steps, "echo 'bm: all images are pinned by digest and already present, skipping the pull'")
+			continue
+		}
+		step.Args = withComposeParallel(step)
+		steps = append(steps, "echo "+util.QuoteArgForShell("bm: "+step.Name), remoteStepCommand(step))
+	}
+	detachScript := detachedRunScript(stack, action, steps)
+
+	cmdDesc := fmt.Sprintf("detached '%s' for stack %s", action, stack.Identifier())
+	logger.Debug("Starting detached run",
+		"stack_identifier", stack.Identifier(),
+		"action", action,
+		"remote_command", detachScript)
+	output, err := runSSHOutputCommand(*stack.HostConfig, detachScript, cmdDesc)
+	if err != nil {
+		var exitErr *gossh.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitStatus() == detachedBusyStatus {
+			return busyError(stack, strings.TrimSpace(string(output)))
+		}
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// The run changes the stack once it gets going
+	InvalidateStatus(stack.Identifier())
+	logger.Info("Detached run started",
+		"stack_identifier", stack.Identifier(),
+		"action", action,
+		"log", DetachedLogPath(stack))
+	return nil
+}
+
+// detachedRunScript returns the script that starts the step commands of a
+// detached run in the stack's directory.
+// Where flock(1) is available, the stack's lock is taken by the launcher on a
+// file descriptor that the background run inherits, so it is taken only once
+// and held until the run ends: a busy stack is reported with its holder and
+// detachedBusyStatus before the launcher exits, and no other operation can
+// take the lock in between. The log is only replaced once the lock is held,
+// so a run that didn't get the lock can't clobber another's log.
+func detachedRunScript(stack discovery.Stack, action string, steps []string) string {
+	started := fmt.Sprintf("bm: started '%s' on %s, pid ", action, stack.Identifier())
+	runScript := strings.Join([]string{
+		"exec > " + detachedLogWord(stack) + " 2>&1",
+		fmt.Sprintf(`echo %s"$$ since $(date)" > %s`, util.QuoteArgForShell(fmt.Sprintf("detached '%s', pid ", action)), stackLockFile),
+		fmt.Sprintf(`echo %s"$$ at $(date)"`, util.QuoteArgForShell(started)),
+		strings.Join(steps, " && "),
+		`status=$?`,
+		`echo "bm: finished with exit status $status at $(date)"`,
+		`exit $status`,
+	}, "\n")
+
+	remoteStackPath := filepath.Join(stack.AbsoluteRemoteRoot, stack.Path)
+	return strings.Join([]string{
+		"cd " + util.QuoteArgForShell(remoteStackPath) + " || exit 1",
+		`mkdir -p "$HOME"/` + util.QuoteArgForShell(detachedLogDir) + " || exit 1",
+		"if command -v flock >/dev/null 2>&1; then",
+		// Appending keeps the holder written by the current owner, if any
+		fmt.Sprintf("  exec %d>>%s || exit 1", detachedLockFD, stackLockFile),
+		fmt.Sprintf("  flock -n %d || { cat %s; exit %d; }", detachedLockFD, stackLockFile, detachedBusyStatus),
+		"fi",
+		fmt.Sprintf(`nohup sh -c %s > /dev/null 2>&1 < /dev/null &`, util.QuoteArgForShell(runScript)),
+	}, "\n")
+}
+
+// StreamDetachedLog shows the log of a remote stack's last detached run: its
+// last tail lines (all of them if tail is negative), and with follow, the lines
+// added until interrupted. Output is streamed like StreamCommand's.
+func StreamDetachedLog(stack discovery.Stack, tail int, follow bool, cliMode bool) (<-chan OutputLine, <-chan error) {
+	outChan := make(chan OutputLine, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(outChan)
+		defer close(errChan)
+
+		if !stack.IsRemote {
+			errChan <- fmt.Errorf("%s is a local stack; only remote stacks are run detached", stack.Identifier())
+			return
+		}
+		if stack.HostConfig == nil {
+			errChan <- fmt.Errorf("internal error: HostConfig is nil for remote stack %s", stack.Identifier())
+			return
+		}
+
+		lines := "+1" // From the first line
+		if tail >= 0 {
+			lines = strconv.Itoa(tail)
+		}
+		tailCmd := "tail -n " + lines
+		if follow {
+			tailCmd += " -f"
+		}
+		logWord := detachedLogWord(stack)
+		remoteCmd := fmt.Sprintf("if [ ! -f %s ]; then echo 'no detached run has been started on this stack' >&2; exit 1; fi; %s %s",
+			logWord, tailCmd, logWord)
+
+		cmdDesc := fmt.Sprintf("detached run log of stack %s", stack.Identifier())
+		runSSHCommand(*stack.HostConfig, remoteCmd, cmdDesc, 0, cliMode, outChan, errChan)
+	}()
+
+	return outChan, errChan
+}
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's detect.go file picks the container runtime used on each
+// host when container_runtime is "auto" or unset: the first of podman and
+// docker that is installed and has a working compose. The result is cached
+// per host for the lifetime of the process, and a failed detection for
+// runtimeDetectionRetry. Sequence builders don't detect the runtime
+// themselves (see runtimeCommand): the steps resolve it when they run, so
+// that building a sequence, e.g. in the TUI's Update, never waits on a host.
+
+package runner
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/logger"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// runtimeCandidates are the runtimes detection looks for, in order of preference.
+var runtimeCandidates = []string{"podman", "docker"}
+
+// fallbackRuntime is used on hosts where no candidate has a working compose,
+// so that the commands run fail with the runtime's own error.
+const fallbackRuntime = "podman"
+
+// runtimeDetectionRetry is how long a failed detection is remembered before
+// the host is tried again, so that an unreachable host doesn't cost a dial
+// timeout on every status check or action.
+const runtimeDetectionRetry = 30 * time.Second
+
+// detectedRuntimes caches the detected runtime of each host by host name
+// ("local" for this machine), failedDetections the time and error of its last
+// failed detection, and runtimeDetections coalesces concurrent detections on
+// the same host, e.g. by the status checks of its stacks.
+var (
+	detectedRuntimes = struct {
+		sync.Mutex
+		byHost map[string]DetectedRuntime
+		failed map[string]failedDetection
+	}{byHost: make(map[string]DetectedRuntime), failed: make(map[string]failedDetection)}
+	runtimeDetections singleflight.Group
+)
+
+// failedDetection is a failed runtime detection remembered for
+// runtimeDetectionRetry.
+type failedDetection struct {
+	at  time.Time
+	err error
+}
+
+// DetectedRuntime is the container runtime found on a host.
+type DetectedRuntime struct {
+	Runtime string // "podman" or "docker"
+	Path    string // Path of the runtime on the host
+}
+
+// runtimeDetectionScript prints the first candidate runtime that is installed
+// and whose `compose version` succeeds, followed by its path, e.g.
+// "docker /usr/bin/docker". It prints nothing if there is none, and always
+// succeeds, so that only a failure to run it is an error.
+func runtimeDetectionScript() string {
+	return fmt.Sprintf(`for r in %s; do p=$(command -v "$r") && "$r" compose version >/dev/null 2>&1 && { echo "$r $p"; break; }; done; true`,
+		strings.Join(runtimeCandidates, " "))
+}
+
+// parseDetectedRuntime reads the output of runtimeDetectionScript. Lines
+// printed by login scripts and the like are skipped.
+func parseDetectedRuntime(output []byte) (DetectedRuntime, bool) {
+	for line := range strings.Lines(string(output)) {
+		runtime, path, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok && slices.Contains(runtimeCandidates, runtime) && strings.HasPrefix(path, "/") {
+			return DetectedRuntime{Runtime: runtime, Path: path}, true
+		}
+	}
+	return DetectedRuntime{}, false
+}
+
+// ContainerRuntimeFor returns the container runtime used on host (nil for the
+// local machine): the configured one, or the one detected there if
+// container_runtime is "auto" or unset. It may connect to the host, so it
+// must not be called from the TUI's Update; sequence builders use
+// runtimeCommand instead.
+func ContainerRuntimeFor(host *config.SSHHost) string {
+	runtime, _ := resolveRuntime(host)
+	return runtime
+}
+
+// resolveRuntime is ContainerRuntimeFor, also returning the detection error if
+// the fallback runtime is used because the host couldn't be reached.
+func resolveRuntime(host *config.SSHHost) (string, error) {
+	if runtime := config.GetContainerRuntime(host); runtime != "" {
+		return runtime, nil
+	}
+	detected, err := DetectRuntime(host)
+	if errors.Is(err, errNoRuntimeFound) {
+		logger.Warn("No container runtime detected, using the fallback",
+			"host", hostName(host),
+			"runtime", fallbackRuntime)
+		return fallbackRuntime, nil
+	}
+	if err != nil {
+		logger.Warn("Could not detect the container runtime, using the fallback",
+			"host", hostName(host),
+			"runtime", fallbackRuntime,
+			"error", err)
+		return fallbackRuntime, err
+	}
+	return detected.Runtime, nil
+}
+
+// runtimeCommand returns the command of a step running the container runtime
+// of host: the configured runtime, the one already detected there, or
+// config.ContainerRuntimeAuto to have the step detect it when it runs (see
+// resolveStepRuntime). Unlike ContainerRuntimeFor, it never connects to the host.
+func runtimeCommand(host *config.SSHHost) string {
+	if runtime := config.GetContainerRuntime(host); runtime != "" {
+		return runtime
+	}
+	detectedRuntimes.Lock()
+	detected, ok := detectedRuntimes.byHost[hostName(host)]
+	detectedRuntimes.Unlock()
+	if ok {
+		return detected.Runtime
+	}
+	return config.ContainerRuntimeAuto
+}
+
+// resolveStepRuntime replaces a config.ContainerRuntimeAuto command of step
+// (see runtimeCommand) with the runtime of its stack's host.
+func resolveStepRuntime(step *CommandStep) {
+	if step.Command == config.ContainerRuntimeAuto {
+		step.Command = ContainerRuntimeFor(step.Stack.HostConfig)
+	}
+}
+
+// resolveHostStepRuntime is resolveStepRuntime for a host step, rebuilding it
+// with forRuntime if it depends on the runtime beyond its command.
+func resolveHostStepRuntime(step *HostCommandStep) {
+	if step.forRuntime != nil {
+		*step = step.forRuntime(ContainerRuntimeFor(step.Target.HostConfig))
+		return
+	}
+	if step.Command == config.ContainerRuntimeAuto {
+		step.Command = ContainerRuntimeFor(step.Target.HostConfig)
+	}
+}
+
+// errNoRuntimeFound is returned by DetectRuntime for a host it reached that has
+// neither runtime with a working compose.
+var errNoRuntimeFound = errors.New("no container runtime with a working compose was found")
+
+// DetectRuntime returns the first of podman and docker with a working compose
+// on host (nil for the local machine), regardless of container_runtime.
+// Successful detections are cached; a failed one is returned again for
+// runtimeDetectionRetry before the host is tried again.
+func DetectRuntime(host *config.SSHHost) (DetectedRuntime, error) {
+	key := hostName(host)
+	detectedRuntimes.Lock()
+	detected, ok := detectedRuntimes.byHost[key]
+	failed, hasFailed := detectedRuntimes.failed[key]
+	detectedRuntimes.Unlock()
+	if ok {
+		return detected, nil
+	}
+	if hasFailed && time.Since(failed.at) < runtimeDetectionRetry {
+		return DetectedRuntime{}, failed.err
+	}
+
+	result, err, _ := runtimeDetections.Do(key, func() (any, error) {
+		detected, err := detectRuntime(host)
+		detectedRuntimes.Lock()
+		if err != nil {
+			detectedRuntimes.failed[key] = failedDetection{at: time.Now(), err: err}
+		} else {
+			detectedRuntimes.byHost[key] = detected
+			delete(detectedRuntimes.failed, key)
+		}
+		detectedRuntimes.Unlock()
+		return detected, err
+	})
+	return result.(DetectedRuntime), err
+}
+
+// detectRuntime runs the detection on host, bypassing the cache.
+func detectRuntime(host *config.SSHHost) (DetectedRuntime, error) {
+	key := hostName(host)
+	startTime := time.Now()
+	cmdDesc := fmt.Sprintf("container runtime detection on host %s", key)
+	var output []byte
+	var err error
+	if host != nil {
+		output, err = runSSHOutputCommand(*host, runtimeDetectionScript(), cmdDesc)
+	} else {
+		output, err = exec.Command("sh", "-c", runtimeDetectionScript()).Output()
+	}
+	if err != nil {
+		return DetectedRuntime{}, fmt.Errorf("%s failed: %w", cmdDesc, err)
+	}
+	detected, ok := parseDetectedRuntime(output)
+	if !ok {
+		return DetectedRuntime{}, fmt.Errorf("neither %s with a working compose was found on host %s: %w",
+			strings.Join(runtimeCandidates, " nor "), key, errNoRuntimeFound)
+	}
+
+	logger.Info("Detected container runtime",
+		"host", key,
+		"runtime", detected.Runtime,
+		"path", detected.Path,
+		"duration", time.Since(startTime))
+	return detected, nil
+}
+
+// hostName returns the name of host, or "local" for nil.
+func hostName(host *config.SSHHost) string {
+	if host == nil {
+		return "local"
+	}
+	return host.Name
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's disk.go file implements host disk usage checks. It runs `df`
+// locally or over SSH for the root filesystem and the container runtime's
+// storage directory, so low disk space can be spotted before pulling images.
+
+package runner
+
+import (
+	"bucket-manager/internal/logger"
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilesystemUsage describes space usage of a single mounted filesystem.
+type FilesystemUsage struct {
+	Filesystem   string // Device or filesystem name as reported by df
+	MountPoint   string // Where the filesystem is mounted
+	TotalKiB     uint64 // Total size in KiB
+	UsedKiB      uint64 // Used space in KiB
+	AvailableKiB uint64 // Space available to unprivileged users in KiB
+}
+
+// FreePercent returns the available space as a percentage of the total size.
+func (f FilesystemUsage) FreePercent() float64 {
+	if f.TotalKiB == 0 {
+		return 0
+	}
+	return float64(f.AvailableKiB) * 100 / float64(f.TotalKiB)
+}
+
+// HostDiskUsage holds the disk usage information gathered for a host.
+type HostDiskUsage struct {
+	Target      HostTarget
+	Filesystems []FilesystemUsage // Root filesystem first, then container storage if on a different mount
+	Error       error
+}
+
+// LowestFree returns the checked filesystem with the least free space.
+// The boolean is false if no filesystem information is available.
+func (h HostDiskUsage) LowestFree() (FilesystemUsage, bool) {
+	if len(h.Filesystems) == 0 {
+		return FilesystemUsage{}, false
+	}
+	lowest := h.Filesystems[0]
+	for _, fs := range h.Filesystems[1:] {
+		if fs.FreePercent() < lowest.FreePercent() {
+			lowest = fs
+		}
+	}
+	return lowest, true
+}
+
+// diskUsageScript builds a POSIX shell snippet that reports usage of "/" and of
+// the runtime's storage directory (if the runtime reports one). runtimeCmd is
+// the command used to invoke runtime, which may include a sudo prefix and the
+// compose_global_args.
+func diskUsageScript(runtime, runtimeCmd string) string {
+	storeQuery := "{{.Store.GraphRoot}}" // podman
+	if runtime == "docker" {
+		storeQuery = "{{.DockerRootDir}}"
+	}
+	return fmt.Sprintf(`store=$(%s info --format '%s' 2>/dev/null); df -Pk / ${store:+"$store"}`, runtimeCmd, storeQuery)
+}
+
+// parseDfOutput parses POSIX `df -Pk` output, skipping the header and
+// de-duplicating filesystems that share a mount point.
+func parseDfOutput(output []byte) []FilesystemUsage {
+	var filesystems []FilesystemUsage
+	seenMounts := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		total, errTotal := strconv.ParseUint(fields[1], 10, 64)
+		used, errUsed := strconv.ParseUint(fields[2], 10, 64)
+		avail, errAvail := strconv.ParseUint(fields[3], 10, 64)
+		if errTotal != nil || errUsed != nil || errAvail != nil {
+			continue // Header line or unexpected format
+		}
+		mount := strings.Join(fields[5:], " ")
+		if seenMounts[mount] {
+			continue
+		}
+		seenMounts[mount] = true
+		filesystems = append(filesystems, FilesystemUsage{
+			Filesystem:   fields[0],
+			MountPoint:   mount,
+			TotalKiB:     total,
+			UsedKiB:      used,
+			AvailableKiB: avail,
+		})
+	}
+	return filesystems
+}
+
+// GetHostDiskUsage reports disk usage for the root filesystem and the container
+// storage directory on the target host (local or remote).
+func GetHostDiskUsage(target HostTarget) HostDiskUsage {
+	startTime := time.Now()
+	runtime := ContainerRuntimeFor(target.HostConfig)
+	usage := HostDiskUsage{Target: target}
+	cmdDesc := fmt.Sprintf("disk usage check for host %s", target.ServerName)
+	script := diskUsageScript(runtime, runtimeShellCommand(runtime))
+
+	logger.Debug("Checking host disk usage",
+		"server_name", target.ServerName,
+		"is_remote", target.IsRemote)
+
+	var output []byte
+	var cmdErr error
+	if target.IsRemote {
+		if target.HostConfig == nil {
+			usage.Error = fmt.Errorf("internal error: HostConfig is nil for remote host %s", target.ServerName)
+			return usage
+		}
+		// Query the storage location as the configured identity, since rootful and
+		// rootless podman keep images in different places.
+		script = diskUsageScript(runtime, runAsCommand(target.HostConfig.RunAsUserFor(""), runtimeShellCommand(runtime)))
+		output, cmdErr = runSSHOutputCommand(*target.HostConfig, script, cmdDesc)
+	} else {
+		output, cmdErr = exec.Command("sh", "-c", script).CombinedOutput()
+	}
+
+	usage.Filesystems = parseDfOutput(output)
+	if len(usage.Filesystems) == 0 {
+		// df may exit non-zero for an inaccessible storage dir while still reporting "/",
+		// so only treat the check as failed if nothing could be parsed.
+		if cmdErr != nil {
+			usage.Error = fmt.Errorf("failed to run %s: %w", cmdDesc, cmdErr)
+		} else {
+			usage.Error = fmt.Errorf("no filesystem information returned by %s", cmdDesc)
+		}
+		logger.Warn("Host disk usage check failed",
+			"server_name", target.ServerName,
+			"error", usage.Error,
+			"duration", time.Since(startTime))
+		return usage
+	}
+
+	logger.Debug("Host disk usage check completed",
+		"server_name", target.ServerName,
+		"filesystems", len(usage.Filesystems),
+		"duration", time.Since(startTime))
+	return usage
+}
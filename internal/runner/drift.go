@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's drift.go file detects stacks running an outdated config:
+// their compose files changed after their containers were created, so `up`
+// is needed to apply the changes. Remote stacks stat their files in the same
+// SSH command as their `compose ps`.
+
+package runner
+
+import (
+	"bucket-manager/internal/util"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// composeMtimeMarker starts the line printed before a remote stack's `compose
+// ps` output with the modification time of its compose files, in Unix seconds
+// (empty if none were found).
+const composeMtimeMarker = "--- bm mtime"
+
+// composeMtimePattern matches the line printed by composeModTimeCommand.
+var composeMtimePattern = regexp.MustCompile(`^` + composeMtimeMarker + ` (\d*)$`)
+
+// driftFiles lists the files whose changes take effect on the next `up`: the
+// compose files compose reads by default, their overrides and .env.
+var driftFiles = []string{
+	"compose.yaml",
+	"compose.yml",
+	"docker-compose.yaml",
+	"docker-compose.yml",
+	"compose.override.yaml",
+	"compose.override.yml",
+	"docker-compose.override.yaml",
+	"docker-compose.override.yml",
+	".env",
+}
+
+// composeModTimeCommand returns a shell command printing composeMtimeMarker
+// and the newest modification time of the driftFiles in the current directory.
+func composeModTimeCommand() string {
+	quoted := make([]string, len(driftFiles))
+	for i, name := range driftFiles {
+		quoted[i] = util.QuoteArgForShell(name)
+	}
+	// The marker is an argument since printf would take its leading dashes as an option
+	return fmt.Sprintf(`printf '%%s %%s\n' %s "$(for f in %s; do [ -f "$f" ] && stat -c %%Y "$f"; done 2>/dev/null | sort -n | tail -n 1)"`,
+		util.QuoteArgForShell(composeMtimeMarker), strings.Join(quoted, " "))
+}
+
+// splitComposeModTime removes the line printed by composeModTimeCommand from
+// output, returning the rest and the time it held (zero if there was none).
+func splitComposeModTime(output []byte) ([]byte, time.Time) {
+	var modTime time.Time
+	lines := bytes.Split(output, []byte("\n"))
+	kept := lines[:0]
+	for _, line := range lines {
+		match := composeMtimePattern.FindSubmatch(bytes.TrimSuffix(line, []byte("\r")))
+		if match == nil {
+			kept = append(kept, line)
+			continue
+		}
+		if seconds, err := strconv.ParseInt(string(match[1]), 10, 64); err == nil {
+			modTime = time.Unix(seconds, 0)
+		}
+	}
+	return bytes.Join(kept, []byte("\n")), modTime
+}
+
+// localComposeModTime returns the newest modification time of the driftFiles
+// in a local stack directory, truncated to seconds like container creation
+// times, or the zero time if there are none.
+func localComposeModTime(stackPath string) time.Time {
+	var newest time.Time
+	for _, name := range driftFiles {
+		fileInfo, err := os.Stat(filepath.Join(stackPath, name))
+		if err == nil && fileInfo.ModTime().After(newest) {
+			newest = fileInfo.ModTime()
+		}
+	}
+	return newest.Truncate(time.Second)
+}
+
+// IsStale reports whether the stack's compose files changed after its newest
+// container was created, meaning it runs an outdated config until the next
+// `up`. Stopped stacks, and stacks whose times are unknown, are never stale.
+func (i StackRuntimeInfo) IsStale() bool {
+	if i.ComposeModTime.IsZero() || (i.OverallStatus != StatusUp && i.OverallStatus != StatusPartial) {
+		return false
+	}
+	var newest time.Time
+	for _, c := range i.Containers {
+		if c.Created.After(newest) {
+			newest = c.Created
+		}
+	}
+	return !newest.IsZero() && i.ComposeModTime.After(newest)
+}
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's hosttest.go file checks the connection to a remote host
+// step by step, to diagnose why its stacks don't show up without running a
+// full discovery.
+
+package runner
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HostTest is the result of TestHost.
+type HostTest struct {
+	Host            string        // Name of the host
+	AuthMethod      string        // Authentication method that succeeded: "key", "agent" or "password"
+	Latency         time.Duration // Time taken to connect and authenticate
+	Runtime         string        // Container runtime looked for, or the one found if RuntimeDetected
+	RuntimePath     string        // Path of the runtime on the host; empty if it wasn't found
+	RuntimeDetected bool          // The runtime isn't configured, so podman and docker were looked for
+	RemoteRoot      string        // Absolute path of the directory searched for stacks
+	RemoteRootError error         // Why the remote root couldn't be resolved
+	Error           error         // Connection or remote command failure; the other fields are unset after it
+}
+
+// hostTestEcho is printed by the trivial command run by TestHost.
+const hostTestEcho = "ok"
+
+// TestHost connects to a remote host on a new connection, runs a trivial
+// command, looks for the container runtime and resolves the remote root.
+// Connection errors wrap ssh.ErrNetwork, ssh.ErrAlgorithm, ssh.ErrHostKey or ssh.ErrAuth.
+func TestHost(hostConfig config.SSHHost) HostTest {
+	runtime := config.GetContainerRuntime(&hostConfig)
+	result := HostTest{Host: hostConfig.Name, Runtime: runtime, RuntimeDetected: runtime == ""}
+	if sshManager == nil {
+		result.Error = fmt.Errorf("ssh manager not initialized for testing %s", hostConfig.Name)
+		return result
+	}
+
+	conn, err := sshManager.TestConnection(hostConfig)
+	result.AuthMethod = conn.AuthMethod
+	result.Latency = conn.Latency
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer conn.Client.Close()
+	logger.Debug("Host connection test connected",
+		"host", hostConfig.Name,
+		"auth_method", conn.AuthMethod,
+		"latency", conn.Latency)
+
+	session, err := conn.Client.NewSession()
+	if err != nil {
+		result.Error = fmt.Errorf("failed to create ssh session on %s: %w", hostConfig.Name, err)
+		return result
+	}
+	// command -v fails if the runtime is missing, so its status is ignored
+	cmd := fmt.Sprintf("echo %s; command -v %s || true", hostTestEcho, util.QuoteArgForShell(runtime))
+	if result.RuntimeDetected {
+		cmd = fmt.Sprintf("echo %s; %s", hostTestEcho, runtimeDetectionScript())
+	}
+	output, err := session.CombinedOutput(cmd)
+	session.Close()
+	if err != nil {
+		result.Error = fmt.Errorf("remote command failed on %s: %w\nOutput: %s", hostConfig.Name, err, string(output))
+		return result
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if strings.TrimSpace(lines[0]) != hostTestEcho {
+		// Usually a login script printing to stdout, which confuses commands parsing their output
+		result.Error = fmt.Errorf("unexpected output from `echo %s` on %s: %q", hostTestEcho, hostConfig.Name, string(output))
+		return result
+	}
+	if result.RuntimeDetected {
+		if detected, ok := parseDetectedRuntime(output); ok {
+			result.Runtime, result.RuntimePath = detected.Runtime, detected.Path
+		}
+	} else if len(lines) > 1 {
+		result.RuntimePath = strings.TrimSpace(lines[len(lines)-1])
+	}
+
+	result.RemoteRoot, result.RemoteRootError = discovery.ResolveRemoteRoot(conn.Client, &hostConfig)
+	return result
+}
@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's images.go file implements container image listing and
+// targeted image cleanup on a host, as a lighter alternative to a full
+// system prune.
+
+package runner
+
+import (
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ImageInfo describes a single container image on a host.
+type ImageInfo struct {
+	Repository string
+	Tag        string
+	ID         string // Short image ID
+	Size       string // Human-readable size
+	Age        string // Human-readable time since the image was created
+}
+
+// HostImages holds the images found on a host.
+type HostImages struct {
+	Target HostTarget
+	Images []ImageInfo
+	Error  error
+}
+
+// podmanImage is the subset of `podman images --format json` output that is used.
+type podmanImage struct {
+	ID      string   `json:"Id"`
+	Names   []string `json:"Names"`
+	Size    int64    `json:"Size"`
+	Created int64    `json:"Created"` // Unix timestamp
+}
+
+// dockerImage is the subset of a `docker images --format json` line that is used.
+type dockerImage struct {
+	ID           string `json:"ID"`
+	Repository   string `json:"Repository"`
+	Tag          string `json:"Tag"`
+	Size         string `json:"Size"`
+	CreatedSince string `json:"CreatedSince"`
+}
+
+// shortImageID trims an image ID to the 12-character form shown by the runtimes.
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// splitImageName splits "registry/repo:tag" into repository and tag. The tag
+// separator is the last ':' after the last '/', so registry ports are kept.
+func splitImageName(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	colon := strings.LastIndex(name, ":")
+	if colon > slash {
+		return name[:colon], name[colon+1:]
+	}
+	return name, "<none>"
+}
+
+// parsePodmanImages parses the JSON array printed by podman. Any warnings
+// printed around the array are ignored.
+func parsePodmanImages(output []byte) ([]ImageInfo, error) {
+	start := bytes.IndexByte(output, '[')
+	end := bytes.LastIndexByte(output, ']')
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no image list found in output")
+	}
+
+	var raw []podmanImage
+	if err := json.Unmarshal(output[start:end+1], &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse image list: %w", err)
+	}
+
+	var images []ImageInfo
+	for _, img := range raw {
+		info := ImageInfo{
+			Repository: "<none>",
+			Tag:        "<none>",
+			ID:         shortImageID(img.ID),
+			Size:       util.FormatKiB(uint64(img.Size) / 1024),
+			Age:        util.FormatAge(time.Since(time.Unix(img.Created, 0))),
+		}
+		if len(img.Names) == 0 {
+			images = append(images, info) // Dangling image
+			continue
+		}
+		// An image with several names is listed once per name, like `podman images`.
+		for _, name := range img.Names {
+			info.Repository, info.Tag = splitImageName(name)
+			images = append(images, info)
+		}
+	}
+	return images, nil
+}
+
+// parseDockerImages parses the one-object-per-line JSON printed by docker.
+func parseDockerImages(output []byte) ([]ImageInfo, error) {
+	var images []ImageInfo
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue // Skip warnings mixed into the output
+		}
+		var img dockerImage
+		if err := json.Unmarshal(line, &img); err != nil {
+			return nil, fmt.Errorf("failed to parse image entry: %w", err)
+		}
+		images = append(images, ImageInfo{
+			Repository: img.Repository,
+			Tag:        img.Tag,
+			ID:         shortImageID(img.ID),
+			Size:       img.Size,
+			Age:        strings.TrimSuffix(img.CreatedSince, " ago"),
+		})
+	}
+	return images, scanner.Err()
+}
+
+// GetHostImages lists the container images on the target host (local or remote).
+func GetHostImages(target HostTarget) HostImages {
+	startTime := time.Now()
+	runtime := ContainerRuntimeFor(target.HostConfig)
+	result := HostImages{Target: target}
+	cmdDesc := fmt.Sprintf("image listing for host %s", target.ServerName)
+	args := runtimeArgs("images", "--format", "json")
+
+	logger.Debug("Listing host images",
+		"server_name", target.ServerName,
+		"is_remote", target.IsRemote)
+
+	var output []byte
+	var cmdErr error
+	if target.IsRemote {
+		if target.HostConfig == nil {
+			result.Error = fmt.Errorf("internal error: HostConfig is nil for remote host %s", target.ServerName)
+			return result
+		}
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = util.QuoteArgForShell(arg)
+		}
+		remoteCmd := runAsCommand(target.HostConfig.RunAsUserFor(""), runtime) + " " + strings.Join(quoted, " ")
+		output, cmdErr = runSSHOutputCommand(*target.HostConfig, remoteCmd, cmdDesc)
+	} else {
+		output, cmdErr = exec.Command(runtime, args...).CombinedOutput()
+	}
+	if cmdErr != nil {
+		result.Error = fmt.Errorf("failed to run %s: %w", cmdDesc, cmdErr)
+		if out := strings.TrimSpace(string(output)); out != "" {
+			result.Error = fmt.Errorf("%w\nOutput: %s", result.Error, out)
+		}
+		return result
+	}
+
+	if runtime == "docker" {
+		result.Images, result.Error = parseDockerImages(output)
+	} else {
+		result.Images, result.Error = parsePodmanImages(output)
+	}
+	if result.Error != nil {
+		result.Error = fmt.Errorf("%s: %w", cmdDesc, result.Error)
+	}
+
+	logger.Debug("Host image listing completed",
+		"server_name", target.ServerName,
+		"image_count", len(result.Images),
+		"error", result.Error,
+		"duration", time.Since(startTime))
+	return result
+}
+
+// ImagePruneOptions selects which images PruneImagesHostStep removes.
+type ImagePruneOptions struct {
+	DanglingOnly bool          // Only remove untagged images; otherwise all unused images
+	Until        time.Duration // Only remove images created more than this long ago (0 for no limit)
+}
+
+// PruneImagesHostStep creates a step that removes unused images on the target,
+// leaving containers, networks and volumes untouched.
+func PruneImagesHostStep(target HostTarget, opts ImagePruneOptions) HostCommandStep {
+	args := runtimeArgs("image", "prune", "-f")
+	if !opts.DanglingOnly {
+		args = append(args, "-a")
+	}
+	if opts.Until > 0 {
+		args = append(args, "--filter", "until="+opts.Until.String())
+	}
+
+	name := "Prune Unused Images"
+	if opts.DanglingOnly {
+		name = "Prune Dangling Images"
+	}
+	return HostCommandStep{
+		Name:    name,
+		Command: runtimeCommand(target.HostConfig),
+		Args:    args,
+		Target:  target,
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"syscall"
+	"time"
 )
 
 // runLocalCommand executes a command locally on the host system.
@@ -22,11 +23,19 @@ import (
 // Parameters:
 //   - cmd: The prepared exec.Cmd to execute
 //   - cmdDesc: Description of the command for error messages
+//   - timeout: Stops the command if it runs longer (zero means no limit)
 //   - cliMode: Whether to use direct terminal output or channel-based output
 //   - outChan: Channel to send command output lines
 //   - errChan: Channel to send execution errors
-func runLocalCommand(cmd *exec.Cmd, cmdDesc string, cliMode bool, outChan chan<- OutputLine, errChan chan<- error) {
+func runLocalCommand(cmd *exec.Cmd, cmdDesc string, timeout time.Duration, cliMode bool, outChan chan<- OutputLine, errChan chan<- error) {
 	var cmdErr error
+	var timer *operationTimer
+	startTimer := func() {
+		timer = startOperationTimer(timeout,
+			func() { cmd.Process.Signal(syscall.SIGTERM) },
+			func() { cmd.Process.Kill() })
+	}
+
 	if cliMode {
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -35,6 +44,7 @@ func runLocalCommand(cmd *exec.Cmd, cmdDesc string, cliMode bool, outChan chan<-
 			errChan <- fmt.Errorf("failed to start %s: %w", cmdDesc, err)
 			return
 		}
+		startTimer()
 		cmdErr = cmd.Wait()
 	} else {
 		stdoutPipe, err := cmd.StdoutPipe()
@@ -53,17 +63,23 @@ func runLocalCommand(cmd *exec.Cmd, cmdDesc string, cliMode bool, outChan chan<-
 			return
 		}
 
+		startTimer()
 		outputDone := make(chan struct{}, 2) // Wait for both streamPipe goroutines
 		go streamPipe(stdoutPipe, outChan, outputDone, false)
 		go streamPipe(stderrPipe, outChan, outputDone, true)
 
-		cmdErr = cmd.Wait()
-
-		// Wait for pipe readers to finish *after* command Wait returns
+		// Wait for pipe readers to finish *before* command Wait, which closes
+		// the pipes and would drop output that hasn't been read yet
 		<-outputDone
 		<-outputDone
+
+		cmdErr = cmd.Wait()
 	}
 
+	if timer.finish() {
+		errChan <- fmt.Errorf("%s: %w", cmdDesc, &TimeoutError{Timeout: timeout})
+		return
+	}
 	if cmdErr != nil {
 		exitCode := -1
 		if exitError, ok := cmdErr.(*exec.ExitError); ok {
@@ -72,7 +88,7 @@ func runLocalCommand(cmd *exec.Cmd, cmdDesc string, cliMode bool, outChan chan<-
 			}
 		}
 		if exitCode != -1 {
-			errChan <- fmt.Errorf("%s exited with status %d: %w", cmdDesc, exitCode, cmdErr)
+			errChan <- fmt.Errorf("%s: %w", cmdDesc, &ExitError{Status: exitCode, Err: cmdErr})
 		} else {
 			errChan <- fmt.Errorf("%s failed: %w", cmdDesc, cmdErr)
 		}
@@ -0,0 +1,262 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's lock.go file implements per-stack operation locks, so two
+// bucket-manager instances (e.g. the CLI and the web server) can't run compose
+// commands on the same stack at the same time.
+
+package runner
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// stackLockFile is the lock file created in each stack directory.
+const stackLockFile = ".bm.lock"
+
+// ErrStackBusy is returned by LockStack when another operation holds the stack's lock.
+var ErrStackBusy = errors.New("stack busy")
+
+// StackLock is a held lock on a stack, released with Release.
+type StackLock struct {
+	stack   discovery.Stack
+	release func() error
+}
+
+// LockStack takes the operation lock of a stack without waiting. If another
+// operation holds it, an error wrapping ErrStackBusy is returned.
+//
+// Local stacks are locked with flock(2) on .bm.lock in the stack directory.
+// Remote stacks are locked by running flock(1) on the same file over an SSH
+// session that stays open until the lock is released. Either way, the lock is
+// released automatically if the holder dies or loses its connection.
+func LockStack(stack discovery.Stack) (*StackLock, error) {
+	var release func() error
+	var err error
+	if stack.IsRemote {
+		release, err = lockRemoteStack(stack)
+	} else {
+		release, err = lockLocalStack(stack)
+	}
+	if err != nil {
+		if errors.Is(err, ErrStackBusy) {
+			logger.Warn("Stack is locked by another operation", "stack", stack.Identifier(), "error", err)
+		}
+		return nil, err
+	}
+	logger.Debug("Stack lock acquired", "stack", stack.Identifier())
+	return &StackLock{stack: stack, release: release}, nil
+}
+
+// Release releases the lock. It is safe to call on a nil lock.
+func (l *StackLock) Release() error {
+	if l == nil || l.release == nil {
+		return nil
+	}
+	err := l.release()
+	l.release = nil
+	if err != nil {
+		logger.Warn("Failed to release stack lock", "stack", l.stack.Identifier(), "error", err)
+	} else {
+		logger.Debug("Stack lock released", "stack", l.stack.Identifier())
+	}
+	return err
+}
+
+// LockStacks locks every stack, or none: if any lock can't be taken, the ones
+// already acquired are released and the error is returned.
+func LockStacks(stacks []discovery.Stack) ([]*StackLock, error) {
+	var locks []*StackLock
+	for _, stack := range stacks {
+		lock, err := LockStack(stack)
+		if err != nil {
+			ReleaseStackLocks(locks)
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// ReleaseStackLocks releases all the given locks.
+func ReleaseStackLocks(locks []*StackLock) {
+	for _, lock := range locks {
+		lock.Release()
+	}
+}
+
+// SequenceStacks returns the distinct stacks the steps of a sequence act on.
+func SequenceStacks(sequence []CommandStep) []discovery.Stack {
+	var stacks []discovery.Stack
+	for _, step := range sequence {
+		if !slices.ContainsFunc(stacks, func(s discovery.Stack) bool { return s.Identifier() == step.Stack.Identifier() }) {
+			stacks = append(stacks, step.Stack)
+		}
+	}
+	return stacks
+}
+
+// readOnlyComposeCommands are compose subcommands that don't change a stack,
+// so they may run while another operation holds its lock.
+var readOnlyComposeCommands = []string{"logs", "ps", "config"}
+
+// SequenceNeedsLock reports whether a sequence changes its stacks, i.e. runs
+// anything other than read-only compose subcommands such as logs. Quadlet
+// stacks aren't locked, as systemd already queues the jobs of each service.
+func SequenceNeedsLock(sequence []CommandStep) bool {
+	for _, step := range sequence {
+		if step.Stack.Quadlet != nil {
+			continue
+		}
+		subcommand, ok := composeSubcommand(step.Args)
+		if !ok || !slices.Contains(readOnlyComposeCommands, subcommand) {
+			return true
+		}
+	}
+	return false
+}
+
+// composeSubcommand returns the compose subcommand run by a step's arguments,
+// skipping the global flags added by composeArgs and profileComposeArgs. ok is
+// false if the arguments aren't a compose command.
+func composeSubcommand(args []string) (subcommand string, ok bool) {
+	i := composeIndex(args)
+	if i < 0 {
+		return "", false
+	}
+	args = args[i+1:]
+	for len(args) >= 2 && (args[0] == "-p" || args[0] == "--profile") {
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return "", false
+	}
+	return args[0], true
+}
+
+// composeIndex returns the position of "compose" in a step's arguments, after
+// any compose_global_args (which are all single flags), or -1 if the arguments
+// aren't a compose command.
+func composeIndex(args []string) int {
+	for i, arg := range args {
+		if arg == "compose" {
+			return i
+		}
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+	}
+	return -1
+}
+
+// lockHolder describes this process, for lock files and error messages.
+func lockHolder() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("pid %d on %s since %s", os.Getpid(), hostname, time.Now().Format(time.RFC3339))
+}
+
+// lockRemoteStack holds `flock -n` on the stack's lock file in an SSH session.
+// The remote side records the holder in the file, prints "locked" once the lock
+// is held and keeps it until its stdin is closed. flock exits with status 1
+// (and no message) if the lock is already taken.
+func lockRemoteStack(stack discovery.Stack) (func() error, error) {
+	cmdDesc := fmt.Sprintf("lock for stack %s", stack.Identifier())
+	if sshManager == nil {
+		return nil, fmt.Errorf("ssh manager not initialized for %s", cmdDesc)
+	}
+	if stack.HostConfig == nil {
+		return nil, fmt.Errorf("internal error: HostConfig is nil for remote stack %s", stack.Identifier())
+	}
+	if stack.AbsoluteRemoteRoot == "" {
+		return nil, fmt.Errorf("internal error: AbsoluteRemoteRoot is empty for remote stack %s", stack.Identifier())
+	}
+
+	client, err := sshManager.GetClient(*stack.HostConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ssh client for %s: %w", cmdDesc, err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ssh session for %s: %w", cmdDesc, err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get ssh stdin pipe for %s: %w", cmdDesc, err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to get ssh stdout pipe for %s: %w", cmdDesc, err)
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	// The lock is taken as the user the stack's commands run as, who can
+	// create the lock file in the stack directory. flock runs through sh so
+	// that a missing flock exits with 127 even under sudo.
+	remoteStackPath := filepath.Join(stack.AbsoluteRemoteRoot, stack.Path)
+	runAsUser := stackRunAsUser(stack)
+	holdScript := fmt.Sprintf("echo %s > %s; echo locked; exec cat >/dev/null",
+		util.QuoteArgForShell(lockHolder()), stackLockFile)
+	lockCmd := fmt.Sprintf("exec flock -n %s sh -c %s", stackLockFile, util.QuoteArgForShell(holdScript))
+	remoteCmd := fmt.Sprintf("cd %s && exec %s",
+		util.QuoteArgForShell(remoteStackPath), runAsCommand(runAsUser, "sh -c "+util.QuoteArgForShell(lockCmd)))
+	if err := session.Start(remoteCmd); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start remote command for %s: %w", cmdDesc, err)
+	}
+
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	if strings.TrimSpace(line) == "locked" {
+		return func() error {
+			stdin.Close() // Ends `cat`, which releases the lock
+			err := session.Wait()
+			session.Close()
+			return err
+		}, nil
+	}
+
+	waitErr := session.Wait()
+	session.Close()
+	var exitErr *gossh.ExitError
+	if errors.As(waitErr, &exitErr) {
+		switch {
+		case exitErr.ExitStatus() == 1 && stderr.Len() == 0:
+			holder, _ := runSSHOutputCommand(*stack.HostConfig,
+				runAsCommand(runAsUser, "cat "+util.QuoteArgForShell(filepath.Join(remoteStackPath, stackLockFile))), cmdDesc)
+			return nil, busyError(stack, strings.TrimSpace(string(holder)))
+		case exitErr.ExitStatus() == 127:
+			// flock(1) isn't installed; run unlocked rather than blocking every operation
+			logger.Warn("flock is not available on remote host, stack operations are not locked",
+				"host", stack.ServerName, "stack", stack.Identifier())
+			return func() error { return nil }, nil
+		}
+	}
+	if waitErr == nil {
+		waitErr = io.ErrUnexpectedEOF
+	}
+	return nil, fmt.Errorf("failed to take %s: %w: %s", cmdDesc, waitErr, strings.TrimSpace(stderr.String()))
+}
+
+// busyError builds the error returned when a stack's lock is held elsewhere.
+func busyError(stack discovery.Stack, holder string) error {
+	if holder == "" {
+		return fmt.Errorf("%w: %s is locked by another operation", ErrStackBusy, stack.Identifier())
+	}
+	return fmt.Errorf("%w: %s is locked by another operation (%s)", ErrStackBusy, stack.Identifier(), holder)
+}
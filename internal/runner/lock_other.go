@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+//go:build !unix
+
+// Package runner's lock_other.go file provides a no-op local stack lock on
+// platforms without flock(2). Remote stacks are still locked.
+
+package runner
+
+import "bucket-manager/internal/discovery"
+
+// lockLocalStack is a no-op on this platform. The returned function does nothing.
+func lockLocalStack(stack discovery.Stack) (func() error, error) {
+	return func() error { return nil }, nil
+}
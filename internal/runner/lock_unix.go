@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+//go:build unix
+
+// Package runner's lock_unix.go file implements local stack locks with flock(2).
+
+package runner
+
+import (
+	"bucket-manager/internal/discovery"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// lockLocalStack takes a non-blocking flock(2) on the stack's lock file and
+// records this process as the holder in it.
+func lockLocalStack(stack discovery.Stack) (func() error, error) {
+	lockPath := filepath.Join(stack.Path, stackLockFile)
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stack lock file %s: %w", lockPath, err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder, _ := os.ReadFile(lockPath)
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, busyError(stack, strings.TrimSpace(string(holder)))
+		}
+		return nil, fmt.Errorf("failed to lock stack lock file %s: %w", lockPath, err)
+	}
+
+	// The holder is informational only, so failing to record it is not an error
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintln(f, lockHolder())
+	}
+
+	return func() error {
+		defer f.Close()
+		return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}, nil
+}
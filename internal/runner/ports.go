@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's ports.go file reads the ports a stack's containers publish
+// on the host from their status, and turns the TCP ones into URLs that the
+// stack's web interfaces may be reached at.
+
+package runner
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PublishedPort is a container port published on the host.
+type PublishedPort struct {
+	HostIP        string // Address the port is bound to, e.g. "0.0.0.0", "::" or "127.0.0.1"
+	HostPort      int
+	ContainerPort int
+	Protocol      string // "tcp" or "udp"
+}
+
+// httpsContainerPorts are container ports assumed to serve HTTPS rather than HTTP.
+var httpsContainerPorts = []int{443, 8443}
+
+// PublishedPorts returns the ports the container publishes on the host, parsed
+// from Ports ("0.0.0.0:8080->80/tcp, [::]:8080->80/tcp, 9000/tcp"). Ports that
+// aren't published, like "9000/tcp", are left out, and ranges like
+// "0.0.0.0:8000-8001->8000-8001/tcp" are listed port by port.
+func (c ContainerState) PublishedPorts() []PublishedPort {
+	var ports []PublishedPort
+	for _, mapping := range strings.Split(c.Ports, ",") {
+		host, container, ok := strings.Cut(strings.TrimSpace(mapping), "->")
+		if !ok {
+			continue
+		}
+		sep := strings.LastIndex(host, ":")
+		if sep == -1 {
+			continue
+		}
+		hostIP := strings.Trim(host[:sep], "[]")
+		containerRange, protocol, _ := strings.Cut(container, "/")
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		hostFirst, hostLast, err := parsePortRange(host[sep+1:])
+		if err != nil {
+			continue
+		}
+		containerFirst, containerLast, err := parsePortRange(containerRange)
+		if err != nil || containerLast-containerFirst != hostLast-hostFirst {
+			continue
+		}
+		for offset := 0; hostFirst+offset <= hostLast; offset++ {
+			ports = append(ports, PublishedPort{
+				HostIP:        hostIP,
+				HostPort:      hostFirst + offset,
+				ContainerPort: containerFirst + offset,
+				Protocol:      protocol,
+			})
+		}
+	}
+	return ports
+}
+
+// parsePortRange parses a port ("8080") or a port range ("8000-8001").
+func parsePortRange(s string) (int, int, error) {
+	firstStr, lastStr, isRange := strings.Cut(s, "-")
+	first, err := strconv.Atoi(firstStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	last := first
+	if isRange {
+		if last, err = strconv.Atoi(lastStr); err != nil {
+			return 0, 0, err
+		}
+	}
+	if first <= 0 || last < first || last > 65535 {
+		return 0, 0, fmt.Errorf("invalid port range '%s'", s)
+	}
+	return first, last, nil
+}
+
+// StackURL is a web address that a service of a stack may be reached at.
+type StackURL struct {
+	Service string
+	URL     string // e.g. "http://server1.example.com:8080"
+}
+
+// StackURLs returns a URL for each TCP port published by the stack's
+// containers: https for container ports 443 and 8443, http otherwise. Ports
+// bound to every address are reached through localhost for local stacks and
+// through the SSH host's hostname for remote ones. Ports bound to loopback on
+// a remote host are left out, as they can't be reached from here, and a port
+// published on both IPv4 and IPv6 is only listed once.
+func StackURLs(info StackRuntimeInfo) []StackURL {
+	var urls []StackURL
+	for _, c := range info.Containers {
+		for _, port := range c.PublishedPorts() {
+			if port.Protocol != "tcp" {
+				continue
+			}
+			host := port.HostIP
+			ip := net.ParseIP(host)
+			switch {
+			case host == "" || (ip != nil && ip.IsUnspecified()):
+				host = "localhost"
+				if info.Stack.IsRemote && info.Stack.HostConfig != nil {
+					host = strings.Trim(info.Stack.HostConfig.Hostname, "[]")
+				}
+			case ip != nil && ip.IsLoopback() && info.Stack.IsRemote:
+				continue
+			}
+
+			scheme := "http"
+			if slices.Contains(httpsContainerPorts, port.ContainerPort) {
+				scheme = "https"
+			}
+			url := StackURL{Service: c.Service, URL: scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port.HostPort))}
+			if !slices.Contains(urls, url) {
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls
+}
@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's psoutput.go file parses the output of `compose ps --format
+// json`, which differs between compose providers and their versions: current
+// docker compose prints one JSON object per line, older releases a single JSON
+// array, and podman-compose passes podman's own `ps` JSON through, whose
+// fields have different types.
+
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"bucket-manager/internal/logger"
+)
+
+// composeServiceLabel is the label holding a container's compose service name.
+const composeServiceLabel = "com.docker.compose.service"
+
+// rawContainerState is a container as printed by any compose provider. Fields
+// whose type varies between providers are decoded later.
+type rawContainerState struct {
+	Name    json.RawMessage // docker compose: string
+	Names   json.RawMessage // podman: array of strings
+	Command json.RawMessage // docker compose: string; podman: array of strings
+	Service string
+	Status  string          // e.g. "running" or "Up 2 minutes"
+	State   string          // e.g. "running"
+	Ports   json.RawMessage // docker compose: string; podman: array of port mappings
+	Labels  json.RawMessage // docker: "key=value,..." string; podman: object
+
+	Created   json.RawMessage // Unix time, from podman and older docker compose releases
+	CreatedAt string          // docker compose: e.g. "2025-05-01 10:00:00 +0200 CEST"; podman: e.g. "2 hours ago"
+}
+
+// dockerTimeLayout is the layout of the times printed by docker, such as CreatedAt.
+const dockerTimeLayout = "2006-01-02 15:04:05 -0700 MST"
+
+// podmanPortMapping is one entry of the Ports array printed by podman.
+type podmanPortMapping struct {
+	HostIP        string `json:"host_ip"`
+	ContainerPort int    `json:"container_port"`
+	HostPort      int    `json:"host_port"`
+	Protocol      string `json:"protocol"`
+}
+
+// parseContainerStatusOutput parses the output of `compose ps --format json`.
+// It accepts newline-delimited JSON objects, a JSON array of objects, an
+// object wrapping the array in a "Containers" field, or any mix of them, and
+// the "no containers found" message some providers print instead of JSON.
+// Lines that aren't JSON, such as warnings, are skipped if containers were
+// found; otherwise they are reported as an error.
+func parseContainerStatusOutput(output []byte) ([]ContainerState, error) {
+	containers := []ContainerState{}
+	var firstNonJSONError error
+
+	rest := output
+	for {
+		rest = bytes.TrimLeft(rest, " \t\r\n")
+		if len(rest) == 0 {
+			break
+		}
+
+		if rest[0] != '{' && rest[0] != '[' {
+			// Not JSON, e.g. a warning from the compose provider; skip the line
+			line, remainder, _ := bytes.Cut(rest, []byte("\n"))
+			if firstNonJSONError == nil {
+				firstNonJSONError = fmt.Errorf("unexpected non-JSON output from compose ps: %s", strings.TrimSpace(string(line)))
+			}
+			rest = remainder
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(rest))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			line, _, _ := bytes.Cut(rest, []byte("\n"))
+			return nil, fmt.Errorf("failed to decode container status JSON: %w\nLine: %s", err, string(line))
+		}
+		rest = rest[decoder.InputOffset():]
+
+		found, err := decodeContainerStates(value)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, found...)
+	}
+
+	if firstNonJSONError != nil {
+		if strings.Contains(strings.ToLower(string(output)), "no containers found") {
+			// Treat "no containers found" as a successful parse yielding zero containers
+			return []ContainerState{}, nil
+		}
+		if len(containers) == 0 {
+			return nil, firstNonJSONError
+		}
+		logger.Debug("Skipped non-JSON compose ps output", "error", firstNonJSONError)
+	}
+	return containers, nil
+}
+
+// decodeContainerStates decodes a top-level JSON value of `compose ps` output:
+// an array of containers, an object with a "Containers" array, or a single container.
+func decodeContainerStates(value json.RawMessage) ([]ContainerState, error) {
+	var items []json.RawMessage
+	if value[0] == '[' {
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, fmt.Errorf("failed to decode container status JSON array: %w", err)
+		}
+	} else {
+		var wrapper struct {
+			Containers []json.RawMessage
+		}
+		if err := json.Unmarshal(value, &wrapper); err == nil && wrapper.Containers != nil {
+			items = wrapper.Containers
+		} else {
+			items = []json.RawMessage{value}
+		}
+	}
+
+	containers := make([]ContainerState, 0, len(items))
+	for _, item := range items {
+		var raw rawContainerState
+		if err := json.Unmarshal(item, &raw); err != nil {
+			return nil, fmt.Errorf("failed to decode container status JSON: %w\nValue: %s", err, string(item))
+		}
+		containers = append(containers, raw.containerState())
+	}
+	return containers, nil
+}
+
+// containerState converts a container as printed by a compose provider to a ContainerState.
+func (r rawContainerState) containerState() ContainerState {
+	c := ContainerState{
+		Name:    jsonStringOrFirst(r.Name),
+		Command: jsonStringOrJoined(r.Command),
+		Service: r.Service,
+		Status:  r.Status,
+		Ports:   jsonPorts(r.Ports),
+		Created: jsonCreatedTime(r.Created, r.CreatedAt),
+	}
+	if c.Name == "" {
+		c.Name = jsonStringOrFirst(r.Names)
+	}
+	if c.Status == "" {
+		c.Status = r.State
+	}
+	if c.Service == "" {
+		c.Service = jsonLabel(r.Labels, composeServiceLabel)
+	}
+	return c
+}
+
+// jsonStringOrFirst returns a JSON string, or the first element of a JSON
+// array of strings. Anything else yields "".
+func jsonStringOrFirst(value json.RawMessage) string {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	var list []string
+	if json.Unmarshal(value, &list) == nil && len(list) > 0 {
+		return list[0]
+	}
+	return ""
+}
+
+// jsonStringOrJoined returns a JSON string, or the elements of a JSON array of
+// strings joined with spaces. Anything else yields "".
+func jsonStringOrJoined(value json.RawMessage) string {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	var list []string
+	if json.Unmarshal(value, &list) == nil {
+		return strings.Join(list, " ")
+	}
+	return ""
+}
+
+// jsonPorts returns the published ports of a container in docker's
+// "0.0.0.0:8080->80/tcp" notation, from either that string or podman's array
+// of port mappings.
+func jsonPorts(value json.RawMessage) string {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	var mappings []podmanPortMapping
+	if json.Unmarshal(value, &mappings) != nil {
+		return ""
+	}
+	ports := make([]string, 0, len(mappings))
+	for _, p := range mappings {
+		protocol := p.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		port := strconv.Itoa(p.ContainerPort) + "/" + protocol
+		if p.HostPort != 0 {
+			hostIP := p.HostIP
+			if hostIP == "" {
+				hostIP = "0.0.0.0"
+			}
+			port = fmt.Sprintf("%s:%d->%s", hostIP, p.HostPort, port)
+		}
+		ports = append(ports, port)
+	}
+	return strings.Join(ports, ", ")
+}
+
+// jsonCreatedTime returns the creation time of a container from a Unix time,
+// or else a time in dockerTimeLayout. Relative times like podman's CreatedAt
+// can't be parsed, so they yield the zero time.
+func jsonCreatedTime(created json.RawMessage, createdAt string) time.Time {
+	var seconds int64
+	if json.Unmarshal(created, &seconds) == nil && seconds > 0 {
+		return time.Unix(seconds, 0)
+	}
+	if t, err := time.Parse(dockerTimeLayout, createdAt); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// jsonLabel returns the value of a container label from either a JSON object
+// of labels or docker's "key=value,key=value" string.
+func jsonLabel(value json.RawMessage, name string) string {
+	var labels map[string]string
+	if json.Unmarshal(value, &labels) == nil {
+		return labels[name]
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		for _, pair := range strings.Split(s, ",") {
+			if key, labelValue, ok := strings.Cut(pair, "="); ok && key == name {
+				return labelValue
+			}
+		}
+	}
+	return ""
+}
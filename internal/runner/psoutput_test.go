@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+package runner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseContainerStatusOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []ContainerState
+	}{
+		{
+			name: "docker compose NDJSON",
+			output: `{"Name":"app-web-1","Command":"\"nginx -g 'daemon off;'\"","Service":"web","State":"running","Status":"Up 2 minutes","Ports":"0.0.0.0:8080->80/tcp","CreatedAt":"2025-05-01 10:00:00 +0000 UTC"}
+{"Name":"app-db-1","Command":"\"docker-entrypoint.sh postgres\"","Service":"db","State":"exited","Status":"Exited (0) 1 minute ago","Ports":"","CreatedAt":"2025-05-01 10:00:00 +0000 UTC"}
+`,
+			want: []ContainerState{
+				{
+					Name:    "app-web-1",
+					Command: `"nginx -g 'daemon off;'"`,
+					Service: "web",
+					Status:  "Up 2 minutes",
+					Ports:   "0.0.0.0:8080->80/tcp",
+					Created: time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC),
+				},
+				{
+					Name:    "app-db-1",
+					Command: `"docker-entrypoint.sh postgres"`,
+					Service: "db",
+					Status:  "Exited (0) 1 minute ago",
+					Created: time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name:   "podman JSON array",
+			output: `[{"Names":["app_web_1"],"Command":["nginx","-g","daemon off;"],"State":"running","Status":"","Ports":[{"host_ip":"","container_port":80,"host_port":8080,"protocol":"tcp"},{"container_port":443}],"Labels":{"com.docker.compose.service":"web"},"Created":1746093600,"CreatedAt":"2 hours ago"}]`,
+			want: []ContainerState{
+				{
+					Name:    "app_web_1",
+					Command: "nginx -g daemon off;",
+					Service: "web",
+					Status:  "running",
+					Ports:   "0.0.0.0:8080->80/tcp, 443/tcp",
+					Created: time.Unix(1746093600, 0),
+				},
+			},
+		},
+		{
+			name:   "wrapper object",
+			output: `{"Containers":[{"Name":"app-web-1","Service":"web","State":"running","Labels":"com.docker.compose.project=app,com.docker.compose.service=web"},{"Name":"app-worker-1","State":"created","Labels":"com.docker.compose.service=worker"}]}`,
+			want: []ContainerState{
+				{Name: "app-web-1", Service: "web", Status: "running"},
+				{Name: "app-worker-1", Service: "worker", Status: "created"},
+			},
+		},
+		{
+			name:   "no containers",
+			output: "no containers found\n",
+			want:   []ContainerState{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseContainerStatusOutput([]byte(tt.output))
+			if err != nil {
+				t.Fatalf("parseContainerStatusOutput() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseContainerStatusOutput() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's pull.go file builds the image pull step of stack sequences
+// and limits how many images compose pulls in parallel, for compose providers
+// that support it.
+
+package runner
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PullStep builds the step pulling a stack's images, with the image pull
+// parallelism configured for the stack (pull_parallel / stack_pull_parallel).
+// Images of services in the given compose profiles (or the stack's default
+// profiles if nil) are pulled too.
+func PullStep(stack discovery.Stack, runtime string, profiles []string) CommandStep {
+	cfg, err := config.LoadConfigCached()
+	if err != nil {
+		logger.Warn("Could not load config to check pull_parallel, using compose's default", "error", err)
+	}
+	return CommandStep{
+		Name:            "Pull Images",
+		Command:         runtime,
+		Args:            profileComposeArgs(stack, profiles, "pull"),
+		Stack:           stack,
+		ComposeParallel: cfg.GetPullParallel(stack.Identifier(), stack.Name),
+	}
+}
+
+// composeParallelMinVersions lists compose providers whose releases before the
+// given version are known not to have the global --parallel flag. Newer releases
+// and other providers are checked through their help output.
+var composeParallelMinVersions = map[string]string{
+	"docker-compose": "2", // v1, still used as podman's compose provider on some hosts
+}
+
+// composeParallelSupport caches, per host, runtime and user, whether the
+// compose provider accepts the global --parallel flag.
+var composeParallelSupport sync.Map
+
+// composeSupportsParallel reports whether the compose provider used by step's
+// runtime on the stack's host accepts --parallel. Providers whose version is
+// known to predate the flag are ruled out right away; otherwise the help output
+// is checked. Older podman-compose releases don't have the flag and would fail with it.
+func composeSupportsParallel(step CommandStep) bool {
+	stack := step.Stack
+	key := "local\x00" + step.Command
+	if stack.IsRemote {
+		if stack.HostConfig == nil {
+			return false
+		}
+		key = stack.HostConfig.Name + "\x00" + step.Command + "\x00" + stack.HostConfig.RunAsUserFor(stack.Name)
+	}
+	if supported, ok := composeParallelSupport.Load(key); ok {
+		return supported.(bool)
+	}
+
+	version := GetComposeVersion(HostTarget{IsRemote: stack.IsRemote, HostConfig: stack.HostConfig, ServerName: stack.ServerName})
+	if minimum, ok := composeParallelMinVersions[version.Provider]; ok && version.Version != "" && !version.AtLeast(minimum) {
+		logger.Info("Compose provider version does not support --parallel, pulling with its default",
+			"server_name", stack.ServerName, "compose", version.String())
+		composeParallelSupport.Store(key, false)
+		return false
+	}
+
+	helpArgs := slices.Concat(composeGlobalArgs(), []string{"compose", "--help"})
+	var output []byte
+	var err error
+	if stack.IsRemote {
+		remoteCmdParts := []string{runAsCommand(stack.HostConfig.RunAsUserFor(stack.Name), step.Command)}
+		for _, arg := range helpArgs {
+			remoteCmdParts = append(remoteCmdParts, util.QuoteArgForShell(arg))
+		}
+		remoteCmd := strings.Join(remoteCmdParts, " ") + " 2>&1"
+		output, err = runSSHOutputCommand(*stack.HostConfig, remoteCmd, fmt.Sprintf("compose flag check on %s", stack.ServerName))
+	} else {
+		output, err = exec.Command(step.Command, helpArgs...).CombinedOutput()
+	}
+	supported := err == nil && strings.Contains(string(output), "--parallel")
+	if err != nil {
+		logger.Debug("Could not check compose for --parallel support, omitting it",
+			"server_name", stack.ServerName, "runtime", step.Command, "error", err)
+	} else if !supported {
+		logger.Info("Compose provider does not support --parallel, pulling with its default",
+			"server_name", stack.ServerName, "runtime", step.Command)
+	}
+	composeParallelSupport.Store(key, supported)
+	return supported
+}
+
+// withComposeParallel returns the step's arguments with "--parallel N" added
+// after "compose" if a limit is set and the compose provider supports it.
+func withComposeParallel(step CommandStep) []string {
+	i := composeIndex(step.Args)
+	if step.ComposeParallel <= 0 || i < 0 || !composeSupportsParallel(step) {
+		return step.Args
+	}
+	return slices.Concat(step.Args[:i+1], []string{"--parallel", strconv.Itoa(step.ComposeParallel)}, step.Args[i+1:])
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's quadlet.go file runs the stacks that are Podman quadlets
+// (see discovery.Quadlet): they are started and stopped with systemctl --user,
+// their status is the state of their service and their logs come from journald.
+
+package runner
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/util"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// quadletStep builds a step running `systemctl --user <verb>` on the quadlet's service.
+func quadletStep(stack discovery.Stack, name, verb string) CommandStep {
+	return CommandStep{
+		Name:    name,
+		Command: "systemctl",
+		Args:    []string{"--user", verb, stack.Quadlet.Unit},
+		Stack:   stack,
+	}
+}
+
+// quadletPullSteps builds the step pulling the quadlet's image with podman, or
+// none if its image isn't known (e.g. it is built by another quadlet).
+func quadletPullSteps(stack discovery.Stack) []CommandStep {
+	if stack.Quadlet.Image == "" {
+		return nil
+	}
+	return []CommandStep{{
+		Name:    "Pull Image",
+		Command: "podman",
+		Args:    []string{"pull", stack.Quadlet.Image},
+		Stack:   stack,
+	}}
+}
+
+// quadletSequence returns the steps of a stack action ("up", "down", "start",
+// "stop", "pull", "create" or "refresh") for a quadlet. Refreshing pulls the
+// image and restarts the service. Creating only pulls it, as systemd creates
+// the container when the service starts. Starting and stopping are the same as
+// up and down, as systemd recreates the container on every start.
+func quadletSequence(stack discovery.Stack, action string) []CommandStep {
+	switch action {
+	case "up", "start":
+		return []CommandStep{quadletStep(stack, "Start Service", "start")}
+	case "down", "stop":
+		return []CommandStep{quadletStep(stack, "Stop Service", "stop")}
+	case "pull", "create":
+		return quadletPullSteps(stack)
+	default: // refresh
+		return append(quadletPullSteps(stack), quadletStep(stack, "Restart Service", "restart"))
+	}
+}
+
+// quadletLogsStep builds the step showing the journal of a quadlet's service.
+func quadletLogsStep(stack discovery.Stack, opts LogsOptions) CommandStep {
+	args := []string{"--user", "--unit", stack.Quadlet.Unit, "--no-pager", "--output", "cat"}
+	switch {
+	case opts.Tail == 0:
+		args = append(args, "--lines", strconv.Itoa(logsTail))
+	case opts.Tail > 0:
+		args = append(args, "--lines", strconv.Itoa(opts.Tail))
+	}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	return CommandStep{
+		Name:    "Show Logs",
+		Command: "journalctl",
+		Args:    args,
+		Stack:   stack,
+	}
+}
+
+// getQuadletStatus derives a quadlet's status from `systemctl --user is-active`.
+func getQuadletStatus(stack discovery.Stack) StackRuntimeInfo {
+	info := StackRuntimeInfo{Stack: stack, OverallStatus: StatusUnknown}
+	cmdDesc := fmt.Sprintf("status check for quadlet %s", stack.Identifier())
+
+	var output []byte
+	var err error
+	if stack.IsRemote {
+		if stack.HostConfig == nil {
+			info.OverallStatus = StatusError
+			info.Error = fmt.Errorf("internal error: HostConfig is nil for remote stack %s", stack.Identifier())
+			return info
+		}
+		output, err = runSSHOutputCommand(*stack.HostConfig, "systemctl --user is-active "+util.QuoteArgForShell(stack.Quadlet.Unit), cmdDesc)
+	} else {
+		output, err = exec.Command("systemctl", "--user", "is-active", stack.Quadlet.Unit).Output()
+	}
+
+	// is-active exits non-zero for any state other than active, so the printed
+	// state matters more than the error
+	state, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	switch state {
+	case "active":
+		info.OverallStatus = StatusUp
+	case "activating", "deactivating", "reloading", "refreshing":
+		info.OverallStatus = StatusPartial
+	case "inactive":
+		info.OverallStatus = StatusDown
+	case "failed":
+		info.OverallStatus = StatusError
+		info.Error = fmt.Errorf("%s has failed, see `journalctl --user -u %s`", stack.Quadlet.Unit, stack.Quadlet.Unit)
+	default:
+		info.OverallStatus = StatusError
+		if err == nil {
+			err = fmt.Errorf("unexpected state %q", state)
+		}
+		info.Error = fmt.Errorf("failed to run %s: %w", cmdDesc, err)
+	}
+	return info
+}
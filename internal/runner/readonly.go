@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's readonly.go file decides which steps are refused in
+// read-only mode (see config.IsReadOnly). StreamCommand and RunHostCommand
+// enforce it, as do StackShellCommand and ServiceExecCommand for interactive
+// shells, so every interface is covered even if it doesn't check first.
+
+package runner
+
+import (
+	"bucket-manager/internal/config"
+	"slices"
+)
+
+// destructiveComposeCommands are compose subcommands that stop, pause or
+// remove containers, refused in read-only mode.
+var destructiveComposeCommands = []string{"down", "stop", "restart", "kill", "rm", "pause", "unpause"}
+
+// arbitraryComposeCommands are compose subcommands that run any program in a
+// container or write into one, so they could stop or remove anything. They
+// are refused in read-only mode, e.g. when typed as a TUI compose command.
+var arbitraryComposeCommands = []string{"exec", "run", "cp"}
+
+// allowedQuadletCommands are the commands of quadlet steps allowed in read-only
+// mode, as they only start, pull or show logs (see quadlet.go).
+var allowedQuadletCommands = [][]string{{"systemctl", "--user", "start"}, {"podman", "pull"}, {"journalctl"}}
+
+// CheckSequenceWritable returns a config.ErrReadOnly error in read-only mode if
+// any step of the sequence stops or removes containers, runs arbitrary programs
+// in them, or isn't a compose command (like the system prune run by a local refresh).
+func CheckSequenceWritable(sequence []CommandStep) error {
+	if !config.IsReadOnly() {
+		return nil
+	}
+	for _, step := range sequence {
+		if step.Stack.Quadlet != nil {
+			command := append([]string{step.Command}, step.Args...)
+			if !slices.ContainsFunc(allowedQuadletCommands, func(prefix []string) bool {
+				return len(command) >= len(prefix) && slices.Equal(command[:len(prefix)], prefix)
+			}) {
+				return config.ReadOnlyError("'" + step.Name + "'")
+			}
+			continue
+		}
+		subcommand, ok := composeSubcommand(step.Args)
+		if !ok || slices.Contains(destructiveComposeCommands, subcommand) || slices.Contains(arbitraryComposeCommands, subcommand) {
+			return config.ReadOnlyError("'" + step.Name + "'")
+		}
+	}
+	return nil
+}
+
+// CheckHostStepWritable returns a config.ErrReadOnly error in read-only mode,
+// as every host step (such as prune) removes resources.
+func CheckHostStepWritable(step HostCommandStep) error {
+	return config.CheckWritable("'" + step.Name + "'")
+}
@@ -12,15 +12,15 @@ import (
 	"bucket-manager/internal/logger"
 	"bucket-manager/internal/ssh"
 	"bucket-manager/internal/util"
-	"bufio"
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -37,6 +37,15 @@ func InitSSHManager(manager *ssh.Manager) {
 	sshManager = manager
 }
 
+// runAsCommand prefixes a remote command so it runs as the given user through
+// passwordless sudo. The command is returned unchanged if user is empty.
+func runAsCommand(user, command string) string {
+	if user == "" {
+		return command
+	}
+	return "sudo -n -u " + util.QuoteArgForShell(user) + " -- " + command
+}
+
 // CommandStep represents a single command to be executed within a stack's directory
 // Used for stack operations like starting, stopping, pulling images, etc.
 type CommandStep struct {
@@ -44,6 +53,63 @@ type CommandStep struct {
 	Command string          // The executable command (e.g., 'podman')
 	Args    []string        // Command arguments (e.g., ['compose', 'up', '-d'])
 	Stack   discovery.Stack // The target stack where the command will be executed
+	Env     []string        // Extra KEY=VALUE environment variables for the command
+
+	// ComposeParallel limits parallel compose operations (compose --parallel).
+	// It is dropped if the compose provider doesn't support the flag; zero leaves
+	// compose's default.
+	ComposeParallel int
+
+	// SkipIfPinned skips a pull step when every image it would pull is pinned
+	// by digest and already on the host (see smart_up.go).
+	SkipIfPinned bool
+
+	// Timeout stops the command if it runs longer, failing the step with a
+	// *TimeoutError. Zero means no limit.
+	Timeout time.Duration
+}
+
+// ValidateEnv checks that every entry is a KEY=VALUE assignment with a valid
+// shell variable name as the key.
+func ValidateEnv(env []string) error {
+	for _, entry := range env {
+		name, _, found := strings.Cut(entry, "=")
+		if !found {
+			return fmt.Errorf("invalid environment variable '%s': expected KEY=VALUE", entry)
+		}
+		if !isEnvName(name) {
+			return fmt.Errorf("invalid environment variable name '%s'", name)
+		}
+	}
+	return nil
+}
+
+// isEnvName reports whether name is a valid shell variable name.
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		if !isLetter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// envCommand prefixes a remote command with `env` so the given assignments
+// apply to it. `env` is used instead of shell assignments so the variables
+// also survive a runAsCommand sudo wrapper.
+func envCommand(env []string, command string) string {
+	if len(env) == 0 {
+		return command
+	}
+	parts := []string{"env"}
+	for _, entry := range env {
+		parts = append(parts, util.QuoteArgForShell(entry))
+	}
+	return strings.Join(parts, " ") + " " + command
 }
 
 // OutputLine represents a single line of command output with its source indicator
@@ -53,6 +119,26 @@ type OutputLine struct {
 	IsError bool   // True if the line came from stderr, false if from stdout
 }
 
+// ExitError reports that a command ran to completion but exited with a non-zero
+// status, so callers can tell command failures apart from connection or setup
+// errors and pass the status on (e.g. as the CLI's exit code).
+type ExitError struct {
+	Status int   // Exit status of the command
+	Remote bool  // True if the command ran on a remote host over SSH
+	Err    error // Underlying *exec.ExitError or *ssh.ExitError
+}
+
+func (e *ExitError) Error() string {
+	if e.Remote {
+		return fmt.Sprintf("remote command exited with status %d", e.Status)
+	}
+	return fmt.Sprintf("command exited with status %d", e.Status)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 // HostTarget defines the target for a host-level command (local or a specific remote).
 type HostTarget struct {
 	IsRemote   bool
@@ -66,6 +152,12 @@ type HostCommandStep struct {
 	Command string
 	Args    []string
 	Target  HostTarget
+	Timeout time.Duration // Stops the command if it runs longer; zero means no limit
+
+	// forRuntime rebuilds the step for the host's container runtime when it
+	// runs, for steps built before the runtime was detected whose arguments
+	// depend on it (see resolveHostStepRuntime).
+	forRuntime func(runtime string) HostCommandStep
 }
 
 // RunHostCommand executes a command directly on a target host (local or remote).
@@ -83,6 +175,12 @@ func RunHostCommand(step HostCommandStep, cliMode bool) (<-chan OutputLine, <-ch
 
 		startTime := time.Now()
 		cmdDesc := fmt.Sprintf("step '%s' for host %s", step.Name, step.Target.ServerName)
+		if err := CheckHostStepWritable(step); err != nil {
+			logger.Warn("Refusing host command in read-only mode", "step_name", step.Name, "server_name", step.Target.ServerName)
+			errChan <- err
+			return
+		}
+		resolveHostStepRuntime(&step)
 
 		logger.Debug("Host command execution starting",
 			"step_name", step.Name,
@@ -102,7 +200,7 @@ func RunHostCommand(step HostCommandStep, cliMode bool) (<-chan OutputLine, <-ch
 				return
 			}
 			// Construct the remote command string (command args...) - No cd needed for host commands
-			remoteCmdParts := []string{step.Command}
+			remoteCmdParts := []string{runAsCommand(step.Target.HostConfig.RunAsUserFor(""), step.Command)}
 			for _, arg := range step.Args {
 				remoteCmdParts = append(remoteCmdParts, util.QuoteArgForShell(arg))
 			}
@@ -112,7 +210,7 @@ func RunHostCommand(step HostCommandStep, cliMode bool) (<-chan OutputLine, <-ch
 				"host_name", step.Target.HostConfig.Name,
 				"remote_command", remoteCmdString)
 
-			runSSHCommand(*step.Target.HostConfig, remoteCmdString, cmdDesc, cliMode, outChan, errChan)
+			runSSHCommand(*step.Target.HostConfig, remoteCmdString, cmdDesc, step.Timeout, cliMode, outChan, errChan)
 		} else {
 			cmd := exec.Command(step.Command, step.Args...)
 			// cmd.Dir is not set for host commands, run in the default working directory
@@ -122,7 +220,7 @@ func RunHostCommand(step HostCommandStep, cliMode bool) (<-chan OutputLine, <-ch
 				"command", step.Command,
 				"args", step.Args)
 
-			runLocalCommand(cmd, localCmdDesc, cliMode, outChan, errChan)
+			runLocalCommand(cmd, localCmdDesc, step.Timeout, cliMode, outChan, errChan)
 		}
 
 		duration := time.Since(startTime)
@@ -170,12 +268,30 @@ func StreamCommand(step CommandStep, cliMode bool) (<-chan OutputLine, <-chan er
 
 		startTime := time.Now()
 		cmdDesc := fmt.Sprintf("step '%s' for stack %s", step.Name, step.Stack.Identifier())
+		if err := CheckSequenceWritable([]CommandStep{step}); err != nil {
+			logger.Warn("Refusing command in read-only mode", "step_name", step.Name, "stack_identifier", step.Stack.Identifier())
+			errChan <- err
+			return
+		}
+		resolveStepRuntime(&step)
+		if step.SkipIfPinned && pinnedImagesPresent(step) {
+			const msg = "All images are pinned by digest and already present, skipping the pull.\n"
+			if cliMode {
+				fmt.Print(msg)
+			} else {
+				outChan <- OutputLine{Line: msg}
+			}
+			return
+		}
+		step.Args = withComposeParallel(step)
+		cmdErrChan := make(chan error, 1)
 
 		logger.Debug("Command execution starting",
 			"step_name", step.Name,
 			"stack_identifier", step.Stack.Identifier(),
 			"command", step.Command,
 			"args", step.Args,
+			"env", step.Env,
 			"is_remote", step.Stack.IsRemote,
 			"cli_mode", cliMode)
 
@@ -196,22 +312,19 @@ func StreamCommand(step CommandStep, cliMode bool) (<-chan OutputLine, <-chan er
 				errChan <- err
 				return
 			}
-			remoteStackPath := filepath.Join(step.Stack.AbsoluteRemoteRoot, step.Stack.Path)
-			remoteCmdParts := []string{"cd", util.QuoteArgForShell(remoteStackPath), "&&", step.Command}
-			for _, arg := range step.Args {
-				remoteCmdParts = append(remoteCmdParts, util.QuoteArgForShell(arg))
-			}
-			remoteCmdString := strings.Join(remoteCmdParts, " ")
+			remoteCmdString := remoteStepCommand(step)
 
 			logger.Debug("Executing remote command",
 				"host_name", step.Stack.HostConfig.Name,
-				"remote_command", remoteCmdString,
-				"stack_path", remoteStackPath)
+				"remote_command", remoteCmdString)
 
-			runSSHCommand(*step.Stack.HostConfig, remoteCmdString, cmdDesc, cliMode, outChan, errChan)
+			runSSHCommand(*step.Stack.HostConfig, remoteCmdString, cmdDesc, step.Timeout, cliMode, outChan, cmdErrChan)
 		} else {
 			cmd := exec.Command(step.Command, step.Args...)
 			cmd.Dir = step.Stack.Path
+			if len(step.Env) > 0 {
+				cmd.Env = append(os.Environ(), step.Env...)
+			}
 			localCmdDesc := fmt.Sprintf("local %s", cmdDesc)
 
 			logger.Debug("Executing local command",
@@ -219,7 +332,16 @@ func StreamCommand(step CommandStep, cliMode bool) (<-chan OutputLine, <-chan er
 				"args", step.Args,
 				"working_dir", step.Stack.Path)
 
-			runLocalCommand(cmd, localCmdDesc, cliMode, outChan, errChan)
+			runLocalCommand(cmd, localCmdDesc, step.Timeout, cliMode, outChan, cmdErrChan)
+		}
+
+		// Invalidated before the error is passed on, so that a status check made
+		// once the step is reported finished can't get the status from before it
+		InvalidateStatus(step.Stack.Identifier())
+		select {
+		case err := <-cmdErrChan:
+			errChan <- err
+		default:
 		}
 
 		duration := time.Since(startTime)
@@ -233,66 +355,233 @@ func StreamCommand(step CommandStep, cliMode bool) (<-chan OutputLine, <-chan er
 	return outChan, errChan
 }
 
-func UpSequence(stack discovery.Stack) []CommandStep {
-	runtime := config.GetContainerRuntime()
+// stackRunAsUser returns the user the commands of a remote stack run as, ""
+// for the SSH user. The stack's HostConfig must be set.
+func stackRunAsUser(stack discovery.Stack) string {
+	if stack.Quadlet != nil {
+		return "" // systemctl --user manages the SSH user's own services
+	}
+	return stack.HostConfig.RunAsUserFor(stack.Name)
+}
+
+// remoteStepCommand returns the shell command running step on its remote host:
+// in the stack's directory, as the stack's user. The stack's HostConfig and
+// AbsoluteRemoteRoot must be set.
+func remoteStepCommand(step CommandStep) string {
+	remoteStackPath := filepath.Join(step.Stack.AbsoluteRemoteRoot, step.Stack.Path)
+	remoteCmdParts := []string{"cd", util.QuoteArgForShell(remoteStackPath), "&&", runAsCommand(stackRunAsUser(step.Stack), envCommand(step.Env, step.Command))}
+	for _, arg := range step.Args {
+		remoteCmdParts = append(remoteCmdParts, util.QuoteArgForShell(arg))
+	}
+	return strings.Join(remoteCmdParts, " ")
+}
+
+// composeArgs builds the arguments of a compose subcommand for stack, after the
+// configured compose_global_args, selecting its project explicitly with -p when
+// the project name is known.
+func composeArgs(stack discovery.Stack, args ...string) []string {
+	if stack.ProjectName == "" {
+		return slices.Concat(composeGlobalArgs(), []string{"compose"}, args)
+	}
+	return slices.Concat(composeGlobalArgs(), []string{"compose", "-p", stack.ProjectName}, args)
+}
+
+// composeGlobalArgs returns the runtime flags configured to precede "compose"
+// (compose_global_args).
+func composeGlobalArgs() []string {
+	// LoadConfigCached returns a zero Config on error, which adds no flags
+	cfg, _ := config.LoadConfigCached()
+	return cfg.GetComposeGlobalArgs()
+}
+
+// runtimeArgs builds the arguments of one of the runtime's own commands (like
+// "system prune"), after the compose_global_args, so that it works on the
+// same engine as the compose commands, e.g. the one of a podman connection.
+func runtimeArgs(args ...string) []string {
+	return slices.Concat(composeGlobalArgs(), args)
+}
+
+// runtimeShellCommand returns runtime followed by the compose_global_args as
+// a shell command prefix, for scripts running several runtime commands.
+func runtimeShellCommand(runtime string) string {
+	parts := []string{runtime}
+	for _, arg := range composeGlobalArgs() {
+		parts = append(parts, util.QuoteArgForShell(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// profileComposeArgs is composeArgs with a --profile flag for each of the
+// stack's compose profiles (see stackProfiles) before the subcommand.
+func profileComposeArgs(stack discovery.Stack, profiles []string, args ...string) []string {
+	var profileArgs []string
+	for _, profile := range stackProfiles(stack, profiles) {
+		profileArgs = append(profileArgs, "--profile", profile)
+	}
+	return composeArgs(stack, append(profileArgs, args...)...)
+}
+
+// stackProfiles returns profiles, or the stack's default compose profiles
+// (stack_compose_profiles) if profiles is nil.
+func stackProfiles(stack discovery.Stack, profiles []string) []string {
+	if profiles != nil {
+		return profiles
+	}
+	cfg, err := config.LoadConfigCached()
+	if err != nil {
+		logger.Warn("Could not load config to check stack_compose_profiles, enabling no profiles", "error", err)
+	}
+	return cfg.GetComposeProfiles(stack.Identifier(), stack.Name)
+}
+
+// The sequence builders below enable the given compose profiles, or the
+// stack's configured default profiles if profiles is nil. Quadlet stacks get
+// their systemctl equivalents instead (see quadletSequence).
+
+// UpSequence pulls a stack's images and starts it. With smart_up, the pull is
+// skipped if the images are pinned by digest and already present.
+func UpSequence(stack discovery.Stack, profiles []string) []CommandStep {
+	cfg, err := config.LoadConfigCached()
+	if err != nil {
+		logger.Warn("Could not load config to check smart_up, always pulling", "error", err)
+	}
+	return upSequence(stack, profiles, cfg.SmartUp)
+}
+
+// SmartUpSequence is UpSequence with smart_up enabled, for `bm up --smart`.
+func SmartUpSequence(stack discovery.Stack, profiles []string) []CommandStep {
+	return upSequence(stack, profiles, true)
+}
+
+func upSequence(stack discovery.Stack, profiles []string, smart bool) []CommandStep {
+	if stack.Quadlet != nil {
+		return quadletSequence(stack, "up")
+	}
+	runtime := runtimeCommand(stack.HostConfig)
+	pull := PullStep(stack, runtime, profiles)
+	pull.SkipIfPinned = smart
 	return []CommandStep{
+		pull,
 		{
-			Name:    "Pull Images",
+			Name:    "Start Containers",
 			Command: runtime,
-			Args:    []string{"compose", "pull"},
+			Args:    profileComposeArgs(stack, profiles, "up", "-d"),
 			Stack:   stack,
 		},
+	}
+}
+func PullSequence(stack discovery.Stack, profiles []string) []CommandStep {
+	if stack.Quadlet != nil {
+		return quadletSequence(stack, "pull")
+	}
+	runtime := runtimeCommand(stack.HostConfig)
+	return []CommandStep{
+		PullStep(stack, runtime, profiles),
+	}
+}
+
+// CreateSequence pulls a stack's images and creates its containers without
+// starting them, so that a later `up` starts them right away.
+func CreateSequence(stack discovery.Stack, profiles []string) []CommandStep {
+	if stack.Quadlet != nil {
+		return quadletSequence(stack, "create")
+	}
+	runtime := runtimeCommand(stack.HostConfig)
+	return []CommandStep{
+		PullStep(stack, runtime, profiles),
 		{
-			Name:    "Start Containers",
+			Name:    "Create Containers",
 			Command: runtime,
-			Args:    []string{"compose", "up", "-d"},
+			Args:    profileComposeArgs(stack, profiles, "create"),
 			Stack:   stack,
 		},
 	}
 }
-func PullSequence(stack discovery.Stack) []CommandStep {
-	runtime := config.GetContainerRuntime()
+
+// DefaultStopTimeout leaves how long containers get to stop gracefully before
+// they are killed to compose (10 seconds), when passed as the stopTimeout of
+// DownSequence, StopSequence or RefreshSequence.
+const DefaultStopTimeout = -1
+
+// stopArgs returns a compose subcommand stopping containers, with --timeout
+// if stopTimeout (in seconds) isn't DefaultStopTimeout.
+func stopArgs(subcommand string, stopTimeout int) []string {
+	if stopTimeout < 0 {
+		return []string{subcommand}
+	}
+	return []string{subcommand, "--timeout", strconv.Itoa(stopTimeout)}
+}
+
+// StopSequence stops a stack's containers without removing them, unlike
+// DownSequence, so StartSequence can start them again with their state intact.
+// Containers get stopTimeout seconds to stop before they are killed (see
+// DefaultStopTimeout); quadlets use their unit's stop timeout instead.
+func StopSequence(stack discovery.Stack, profiles []string, stopTimeout int) []CommandStep {
+	if stack.Quadlet != nil {
+		return quadletSequence(stack, "stop")
+	}
 	return []CommandStep{
 		{
-			Name:    "Pull Images",
-			Command: runtime,
-			Args:    []string{"compose", "pull"},
+			Name:    "Stop Containers (keeping them)",
+			Command: runtimeCommand(stack.HostConfig),
+			Args:    profileComposeArgs(stack, profiles, stopArgs("stop", stopTimeout)...),
 			Stack:   stack,
 		},
 	}
 }
 
-func DownSequence(stack discovery.Stack) []CommandStep {
-	runtime := config.GetContainerRuntime()
+// StartSequence starts a stack's existing containers, e.g. after StopSequence,
+// without pulling images or recreating them like UpSequence may.
+func StartSequence(stack discovery.Stack, profiles []string) []CommandStep {
+	if stack.Quadlet != nil {
+		return quadletSequence(stack, "start")
+	}
 	return []CommandStep{
 		{
-			Name:    "Stop Containers",
-			Command: runtime,
-			Args:    []string{"compose", "down"},
+			Name:    "Start Existing Containers",
+			Command: runtimeCommand(stack.HostConfig),
+			Args:    profileComposeArgs(stack, profiles, "start"),
 			Stack:   stack,
 		},
 	}
 }
 
-func RefreshSequence(stack discovery.Stack) []CommandStep {
-	runtime := config.GetContainerRuntime()
-	steps := []CommandStep{
+// DownSequence stops and removes a stack's containers, giving them stopTimeout
+// seconds to stop (see StopSequence).
+func DownSequence(stack discovery.Stack, profiles []string, stopTimeout int) []CommandStep {
+	if stack.Quadlet != nil {
+		return quadletSequence(stack, "down")
+	}
+	runtime := runtimeCommand(stack.HostConfig)
+	return []CommandStep{
 		{
-			Name:    "Pull Images",
+			Name:    "Stop Containers",
 			Command: runtime,
-			Args:    []string{"compose", "pull"},
+			Args:    profileComposeArgs(stack, profiles, stopArgs("down", stopTimeout)...),
 			Stack:   stack,
 		},
+	}
+}
+
+// RefreshSequence pulls a stack's images and recreates its containers, giving
+// the old ones stopTimeout seconds to stop (see StopSequence).
+func RefreshSequence(stack discovery.Stack, profiles []string, stopTimeout int) []CommandStep {
+	if stack.Quadlet != nil {
+		return quadletSequence(stack, "refresh")
+	}
+	runtime := runtimeCommand(stack.HostConfig)
+	steps := []CommandStep{
+		PullStep(stack, runtime, profiles),
 		{
 			Name:    "Stop Containers",
 			Command: runtime,
-			Args:    []string{"compose", "down"},
+			Args:    profileComposeArgs(stack, profiles, stopArgs("down", stopTimeout)...),
 			Stack:   stack,
 		},
 		{
 			Name:    "Start Containers",
 			Command: runtime,
-			Args:    []string{"compose", "up", "-d"},
+			Args:    profileComposeArgs(stack, profiles, "up", "-d"),
 			Stack:   stack,
 		},
 	}
@@ -301,22 +590,142 @@ func RefreshSequence(stack discovery.Stack) []CommandStep {
 		steps = append(steps, CommandStep{
 			Name:    "Prune Local System",
 			Command: runtime,
-			Args:    []string{"system", "prune", "-af"},
+			Args:    runtimeArgs("system", "prune", "-af"),
 			Stack:   stack,
 		})
 	}
 	return steps
 }
 
-// PruneHostStep creates a command step to prune the container system on a target host.
-func PruneHostStep(target HostTarget) HostCommandStep {
-	runtime := config.GetContainerRuntime()
-	return HostCommandStep{
-		Name:    "Prune System",
-		Command: runtime,
-		Args:    []string{"system", "prune", "-af"},
+// PruneScope limits a prune to one kind of resource.
+type PruneScope string
+
+const (
+	PruneAll        PruneScope = ""           // Containers, networks, images and build cache (system prune)
+	PruneImages     PruneScope = "images"     // Unused images only
+	PruneContainers PruneScope = "containers" // Stopped containers only
+	PruneNetworks   PruneScope = "networks"   // Unused networks only
+)
+
+// PruneScopes lists the accepted PruneScope values other than PruneAll.
+var PruneScopes = []PruneScope{PruneImages, PruneContainers, PruneNetworks}
+
+// PruneOptions narrows down what PruneHostStep removes. The zero value is a
+// full system prune.
+type PruneOptions struct {
+	Scope      PruneScope    // Kind of resource to prune
+	OlderThan  time.Duration // Only remove resources created more than this long ago (0 for no limit)
+	KeepLatest int           // Keep the N newest images of each repository (images scope only, 0 to disable)
+}
+
+// Validate checks that the options can be combined.
+func (o PruneOptions) Validate() error {
+	if o.Scope != PruneAll && !slices.Contains(PruneScopes, o.Scope) {
+		return fmt.Errorf("unknown prune scope '%s'", o.Scope)
+	}
+	if o.OlderThan < 0 {
+		return fmt.Errorf("older-than duration must not be negative")
+	}
+	if o.KeepLatest < 0 {
+		return fmt.Errorf("keep-latest must not be negative")
+	}
+	if o.KeepLatest > 0 && o.Scope != PruneImages {
+		return fmt.Errorf("keep-latest only applies to images")
+	}
+	return nil
+}
+
+// PruneHostStep creates a command step to prune the container system on a
+// target host, limited by opts.
+func PruneHostStep(target HostTarget, opts PruneOptions) HostCommandStep {
+	return pruneHostStep(target, runtimeCommand(target.HostConfig), opts)
+}
+
+func pruneHostStep(target HostTarget, runtime string, opts PruneOptions) HostCommandStep {
+	var filterArgs []string
+	if opts.OlderThan > 0 {
+		filterArgs = []string{"--filter", "until=" + opts.OlderThan.String()}
+	}
+
+	step := HostCommandStep{Command: runtime, Target: target}
+	switch opts.Scope {
+	case PruneImages:
+		if opts.KeepLatest > 0 && runtime == config.ContainerRuntimeAuto {
+			step.Name = fmt.Sprintf("Prune Images (keeping latest %d)", opts.KeepLatest)
+			step.forRuntime = func(runtime string) HostCommandStep {
+				return pruneHostStep(target, runtime, opts)
+			}
+			return step
+		}
+		if opts.KeepLatest > 0 {
+			step.Name = fmt.Sprintf("Prune Images (keeping latest %d)", opts.KeepLatest)
+			step.Command = "sh"
+			step.Args = []string{"-c", keepLatestImagesScript(runtimeShellCommand(runtime), opts)}
+			return step
+		}
+		step.Name = "Prune Images"
+		step.Args = runtimeArgs(append([]string{"image", "prune", "-af"}, filterArgs...)...)
+	case PruneContainers:
+		step.Name = "Prune Containers"
+		step.Args = runtimeArgs(append([]string{"container", "prune", "-f"}, filterArgs...)...)
+	case PruneNetworks:
+		step.Name = "Prune Networks"
+		step.Args = runtimeArgs(append([]string{"network", "prune", "-f"}, filterArgs...)...)
+	default:
+		step.Name = "Prune System"
+		step.Args = runtimeArgs(append([]string{"system", "prune", "-af"}, filterArgs...)...)
+	}
+	return step
+}
+
+// keepLatestImagesScript builds a shell script removing every tagged image but
+// the opts.KeepLatest newest of each repository (both runtimes list images
+// newest first), limited to images older than opts.OlderThan if set. Images
+// still used by a container can't be removed and are reported as kept.
+// runtime is the shell command invoking the runtime (see runtimeShellCommand).
+func keepLatestImagesScript(runtime string, opts PruneOptions) string {
+	script := fmt.Sprintf(`candidates=$(%[1]s images --no-trunc --format '{{.Repository}} {{.ID}}' | awk -v keep=%[2]d '$1 != "<none>" && ++seen[$1] > keep { print $2 }' | sort -u)`,
+		runtime, opts.KeepLatest)
+	if opts.OlderThan > 0 {
+		script += fmt.Sprintf(`
+old=$(%s images --no-trunc -q --filter %s)
+candidates=$(printf '%%s\n' "$candidates" | grep -Fx -e "$old")`,
+			runtime, util.QuoteArgForShell("until="+opts.OlderThan.String()))
+	}
+	script += fmt.Sprintf(`
+for id in $candidates; do
+  if %[1]s rmi "$id" >/dev/null 2>&1; then echo "Removed $id"; else echo "Kept $id (in use)"; fi
+done
+echo "Done."`, runtime)
+	return script
+}
+
+// RestartRuntimeHostStep creates a step that restarts the container runtime
+// on the target host with its configured runtime_restart_command.
+func RestartRuntimeHostStep(target HostTarget) HostCommandStep {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Warn("Could not load config to check runtime_restart_command, using the default", "error", err)
+	}
+	step := HostCommandStep{
+		Name:    "Restart Container Runtime",
+		Command: "sh",
 		Target:  target,
 	}
+	runtime := runtimeCommand(target.HostConfig)
+	// Without a configured restart command, the default depends on the runtime
+	defaultCommand := cfg.RuntimeRestartCommandFor(target.HostConfig, "docker") != cfg.RuntimeRestartCommandFor(target.HostConfig, "podman")
+	if runtime == config.ContainerRuntimeAuto && defaultCommand {
+		step.forRuntime = func(runtime string) HostCommandStep {
+			resolved := step
+			resolved.forRuntime = nil
+			resolved.Args = []string{"-c", cfg.RuntimeRestartCommandFor(target.HostConfig, runtime)}
+			return resolved
+		}
+		return step
+	}
+	step.Args = []string{"-c", cfg.RuntimeRestartCommandFor(target.HostConfig, runtime)}
+	return step
 }
 
 type StackStatus string
@@ -330,62 +739,30 @@ const (
 )
 
 type ContainerState struct {
-	Name    string `json:"Name"`
-	Command string `json:"Command"`
-	Service string `json:"Service"`
-	Status  string `json:"Status"` // e.g., "running", "exited(0)", "created"
-	Ports   string `json:"Ports"`
+	Name    string    `json:"Name"`
+	Command string    `json:"Command"`
+	Service string    `json:"Service"`
+	Status  string    `json:"Status"` // e.g., "running", "exited(0)", "created"
+	Ports   string    `json:"Ports"`
+	Created time.Time `json:"Created,omitzero"` // Zero if the compose provider doesn't report it
+}
+
+// IsRunning reports whether the container's status says it is up.
+func (c ContainerState) IsRunning() bool {
+	// Consider variations in status strings (case-insensitive)
+	statusLower := strings.ToLower(c.Status)
+	return strings.Contains(statusLower, "running") ||
+		strings.Contains(statusLower, "healthy") ||
+		strings.HasPrefix(statusLower, "up")
 }
 
 // StackRuntimeInfo holds the status information for a stack.
 type StackRuntimeInfo struct {
-	Stack         discovery.Stack
-	OverallStatus StackStatus
-	Containers    []ContainerState
-	Error         error
-}
-
-// parseContainerStatusOutput processes the JSON stream output from 'compose ps'.
-func parseContainerStatusOutput(output []byte) ([]ContainerState, error) {
-	var containers []ContainerState
-	var firstUnmarshalError error
-
-	scanner := bufio.NewScanner(bytes.NewReader(output))
-	for scanner.Scan() {
-		lineBytes := scanner.Bytes()
-		if len(bytes.TrimSpace(lineBytes)) == 0 {
-			continue
-		}
-
-		var container ContainerState
-		if errUnmarshal := json.Unmarshal(lineBytes, &container); errUnmarshal != nil {
-			// Store the first error encountered, but continue trying to parse other lines
-			if firstUnmarshalError == nil {
-				firstUnmarshalError = fmt.Errorf("failed to decode container status JSON line: %w\nLine: %s", errUnmarshal, string(lineBytes))
-			}
-			continue
-		}
-		containers = append(containers, container)
-	}
-
-	if errScan := scanner.Err(); errScan != nil {
-		if firstUnmarshalError == nil { // Prioritize unmarshal errors over scan errors
-			firstUnmarshalError = fmt.Errorf("error scanning command output: %w", errScan)
-		}
-	}
-
-	// Check if the error might be the "no containers found" case by examining the raw output
-	// This check is done here because even if parsing fails, the output might indicate no containers.
-	if firstUnmarshalError != nil {
-		outputLower := strings.ToLower(string(output))
-		if strings.Contains(outputLower, "no containers found") {
-			// Treat "no containers found" as a successful parse yielding zero containers, clear the error.
-			return []ContainerState{}, nil
-		}
-		return nil, firstUnmarshalError
-	}
-
-	return containers, nil
+	Stack          discovery.Stack
+	OverallStatus  StackStatus
+	Containers     []ContainerState
+	Error          error
+	ComposeModTime time.Time // Newest modification time of the compose files; see IsStale
 }
 
 // aggregateOverallStatus determines the overall stack status based on container states.
@@ -397,13 +774,7 @@ func aggregateOverallStatus(containers []ContainerState) StackStatus {
 	allRunning := true
 	anyRunning := false
 	for _, c := range containers {
-		// Consider variations in status strings (case-insensitive)
-		statusLower := strings.ToLower(c.Status)
-		isRunning := strings.Contains(statusLower, "running") ||
-			strings.Contains(statusLower, "healthy") ||
-			strings.HasPrefix(statusLower, "up")
-
-		if isRunning {
+		if c.IsRunning() {
 			anyRunning = true
 		} else {
 			allRunning = false
@@ -427,11 +798,32 @@ func aggregateOverallStatus(containers []ContainerState) StackStatus {
 	return StatusUnknown
 }
 
+// GetStackStatus checks the status of a single stack with `compose ps`, locally
+// or over SSH, unless it was checked within status_cache_ttl (see
+// status_cache.go). GetStackStatuses checks many stacks with fewer SSH round trips.
 func GetStackStatus(stack discovery.Stack) StackRuntimeInfo {
-	runtime := config.GetContainerRuntime()
+	ttl := statusCacheTTL()
+	if info, ok := cachedStackStatus(stack, ttl); ok {
+		return info
+	}
+	return checkStatusesShared([]discovery.Stack{stack}, ttl, checkSingleStatus)[0]
+}
+
+// checkStackStatus checks the status of a single stack, bypassing the cache.
+func checkStackStatus(stack discovery.Stack) StackRuntimeInfo {
+	if stack.Quadlet != nil {
+		return getQuadletStatus(stack)
+	}
 	info := StackRuntimeInfo{Stack: stack, OverallStatus: StatusUnknown}
 	cmdDesc := fmt.Sprintf("status check for stack %s", stack.Identifier())
-	psArgs := []string{"compose", "ps", "--format", "json", "-a"}
+	runtime, err := resolveRuntime(stack.HostConfig)
+	if err != nil {
+		// The host couldn't be reached to detect its runtime; don't dial it again
+		info.OverallStatus = StatusError
+		info.Error = fmt.Errorf("failed to run %s: %w", cmdDesc, err)
+		return info
+	}
+	psArgs := composeArgs(stack, "ps", "--format", "json", "-a")
 
 	var output []byte
 	var cmdErr error
@@ -439,8 +831,10 @@ func GetStackStatus(stack discovery.Stack) StackRuntimeInfo {
 
 	// 1. Execute command (local or remote)
 	if stack.IsRemote {
-		output, cmdErr = runSSHStatusCheck(stack, runtime, psArgs, cmdDesc)
-		// runSSHStatusCheck returns combined output and the command error
+		output, cmdErr = runSSHStatusCheck(stack, runtime, nil, psArgs, cmdDesc)
+		// runSSHStatusCheck returns combined output and the command error,
+		// preceded by the compose files' modification time
+		output, info.ComposeModTime = splitComposeModTime(output)
 	} else {
 		cmd := exec.Command(runtime, psArgs...)
 		cmd.Dir = stack.Path
@@ -451,8 +845,18 @@ func GetStackStatus(stack discovery.Stack) StackRuntimeInfo {
 		cmdErr = cmd.Run()
 		output = stdoutBuf.Bytes()
 		stderrStr = stderrBuf.String() // Capture stderr for local
+		info.ComposeModTime = localComposeModTime(stack.Path)
 	}
 
+	return stackStatusFromOutput(info, cmdDesc, output, cmdErr, stderrStr)
+}
+
+// stackStatusFromOutput completes info from the output of a stack's `compose ps`,
+// the error it returned and, for local stacks, its stderr. Remote output is
+// combined, so it also holds the remote stderr.
+func stackStatusFromOutput(info StackRuntimeInfo, cmdDesc string, output []byte, cmdErr error, stderrStr string) StackRuntimeInfo {
+	stack := info.Stack
+
 	// 2. Handle command execution errors
 	if cmdErr != nil {
 		// Check common errors indicating the stack is simply down or doesn't exist
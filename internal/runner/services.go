@@ -0,0 +1,346 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's services.go file implements per-service operations. It lists
+// the services defined in a stack's compose file, including services that have
+// no containers, reads the stack's resolved compose configuration, and builds
+// the commands used to act on a single service.
+
+package runner
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/util"
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// logsTail is the number of log lines shown by LogsSequence and ServiceLogsSequence.
+const logsTail = 200
+
+// GetStackServices returns the services defined in the stack's compose file,
+// as reported by `compose config --services`.
+func GetStackServices(stack discovery.Stack) ([]string, error) {
+	if stack.Quadlet != nil {
+		return nil, nil // A quadlet is a single service, managed as the stack itself
+	}
+	output, err := runComposeQuery(stack, nil, fmt.Sprintf("service listing for stack %s", stack.Identifier()), "config", "--services")
+	if err != nil {
+		return nil, err
+	}
+
+	var services []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		// Service names never contain spaces; skip any warnings the runtime mixes into the output.
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.ContainsAny(line, " \t") {
+			continue
+		}
+		services = append(services, line)
+	}
+	return services, nil
+}
+
+// ComposeConfig is the part of a stack's resolved compose configuration
+// (`compose config`) shown by `bm inspect`.
+type ComposeConfig struct {
+	ProjectName string           `json:"projectName"`
+	Services    []ComposeService `json:"services"` // Sorted by name
+}
+
+// ComposeService is a service of a resolved compose configuration.
+type ComposeService struct {
+	Name  string `json:"name"`
+	Image string `json:"image,omitempty"` // Empty for services that are only built
+}
+
+// composeConfigKeyPattern matches a top-level key of `compose config` output,
+// e.g. "name: app" or "services:".
+var composeConfigKeyPattern = regexp.MustCompile(`^[a-z_-]+:(\s|$)`)
+
+// GetStackComposeConfig returns the project name and services of the stack's
+// compose configuration as resolved by `compose config`, with its
+// interpolation, profiles and override files applied.
+func GetStackComposeConfig(stack discovery.Stack) (ComposeConfig, error) {
+	if stack.Quadlet != nil {
+		return ComposeConfig{}, fmt.Errorf("%s is a quadlet, not a compose project", stack.Identifier())
+	}
+	output, err := runComposeQuery(stack, nil, fmt.Sprintf("compose config for stack %s", stack.Identifier()), "config")
+	if err != nil {
+		return ComposeConfig{}, err
+	}
+	output, err = trimComposeConfigOutput(stack, output)
+	if err != nil {
+		return ComposeConfig{}, err
+	}
+
+	var doc struct {
+		Name     string `yaml:"name"`
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(output, &doc); err != nil {
+		return ComposeConfig{}, fmt.Errorf("failed to parse compose config for stack %s: %w", stack.Identifier(), err)
+	}
+	cfg := ComposeConfig{ProjectName: doc.Name}
+	for _, name := range slices.Sorted(maps.Keys(doc.Services)) {
+		cfg.Services = append(cfg.Services, ComposeService{Name: name, Image: doc.Services[name].Image})
+	}
+	return cfg, nil
+}
+
+// GetStackEffectiveConfig returns the stack's compose configuration as
+// resolved by `compose config` with the given profiles (the stack's default
+// profiles if nil) enabled and extra environment variables set: the
+// configuration that up would apply.
+func GetStackEffectiveConfig(stack discovery.Stack, profiles []string, env []string) (string, error) {
+	if stack.Quadlet != nil {
+		return "", fmt.Errorf("%s is a quadlet, not a compose project", stack.Identifier())
+	}
+	var args []string
+	for _, profile := range stackProfiles(stack, profiles) {
+		args = append(args, "--profile", profile)
+	}
+	output, err := runComposeQuery(stack, env, fmt.Sprintf("compose config for stack %s", stack.Identifier()), append(args, "config")...)
+	if err != nil {
+		return "", err
+	}
+	output, err = trimComposeConfigOutput(stack, output)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// trimComposeConfigOutput skips the messages mixed into `compose config`
+// output, like podman's note about the compose provider it runs, up to the
+// first top-level key of the configuration.
+func trimComposeConfigOutput(stack discovery.Stack, output []byte) ([]byte, error) {
+	lines := strings.SplitAfter(string(output), "\n")
+	start := slices.IndexFunc(lines, composeConfigKeyPattern.MatchString)
+	if start == -1 {
+		return nil, fmt.Errorf("compose config for stack %s printed no configuration", stack.Identifier())
+	}
+	return []byte(strings.Join(lines[start:], "")), nil
+}
+
+// runComposeQuery runs a read-only compose subcommand on the stack, locally or
+// over SSH, with extra KEY=VALUE environment variables (if any) set, and
+// returns its standard output.
+func runComposeQuery(stack discovery.Stack, env []string, cmdDesc string, args ...string) ([]byte, error) {
+	return runStackQuery(stack, env, cmdDesc, composeArgs(stack, args...))
+}
+
+// runStackQuery is runComposeQuery for container runtime arguments that
+// already include "compose" and its project flags.
+func runStackQuery(stack discovery.Stack, env []string, cmdDesc string, args []string) ([]byte, error) {
+	runtime := ContainerRuntimeFor(stack.HostConfig)
+
+	if stack.IsRemote {
+		out, err := runSSHStatusCheck(stack, runtime, env, args, cmdDesc)
+		if err != nil {
+			return nil, fmt.Errorf("%w\nOutput: %s", err, strings.TrimSpace(string(out)))
+		}
+		out, _ = splitComposeModTime(out)
+		return out, nil
+	}
+
+	cmd := exec.Command(runtime, args...)
+	cmd.Dir = stack.Path
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %s: %w", cmdDesc, strings.TrimSpace(stderrBuf.String()), err)
+	}
+	return stdoutBuf.Bytes(), nil
+}
+
+// LogsOptions selects the logs shown by LogsStep.
+type LogsOptions struct {
+	Services []string // Only show these services; all services if empty
+	Tail     int      // Recent lines shown per service; zero means logsTail, negative means all
+	Follow   bool     // Keep streaming new log lines until interrupted
+}
+
+// LogsStep builds the step showing the logs of a stack.
+func LogsStep(stack discovery.Stack, opts LogsOptions) CommandStep {
+	if stack.Quadlet != nil {
+		return quadletLogsStep(stack, opts)
+	}
+	args := []string{"logs"}
+	switch {
+	case opts.Tail == 0:
+		args = append(args, "--tail", strconv.Itoa(logsTail))
+	case opts.Tail > 0:
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	args = append(args, opts.Services...)
+
+	name := "Show Logs"
+	if len(opts.Services) > 0 {
+		name = fmt.Sprintf("Logs for %s", strings.Join(opts.Services, ", "))
+	}
+	return CommandStep{
+		Name:    name,
+		Command: runtimeCommand(stack.HostConfig),
+		Args:    composeArgs(stack, args...),
+		Stack:   stack,
+	}
+}
+
+// LogsSequence shows the most recent logs of every service in the stack.
+func LogsSequence(stack discovery.Stack) []CommandStep {
+	return []CommandStep{LogsStep(stack, LogsOptions{})}
+}
+
+// ServiceLogsSequence shows the most recent logs of a single service.
+func ServiceLogsSequence(stack discovery.Stack, service string) []CommandStep {
+	return []CommandStep{LogsStep(stack, LogsOptions{Services: []string{service}})}
+}
+
+// ServiceRestartSequence restarts a single service. Stopping and starting it
+// (rather than `compose restart`) also brings up services that have no container yet.
+func ServiceRestartSequence(stack discovery.Stack, service string) []CommandStep {
+	runtime := runtimeCommand(stack.HostConfig)
+	return []CommandStep{
+		{
+			Name:    fmt.Sprintf("Stop %s", service),
+			Command: runtime,
+			Args:    composeArgs(stack, "stop", service),
+			Stack:   stack,
+		},
+		{
+			Name:    fmt.Sprintf("Start %s", service),
+			Command: runtime,
+			Args:    composeArgs(stack, "up", "-d", service),
+			Stack:   stack,
+		},
+	}
+}
+
+// ttyComposeCommands are compose subcommands that allocate a TTY by default,
+// which fails when their output is captured rather than shown in a terminal.
+var ttyComposeCommands = []string{"exec", "run"}
+
+// ComposeCommandSequence builds a step running a compose subcommand typed by
+// the user on a stack, e.g. "exec db backup.sh". The command is split into
+// words like a shell would; a leading "compose", "<runtime> compose" or
+// "<runtime>-compose" is dropped, and anything that isn't a compose subcommand
+// is refused. -T is added to exec and run, whose output is captured.
+func ComposeCommandSequence(stack discovery.Stack, command string) ([]CommandStep, error) {
+	if stack.Quadlet != nil {
+		return nil, fmt.Errorf("%s is a quadlet, not a compose project", stack.Identifier())
+	}
+	args, err := util.SplitShellWords(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command: %w", err)
+	}
+
+	if len(args) > 0 && (args[0] == "podman" || args[0] == "docker") {
+		if len(args) < 2 || args[1] != "compose" {
+			return nil, fmt.Errorf("only compose subcommands can be run, not '%s'", strings.Join(args, " "))
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 && (args[0] == "compose" || args[0] == "podman-compose" || args[0] == "docker-compose") {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no compose subcommand given")
+	}
+	if strings.HasPrefix(args[0], "-") {
+		// Global flags like -f could point compose at another project
+		return nil, fmt.Errorf("the command must start with a compose subcommand, not '%s'", args[0])
+	}
+
+	if slices.Contains(ttyComposeCommands, args[0]) && !slices.Contains(args, "-T") && !slices.Contains(args, "--no-TTY") && !slices.Contains(args, "--no-tty") {
+		args = slices.Concat(args[:1], []string{"-T"}, args[1:])
+	}
+
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`") {
+			words[i] = util.QuoteArgForShell(arg)
+		}
+	}
+	return []CommandStep{
+		{
+			Name:    "compose " + strings.Join(words, " "),
+			Command: runtimeCommand(stack.HostConfig),
+			Args:    composeArgs(stack, args...),
+			Stack:   stack,
+		},
+	}, nil
+}
+
+// ActionSequence builds the step running one of the custom actions defined in
+// the stack's .bm.yaml. The action's command is checked like a compose command
+// typed by the user (see ComposeCommandSequence).
+func ActionSequence(stack discovery.Stack, name string) ([]CommandStep, error) {
+	command, ok := stack.Actions[name]
+	if !ok {
+		return nil, fmt.Errorf("stack %s has no action '%s'", stack.Identifier(), name)
+	}
+	steps, err := ComposeCommandSequence(stack, command)
+	if err != nil {
+		return nil, fmt.Errorf("action '%s': %w", name, err)
+	}
+	steps[0].Name = fmt.Sprintf("%s: %s", name, steps[0].Name)
+	return steps, nil
+}
+
+// ServiceExecCommand builds an interactive command that opens a shell inside a
+// running service container. Like the exec compose command, it is refused in
+// read-only mode.
+func ServiceExecCommand(stack discovery.Stack, service string) (*exec.Cmd, error) {
+	if err := config.CheckWritable("opening a shell in a container"); err != nil {
+		return nil, err
+	}
+	runtime := ContainerRuntimeFor(stack.HostConfig)
+	execArgs := composeArgs(stack, "exec", service, "sh")
+
+	if !stack.IsRemote {
+		cmd := exec.Command(runtime, execArgs...)
+		cmd.Dir = stack.Path
+		return cmd, nil
+	}
+
+	if stack.HostConfig == nil {
+		return nil, fmt.Errorf("internal error: HostConfig is nil for remote stack %s", stack.Identifier())
+	}
+	if stack.AbsoluteRemoteRoot == "" {
+		return nil, fmt.Errorf("internal error: AbsoluteRemoteRoot is empty for remote stack %s", stack.Identifier())
+	}
+
+	host := stack.HostConfig
+	remoteStackPath := filepath.Join(stack.AbsoluteRemoteRoot, stack.Path)
+	runtimeCmd := runAsCommand(host.RunAsUserFor(stack.Name), runtime)
+	remoteCmdParts := []string{"cd", util.QuoteArgForShell(remoteStackPath), "&&", runtimeCmd}
+	for _, arg := range execArgs {
+		remoteCmdParts = append(remoteCmdParts, util.QuoteArgForShell(arg))
+	}
+
+	return interactiveSSHCommand(*stack.HostConfig, strings.Join(remoteCmdParts, " "))
+}
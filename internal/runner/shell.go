@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's shell.go file builds interactive commands that hand the
+// terminal over to the user, such as a shell in a stack's directory.
+
+package runner
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/util"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// StackShellCommand builds an interactive login shell in the stack's directory.
+// Local stacks use $SHELL (or /bin/sh); remote stacks use the remote user's shell.
+// It is refused in read-only mode, as anything can be run from the shell.
+func StackShellCommand(stack discovery.Stack) (*exec.Cmd, error) {
+	if err := config.CheckWritable("opening a shell"); err != nil {
+		return nil, err
+	}
+	if !stack.IsRemote {
+		shell := os.Getenv("SHELL")
+		if shell == "" {
+			shell = "/bin/sh"
+		}
+		cmd := exec.Command(shell)
+		cmd.Dir = stack.Path
+		return cmd, nil
+	}
+
+	if stack.HostConfig == nil {
+		return nil, fmt.Errorf("internal error: HostConfig is nil for remote stack %s", stack.Identifier())
+	}
+	if stack.AbsoluteRemoteRoot == "" {
+		return nil, fmt.Errorf("internal error: AbsoluteRemoteRoot is empty for remote stack %s", stack.Identifier())
+	}
+
+	remoteStackPath := filepath.Join(stack.AbsoluteRemoteRoot, stack.Path)
+	remoteCmd := fmt.Sprintf(`cd %s && exec "${SHELL:-/bin/sh}" -l`, util.QuoteArgForShell(remoteStackPath))
+	return interactiveSSHCommand(*stack.HostConfig, remoteCmd)
+}
+
+// interactiveSSHCommand builds a command running remoteCmd on host with the
+// system ssh client, since interactive sessions need a terminal.
+func interactiveSSHCommand(host config.SSHHost, remoteCmd string) (*exec.Cmd, error) {
+	sshArgs := []string{"-t"}
+	if host.ForwardAgent {
+		sshArgs = append(sshArgs, "-A")
+	}
+	sshArgs = append(sshArgs, "-p", strconv.Itoa(host.SSHPort()))
+	if host.KeyPath != "" {
+		keyPath, err := config.ResolvePath(host.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+		sshArgs = append(sshArgs, "-i", keyPath)
+	}
+	target := host.Hostname
+	if host.User != "" {
+		target = host.User + "@" + host.Hostname
+	}
+	sshArgs = append(sshArgs, target, remoteCmd)
+
+	return exec.Command("ssh", sshArgs...), nil
+}
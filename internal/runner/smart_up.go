@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's smart_up.go file lets up skip pulling images that can't
+// have changed (smart_up, `bm up --smart`): images pinned by digest
+// (image@sha256:...) that are already on the host. Images referenced by tag
+// are always pulled, as only the registry knows whether the tag has moved.
+
+package runner
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pinnedImagesPresent reports whether every image the pull step would pull is
+// pinned by digest and already on the host, so that the pull can be skipped.
+// If that can't be determined, it is logged and false is returned: the images
+// are pulled as usual.
+func pinnedImagesPresent(step CommandStep) bool {
+	stack := step.Stack
+	images, err := pullStepImages(step)
+	if err != nil {
+		logger.Warn("Could not list the images of the pull step, pulling them", "stack_identifier", stack.Identifier(), "error", err)
+		return false
+	}
+	if len(images) == 0 {
+		return false
+	}
+	for _, image := range images {
+		if !strings.Contains(image, "@sha256:") {
+			logger.Debug("Image isn't pinned by digest, pulling", "stack_identifier", stack.Identifier(), "image", image)
+			return false
+		}
+	}
+
+	if err := inspectImages(stack, step.Command, images); err != nil {
+		logger.Debug("Pinned images aren't all present, pulling", "stack_identifier", stack.Identifier(), "error", err)
+		return false
+	}
+	logger.Info("Skipping pull of pinned images that are present",
+		"stack_identifier", stack.Identifier(),
+		"images", images)
+	return true
+}
+
+// pullStepImages returns the images the pull step would pull: those of the
+// services in its compose configuration, with its profiles and environment.
+func pullStepImages(step CommandStep) ([]string, error) {
+	// The step's arguments end with "pull"; the same arguments with "config"
+	// resolve the configuration it works on
+	if len(step.Args) == 0 || step.Args[len(step.Args)-1] != "pull" {
+		return nil, fmt.Errorf("not a pull step: %v", step.Args)
+	}
+	args := slices.Clone(step.Args)
+	args[len(args)-1] = "config"
+
+	stack := step.Stack
+	output, err := runStackQuery(stack, step.Env, fmt.Sprintf("compose config for stack %s", stack.Identifier()), args)
+	if err != nil {
+		return nil, err
+	}
+	output, err = trimComposeConfigOutput(stack, output)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(output, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse compose config for stack %s: %w", stack.Identifier(), err)
+	}
+	var images []string
+	for _, service := range doc.Services {
+		if service.Image != "" && !slices.Contains(images, service.Image) {
+			images = append(images, service.Image)
+		}
+	}
+	slices.Sort(images)
+	return images, nil
+}
+
+// inspectImages runs `image inspect` on the images on the stack's host as the
+// stack's user, which fails unless all of them are present.
+func inspectImages(stack discovery.Stack, runtime string, images []string) error {
+	args := runtimeArgs(append([]string{"image", "inspect", "--format", "{{.Id}}"}, images...)...)
+	cmdDesc := fmt.Sprintf("image check for stack %s", stack.Identifier())
+
+	var output []byte
+	var err error
+	if stack.IsRemote {
+		if stack.HostConfig == nil {
+			return fmt.Errorf("internal error: HostConfig is nil for remote stack %s", stack.Identifier())
+		}
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = util.QuoteArgForShell(arg)
+		}
+		remoteCmd := runAsCommand(stack.HostConfig.RunAsUserFor(stack.Name), runtime) + " " + strings.Join(quoted, " ")
+		output, err = runSSHOutputCommand(*stack.HostConfig, remoteCmd, cmdDesc)
+	} else {
+		output, err = exec.Command(runtime, args...).CombinedOutput()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", cmdDesc, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
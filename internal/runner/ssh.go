@@ -10,6 +10,8 @@ package runner
 import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/ssh"
 	"bucket-manager/internal/util"
 	"fmt"
 	"io"
@@ -17,6 +19,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	gossh "golang.org/x/crypto/ssh"
 )
@@ -28,6 +31,7 @@ import (
 //   - hostConfig: SSH host configuration for the remote connection
 //   - remoteCmdString: The command string to execute on the remote host
 //   - cmdDesc: Description of the command for error messages
+//   - timeout: Stops the command if it runs longer (zero means no limit)
 //   - cliMode: Whether to stream output directly to terminal or through channels
 //   - outChan: Channel for sending command output lines
 //   - errChan: Channel for sending execution errors
@@ -35,6 +39,7 @@ func runSSHCommand(
 	hostConfig config.SSHHost,
 	remoteCmdString string,
 	cmdDesc string,
+	timeout time.Duration,
 	cliMode bool,
 	outChan chan<- OutputLine,
 	errChan chan<- error,
@@ -57,6 +62,11 @@ func runSSHCommand(
 	}
 	defer session.Close()
 
+	if err := ssh.RequestAgentForwarding(session, hostConfig); err != nil {
+		// Only commands that need the agent fail without it, so carry on
+		logger.Warn("Running without SSH agent forwarding", "command", cmdDesc, "error", err)
+	}
+
 	stdoutPipe, err := session.StdoutPipe()
 	if err != nil {
 		errChan <- fmt.Errorf("failed to get ssh stdout pipe for %s: %w", cmdDesc, err)
@@ -87,6 +97,11 @@ func runSSHCommand(
 		errChan <- fmt.Errorf("failed to start remote command for %s: %w", cmdDesc, err)
 		return
 	}
+	// Servers that ignore signal requests still end the command once the session
+	// is closed (with SIGHUP when a pty was allocated)
+	timer := startOperationTimer(timeout,
+		func() { session.Signal(gossh.SIGTERM) },
+		func() { session.Close() })
 
 	var cmdErr error
 	if cliMode {
@@ -120,13 +135,17 @@ func runSSHCommand(
 		<-outputDone
 	}
 
+	if timer.finish() {
+		errChan <- fmt.Errorf("%s: %w", cmdDesc, &TimeoutError{Timeout: timeout})
+		return
+	}
 	if cmdErr != nil {
 		exitCode := -1
 		if exitErr, ok := cmdErr.(*gossh.ExitError); ok {
 			exitCode = exitErr.ExitStatus()
 		}
 		if exitCode != -1 {
-			errChan <- fmt.Errorf("%s exited with status %d: %w", cmdDesc, exitCode, cmdErr)
+			errChan <- fmt.Errorf("%s: %w", cmdDesc, &ExitError{Status: exitCode, Remote: true, Err: cmdErr})
 		} else {
 			errChan <- fmt.Errorf("%s failed: %w", cmdDesc, cmdErr)
 		}
@@ -134,8 +153,10 @@ func runSSHCommand(
 	}
 }
 
-// runSSHStatusCheck executes compose ps remotely via SSH and returns the combined output.
-func runSSHStatusCheck(stack discovery.Stack, runtime string, psArgs []string, cmdDesc string) ([]byte, error) {
+// runSSHStatusCheck executes a compose query (like ps) remotely via SSH, with
+// extra KEY=VALUE environment variables (if any) set, and returns the combined
+// output.
+func runSSHStatusCheck(stack discovery.Stack, runtime string, env []string, psArgs []string, cmdDesc string) ([]byte, error) {
 	if sshManager == nil {
 		return nil, fmt.Errorf("ssh manager not initialized for %s", cmdDesc)
 	}
@@ -158,7 +179,8 @@ func runSSHStatusCheck(stack discovery.Stack, runtime string, psArgs []string, c
 		return nil, fmt.Errorf("internal error: AbsoluteRemoteRoot is empty for remote stack %s", stack.Identifier())
 	}
 	remoteStackPath := filepath.Join(stack.AbsoluteRemoteRoot, stack.Path)
-	remoteCmdParts := []string{"cd", util.QuoteArgForShell(remoteStackPath), "&&", runtime}
+	runtimeCmd := runAsCommand(stack.HostConfig.RunAsUserFor(stack.Name), envCommand(env, runtime))
+	remoteCmdParts := []string{"cd", util.QuoteArgForShell(remoteStackPath), "&&", composeModTimeCommand(), "&&", runtimeCmd}
 	for _, arg := range psArgs {
 		remoteCmdParts = append(remoteCmdParts, util.QuoteArgForShell(arg))
 	}
@@ -171,3 +193,29 @@ func runSSHStatusCheck(stack discovery.Stack, runtime string, psArgs []string, c
 	}
 	return output, nil
 }
+
+// runSSHOutputCommand executes a short, non-interactive command on a remote host
+// and returns its combined output. It is used for host-level checks that don't
+// need streaming, such as disk usage queries.
+func runSSHOutputCommand(hostConfig config.SSHHost, remoteCmdString string, cmdDesc string) ([]byte, error) {
+	if sshManager == nil {
+		return nil, fmt.Errorf("ssh manager not initialized for %s", cmdDesc)
+	}
+
+	client, clientErr := sshManager.GetClient(hostConfig)
+	if clientErr != nil {
+		return nil, fmt.Errorf("failed to get ssh client for %s: %w", cmdDesc, clientErr)
+	}
+
+	session, sessionErr := client.NewSession()
+	if sessionErr != nil {
+		return nil, fmt.Errorf("failed to create ssh session for %s: %w", cmdDesc, sessionErr)
+	}
+	defer session.Close()
+
+	output, err := session.CombinedOutput(remoteCmdString)
+	if err != nil {
+		return output, fmt.Errorf("remote command failed for %s: %w", cmdDesc, err)
+	}
+	return output, nil
+}
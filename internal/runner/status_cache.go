@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's status_cache.go file reuses recently checked stack statuses
+// for status_cache_ttl, so that repeated checks of the same stack (moving
+// through the TUI list, polling the web API) don't each run `compose ps`, and
+// lets concurrent requests for the same statuses share a single check.
+// Running any step on a stack drops its cached status.
+
+package runner
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cachedStatus is a stack status and when its check started.
+type cachedStatus struct {
+	info    StackRuntimeInfo
+	checked time.Time
+}
+
+// statusCache holds the last status of each stack and when it was last
+// invalidated, keyed by stack identifier. generation counts invalidations.
+var statusCache = struct {
+	sync.Mutex
+	entries     map[string]cachedStatus
+	invalidated map[string]time.Time
+	generation  uint64
+}{entries: make(map[string]cachedStatus), invalidated: make(map[string]time.Time)}
+
+// statusChecks coalesces concurrent checks of the same stacks, e.g. when
+// several web clients poll the dashboard at once. See checkStatusesShared.
+var statusChecks singleflight.Group
+
+// statusCacheTTL returns the configured lifetime of cached statuses. It is
+// read for every status check, so the config is only loaded again once changed.
+func statusCacheTTL() time.Duration {
+	cfg, err := config.LoadConfigCached()
+	if err != nil {
+		logger.Warn("Could not load config to check status_cache_ttl", "error", err)
+	}
+	return cfg.GetStatusCacheTTL()
+}
+
+// cachedStackStatus returns the cached status of a stack if it was checked
+// less than ttl ago.
+func cachedStackStatus(stack discovery.Stack, ttl time.Duration) (StackRuntimeInfo, bool) {
+	if ttl <= 0 {
+		return StackRuntimeInfo{}, false
+	}
+	statusCache.Lock()
+	defer statusCache.Unlock()
+	entry, ok := statusCache.entries[stack.Identifier()]
+	if !ok || time.Since(entry.checked) >= ttl {
+		return StackRuntimeInfo{}, false
+	}
+	entry.info.Stack = stack
+	return entry.info, true
+}
+
+// cacheStackStatus stores a status whose check started at checked. Failed
+// checks aren't stored, so they are retried on the next check, and neither are
+// checks that overlapped a step run on the stack, as they may predate its changes.
+func cacheStackStatus(info StackRuntimeInfo, checked time.Time, ttl time.Duration) {
+	if ttl <= 0 || info.Error != nil || info.OverallStatus == StatusError {
+		return
+	}
+	identifier := info.Stack.Identifier()
+	statusCache.Lock()
+	defer statusCache.Unlock()
+	if !checked.After(statusCache.invalidated[identifier]) {
+		return
+	}
+	statusCache.entries[identifier] = cachedStatus{info: info, checked: checked}
+}
+
+// checkStatusesShared checks stacks with check, which returns their statuses
+// in the same order, and caches the results. Callers asking for the same
+// stacks while a check is running wait for its results instead of running
+// `compose ps` again. Checks started before a stack's status was invalidated
+// are not shared with later callers, as they may predate its changes.
+func checkStatusesShared(stacks []discovery.Stack, ttl time.Duration, check func([]discovery.Stack) []StackRuntimeInfo) []StackRuntimeInfo {
+	identifiers := make([]string, len(stacks))
+	for i, stack := range stacks {
+		identifiers[i] = stack.Identifier()
+	}
+	statusCache.Lock()
+	key := fmt.Sprintf("%d|%s", statusCache.generation, strings.Join(identifiers, ","))
+	statusCache.Unlock()
+
+	result, _, shared := statusChecks.Do(key, func() (any, error) {
+		checked := time.Now()
+		infos := check(stacks)
+		for _, info := range infos {
+			cacheStackStatus(info, checked, ttl)
+		}
+		return infos, nil
+	})
+	if shared {
+		logger.Debug("Shared a concurrent status check", "stacks", identifiers)
+	}
+
+	// The results are shared between callers: copy them, with each caller's own stacks
+	infos := slices.Clone(result.([]StackRuntimeInfo))
+	for i := range infos {
+		if i < len(stacks) {
+			infos[i].Stack = stacks[i]
+		}
+	}
+	return infos
+}
+
+// checkSingleStatus checks the status of the only stack in stacks, for checkStatusesShared.
+func checkSingleStatus(stacks []discovery.Stack) []StackRuntimeInfo {
+	return []StackRuntimeInfo{checkStackStatus(stacks[0])}
+}
+
+// InvalidateStatus drops the cached status of the stack with the given
+// identifier, so that its next check runs `compose ps` again. StreamCommand
+// calls it after each step, as up, down and refresh change the status.
+func InvalidateStatus(identifier string) {
+	statusCache.Lock()
+	defer statusCache.Unlock()
+	delete(statusCache.entries, identifier)
+	statusCache.invalidated[identifier] = time.Now()
+	statusCache.generation++
+}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's statuses.go file checks the status of many stacks at once,
+// running a single SSH command per remote host instead of one per stack.
+
+package runner
+
+import (
+	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statusMarker ends the output of each stack in a batched status check. It is
+// followed by the stack's index in the batch and the exit status of its
+// `compose ps`.
+const statusMarker = "--- bm status"
+
+// statusMarkerPattern matches the line printed after each stack's output.
+var statusMarkerPattern = regexp.MustCompile(`^` + statusMarker + ` (\d+) (\d+)$`)
+
+// GetStackStatuses checks the status of the given stacks, sending each result
+// on the returned channel as soon as it is known. The channel is closed once
+// every stack has been checked.
+//
+// Stacks checked within status_cache_ttl are answered from the cache. Local
+// stacks are checked concurrently as with GetStackStatus. The stacks of each
+// remote host are checked with one SSH command, which runs `compose ps` in each
+// stack directory in turn and prints a marker line after each, so a full status
+// scan costs one round trip per host rather than one per stack. Concurrent
+// calls for the same stacks share these checks (see checkStatusesShared).
+func GetStackStatuses(stacks []discovery.Stack) <-chan StackRuntimeInfo {
+	results := make(chan StackRuntimeInfo, len(stacks))
+	var wg sync.WaitGroup
+	ttl := statusCacheTTL()
+
+	var hostOrder []string
+	hostStacks := make(map[string][]discovery.Stack)
+	for _, stack := range stacks {
+		if info, ok := cachedStackStatus(stack, ttl); ok {
+			results <- info
+			continue
+		}
+		if !stack.IsRemote || stack.HostConfig == nil || stack.AbsoluteRemoteRoot == "" || stack.Quadlet != nil {
+			// Local stacks, quadlets, and remote stacks GetStackStatus will report an error for
+			wg.Add(1)
+			go func(s discovery.Stack) {
+				defer wg.Done()
+				results <- checkStatusesShared([]discovery.Stack{s}, ttl, checkSingleStatus)[0]
+			}(stack)
+			continue
+		}
+		if _, ok := hostStacks[stack.HostConfig.Name]; !ok {
+			hostOrder = append(hostOrder, stack.HostConfig.Name)
+		}
+		hostStacks[stack.HostConfig.Name] = append(hostStacks[stack.HostConfig.Name], stack)
+	}
+
+	for _, host := range hostOrder {
+		wg.Add(1)
+		go func(stacks []discovery.Stack) {
+			defer wg.Done()
+			check := getRemoteStackStatuses
+			if len(stacks) == 1 {
+				check = checkSingleStatus
+			}
+			for _, info := range checkStatusesShared(stacks, ttl, check) {
+				results <- info
+			}
+		}(hostStacks[host])
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// getRemoteStackStatuses checks the status of stacks on the same remote host
+// with a single SSH command, returning the results in the order of stacks.
+func getRemoteStackStatuses(stacks []discovery.Stack) []StackRuntimeInfo {
+	startTime := time.Now()
+	hostConfig := *stacks[0].HostConfig
+	batchDesc := fmt.Sprintf("status check for %d stacks on host %s", len(stacks), hostConfig.Name)
+	runtime, err := resolveRuntime(&hostConfig)
+	if err != nil {
+		// The host couldn't be reached to detect its runtime; don't dial it again
+		results := make([]StackRuntimeInfo, len(stacks))
+		for i, stack := range stacks {
+			results[i] = StackRuntimeInfo{
+				Stack:         stack,
+				OverallStatus: StatusError,
+				Error:         fmt.Errorf("failed to run status check for stack %s: %w", stack.Identifier(), err),
+			}
+		}
+		return results
+	}
+
+	var script strings.Builder
+	for i, stack := range stacks {
+		remoteStackPath := filepath.Join(stack.AbsoluteRemoteRoot, stack.Path)
+		cmdParts := []string{runAsCommand(stack.HostConfig.RunAsUserFor(stack.Name), runtime)}
+		for _, arg := range composeArgs(stack, "ps", "--format", "json", "-a") {
+			cmdParts = append(cmdParts, util.QuoteArgForShell(arg))
+		}
+		// The subshell keeps the cd to this stack; the leading newline of the
+		// marker ends any unterminated output line.
+		fmt.Fprintf(&script, "(cd %s && %s && %s) 2>&1; printf '\\n%s %d %%d\\n' $?\n",
+			util.QuoteArgForShell(remoteStackPath), composeModTimeCommand(), strings.Join(cmdParts, " "), statusMarker, i)
+	}
+
+	output, batchErr := runSSHOutputCommand(hostConfig, script.String(), batchDesc)
+
+	// Split the output into each stack's section, ended by its marker line
+	sections := make([][]byte, len(stacks))
+	exitStatuses := make([]int, len(stacks))
+	seen := make([]bool, len(stacks))
+	var current [][]byte
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		match := statusMarkerPattern.FindSubmatch(bytes.TrimSuffix(line, []byte("\r")))
+		if match == nil {
+			current = append(current, line)
+			continue
+		}
+		index, _ := strconv.Atoi(string(match[1]))
+		if index < len(stacks) {
+			sections[index] = bytes.Join(current, []byte("\n"))
+			exitStatuses[index], _ = strconv.Atoi(string(match[2]))
+			seen[index] = true
+		}
+		current = nil
+	}
+
+	results := make([]StackRuntimeInfo, len(stacks))
+	for i, stack := range stacks {
+		info := StackRuntimeInfo{Stack: stack, OverallStatus: StatusUnknown}
+		cmdDesc := fmt.Sprintf("status check for stack %s", stack.Identifier())
+		if !seen[i] {
+			// The batch was cut short before reaching this stack
+			err := batchErr
+			if err == nil {
+				err = fmt.Errorf("no status returned by %s", batchDesc)
+			}
+			info.OverallStatus = StatusError
+			info.Error = fmt.Errorf("failed to run %s: %w", cmdDesc, err)
+			results[i] = info
+			continue
+		}
+		var cmdErr error
+		if exitStatuses[i] != 0 {
+			// Worded like the error of a single status check over SSH
+			cmdErr = fmt.Errorf("remote command failed for %s: Process exited with status %d", cmdDesc, exitStatuses[i])
+		}
+		var section []byte
+		section, info.ComposeModTime = splitComposeModTime(sections[i])
+		results[i] = stackStatusFromOutput(info, cmdDesc, section, cmdErr, "")
+	}
+
+	logger.Debug("Batched status check completed",
+		"host", hostConfig.Name,
+		"stack_count", len(stacks),
+		"error", batchErr,
+		"duration", time.Since(startTime))
+	return results
+}
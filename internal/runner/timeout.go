@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's timeout.go file bounds how long a command may run: once a
+// step's timeout passes, its process is asked to stop and, failing that, killed.
+
+package runner
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// timeoutGracePeriod is how long a timed-out command gets to exit after being
+// asked to stop before it is killed.
+const timeoutGracePeriod = 10 * time.Second
+
+// TimeoutError reports that a command was stopped because it ran longer than
+// its step's timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("operation timed out after %s", e.Timeout)
+}
+
+// operationTimer stops a running command once its timeout has passed.
+type operationTimer struct {
+	timer    *time.Timer
+	done     chan struct{}
+	timedOut atomic.Bool
+}
+
+// startOperationTimer calls stop once timeout has passed, and kill if the
+// command still hasn't finished timeoutGracePeriod later. A zero timeout never fires.
+func startOperationTimer(timeout time.Duration, stop, kill func()) *operationTimer {
+	t := &operationTimer{done: make(chan struct{})}
+	if timeout > 0 {
+		t.timer = time.AfterFunc(timeout, func() {
+			t.timedOut.Store(true)
+			stop()
+			select {
+			case <-t.done:
+			case <-time.After(timeoutGracePeriod):
+				kill()
+			}
+		})
+	}
+	return t
+}
+
+// finish stops the timer once the command has exited, and reports whether the
+// command was stopped because it timed out.
+func (t *operationTimer) finish() bool {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	close(t.done)
+	return t.timedOut.Load()
+}
@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package runner's version.go file detects the container runtime and compose
+// versions of a host, so behavior differences between hosts can be explained
+// and flags missing from older compose releases can be left out.
+
+package runner
+
+import (
+	"bucket-manager/internal/logger"
+	"bucket-manager/internal/util"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ComposeVersion describes the container runtime and compose implementation of a host.
+type ComposeVersion struct {
+	Runtime        string // Container runtime, "podman" or "docker"
+	RuntimeVersion string // e.g. "4.9.3"; empty if unknown
+	Provider       string // Compose implementation run by podman, e.g. "podman-compose"; empty for docker's plugin or if unknown
+	Version        string // Compose version without a leading "v", e.g. "1.0.6"; empty if unknown
+	Error          error
+}
+
+// String formats the versions as e.g. "podman 4.9.3 / compose 1.0.6 (podman-compose)".
+func (v ComposeVersion) String() string {
+	runtimeVersion, composeVersion := v.RuntimeVersion, v.Version
+	if runtimeVersion == "" {
+		runtimeVersion = "?"
+	}
+	if composeVersion == "" {
+		composeVersion = "?"
+	}
+	s := fmt.Sprintf("%s %s / compose %s", v.Runtime, runtimeVersion, composeVersion)
+	if v.Provider != "" {
+		s += " (" + v.Provider + ")"
+	}
+	return s
+}
+
+// AtLeast reports whether the compose version is known and not older than minimum
+// (dotted numbers, e.g. "2.15").
+func (v ComposeVersion) AtLeast(minimum string) bool {
+	return v.Version != "" && compareVersions(v.Version, minimum) >= 0
+}
+
+// composeVersions caches successful version checks per host, runtime and user.
+var composeVersions sync.Map
+
+// composeVersionSeparator separates the runtime and compose version output.
+const composeVersionSeparator = "--- compose"
+
+// GetComposeVersion returns the container runtime and compose versions of the
+// target host, running `<runtime> version` and `<runtime> compose version
+// --format json` locally or over SSH. Successful results are cached for the
+// lifetime of the process.
+func GetComposeVersion(target HostTarget) ComposeVersion {
+	runtime := ContainerRuntimeFor(target.HostConfig)
+	key := "local\x00" + runtime
+	runtimeCmd := runtime
+	if target.IsRemote {
+		if target.HostConfig == nil {
+			return ComposeVersion{Runtime: runtime, Error: fmt.Errorf("internal error: HostConfig is nil for remote host %s", target.ServerName)}
+		}
+		user := target.HostConfig.RunAsUserFor("")
+		key = target.HostConfig.Name + "\x00" + runtime + "\x00" + user
+		runtimeCmd = runAsCommand(user, runtime)
+	}
+	if cached, ok := composeVersions.Load(key); ok {
+		return cached.(ComposeVersion)
+	}
+
+	startTime := time.Now()
+	cmdDesc := fmt.Sprintf("compose version check for host %s", target.ServerName)
+	// The compose_global_args may select another engine, e.g. a podman connection
+	for _, arg := range composeGlobalArgs() {
+		runtimeCmd += " " + util.QuoteArgForShell(arg)
+	}
+	script := fmt.Sprintf("%[1]s version --format '{{.Client.Version}}' 2>&1; echo '%[2]s'; %[1]s compose version --format json 2>&1",
+		runtimeCmd, composeVersionSeparator)
+
+	var output []byte
+	var err error
+	if target.IsRemote {
+		output, err = runSSHOutputCommand(*target.HostConfig, script, cmdDesc)
+	} else {
+		output, err = exec.Command("sh", "-c", script).CombinedOutput()
+	}
+
+	version := parseComposeVersion(runtime, output)
+	if version.Version == "" && version.RuntimeVersion == "" {
+		if err == nil {
+			err = fmt.Errorf("no version information returned")
+		}
+		version.Error = fmt.Errorf("failed to run %s: %w", cmdDesc, err)
+		logger.Warn("Compose version check failed",
+			"server_name", target.ServerName,
+			"error", version.Error,
+			"duration", time.Since(startTime))
+		return version
+	}
+
+	logger.Debug("Compose version check completed",
+		"server_name", target.ServerName,
+		"version", version.String(),
+		"duration", time.Since(startTime))
+	composeVersions.Store(key, version)
+	return version
+}
+
+var (
+	// versionPattern matches a dotted version number, with an optional leading "v".
+	versionPattern = regexp.MustCompile(`\bv?(\d+\.\d+(?:\.\d+)?)`)
+	// composeProviderPattern matches the notice podman prints before running an
+	// external compose provider, capturing the provider's path.
+	composeProviderPattern = regexp.MustCompile(`compose provider "([^"]+)"`)
+)
+
+// parseComposeVersion parses the output of the script run by GetComposeVersion.
+func parseComposeVersion(runtime string, output []byte) ComposeVersion {
+	version := ComposeVersion{Runtime: runtime}
+	runtimePart, composePart, _ := bytes.Cut(output, []byte(composeVersionSeparator+"\n"))
+
+	if firstLine, _, _ := strings.Cut(strings.TrimSpace(string(runtimePart)), "\n"); firstLine != "" {
+		if match := versionPattern.FindStringSubmatch(firstLine); match != nil && strings.HasPrefix(strings.TrimPrefix(firstLine, "v"), match[1]) {
+			version.RuntimeVersion = match[1]
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(composePart))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := composeProviderPattern.FindStringSubmatch(line); match != nil {
+			version.Provider = filepath.Base(match[1])
+			continue
+		}
+		var parsed struct {
+			Version string `json:"version"`
+		}
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &parsed) == nil && parsed.Version != "" {
+			version.Version = strings.TrimPrefix(parsed.Version, "v")
+			continue
+		}
+		// Providers without JSON output (e.g. docker-compose v1) print
+		// "docker-compose version 1.29.2, build 5becea4c"
+		if version.Version == "" && strings.Contains(strings.ToLower(line), "version") {
+			if match := versionPattern.FindStringSubmatch(line); match != nil {
+				version.Version = match[1]
+			}
+		}
+	}
+	return version
+}
+
+// compareVersions compares two dotted version numbers numerically, treating
+// missing components as zero. It returns -1, 0 or 1.
+func compareVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(aParts), len(bParts)) {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package ssh's diagnose.go file tells connection failures apart (network,
+// algorithm negotiation, host key or authentication), tests a host's connection without going
+// through the connection cache, for `bm config hosts test`, and probes whether
+// hosts can be reached at all.
+
+package ssh
+
+import (
+	"bucket-manager/internal/config"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Errors wrapped by connection failures, by cause.
+var (
+	ErrNetwork   = errors.New("network error")                // The host couldn't be reached
+	ErrAlgorithm = errors.New("no common SSH algorithm")      // The host only supports algorithms that aren't offered
+	ErrHostKey   = errors.New("host key verification failed") // The host key is unknown or doesn't match known_hosts
+	ErrAuth      = errors.New("authentication failed")        // The host refused every authentication method
+)
+
+// classifyDialError wraps an error of ssh.Dial with ErrNetwork, ErrAlgorithm,
+// ErrHostKey or ErrAuth, with a hint on what to check. Other errors are returned unchanged.
+func classifyDialError(err error) error {
+	var keyErr *knownhosts.KeyError
+	var revokedErr *knownhosts.RevokedError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &keyErr) && len(keyErr.Want) == 0:
+		return fmt.Errorf("%w: host key is not in ~/.ssh/known_hosts, connect once with ssh to add it: %w", ErrHostKey, err)
+	case errors.As(err, &keyErr):
+		return fmt.Errorf("%w: host key does not match ~/.ssh/known_hosts (line %d): %w", ErrHostKey, keyErr.Want[0].Line, err)
+	case errors.As(err, &revokedErr):
+		return fmt.Errorf("%w: host key is revoked: %w", ErrHostKey, err)
+	case strings.Contains(err.Error(), "no common algorithm"):
+		return fmt.Errorf("%w: a legacy host may need its algorithms in ssh_algorithms: %w", ErrAlgorithm, err)
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		return fmt.Errorf("%w: check the user, key, agent or password: %w", ErrAuth, err)
+	case errors.As(err, &netErr):
+		return fmt.Errorf("%w: check the hostname, port and that sshd is running: %w", ErrNetwork, err)
+	}
+	return err
+}
+
+// ConnectionTest is the outcome of TestConnection.
+type ConnectionTest struct {
+	Client     *ssh.Client   // The new connection; the caller must close it. Nil on failure.
+	AuthMethod string        // Authentication method that succeeded: "key", "agent" or "password"
+	Latency    time.Duration // Time taken to connect and authenticate
+}
+
+// TestConnection opens a new connection to the host, bypassing the cache so
+// that connecting and authenticating are exercised, and reports how it went.
+func (m *Manager) TestConnection(hostConfig config.SSHHost) (ConnectionTest, error) {
+	var result ConnectionTest
+	start := time.Now()
+	client, err := m.dial(hostConfig, func(method string) {
+		// Methods are tried in turn, so the last one tried is the one that succeeded
+		result.AuthMethod = method
+	})
+	result.Latency = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+	result.Client = client
+	return result, nil
+}
+
+// ProbeHost checks quickly whether the host's SSH port accepts connections,
+// waiting at most timeout, so that an unreachable host can be skipped without
+// waiting for the full SSH dial timeout. A host with a cached connection isn't
+// probed: GetClient checks that connection itself. Failures wrap ErrNetwork.
+func (m *Manager) ProbeHost(hostConfig config.SSHHost, timeout time.Duration) error {
+	m.mu.Lock()
+	_, cached := m.clients[hostConfig.Name]
+	m.mu.Unlock()
+	if cached {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", hostConfig.Address(), timeout)
+	if err != nil {
+		return classifyDialError(err)
+	}
+	conn.Close()
+	return nil
+}
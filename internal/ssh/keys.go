@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package ssh's keys.go file loads the private keys of hosts, decrypting
+// passphrase-protected ones with the passphrase from the host's config or, if
+// it doesn't give one, from the user. Decrypted keys are kept for the rest of
+// the session, so each key's passphrase is needed at most once.
+
+package ssh
+
+import (
+	"bucket-manager/internal/config"
+	"bucket-manager/internal/logger"
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxPassphraseAttempts is how many times the passphrase of a key is asked
+// for before giving up on it, like ssh's NumberOfPasswordPrompts.
+const maxPassphraseAttempts = 3
+
+// PassphrasePrompt asks the user for the passphrase of the encrypted key at
+// keyPath, used by host; retry is set if the previous passphrase was wrong. It
+// returns an error if the user cancels.
+type PassphrasePrompt func(host config.SSHHost, keyPath string, retry bool) (string, error)
+
+// errNoPassphrase is why an encrypted key whose passphrase is neither in the
+// config nor can be asked for isn't used.
+var errNoPassphrase = errors.New("it is passphrase-protected and no passphrase can be asked for; set key_passphrase_command or add the key to the SSH agent")
+
+// errKeyInAgent is why an encrypted key that the SSH agent holds isn't
+// decrypted: the agent signs with it instead, without asking for its passphrase.
+var errKeyInAgent = errors.New("the SSH agent holds the key")
+
+// keyDecryptError is returned for an encrypted key that couldn't be decrypted,
+// after which the host's other authentication methods are still tried.
+type keyDecryptError struct {
+	keyPath string
+	err     error
+}
+
+func (e *keyDecryptError) Error() string {
+	return fmt.Sprintf("could not decrypt private key file %s: %v", e.keyPath, e.err)
+}
+
+func (e *keyDecryptError) Unwrap() error {
+	return e.err
+}
+
+var (
+	passphrasePromptMu sync.Mutex
+	passphrasePrompt   PassphrasePrompt
+
+	// keySigners caches the signer of each key by its resolved path, and
+	// keyLoads coalesces concurrent loads of the same key, so that connecting
+	// to several hosts with one key asks for its passphrase once.
+	keySigners = struct {
+		sync.Mutex
+		byPath map[string]ssh.Signer
+	}{byPath: make(map[string]ssh.Signer)}
+	keyLoads singleflight.Group
+)
+
+// SetPassphrasePrompt sets how the passphrases of encrypted keys whose hosts
+// don't configure one are asked for. Without a prompt, such keys are skipped.
+func SetPassphrasePrompt(prompt PassphrasePrompt) {
+	passphrasePromptMu.Lock()
+	passphrasePrompt = prompt
+	passphrasePromptMu.Unlock()
+}
+
+// askPassphrase asks for the passphrase of keyPath with the prompt set by
+// SetPassphrasePrompt. Prompts are asked one at a time.
+func askPassphrase(host config.SSHHost, keyPath string, retry bool) (string, error) {
+	passphrasePromptMu.Lock()
+	defer passphrasePromptMu.Unlock()
+	if passphrasePrompt == nil {
+		return "", errNoPassphrase
+	}
+	return passphrasePrompt(host, keyPath, retry)
+}
+
+// loadKeySigner returns the signer of the private key at keyPath, used by
+// host. An encrypted key is decrypted with the host's key_passphrase or
+// key_passphrase_command, or else with a passphrase asked for interactively.
+func loadKeySigner(host config.SSHHost, keyPath string) (ssh.Signer, error) {
+	keySigners.Lock()
+	signer, ok := keySigners.byPath[keyPath]
+	keySigners.Unlock()
+	if ok {
+		return signer, nil
+	}
+
+	result, err, _ := keyLoads.Do(keyPath, func() (any, error) {
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file %s: %w", keyPath, err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+		var missingErr *ssh.PassphraseMissingError
+		if errors.As(err, &missingErr) {
+			if missingErr.PublicKey != nil && agentHasKey(missingErr.PublicKey) {
+				return nil, &keyDecryptError{keyPath, errKeyInAgent}
+			}
+			signer, err = decryptKey(host, keyPath, key)
+		} else if err != nil {
+			err = fmt.Errorf("failed to parse private key file %s: %w", keyPath, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		keySigners.Lock()
+		keySigners.byPath[keyPath] = signer
+		keySigners.Unlock()
+		return signer, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(ssh.Signer), nil
+}
+
+// decryptKey decrypts the encrypted private key read from keyPath. A
+// passphrase from the config is tried once; one asked for is asked again if
+// it is wrong, up to maxPassphraseAttempts times.
+func decryptKey(host config.SSHHost, keyPath string, key []byte) (ssh.Signer, error) {
+	if host.HasKeyPassphrase() {
+		passphrase, err := host.ResolveKeyPassphrase()
+		if err != nil {
+			return nil, &keyDecryptError{keyPath, err}
+		}
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+		if err != nil {
+			return nil, &keyDecryptError{keyPath, fmt.Errorf("with the configured passphrase: %w", err)}
+		}
+		return signer, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		passphrase, err := askPassphrase(host, keyPath, attempt > 1)
+		if err != nil {
+			return nil, &keyDecryptError{keyPath, err}
+		}
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+		if err == nil {
+			logger.Info("Decrypted private key", "host_name", host.Name, "key_path", keyPath)
+			return signer, nil
+		}
+		if !errors.Is(err, x509.IncorrectPasswordError) || attempt == maxPassphraseAttempts {
+			return nil, &keyDecryptError{keyPath, err}
+		}
+		logger.Warn("Wrong passphrase for private key", "host_name", host.Name, "key_path", keyPath)
+	}
+}
+
+// agentHasKey reports whether the SSH agent at SSH_AUTH_SOCK holds key.
+func agentHasKey(key ssh.PublicKey) bool {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return false
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(keys, func(k *agent.Key) bool {
+		return bytes.Equal(k.Marshal(), key.Marshal())
+	})
+}
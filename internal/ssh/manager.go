@@ -9,6 +9,7 @@ package ssh
 import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/logger"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -47,28 +48,76 @@ func (m *Manager) GetClient(hostConfig config.SSHHost) (*ssh.Client, error) {
 
 	m.mu.Lock()
 	client, found := m.clients[hostConfig.Name]
+	m.mu.Unlock()
 	if found {
-		// Send keepalive to check if cached client is still valid (not foolproof).
-		// This helps detect stale connections without a full reconnect attempt
-		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		// Ping outside the lock: a half-open connection can take up to keepaliveTimeout
+		// to detect, and other hosts shouldn't wait for it.
+		err := checkAlive(client)
 		if err == nil {
 			logger.Debug("Reusing existing SSH connection", "host_name", hostConfig.Name)
-			m.mu.Unlock()
 			return client, nil
 		}
-		logger.Debug("Existing SSH connection failed keepalive, reconnecting",
+		logger.Info("Cached SSH connection is dead, reconnecting",
 			"host_name", hostConfig.Name, "error", err)
-		if err := client.Close(); err != nil {
-			logger.Warn("Error closing existing SSH client during reconnect",
+		m.evict(hostConfig.Name, client)
+	}
+
+	logger.Debug("Establishing new SSH connection", "host_name", hostConfig.Name)
+
+	newClient, err := m.dial(hostConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	// Double-check if another goroutine created a client while we were dialing
+	existingClient, found := m.clients[hostConfig.Name]
+	if found {
+		m.mu.Unlock()
+		if err := newClient.Close(); err != nil {
+			logger.Errorf("Error closing redundant SSH client for %s: %v", hostConfig.Name, err)
+		}
+		return existingClient, nil
+	}
+	m.clients[hostConfig.Name] = newClient
+	m.mu.Unlock()
+
+	if hostConfig.ForwardAgent {
+		if err := forwardToLocalAgent(newClient); err != nil {
+			logger.Warn("SSH agent forwarding is unavailable",
 				"host_name", hostConfig.Name, "error", err)
 		}
-		delete(m.clients, hostConfig.Name)
 	}
-	m.mu.Unlock() // Unlock before potentially long Dial operation
 
-	logger.Debug("Establishing new SSH connection", "host_name", hostConfig.Name)
+	return newClient, nil
+}
+
+// applySSHAlgorithms sets the key exchange, cipher and host key algorithms
+// configured for the host (see config.SSHAlgorithms), leaving the client's
+// defaults for the lists that aren't set.
+func applySSHAlgorithms(sshConfig *ssh.ClientConfig, hostConfig config.SSHHost) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Warn("Could not load config to check ssh_algorithms", "host_name", hostConfig.Name, "error", err)
+	}
+	algorithms := cfg.SSHAlgorithmsFor(hostConfig)
+	sshConfig.KeyExchanges = algorithms.KexAlgorithms
+	sshConfig.Ciphers = algorithms.Ciphers
+	sshConfig.HostKeyAlgorithms = algorithms.HostKeyAlgorithms
+	if len(algorithms.KexAlgorithms) > 0 || len(algorithms.Ciphers) > 0 || len(algorithms.HostKeyAlgorithms) > 0 {
+		logger.Debug("Using configured SSH algorithms",
+			"host_name", hostConfig.Name,
+			"kex_algorithms", algorithms.KexAlgorithms,
+			"ciphers", algorithms.Ciphers,
+			"host_key_algorithms", algorithms.HostKeyAlgorithms)
+	}
+}
 
-	authMethods, err := m.getAuthMethods(hostConfig)
+// dial opens a new SSH connection to the host, without caching it. onAuthTry,
+// if not nil, is called with the name of each authentication method ("key",
+// "agent" or "password") as it is tried.
+func (m *Manager) dial(hostConfig config.SSHHost, onAuthTry func(method string)) (*ssh.Client, error) {
+	authMethods, err := m.getAuthMethods(hostConfig, onAuthTry)
 	if err != nil {
 		logger.Error("Failed to prepare SSH auth methods",
 			"host_name", hostConfig.Name, "error", err)
@@ -78,7 +127,7 @@ func (m *Manager) GetClient(hostConfig config.SSHHost) (*ssh.Client, error) {
 		logger.Error("No suitable SSH authentication method found",
 			"host_name", hostConfig.Name,
 			"has_key_path", hostConfig.KeyPath != "",
-			"has_password", hostConfig.Password != "")
+			"has_password", hostConfig.HasPassword())
 		return nil, fmt.Errorf("no suitable authentication method found for %s (key, agent, or password required)", hostConfig.Name)
 	}
 
@@ -86,11 +135,14 @@ func (m *Manager) GetClient(hostConfig config.SSHHost) (*ssh.Client, error) {
 		"host_name", hostConfig.Name,
 		"method_count", len(authMethods))
 
+	// There is no compression option: golang.org/x/crypto/ssh only negotiates
+	// "none", so streamed output can't be compressed on slow links.
 	sshConfig := &ssh.ClientConfig{
 		User:    hostConfig.User,
 		Auth:    authMethods,
-		Timeout: 10 * time.Second,
+		Timeout: DialTimeout,
 	}
+	applySSHAlgorithms(sshConfig, hostConfig)
 	// Add proper host key verification
 	hostKeyCallback, khErr := createHostKeyCallback()
 	if khErr != nil {
@@ -104,11 +156,7 @@ func (m *Manager) GetClient(hostConfig config.SSHHost) (*ssh.Client, error) {
 		sshConfig.HostKeyCallback = hostKeyCallback
 	}
 
-	port := hostConfig.Port
-	if port == 0 {
-		port = 22
-	}
-	addr := fmt.Sprintf("%s:%d", hostConfig.Hostname, port)
+	addr := hostConfig.Address()
 
 	logger.Debug("Attempting SSH connection",
 		"host_name", hostConfig.Name,
@@ -121,35 +169,103 @@ func (m *Manager) GetClient(hostConfig config.SSHHost) (*ssh.Client, error) {
 			"host_name", hostConfig.Name,
 			"address", addr,
 			"error", err)
-		return nil, fmt.Errorf("failed to dial ssh host %s (%s): %w", hostConfig.Name, addr, err)
+		return nil, fmt.Errorf("failed to dial ssh host %s (%s): %w", hostConfig.Name, addr, classifyDialError(err))
 	}
 
 	logger.Info("SSH connection established successfully",
 		"host_name", hostConfig.Name,
 		"address", addr)
+	return newClient, nil
+}
+
+// forwardToLocalAgent makes client serve agent channels opened by the remote
+// side from the local agent at SSH_AUTH_SOCK. Sessions still have to ask for
+// forwarding with RequestAgentForwarding.
+func forwardToLocalAgent(client *ssh.Client) error {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return fmt.Errorf("SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SSH agent: %w", err)
+	}
+	if err := agent.ForwardToAgent(client, agent.NewClient(conn)); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to set up agent forwarding: %w", err)
+	}
+	return nil
+}
+
+// RequestAgentForwarding asks for the local SSH agent to be forwarded to
+// session if the host has ForwardAgent enabled. It must be called before the
+// session's command is started.
+func RequestAgentForwarding(session *ssh.Session, hostConfig config.SSHHost) error {
+	if !hostConfig.ForwardAgent {
+		return nil
+	}
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		return fmt.Errorf("failed to request agent forwarding for %s: %w", hostConfig.Name, err)
+	}
+	return nil
+}
 
+// DialTimeout bounds how long establishing an SSH connection to a host may take.
+const DialTimeout = 10 * time.Second
+
+// keepaliveTimeout bounds how long a health check of a cached client may take.
+// Without it, a connection whose peer vanished (reboot, network change) could
+// block until the TCP stack gives up.
+const keepaliveTimeout = 5 * time.Second
+
+// checkAlive sends a keepalive request over client and waits for the reply.
+func checkAlive(client *ssh.Client) error {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(keepaliveTimeout):
+		return fmt.Errorf("keepalive timed out after %s", keepaliveTimeout)
+	}
+}
+
+// evict removes client from the cache if it is still the cached client for
+// hostName, and closes it. Closing also unblocks a keepalive that timed out.
+func (m *Manager) evict(hostName string, client *ssh.Client) {
 	m.mu.Lock()
-	// Double-check if another goroutine created a client while we were dialing
-	existingClient, found := m.clients[hostConfig.Name]
-	if found {
-		m.mu.Unlock()
-		if err := newClient.Close(); err != nil {
-			logger.Errorf("Error closing redundant SSH client for %s: %v", hostConfig.Name, err)
-		}
-		return existingClient, nil
+	if m.clients[hostName] == client {
+		delete(m.clients, hostName)
 	}
-	m.clients[hostConfig.Name] = newClient
 	m.mu.Unlock()
 
-	return newClient, nil
+	if err := client.Close(); err != nil {
+		logger.Debug("Error closing dead SSH client", "host_name", hostName, "error", err)
+	}
 }
 
 // getAuthMethods prepares authentication methods for SSH connection based on the host configuration.
 // It tries multiple authentication methods in this order:
-// 1. SSH key authentication if KeyPath is provided
+// 1. SSH key authentication if KeyPath is provided; an encrypted key is
+// decrypted by loadKeySigner, or skipped if it can't be
 // 2. SSH agent authentication if SSH_AUTH_SOCK environment variable is available
-// 3. Password authentication if Password is provided in the host config
-func (m *Manager) getAuthMethods(hostConfig config.SSHHost) ([]ssh.AuthMethod, error) {
+// 3. Password authentication if the host config has a password, a password
+// file or a password command; files and commands are only read or run if the
+// server asks for a password
+//
+// onAuthTry, if not nil, is called with "key", "agent" or "password" when the
+// method is tried.
+func (m *Manager) getAuthMethods(hostConfig config.SSHHost, onAuthTry func(method string)) ([]ssh.AuthMethod, error) {
+	tried := func(method string) {
+		if onAuthTry != nil {
+			onAuthTry(method)
+		}
+	}
+
 	var methods []ssh.AuthMethod
 
 	if hostConfig.KeyPath != "" {
@@ -160,23 +276,23 @@ func (m *Manager) getAuthMethods(hostConfig config.SSHHost) ([]ssh.AuthMethod, e
 			keyPath = hostConfig.KeyPath
 		}
 
-		key, err := os.ReadFile(keyPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read private key file %s: %w", keyPath, err)
-		}
-
-		signer, err := ssh.ParsePrivateKey(key)
-		if err != nil {
-			if _, ok := err.(*ssh.PassphraseMissingError); ok {
-				// Log a warning that encrypted keys are not yet supported by this method.
-				// We won't add this key as an auth method in this case.
-				logger.Warnf("Private key file %s is encrypted and passphrase prompting is not yet supported here. Skipping key.", keyPath)
-				// Continue to check other auth methods (agent, password)
-			} else {
-				return nil, fmt.Errorf("failed to parse private key file %s: %w", keyPath, err)
-			}
-		} else {
-			methods = append(methods, ssh.PublicKeys(signer))
+		signer, err := loadKeySigner(hostConfig, keyPath)
+		var decryptErr *keyDecryptError
+		switch {
+		case errors.Is(err, errKeyInAgent):
+			logger.Debug("Using the SSH agent for encrypted private key",
+				"host_name", hostConfig.Name, "key_path", keyPath)
+		case errors.As(err, &decryptErr):
+			// Continue to check other auth methods (agent, password)
+			logger.Warn("Skipping encrypted private key",
+				"host_name", hostConfig.Name, "error", err)
+		case err != nil:
+			return nil, err
+		default:
+			methods = append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				tried("key")
+				return []ssh.Signer{signer}, nil
+			}))
 		}
 	}
 
@@ -184,13 +300,24 @@ func (m *Manager) getAuthMethods(hostConfig config.SSHHost) ([]ssh.AuthMethod, e
 		conn, err := net.Dial("unix", socket)
 		if err == nil { // Silently ignore agent errors if key/password might work
 			agentClient := agent.NewClient(conn)
-			methods = append(methods, ssh.PublicKeysCallback(agentClient.Signers))
+			methods = append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				tried("agent")
+				return agentClient.Signers()
+			}))
 			// Note: We don't close the agent connection here, it's managed by the agent client lifecycle
 		}
 	}
 
-	if hostConfig.Password != "" {
-		methods = append(methods, ssh.Password(hostConfig.Password))
+	if hostConfig.HasPassword() {
+		methods = append(methods, ssh.PasswordCallback(func() (string, error) {
+			tried("password")
+			password, err := hostConfig.ResolvePassword()
+			if err != nil {
+				logger.Error("Failed to get SSH password",
+					"host_name", hostConfig.Name, "error", err)
+			}
+			return password, err
+		}))
 	}
 
 	return methods, nil
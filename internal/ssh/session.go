@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package ssh's session.go file runs commands on SSH sessions without letting
+// an unresponsive remote command block the caller forever.
+
+package ssh
+
+import (
+	"context"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// CombinedOutputContext runs command on session like session.CombinedOutput,
+// but gives up once ctx is done: the remote command is sent SIGKILL, the
+// session is closed and ctx's error is returned without waiting for the
+// command, which may be stuck (e.g. on a hung mount) and never exit.
+func CombinedOutputContext(ctx context.Context, session *ssh.Session, command string) ([]byte, error) {
+	type result struct {
+		output []byte
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, err := session.CombinedOutput(command)
+		done <- result{output: output, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.output, r.err
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGKILL) // Not every server supports signals
+		session.Close()
+		return nil, ctx.Err()
+	}
+}
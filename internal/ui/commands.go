@@ -11,9 +11,17 @@ package ui
 import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
 	"bucket-manager/internal/runner"
+	"bucket-manager/internal/util"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
 	"slices"
+	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -27,9 +35,23 @@ import (
 // It handles both local and remote stack discovery in the background.
 func findStacksCmd() tea.Cmd {
 	return func() tea.Msg {
-		stackChan, errorChan, doneChan := discovery.FindStacks()
+		// Note the hosts being searched, so their reachability can be recorded
+		hosts := []string{"local"}
+		if cfg, err := config.LoadConfig(); err == nil {
+			for _, h := range cfg.SSHHosts {
+				if !h.Disabled {
+					hosts = append(hosts, h.Name)
+				}
+			}
+		}
+		stackChan, errorChan, doneChan := discovery.FindStacks(discovery.RootOverrides{})
 
+		// Stacks and errors are all forwarded before discoveryFinishedMsg, so the
+		// finished handler sees every host's errors.
+		var forwarded sync.WaitGroup
+		forwarded.Add(2)
 		go func() {
+			defer forwarded.Done()
 			for s := range stackChan {
 				if BubbleProgram != nil {
 					BubbleProgram.Send(stackDiscoveredMsg{stack: s})
@@ -38,6 +60,7 @@ func findStacksCmd() tea.Cmd {
 		}()
 
 		go func() {
+			defer forwarded.Done()
 			for e := range errorChan {
 				if BubbleProgram != nil {
 					BubbleProgram.Send(discoveryErrorMsg{err: e})
@@ -47,8 +70,9 @@ func findStacksCmd() tea.Cmd {
 
 		go func() {
 			<-doneChan
+			forwarded.Wait()
 			if BubbleProgram != nil {
-				BubbleProgram.Send(discoveryFinishedMsg{})
+				BubbleProgram.Send(discoveryFinishedMsg{hosts: hosts})
 			}
 		}()
 
@@ -59,12 +83,75 @@ func findStacksCmd() tea.Cmd {
 func loadSshConfigCmd() tea.Cmd {
 	return func() tea.Msg {
 		cfg, err := config.LoadConfig()
-		return sshConfigLoadedMsg{hosts: cfg.SSHHosts, Err: err}
+		return sshConfigLoadedMsg{hosts: cfg.SSHHosts, diskWarnFreePercent: cfg.GetDiskWarnFreePercent(), Err: err}
+	}
+}
+
+// loadHostPickerCmd lists the hosts offered by the "jump to host" picker.
+func loadHostPickerCmd() tea.Cmd {
+	return func() tea.Msg {
+		hosts := []string{"local"}
+		cfg, err := config.LoadConfig()
+		for _, h := range cfg.SSHHosts {
+			if !h.Disabled {
+				hosts = append(hosts, h.Name)
+			}
+		}
+		return hostPickerLoadedMsg{hosts: hosts, err: err}
+	}
+}
+
+// saveGlobalConfigCmd stores the global settings in the config file, keeping
+// everything else (hosts, keybindings, ...) as it is. Settings that make the
+// config invalid, such as a missing local_root directory, are rejected.
+func saveGlobalConfigCmd(settings globalSettings) tea.Cmd {
+	return func() tea.Msg {
+		unlock, err := config.LockConfig()
+		if err != nil {
+			return globalConfigSavedMsg{fmt.Errorf("failed to lock config before saving settings: %w", err)}
+		}
+		defer unlock()
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return globalConfigSavedMsg{fmt.Errorf("failed to load config before saving settings: %w", err)}
+		}
+		settings.applyTo(&cfg)
+		for _, issue := range cfg.Validate() {
+			if !issue.Warning && issue.Host == "" {
+				return globalConfigSavedMsg{errors.New(issue.Message)}
+			}
+		}
+		if err := config.SaveConfig(cfg); err != nil {
+			return globalConfigSavedMsg{fmt.Errorf("failed to save settings: %w", err)}
+		}
+		return globalConfigSavedMsg{nil}
+	}
+}
+
+// savePinnedStacksCmd stores the identifiers of the pinned stacks in the
+// config file, keeping everything else as it is.
+func savePinnedStacksCmd(identifiers []string) tea.Cmd {
+	return func() tea.Msg {
+		err := config.UpdateConfig(func(cfg *config.Config) error {
+			cfg.PinnedStacks = identifiers
+			return nil
+		})
+		if err != nil {
+			return pinnedStacksSavedMsg{fmt.Errorf("failed to save pinned stacks: %w", err)}
+		}
+		return pinnedStacksSavedMsg{nil}
 	}
 }
 
 func saveEditedSshHostCmd(originalName string, editedHost config.SSHHost) tea.Cmd {
 	return func() tea.Msg {
+		unlock, err := config.LockConfig()
+		if err != nil {
+			return sshHostEditedMsg{fmt.Errorf("failed to lock config before saving edit: %w", err)}
+		}
+		defer unlock()
+
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return sshHostEditedMsg{fmt.Errorf("failed to load config before saving edit: %w", err)}
@@ -101,6 +188,12 @@ func saveEditedSshHostCmd(originalName string, editedHost config.SSHHost) tea.Cm
 
 func saveNewSshHostCmd(newHost config.SSHHost) tea.Cmd {
 	return func() tea.Msg {
+		unlock, err := config.LockConfig()
+		if err != nil {
+			return sshHostAddedMsg{fmt.Errorf("failed to lock config before saving: %w", err)}
+		}
+		defer unlock()
+
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return sshHostAddedMsg{fmt.Errorf("failed to load config before saving: %w", err)}
@@ -124,6 +217,12 @@ func saveNewSshHostCmd(newHost config.SSHHost) tea.Cmd {
 
 func removeSshHostCmd(hostToRemove config.SSHHost) tea.Cmd {
 	return func() tea.Msg {
+		unlock, err := config.LockConfig()
+		if err != nil {
+			return stepFinishedMsg{fmt.Errorf("failed to lock config before remove: %w", err)}
+		}
+		defer unlock()
+
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return stepFinishedMsg{fmt.Errorf("failed to load config before remove: %w", err)}
@@ -164,6 +263,12 @@ func saveImportedSshHostsCmd(hostsToSave []config.SSHHost) tea.Cmd {
 			return sshHostsImportedMsg{importedCount: 0, skippedCount: 0, err: nil}
 		}
 
+		unlock, err := config.LockConfig()
+		if err != nil {
+			return sshHostsImportedMsg{err: fmt.Errorf("failed to lock config before saving imports: %w", err)}
+		}
+		defer unlock()
+
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return sshHostsImportedMsg{err: fmt.Errorf("failed to load config before saving imports: %w", err)}
@@ -236,6 +341,147 @@ func runStepCmd(step runner.CommandStep) tea.Cmd {
 	}
 }
 
+// loadStackServicesCmd lists the services defined in a stack's compose file.
+func loadStackServicesCmd(stack discovery.Stack) tea.Cmd {
+	return func() tea.Msg {
+		services, err := runner.GetStackServices(stack)
+		return stackServicesLoadedMsg{stackIdentifier: stack.Identifier(), services: services, err: err}
+	}
+}
+
+// execServiceCmd builds the command opening an interactive shell in a service
+// container, off the Update loop as it may detect the host's container runtime.
+// handleServiceExecReadyMsg then suspends the TUI to run it.
+func execServiceCmd(stack discovery.Stack, service string) tea.Cmd {
+	return func() tea.Msg {
+		cmd, err := runner.ServiceExecCommand(stack, service)
+		if err != nil {
+			return serviceExecFinishedMsg{err: err}
+		}
+		return serviceExecReadyMsg{cmd: cmd}
+	}
+}
+
+// stackShellCmd suspends the TUI and opens an interactive shell in the stack's
+// directory. The shell's own exit status is not an error; ssh reports failures
+// to connect with status 255.
+func stackShellCmd(stack discovery.Stack) tea.Cmd {
+	cmd, err := runner.StackShellCommand(stack)
+	if err != nil {
+		return func() tea.Msg { return stackShellFinishedMsg{stack: stack, err: err} }
+	}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && (!stack.IsRemote || exitErr.ExitCode() != 255) {
+			err = nil
+		}
+		return stackShellFinishedMsg{stack: stack, err: err}
+	})
+}
+
+// openURLCmd opens a web address in the default browser, with open on macOS
+// and xdg-open elsewhere. Their output is discarded so it doesn't garble the TUI.
+func openURLCmd(url string) tea.Cmd {
+	return func() tea.Msg {
+		opener := "xdg-open"
+		if runtime.GOOS == "darwin" {
+			opener = "open"
+		}
+		err := exec.Command(opener, url).Run()
+		return urlOpenedMsg{url: url, err: err}
+	}
+}
+
+// lockStackCmd takes the lock of the stack whose steps the sequence with the
+// given ID starts next, so that they fail fast instead of racing another
+// operation on the stack.
+func lockStackCmd(sequenceID int, stack discovery.Stack) tea.Cmd {
+	return func() tea.Msg {
+		lock, err := runner.LockStack(stack)
+		return sequenceLockedMsg{sequenceID: sequenceID, stackIdentifier: stack.Identifier(), lock: lock, err: err}
+	}
+}
+
+// releaseLocksCmd releases stack locks in the background, since releasing a
+// remote lock waits for its SSH session to end.
+func releaseLocksCmd(locks []*runner.StackLock) tea.Cmd {
+	if len(locks) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		runner.ReleaseStackLocks(locks)
+		return nil
+	}
+}
+
+// completionNotifyCmd alerts that an action finished, as set by
+// completion_notify when it started: with the terminal bell, a desktop
+// notification with the given summary (urgent if the action failed), or both.
+func (m *model) completionNotifyCmd(summary string, failed bool) tea.Cmd {
+	notify := config.Config{CompletionNotify: m.completionNotify}
+	bell, desktop := notify.CompletionBell(), notify.CompletionDesktop()
+	if !bell && !desktop {
+		return nil
+	}
+	return func() tea.Msg {
+		if bell {
+			// Written to stderr, so it can't land inside a frame being drawn on stdout
+			os.Stderr.WriteString("\a")
+		}
+		if desktop {
+			title := "bm: action finished"
+			if failed {
+				title = "bm: action failed"
+			}
+			if err := util.SendDesktopNotification(title, summary, failed); err != nil {
+				logger.Warn("Could not send completion notification", "error", err)
+			}
+		}
+		return nil
+	}
+}
+
+// runBatchStackCmd runs every step of a stack's sequence in order as part of a
+// "refresh all" batch. Output is forwarded through BubbleProgram since several
+// stacks may be running at once; the returned message reports the final result.
+func runBatchStackCmd(stackID string, steps []runner.CommandStep) tea.Cmd {
+	return func() tea.Msg {
+		if runner.SequenceNeedsLock(steps) {
+			locks, err := runner.LockStacks(runner.SequenceStacks(steps))
+			if err != nil {
+				return batchStackFinishedMsg{stackIdentifier: stackID, err: err}
+			}
+			defer runner.ReleaseStackLocks(locks)
+		}
+		for _, step := range steps {
+			outChan, errChan := runner.StreamCommand(step, false)
+			for line := range outChan {
+				if BubbleProgram != nil {
+					BubbleProgram.Send(batchOutputMsg{stackIdentifier: stackID, line: line})
+				}
+			}
+			if err := <-errChan; err != nil {
+				return batchStackFinishedMsg{stackIdentifier: stackID, err: err}
+			}
+		}
+		return batchStackFinishedMsg{stackIdentifier: stackID}
+	}
+}
+
+// batchTickCmd schedules the next batch start after the stagger delay.
+func batchTickCmd(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return batchStartNextMsg{}
+	})
+}
+
+// outputFlushCmd schedules showing the output collected until then.
+func outputFlushCmd() tea.Cmd {
+	return tea.Tick(outputFlushInterval, func(time.Time) tea.Msg {
+		return outputFlushMsg{}
+	})
+}
+
 // waitForOutputCmd waits for the next line of output from a command's output channel.
 func waitForOutputCmd(outChan <-chan runner.OutputLine) tea.Cmd {
 	return func() tea.Msg {
@@ -255,3 +501,16 @@ func waitForErrorCmd(errChan <-chan error) tea.Cmd {
 		return stepFinishedMsg{err}
 	}
 }
+
+// PromptKeyPassphrase asks for the passphrase of an encrypted SSH key in a
+// prompt shown over the current view, blocking until it is answered. It
+// implements ssh.PassphrasePrompt.
+func PromptKeyPassphrase(host config.SSHHost, keyPath string, retry bool) (string, error) {
+	if BubbleProgram == nil {
+		return "", errors.New("the TUI is not running to ask for the passphrase")
+	}
+	reply := make(chan passphraseReply, 1)
+	BubbleProgram.Send(passphrasePromptMsg{host: host, keyPath: keyPath, retry: retry, reply: reply})
+	answer := <-reply
+	return answer.passphrase, answer.err
+}
@@ -7,6 +7,8 @@
 
 package ui
 
+import "time"
+
 // state represents the different views or modes of the TUI.
 // Each state corresponds to a different screen or interaction mode.
 type state int
@@ -24,7 +26,15 @@ const (
 	stateSshConfigImportDetails              // Details of SSH configs being imported
 	stateSshConfigEditForm                   // Form for editing SSH config
 	statePruneConfirm                        // Confirmation before pruning
+	stateRestartRuntimeConfirm               // Confirmation before restarting a host's container runtime
 	stateRunningHostAction                   // View when executing host-level commands
+	stateRunningBatch                        // View when running a queued "refresh all"
+	stateHostPicker                          // Host picker for jumping to a host's stacks
+	stateGlobalConfig                        // Form for editing global settings (local_root etc.)
+	stateSequenceSummary                     // Per-stack results of a multi-stack sequence with failed or skipped stacks
+	stateLastOutput                          // Output of the last sequence run on a stack, reopened after leaving it
+	stateComposeCommand                      // Prompt for a compose subcommand to run on a stack
+	stateActionPicker                        // Picker for one of the custom actions of the detailed stack
 )
 
 // Constants for SSH authentication methods used in the SSH configuration forms.
@@ -38,4 +48,17 @@ const (
 const (
 	// Limit concurrent stack status checks via SSH to avoid overwhelming connections
 	maxConcurrentStatusChecks = 4
+
+	// Maximum number of discovery errors listed in the stack list footer
+	maxDisplayedDiscoveryErrors = 3
+
+	// Lines above the first host ("local") in the SSH config list content
+	configListHeaderLines = 2
+
+	// Longest time between two clicks on the same item for a double-click
+	doubleClickInterval = 400 * time.Millisecond
+
+	// Interval at which streamed command output is shown, so that output
+	// arriving quickly is rendered in batches rather than line by line
+	outputFlushInterval = 50 * time.Millisecond
 )
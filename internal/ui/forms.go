@@ -3,15 +3,18 @@
 
 // Package ui's forms.go file implements form creation and validation for the
 // interactive text-based forms in the TUI. This includes forms for adding and
-// editing SSH hosts, configuring authentication methods, and validating user input.
+// editing SSH hosts, configuring authentication methods, editing the global
+// settings, and validating user input.
 
 package ui
 
 import (
 	"bucket-manager/internal/config"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 )
@@ -120,7 +123,7 @@ func createEditForm(host config.SSHHost) ([]textinput.Model, int, bool) {
 	t = textinput.New()
 	t.Placeholder = "Port (default 22)"
 	portStr := ""
-	if host.Port != 0 { // Only set value if port is non-default
+	if host.Port != 0 { // Left empty if the host uses the default port
 		portStr = strconv.Itoa(host.Port)
 	}
 	t.SetValue(portStr)
@@ -229,17 +232,13 @@ func (m *model) buildHostFromForm() (config.SSHHost, error) {
 	// Validate and parse Port
 	portStr := strings.TrimSpace(m.formInputs[3].Value())
 	if portStr == "" {
-		host.Port = 0 // Use default port (handled by SSH client)
+		host.Port = 0 // config.DefaultSSHPort
 	} else {
 		port, err := strconv.Atoi(portStr)
 		if err != nil || port < 1 || port > 65535 {
 			return host, fmt.Errorf("invalid port number: %s", portStr)
 		}
-		if port == 22 {
-			host.Port = 0 // Store 0 for default port 22
-		} else {
-			host.Port = port
-		}
+		host.Port = port // Kept even if it is 22, so that an explicit port stays explicit
 	}
 
 	// Set auth method fields based on selection
@@ -278,7 +277,15 @@ func (m *model) buildHostFromEditForm() (config.SSHHost, error) {
 		return config.SSHHost{}, fmt.Errorf("internal error: hostToEdit is nil")
 	}
 	originalHost := *m.hostToEdit
-	editedHost := config.SSHHost{}
+	editedHost := config.SSHHost{
+		// Settings without a form field are carried over unchanged
+		RunAsUser:             originalHost.RunAsUser,
+		StackRunAsUsers:       originalHost.StackRunAsUsers,
+		ForwardAgent:          originalHost.ForwardAgent,
+		SSHAlgorithms:         originalHost.SSHAlgorithms,
+		RuntimeRestartCommand: originalHost.RuntimeRestartCommand,
+		ContainerRuntime:      originalHost.ContainerRuntime,
+	}
 
 	// Get values, keeping original if the field is left empty (except for RemoteRoot and auth fields)
 	editedHost.Name = strings.TrimSpace(m.formInputs[0].Value())
@@ -319,11 +326,7 @@ func (m *model) buildHostFromEditForm() (config.SSHHost, error) {
 		if err != nil || port < 1 || port > 65535 {
 			return editedHost, fmt.Errorf("invalid port number: %s", portStr)
 		}
-		if port == 22 {
-			editedHost.Port = 0 // Store 0 for default port 22
-		} else {
-			editedHost.Port = port
-		}
+		editedHost.Port = port // Kept even if it is 22, so that an explicit port stays explicit
 	}
 
 	// Handle auth fields based on selected method and inputs
@@ -356,6 +359,11 @@ func (m *model) buildHostFromEditForm() (config.SSHHost, error) {
 		} else {
 			editedHost.Password = passwordInput
 		}
+		if editedHost.Password != originalHost.Password {
+			// A new plaintext password replaces the other sources
+			editedHost.PasswordFile = ""
+			editedHost.PasswordCommand = ""
+		}
 		editedHost.KeyPath = "" // Clear key path if password is set/kept
 	case authMethodAgent:
 		// Agent auth selected, clear both specific fields
@@ -371,3 +379,195 @@ func (m *model) buildHostFromEditForm() (config.SSHHost, error) {
 	// Note: Name conflict check (if name changed) is performed later in saveEditedSshHostCmd
 	return editedHost, nil
 }
+
+// --- Global Settings Form ---
+
+// Logical focus indices of the global settings form, in display order.
+const (
+	settingsLocalRootField = iota
+	settingsRuntimeField
+	settingsMaxConcurrentField
+	settingsStaggerField
+	settingsPullParallelField
+	settingsDiskWarnField
+	settingsDefaultActionField
+	settingsCollapseField
+	settingsCompletionNotifyField
+	settingsFieldCount
+)
+
+// containerRuntimes lists the runtimes offered by the global settings form.
+var containerRuntimes = config.ContainerRuntimes
+
+// completionNotifyOff is the completion_notify option of the global settings
+// form that leaves it unset.
+const completionNotifyOff = "off"
+
+// completionNotifyOptions lists the completion_notify values offered by the
+// global settings form.
+var completionNotifyOptions = append([]string{completionNotifyOff}, config.CompletionNotifyModes...)
+
+// globalSettings holds the global (non-host) settings edited in the TUI.
+type globalSettings struct {
+	LocalRoot               string
+	ContainerRuntime        string
+	RefreshAllMaxConcurrent int
+	RefreshAllStagger       string
+	PullParallel            int
+	DiskWarnFreePercent     int
+	DefaultAction           string
+	CollapseStepOutput      bool
+	CompletionNotify        string
+}
+
+// globalSettingsFromConfig returns the global settings of cfg, with unset
+// selector values replaced by their defaults.
+func globalSettingsFromConfig(cfg config.Config) globalSettings {
+	s := globalSettings{
+		LocalRoot:               cfg.LocalRoot,
+		ContainerRuntime:        cfg.ContainerRuntime,
+		RefreshAllMaxConcurrent: cfg.RefreshAllMaxConcurrent,
+		RefreshAllStagger:       cfg.RefreshAllStagger,
+		PullParallel:            cfg.PullParallel,
+		DiskWarnFreePercent:     cfg.DiskWarnFreePercent,
+		DefaultAction:           cfg.DefaultAction,
+		CollapseStepOutput:      cfg.CollapseStepOutput,
+		CompletionNotify:        cfg.CompletionNotify,
+	}
+	if !slices.Contains(containerRuntimes, s.ContainerRuntime) {
+		s.ContainerRuntime = containerRuntimes[0]
+	}
+	if !slices.Contains(config.DefaultActions, s.DefaultAction) {
+		s.DefaultAction = config.DefaultStackAction
+	}
+	if !slices.Contains(completionNotifyOptions, s.CompletionNotify) {
+		s.CompletionNotify = completionNotifyOff
+	}
+	return s
+}
+
+// applyTo copies the settings into cfg. Zero values are stored as unset, so
+// the defaults keep applying to them.
+func (s globalSettings) applyTo(cfg *config.Config) {
+	cfg.LocalRoot = s.LocalRoot
+	cfg.ContainerRuntime = s.ContainerRuntime
+	if s.ContainerRuntime == config.ContainerRuntimeAuto {
+		cfg.ContainerRuntime = ""
+	}
+	cfg.RefreshAllMaxConcurrent = s.RefreshAllMaxConcurrent
+	cfg.RefreshAllStagger = s.RefreshAllStagger
+	cfg.PullParallel = s.PullParallel
+	cfg.DiskWarnFreePercent = s.DiskWarnFreePercent
+	cfg.DefaultAction = s.DefaultAction
+	if s.DefaultAction == config.DefaultStackAction {
+		cfg.DefaultAction = ""
+	}
+	cfg.CollapseStepOutput = s.CollapseStepOutput
+	cfg.CompletionNotify = s.CompletionNotify
+	if s.CompletionNotify == completionNotifyOff {
+		cfg.CompletionNotify = ""
+	}
+}
+
+// settingsInputIndex maps a logical field of the global settings form to its
+// index in m.formInputs, or -1 for selector fields without a text input.
+func settingsInputIndex(field int) int {
+	switch field {
+	case settingsLocalRootField:
+		return 0
+	case settingsMaxConcurrentField:
+		return 1
+	case settingsStaggerField:
+		return 2
+	case settingsPullParallelField:
+		return 3
+	case settingsDiskWarnField:
+		return 4
+	}
+	return -1
+}
+
+// createGlobalConfigForm creates the text input fields of the global settings
+// form, filled with the current values. Empty fields use the defaults.
+func createGlobalConfigForm(s globalSettings) []textinput.Model {
+	inputs := make([]textinput.Model, 5)
+	var t textinput.Model
+
+	numberInput := func(value int, placeholder string) textinput.Model {
+		input := textinput.New()
+		input.Placeholder = placeholder
+		input.CharLimit = 4
+		input.Width = 30
+		if value > 0 {
+			input.SetValue(strconv.Itoa(value))
+		}
+		input.Validate = func(value string) error {
+			if value == "" {
+				return nil
+			}
+			if _, err := strconv.Atoi(value); err != nil {
+				return fmt.Errorf("must be a number")
+			}
+			return nil
+		}
+		return input
+	}
+
+	t = textinput.New()
+	t.Placeholder = "default: ~/bucket or ~/compose-bucket"
+	t.SetValue(s.LocalRoot)
+	t.Focus()
+	t.CharLimit = 200
+	t.Width = 50
+	inputs[0] = t
+
+	inputs[1] = numberInput(s.RefreshAllMaxConcurrent, fmt.Sprintf("default %d", config.DefaultRefreshAllMaxConcurrent))
+
+	t = textinput.New()
+	t.Placeholder = fmt.Sprintf("default %s", config.DefaultRefreshAllStagger)
+	t.SetValue(s.RefreshAllStagger)
+	t.CharLimit = 20
+	t.Width = 30
+	inputs[2] = t
+
+	inputs[3] = numberInput(s.PullParallel, "default: compose's own")
+	inputs[4] = numberInput(s.DiskWarnFreePercent, fmt.Sprintf("default %d", config.DefaultDiskWarnFreePercent))
+
+	return inputs
+}
+
+// buildGlobalSettingsFromForm reads the global settings form into a
+// globalSettings, validating the text fields.
+func (m *model) buildGlobalSettingsFromForm() (globalSettings, error) {
+	s := m.formSettings
+	s.LocalRoot = strings.TrimSpace(m.formInputs[0].Value())
+
+	parseCount := func(index int, name string, maxValue int) (int, error) {
+		value := strings.TrimSpace(m.formInputs[index].Value())
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > maxValue {
+			return 0, fmt.Errorf("%s must be a number between 0 and %d", name, maxValue)
+		}
+		return n, nil
+	}
+	var err error
+	if s.RefreshAllMaxConcurrent, err = parseCount(1, "refresh all concurrency", 1000); err != nil {
+		return s, err
+	}
+	s.RefreshAllStagger = strings.TrimSpace(m.formInputs[2].Value())
+	if s.RefreshAllStagger != "" {
+		if d, err := time.ParseDuration(s.RefreshAllStagger); err != nil || d < 0 {
+			return s, fmt.Errorf("refresh all stagger must be a duration such as 2s or 500ms")
+		}
+	}
+	if s.PullParallel, err = parseCount(3, "pull parallelism", 1000); err != nil {
+		return s, err
+	}
+	if s.DiskWarnFreePercent, err = parseCount(4, "disk warning threshold", 100); err != nil {
+		return s, err
+	}
+	return s, nil
+}
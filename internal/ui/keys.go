@@ -2,11 +2,18 @@
 // Copyright (c) 2025 Mufeed Ali
 
 // This file defines the keyboard bindings for the TUI application.
-// It maps keys to actions and provides descriptions for the help menu.
+// It maps keys to actions, provides descriptions for the help menu, and
+// applies user overrides from the config file.
 
 package ui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
 
 // KeyMap defines the keybindings for the application.
 // These bindings are used throughout the TUI for navigation and actions.
@@ -38,6 +45,27 @@ type KeyMap struct {
 	DownAction    key.Binding // Stop/down the selected stack(s)
 	RefreshAction key.Binding // Restart the selected stack(s)
 	PullAction    key.Binding // Pull images for the selected stack(s)
+	CreateAction  key.Binding // Create the containers of the selected stack(s) without starting them
+	StopAction    key.Binding // Stop the selected stack(s), keeping their containers
+	StartAction   key.Binding // Start the existing containers of the selected stack(s)
+
+	RefreshAllAction key.Binding // Queue a staggered refresh of every stack
+	RecheckErrored   key.Binding // Re-check the status of stacks whose status check failed
+	JumpToHost       key.Binding // Pick a host and move the cursor to its first stack
+	PinAction        key.Binding // Pin the stack at the top of the list, or unpin it
+	StackShell       key.Binding // Open an interactive shell in the stack's directory
+	ComposeCommand   key.Binding // Run a compose subcommand typed by the user on the stack
+	StackActions     key.Binding // Pick one of the custom actions defined in the stack's .bm.yaml
+
+	// Service actions (stack details view)
+	ServiceLogsAction    key.Binding // Show logs of the selected service
+	ServiceRestartAction key.Binding // Restart (or start) the selected service
+	ServiceExecAction    key.Binding // Open a shell in the selected service
+	OpenURLAction        key.Binding // Open the web address of the selected service in the browser
+
+	// Output view actions
+	ToggleStepOutput key.Binding // Collapse or expand the output of successful steps
+	LastOutput       key.Binding // Show the output of the last sequence run on the stack
 
 	// Host/SSH configuration actions
 	Remove key.Binding // Remove an item (SSH host)
@@ -45,9 +73,13 @@ type KeyMap struct {
 	Import key.Binding // Import from SSH config
 	Edit   key.Binding // Edit an item (SSH host)
 
+	GlobalSettings key.Binding // Edit global settings such as local_root
+
 	// Misc actions
 	ToggleDisabled key.Binding // Toggle disabled state for a host
 	PruneAction    key.Binding // Prune containers/images
+
+	RestartRuntimeAction key.Binding // Restart the container runtime of a host
 }
 
 // DefaultKeyMap provides the default keybindings.
@@ -141,6 +173,72 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("p"),
 		key.WithHelp("p", "pull images"),
 	),
+	CreateAction: key.NewBinding(
+		key.WithKeys("C"),
+		key.WithHelp("C", "create stack(s)"),
+	),
+	StopAction: key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "stop stack(s)"),
+	),
+	StartAction: key.NewBinding(
+		key.WithKeys("U"),
+		key.WithHelp("U", "start stack(s)"),
+	),
+	RefreshAllAction: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "refresh all stacks"),
+	),
+	RecheckErrored: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "re-check errored"),
+	),
+	JumpToHost: key.NewBinding(
+		key.WithKeys("g"),
+		key.WithHelp("g", "jump to host"),
+	),
+	PinAction: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "pin"),
+	),
+	StackShell: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "shell in stack dir"),
+	),
+	ComposeCommand: key.NewBinding(
+		key.WithKeys(":"),
+		key.WithHelp(":", "compose command"),
+	),
+	StackActions: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "stack actions"),
+	),
+
+	ServiceLogsAction: key.NewBinding(
+		key.WithKeys("l"),
+		key.WithHelp("l", "service logs"),
+	),
+	ServiceRestartAction: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "restart service"),
+	),
+	ServiceExecAction: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "exec into service"),
+	),
+	OpenURLAction: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "open in browser"),
+	),
+
+	ToggleStepOutput: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "collapse/expand output"),
+	),
+	LastOutput: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "last output"),
+	),
 
 	Remove: key.NewBinding(
 		key.WithKeys("d"),
@@ -158,6 +256,10 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("e"),
 		key.WithHelp("e", "edit host"),
 	),
+	GlobalSettings: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "global settings"),
+	),
 
 	ToggleDisabled: key.NewBinding(
 		key.WithKeys(" "),
@@ -167,4 +269,83 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("P"),
 		key.WithHelp("P", "prune host"),
 	),
+	RestartRuntimeAction: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "restart runtime"),
+	),
+}
+
+// keyContexts groups the bindings that are active together in a single view.
+// Bindings within the same context must not share a key, otherwise one action
+// would shadow the other.
+var keyContexts = []struct {
+	name    string
+	actions []string
+}{
+	{"stack list", []string{"Up", "Down", "PgUp", "PgDown", "Home", "End", "Quit", "Enter", "Select", "Config", "UpAction", "DownAction", "RefreshAction", "PullAction", "CreateAction", "StopAction", "StartAction", "RefreshAllAction", "RecheckErrored", "JumpToHost", "PinAction", "StackShell", "ComposeCommand", "LastOutput"}},
+	{"stack details", []string{"Up", "Down", "PgUp", "PgDown", "Home", "End", "Quit", "Back", "ServiceLogsAction", "ServiceRestartAction", "ServiceExecAction", "OpenURLAction", "StackShell", "ComposeCommand", "StackActions", "LastOutput"}},
+	{"host picker", []string{"Up", "Down", "Home", "End", "Quit", "Back", "Enter"}},
+	{"action picker", []string{"Up", "Down", "Home", "End", "Quit", "Back", "Enter"}},
+	{"sequence summary", []string{"Up", "Down", "Home", "End", "Quit", "Back", "Enter"}},
+	{"host list", []string{"Up", "Down", "PgUp", "PgDown", "Home", "End", "Quit", "Back", "Remove", "Add", "Import", "Edit", "GlobalSettings", "PruneAction", "RestartRuntimeAction"}},
+	{"output", []string{"Up", "Down", "PgUp", "PgDown", "Home", "End", "Quit", "Back", "Enter", "ToggleStepOutput"}},
+	{"import selection", []string{"Up", "Down", "PgUp", "PgDown", "Home", "End", "Quit", "Back", "Select", "Enter"}},
+	{"forms", []string{"Up", "Down", "Left", "Right", "Tab", "ShiftTab", "Quit", "Enter", "Esc", "ToggleDisabled"}},
+	{"confirmation", []string{"Quit", "Back", "Yes", "No"}},
+}
+
+// applyKeybindingOverrides returns a copy of base with the bindings named in
+// overrides replaced by the given keys. Action names match KeyMap field names
+// case-insensitively. The help text keeps its description and lists the new keys.
+// An error is returned for unknown actions, empty key lists, or conflicting keys.
+func applyKeybindingOverrides(base KeyMap, overrides map[string][]string) (KeyMap, error) {
+	km := base
+	kmValue := reflect.ValueOf(&km).Elem()
+	kmType := kmValue.Type()
+
+	for action, keys := range overrides {
+		fieldIdx := -1
+		for i := 0; i < kmType.NumField(); i++ {
+			if strings.EqualFold(kmType.Field(i).Name, action) {
+				fieldIdx = i
+				break
+			}
+		}
+		if fieldIdx == -1 {
+			return base, fmt.Errorf("unknown keybinding action '%s'", action)
+		}
+		if len(keys) == 0 {
+			return base, fmt.Errorf("no keys given for keybinding action '%s'", action)
+		}
+
+		field := kmValue.Field(fieldIdx)
+		current := field.Interface().(key.Binding)
+		field.Set(reflect.ValueOf(key.NewBinding(
+			key.WithKeys(keys...),
+			key.WithHelp(strings.Join(keys, "/"), current.Help().Desc),
+		)))
+	}
+
+	if err := validateKeyMap(km); err != nil {
+		return base, err
+	}
+	return km, nil
+}
+
+// validateKeyMap checks that no two actions in the same context share a key.
+func validateKeyMap(km KeyMap) error {
+	kmValue := reflect.ValueOf(km)
+	for _, ctx := range keyContexts {
+		owners := make(map[string]string) // key -> action
+		for _, action := range ctx.actions {
+			binding := kmValue.FieldByName(action).Interface().(key.Binding)
+			for _, k := range binding.Keys() {
+				if other, exists := owners[k]; exists && other != action {
+					return fmt.Errorf("key '%s' is bound to both %s and %s in the %s view", k, other, action, ctx.name)
+				}
+				owners[k] = action
+			}
+		}
+	}
+	return nil
 }
@@ -10,9 +10,15 @@ package ui
 
 import (
 	"bucket-manager/internal/config"
+	"bucket-manager/internal/discovery"
 	"bucket-manager/internal/runner"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -32,6 +38,11 @@ func (m *model) triggerConfigAndStackRefresh() tea.Cmd {
 	m.discoveryErrors = nil
 	m.stackStatuses = make(map[string]runner.StackRuntimeInfo) // Clear statuses
 	m.loadingStatus = make(map[string]bool)
+	m.pendingStatuses = nil
+	m.hostDiskUsage = make(map[string]runner.HostDiskUsage) // Host definitions may have changed
+	m.loadingDiskUsage = make(map[string]bool)
+	m.composeVersions = make(map[string]runner.ComposeVersion)
+	m.loadingComposeVersion = make(map[string]bool)
 	m.cursor = 0       // Reset stack list cursor
 	m.configCursor = 0 // Reset config list cursor
 
@@ -161,19 +172,101 @@ func handleStackDiscoveredMsg(m *model, msg stackDiscoveredMsg) tea.Cmd {
 	// Add the discovered stack
 	m.stacks = append(m.stacks, msg.stack)
 
-	// Fetch status for the newly discovered stack if not already loading/loaded
+	// Queue the newly discovered stack's status if not already loading/loaded;
+	// the queued statuses are fetched together once discovery finishes
 	stackID := msg.stack.Identifier()
 	if !m.loadingStatus[stackID] {
 		if _, loaded := m.stackStatuses[stackID]; !loaded {
 			m.loadingStatus[stackID] = true
-			return m.fetchStackStatusCmd(msg.stack) // Return command to fetch status
+			m.pendingStatuses = append(m.pendingStatuses, msg.stack)
 		}
 	}
-	return nil // No command needed if status is already loading or loaded
+	return nil
+}
+
+// sortStacks puts the discovered stacks in a stable order, pinned stacks
+// first (see compareStacks), since they are appended as their hosts answer.
+// The cursor and the selection stay on the same stacks.
+func (m *model) sortStacks() {
+	var cursorID string
+	if m.cursor >= 0 && m.cursor < len(m.stacks) {
+		cursorID = m.stacks[m.cursor].Identifier()
+	}
+	selectedIDs := make(map[string]bool, len(m.selectedStackIdxs))
+	for idx := range m.selectedStackIdxs {
+		if idx < len(m.stacks) {
+			selectedIDs[m.stacks[idx].Identifier()] = true
+		}
+	}
+
+	// Sort a copy: pointers into the old slice (e.g. the detailed stack) stay valid
+	m.stacks = slices.Clone(m.stacks)
+	slices.SortFunc(m.stacks, m.compareStacks)
+
+	m.selectedStackIdxs = make(map[int]struct{}, len(selectedIDs))
+	for i, stack := range m.stacks {
+		id := stack.Identifier()
+		if id == cursorID {
+			m.cursor = i
+		}
+		if selectedIDs[id] {
+			m.selectedStackIdxs[i] = struct{}{}
+		}
+	}
+}
+
+// compareStacks orders pinned stacks before the others, and stacks that are
+// both pinned or both not as discovery.CompareStacks does.
+func (m *model) compareStacks(a, b discovery.Stack) int {
+	if aPinned, bPinned := m.pinnedStacks[a.Identifier()], m.pinnedStacks[b.Identifier()]; aPinned != bPinned {
+		if aPinned {
+			return -1
+		}
+		return 1
+	}
+	return discovery.CompareStacks(a, b)
+}
+
+// recordDiscoveryError adds err to the discovery errors, collapsing it into an
+// existing entry for the same host (or, for errors not tied to a host, the same
+// message) so a flapping or unreachable host produces a single line.
+func (m *model) recordDiscoveryError(err error) {
+	// Only keep the first line; remote errors may append the full command output.
+	message, _, _ := strings.Cut(err.Error(), "\n")
+	issue := discoveryIssue{message: message, count: 1, fatal: true}
+	var hostErr *discovery.HostError
+	if errors.As(err, &hostErr) {
+		issue.host = hostErr.Host
+		issue.fatal = false
+	}
+
+	for i := range m.discoveryErrors {
+		existing := &m.discoveryErrors[i]
+		sameHost := issue.host != "" && existing.host == issue.host
+		sameMessage := issue.host == "" && existing.host == "" && existing.message == issue.message
+		if sameHost || sameMessage {
+			existing.count++
+			existing.message = issue.message
+			return
+		}
+	}
+
+	// Keep fatal errors ahead of per-host warnings so they are never hidden by the display cap.
+	if issue.fatal {
+		m.discoveryErrors = slices.Insert(m.discoveryErrors, 0, issue)
+	} else {
+		m.discoveryErrors = append(m.discoveryErrors, issue)
+	}
 }
 
 func handleDiscoveryErrorMsg(m *model, msg discoveryErrorMsg) tea.Cmd {
-	m.discoveryErrors = append(m.discoveryErrors, msg.err)
+	m.recordDiscoveryError(msg.err)
+	var hostErr *discovery.HostError
+	if errors.As(msg.err, &hostErr) {
+		seen := m.hostReachability[hostErr.Host]
+		seen.reachable = false
+		m.hostReachability[hostErr.Host] = seen
+	}
 	// Optionally update lastError to show the most recent discovery error
 	m.lastError = msg.err
 	// Potentially transition state if needed, but often just collecting errors is fine
@@ -181,8 +274,18 @@ func handleDiscoveryErrorMsg(m *model, msg discoveryErrorMsg) tea.Cmd {
 	return nil
 }
 
-func handleDiscoveryFinishedMsg(m *model) tea.Cmd {
+func handleDiscoveryFinishedMsg(m *model, msg discoveryFinishedMsg) tea.Cmd {
 	m.isDiscovering = false // Mark discovery as finished
+	m.sortStacks()
+
+	// Every searched host without a discovery error was reached
+	now := time.Now()
+	for _, host := range msg.hosts {
+		failed := slices.ContainsFunc(m.discoveryErrors, func(issue discoveryIssue) bool { return issue.host == host })
+		if !failed {
+			m.hostReachability[host] = hostReachability{reachable: true, lastSeen: now}
+		}
+	}
 
 	// If we were loading stacks, transition to the list state now.
 	if m.currentState == stateLoadingStacks {
@@ -205,8 +308,14 @@ func handleDiscoveryFinishedMsg(m *model) tea.Cmd {
 		}
 		m.viewport.GotoTop() // Reset viewport scroll for the list
 	}
+	// Fetch the statuses of the discovered stacks, one SSH round trip per host
+	var cmd tea.Cmd
+	if len(m.pendingStatuses) > 0 {
+		cmd = fetchStackStatusesCmd(m.pendingStatuses)
+		m.pendingStatuses = nil
+	}
 	// If discovery finished while already in another state (e.g., config), just update isDiscovering flag.
-	return nil
+	return cmd
 }
 
 func handleSshConfigLoadedMsg(m *model, msg sshConfigLoadedMsg) tea.Cmd {
@@ -215,6 +324,7 @@ func handleSshConfigLoadedMsg(m *model, msg sshConfigLoadedMsg) tea.Cmd {
 		m.configuredHosts = []config.SSHHost{} // Clear hosts on error
 	} else {
 		m.configuredHosts = msg.hosts
+		m.diskWarnFreePercent = msg.diskWarnFreePercent
 		m.lastError = nil // Clear error on success
 	}
 
@@ -224,6 +334,54 @@ func handleSshConfigLoadedMsg(m *model, msg sshConfigLoadedMsg) tea.Cmd {
 		m.configCursor = max(0, totalItems-1)
 	}
 	m.sshConfigViewport.GotoTop() // Reset scroll on config load/reload
+
+	// Fetch disk usage for local and enabled remote hosts that haven't been checked yet
+	var cmds []tea.Cmd
+	targets := []runner.HostTarget{{IsRemote: false, ServerName: "local"}}
+	for i := range m.configuredHosts {
+		host := m.configuredHosts[i]
+		if !host.Disabled {
+			targets = append(targets, runner.HostTarget{IsRemote: true, HostConfig: &host, ServerName: host.Name})
+		}
+	}
+	for _, target := range targets {
+		if _, loaded := m.hostDiskUsage[target.ServerName]; !loaded && !m.loadingDiskUsage[target.ServerName] {
+			m.loadingDiskUsage[target.ServerName] = true
+			cmds = append(cmds, m.fetchHostDiskUsageCmd(target))
+		}
+		if _, loaded := m.composeVersions[target.ServerName]; !loaded && !m.loadingComposeVersion[target.ServerName] {
+			m.loadingComposeVersion[target.ServerName] = true
+			cmds = append(cmds, m.fetchComposeVersionCmd(target))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+func handleHostPickerLoadedMsg(m *model, msg hostPickerLoadedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.hostPickerError = fmt.Errorf("failed to load hosts: %w", msg.err)
+	}
+	// Also offer any host that has stacks but is no longer configured
+	hosts := msg.hosts
+	for _, s := range m.stacks {
+		if !slices.Contains(hosts, s.ServerName) {
+			hosts = append(hosts, s.ServerName)
+		}
+	}
+	m.hostPickerHosts = hosts
+	m.hostPickerCursor = min(m.hostPickerCursor, max(0, len(hosts)-1))
+	return nil
+}
+
+func handleHostDiskUsageLoadedMsg(m *model, msg hostDiskUsageLoadedMsg) tea.Cmd {
+	m.loadingDiskUsage[msg.serverName] = false
+	m.hostDiskUsage[msg.serverName] = msg.usage
+	return nil
+}
+
+func handleComposeVersionLoadedMsg(m *model, msg composeVersionLoadedMsg) tea.Cmd {
+	m.loadingComposeVersion[msg.serverName] = false
+	m.composeVersions[msg.serverName] = msg.version
 	return nil
 }
 
@@ -234,9 +392,155 @@ func handleStackStatusLoadedMsg(m *model, msg stackStatusLoadedMsg) tea.Cmd {
 	return nil
 }
 
+// handleStackServicesLoadedMsg stores the service list of the stack shown in the details view.
+// Results for a stack that is no longer being viewed are dropped.
+func handleStackServicesLoadedMsg(m *model, msg stackServicesLoadedMsg) tea.Cmd {
+	if m.detailedStack == nil || m.detailedStack.Identifier() != msg.stackIdentifier {
+		return nil
+	}
+	m.loadingServices = false
+	m.detailServices = msg.services
+	m.servicesError = msg.err
+	m.serviceCursor = 0
+	return nil
+}
+
+// handleServiceExecFinishedMsg records the result of an interactive exec session and
+// refreshes the detailed stack's status, since the session may have changed it.
+func handleServiceExecFinishedMsg(m *model, msg serviceExecFinishedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.servicesError = fmt.Errorf("exec failed: %w", msg.err)
+	}
+	if m.detailedStack == nil {
+		return nil
+	}
+	stackID := m.detailedStack.Identifier()
+	if m.loadingStatus[stackID] {
+		return nil
+	}
+	m.loadingStatus[stackID] = true
+	return m.fetchStackStatusCmd(*m.detailedStack)
+}
+
+// handleStackShellFinishedMsg reports a failed stack shell in the current view and
+// refreshes the stack's status, since it may have been changed from the shell.
+func handleStackShellFinishedMsg(m *model, msg stackShellFinishedMsg) tea.Cmd {
+	if msg.err != nil {
+		err := fmt.Errorf("shell failed: %w", msg.err)
+		if m.currentState == stateStackDetails {
+			m.servicesError = err
+		} else {
+			m.actionError = err
+		}
+	}
+	stackID := msg.stack.Identifier()
+	if m.loadingStatus[stackID] {
+		return nil
+	}
+	m.loadingStatus[stackID] = true
+	return m.fetchStackStatusCmd(msg.stack)
+}
+
+// handleURLOpenedMsg reports a web address that couldn't be opened in the
+// details view.
+func handleURLOpenedMsg(m *model, msg urlOpenedMsg) {
+	if msg.err != nil && m.currentState == stateStackDetails {
+		m.servicesError = fmt.Errorf("could not open %s: %w", msg.url, msg.err)
+	}
+}
+
+// handlePassphrasePromptMsg opens the prompt for the passphrase of an
+// encrypted SSH key over the current view. Prompts are asked one at a time.
+func handlePassphrasePromptMsg(m *model, msg passphrasePromptMsg) tea.Cmd {
+	m.passphrasePrompt = &msg
+	m.passphraseInput = textinput.New()
+	m.passphraseInput.Prompt = "Passphrase: "
+	m.passphraseInput.EchoMode = textinput.EchoPassword
+	m.passphraseInput.Width = max(m.width-16, 20)
+	return m.passphraseInput.Focus()
+}
+
+// handleSequenceLockedMsg starts the steps of a stack once its lock is taken.
+// If it couldn't be, a single-stack sequence shows the error (usually "stack
+// busy"), while a multi-stack sequence skips the stack and goes on with the next.
+func handleSequenceLockedMsg(m *model, msg sequenceLockedMsg) tea.Cmd {
+	if msg.sequenceID != m.sequenceID || m.currentState != stateRunningSequence || m.currentStepIndex >= len(m.currentSequence) ||
+		m.currentSequence[m.currentStepIndex].Stack.Identifier() != msg.stackIdentifier {
+		// The sequence was abandoned while waiting for the lock, maybe for another one
+		return releaseLocksCmd([]*runner.StackLock{msg.lock})
+	}
+	if msg.err != nil && len(m.stacksInSequence) <= 1 {
+		m.lastError = msg.err
+		m.currentState = stateSequenceError
+		m.setOutputContent(m.renderSequenceOutput())
+		return nil
+	}
+	if msg.err != nil {
+		m.skipStackSteps(msg.stackIdentifier)
+		m.sequenceResults[msg.stackIdentifier] = msg.err
+		if m.currentStepIndex >= len(m.currentSequence) {
+			return tea.Batch(m.finishSequence()...)
+		}
+		return m.runNextStepCmd()
+	}
+	m.sequenceLock = msg.lock
+	return m.startNextStepCmd()
+}
+
+// releaseSequenceLock returns a command releasing the lock held on the stack
+// the sequence is running on, if any.
+func (m *model) releaseSequenceLock() tea.Cmd {
+	lock := m.sequenceLock
+	m.sequenceLock = nil
+	if lock == nil {
+		return nil
+	}
+	return releaseLocksCmd([]*runner.StackLock{lock})
+}
+
+// skipStackSteps moves the sequence past the remaining steps of a stack.
+func (m *model) skipStackSteps(stackID string) {
+	for m.currentStepIndex < len(m.currentSequence) && m.currentSequence[m.currentStepIndex].Stack.Identifier() == stackID {
+		m.currentStepIndex++
+	}
+}
+
+// finishSequence shows the outcome once every stack's steps are done or
+// skipped: the output, or the result of each stack if any failed or was
+// skipped. The statuses of the stacks involved are refreshed.
+func (m *model) finishSequence() []tea.Cmd {
+	m.setOutputContent(m.renderSequenceOutput())
+	m.viewport.GotoBottom()
+	cmds := []tea.Cmd{m.releaseSequenceLock(), m.completionNotifyCmd(m.sequenceSummary(), m.sequenceFailures() > 0)}
+	if m.sequenceFailures()+m.sequenceSkipped() > 0 {
+		// Show the result of each stack, starting at the first failed or skipped one
+		m.currentState = stateSequenceSummary
+		m.summaryCursor = slices.IndexFunc(m.stacksInSequence, func(s *discovery.Stack) bool {
+			return s != nil && m.sequenceResults[s.Identifier()] != nil
+		})
+	}
+	// Optionally, refresh status of involved stacks after sequence completion
+	for _, stack := range m.stacksInSequence {
+		if stack != nil {
+			stackID := stack.Identifier()
+			if !m.loadingStatus[stackID] {
+				m.loadingStatus[stackID] = true
+				cmds = append(cmds, m.fetchStackStatusCmd(*stack))
+			}
+		}
+	}
+	// Note: We stay in stateRunningSequence view until user presses Back/Enter
+	return cmds
+}
+
 func handleStepFinishedMsg(m *model, msg stepFinishedMsg) tea.Cmd {
 	var cmds []tea.Cmd
 
+	if m.currentState != stateRunningSequence && m.sequenceLock != nil {
+		// The sequence view was left while this step was running
+		cmds = append(cmds, m.releaseSequenceLock())
+	}
+
 	switch m.currentState {
 	case stateSshConfigRemoveConfirm: // This state implies a 'remove host' step was run
 		m.hostToRemove = nil // Clear the host targeted for removal
@@ -254,45 +558,51 @@ func handleStepFinishedMsg(m *model, msg stepFinishedMsg) tea.Cmd {
 	case stateRunningSequence:
 		m.outputChan = nil // Stop listening for output/errors for this step
 		m.errorChan = nil
-		if msg.err != nil {
+		if n := len(m.stepOutputs); n > 0 {
+			m.stepOutputs[n-1].done = true
+			m.stepOutputs[n-1].err = msg.err
+		}
+		stackID := ""
+		if m.currentStepIndex < len(m.currentSequence) {
+			stackID = m.currentSequence[m.currentStepIndex].Stack.Identifier()
+		}
+		if msg.err != nil && len(m.stacksInSequence) <= 1 {
 			// Step failed
+			summary := "Action failed"
+			if m.currentStepIndex < len(m.currentSequence) {
+				step := m.currentSequence[m.currentStepIndex]
+				summary = fmt.Sprintf("%s failed on %s", step.Name, step.Stack.DisplayName())
+			}
 			m.lastError = msg.err
 			m.currentState = stateSequenceError
-			m.outputContent += errorStyle.Render(fmt.Sprintf("\n--- STEP FAILED: %v ---", msg.err)) + "\n"
-			m.viewport.SetContent(m.outputContent)
+			m.setOutputContent(m.renderSequenceOutput())
 			m.viewport.GotoBottom()
+			cmds = append(cmds, m.releaseSequenceLock(), m.completionNotifyCmd(summary, true))
 		} else {
-			// Step succeeded
-			stepName := "Unknown Step"
-			if m.currentSequence != nil && m.currentStepIndex < len(m.currentSequence) {
-				stepName = m.currentSequence[m.currentStepIndex].Name
-			}
-			m.outputContent += successStyle.Render(fmt.Sprintf("\n--- Step '%s' Succeeded ---", stepName)) + "\n"
 			m.currentStepIndex++ // Move to the next step index
+			if msg.err != nil {
+				// In a multi-stack sequence, a failure only ends the failed stack's
+				// steps; the remaining stacks still run
+				m.skipStackSteps(stackID)
+				m.sequenceResults[stackID] = msg.err
+			} else if m.currentStepIndex >= len(m.currentSequence) || m.currentSequence[m.currentStepIndex].Stack.Identifier() != stackID {
+				m.sequenceResults[stackID] = nil // Last step of this stack
+			}
+			if _, done := m.sequenceResults[stackID]; done {
+				// The stack's steps are over, so its lock isn't needed anymore
+				cmds = append(cmds, m.releaseSequenceLock())
+			}
 
 			if m.currentStepIndex >= len(m.currentSequence) {
-				// Sequence finished successfully
-				m.outputContent += successStyle.Render("\n--- Action Sequence Completed Successfully ---") + "\n"
-				m.viewport.SetContent(m.outputContent)
-				m.viewport.GotoBottom()
-				// Optionally, refresh status of involved stacks after sequence completion
-				for _, stack := range m.stacksInSequence {
-					if stack != nil {
-						stackID := stack.Identifier()
-						if !m.loadingStatus[stackID] {
-							m.loadingStatus[stackID] = true
-							cmds = append(cmds, m.fetchStackStatusCmd(*stack))
-						}
-					}
-				}
-				// Note: We stay in stateRunningSequence view until user presses Back/Enter
+				// Sequence finished, with every stack's steps done or skipped
+				cmds = append(cmds, m.finishSequence()...)
 			} else {
 				// Start the next step
-				cmds = append(cmds, m.startNextStepCmd())
+				cmds = append(cmds, m.runNextStepCmd())
 			}
 		}
 
-	case stateRunningHostAction: // e.g., Prune
+	case stateRunningHostAction: // e.g., Prune or runtime restart
 		m.outputChan = nil
 		m.errorChan = nil
 		stepName := "Unknown Action"
@@ -304,21 +614,21 @@ func handleStepFinishedMsg(m *model, msg stepFinishedMsg) tea.Cmd {
 			// Host action failed
 			m.hostActionError = msg.err // Store specific host action error
 			m.lastError = msg.err       // Also update general lastError for display
-			m.outputContent += errorStyle.Render(fmt.Sprintf("\n--- HOST ACTION '%s' FAILED: %v ---", stepName, msg.err)) + "\n"
-			m.viewport.SetContent(m.outputContent)
+			m.setOutputContent(m.outputContent.String() + errorStyle.Render(fmt.Sprintf("\n--- HOST ACTION '%s' FAILED: %v ---", stepName, msg.err)) + "\n")
 			m.viewport.GotoBottom()
 			m.currentState = stateSshConfigList     // Go back to config list
 			cmds = append(cmds, loadSshConfigCmd()) // Reload config state
+			cmds = append(cmds, m.completionNotifyCmd(fmt.Sprintf("%s failed", stepName), true))
 		} else {
 			// Host action succeeded
-			m.outputContent += successStyle.Render(fmt.Sprintf("\n--- Host Action '%s' Completed Successfully ---", stepName)) + "\n"
-			m.viewport.SetContent(m.outputContent)
+			m.setOutputContent(m.outputContent.String() + successStyle.Render(fmt.Sprintf("\n--- Host Action '%s' Completed Successfully ---", stepName)) + "\n")
 			m.viewport.GotoBottom()
 			m.currentState = stateSshConfigList // Go back to config list
-			m.hostsToPrune = nil                // Clear prune targets
+			m.hostActionTargets = nil           // Clear the action's targets
 			m.hostActionError = nil
 			m.lastError = nil                       // Clear last error on success
 			cmds = append(cmds, loadSshConfigCmd()) // Reload config state
+			cmds = append(cmds, m.completionNotifyCmd(fmt.Sprintf("%s completed", stepName), false))
 		}
 		m.currentHostActionStep = runner.HostCommandStep{} // Clear the current host step
 
@@ -327,6 +637,50 @@ func handleStepFinishedMsg(m *model, msg stepFinishedMsg) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// sequenceSummary describes the outcome of the finished sequence, for
+// completion_notify.
+func (m *model) sequenceSummary() string {
+	failed := m.sequenceFailures()
+	if len(m.stacksInSequence) == 1 && m.stacksInSequence[0] != nil {
+		stack := m.stacksInSequence[0]
+		if failed > 0 {
+			return fmt.Sprintf("Action failed on %s", stack.DisplayName())
+		}
+		return fmt.Sprintf("Action completed on %s", stack.DisplayName())
+	}
+	skippedNote := ""
+	if skipped := m.sequenceSkipped(); skipped > 0 {
+		skippedNote = fmt.Sprintf(", %d skipped as busy", skipped)
+	}
+	if failed > 0 {
+		return fmt.Sprintf("Action failed on %d of %d stacks%s", failed, len(m.stacksInSequence), skippedNote)
+	}
+	return fmt.Sprintf("Action completed on %d stacks%s", len(m.stacksInSequence), skippedNote)
+}
+
+// sequenceFailures returns the number of stacks whose steps failed in the current sequence.
+func (m *model) sequenceFailures() int {
+	failed := 0
+	for _, err := range m.sequenceResults {
+		if err != nil && !errors.Is(err, runner.ErrStackBusy) {
+			failed++
+		}
+	}
+	return failed
+}
+
+// sequenceSkipped returns the number of stacks skipped in the current sequence
+// because another operation held their lock.
+func (m *model) sequenceSkipped() int {
+	skipped := 0
+	for _, err := range m.sequenceResults {
+		if errors.Is(err, runner.ErrStackBusy) {
+			skipped++
+		}
+	}
+	return skipped
+}
+
 func handleChannelsAvailableMsg(m *model, msg channelsAvailableMsg) tea.Cmd {
 	// Check the state to ensure we should be expecting channels
 	if m.currentState == stateRunningSequence || m.currentState == stateRunningHostAction {
@@ -345,17 +699,45 @@ func handleChannelsAvailableMsg(m *model, msg channelsAvailableMsg) tea.Cmd {
 func handleOutputLineMsg(m *model, msg outputLineMsg) tea.Cmd {
 	// Check if we are in a state that displays streaming output and have an active channel
 	if (m.currentState == stateRunningSequence || m.currentState == stateRunningHostAction) && m.outputChan != nil {
-		// Append the raw line content. Lipgloss/terminal handles ANSI.
-		m.outputContent += msg.line.Line
-		m.viewport.SetContent(m.outputContent)
-		m.viewport.GotoBottom()
-		// Continue waiting for more output on the same channel
-		return waitForOutputCmd(m.outputChan)
+		// Append the output, collapsing '\r' redraws. Lipgloss/terminal handles ANSI.
+		if m.currentState == stateRunningSequence && len(m.stepOutputs) > 0 {
+			m.stepOutputs[len(m.stepOutputs)-1].content.Append(msg.line.Line)
+		} else {
+			m.outputContent.Append(msg.line.Line)
+		}
+		// Continue waiting for more output on the same channel, and show what
+		// arrived in the meantime on the next flush rather than after every line
+		cmds := []tea.Cmd{waitForOutputCmd(m.outputChan)}
+		if !m.outputFlushPending {
+			m.outputFlushPending = true
+			cmds = append(cmds, outputFlushCmd())
+		}
+		return tea.Batch(cmds...)
 	}
 	// Ignore if not in the right state or channel is closed
 	return nil
 }
 
+// handleOutputFlushMsg shows the output collected since the last flush and
+// scrolls to it. Output arriving after a step or host action finished has
+// already been shown by the finish, so there is nothing left to flush then.
+func handleOutputFlushMsg(m *model) {
+	m.outputFlushPending = false
+	switch m.currentState {
+	case stateRunningSequence:
+		m.setOutputContent(m.renderSequenceOutput())
+	case stateRunningHostAction:
+		m.setOutputContent(m.outputContent.String())
+	case stateRunningBatch:
+		// The output of several stacks is shown at once, so it isn't followed
+		m.setOutputContent(m.renderBatchOutput())
+		return
+	default:
+		return
+	}
+	m.viewport.GotoBottom()
+}
+
 func handleSshHostAddedMsg(m *model, msg sshHostAddedMsg) tea.Cmd {
 	// This message should only be relevant if we were in the AddForm state
 	if m.currentState == stateSshConfigAddForm {
@@ -388,4 +770,107 @@ func handleSshHostEditedMsg(m *model, msg sshHostEditedMsg) tea.Cmd {
 	return nil
 }
 
+// handleGlobalConfigSavedMsg returns to the host list once the global settings
+// are saved, or shows the error on the form. Leaving the host list afterwards
+// reloads the stacks, since local_root may have changed.
+func handleGlobalConfigSavedMsg(m *model, msg globalConfigSavedMsg) tea.Cmd {
+	if m.currentState != stateGlobalConfig {
+		return nil
+	}
+	if msg.err != nil {
+		m.formError = msg.err
+		return nil
+	}
+	m.defaultAction = m.formSettings.DefaultAction
+	m.sshConfigModified = true
+	m.currentState = stateSshConfigList
+	m.formInputs = nil
+	m.importInfoMsg = "Settings saved."
+	return loadSshConfigCmd()
+}
+
+func handleBatchStartNextMsg(m *model) tea.Cmd {
+	if m.currentState != stateRunningBatch || len(m.batchQueue) == 0 {
+		return nil
+	}
+	if len(m.batchRunning) >= m.batchMaxConcurrent {
+		// Defer the start until a running stack finishes
+		m.batchWaiting = true
+		return nil
+	}
+
+	stack := m.batchQueue[0]
+	m.batchQueue = m.batchQueue[1:]
+	stackID := stack.Identifier()
+	m.batchRunning[stackID] = true
+	m.setOutputContent(m.renderBatchOutput())
+
+	cmds := []tea.Cmd{runBatchStackCmd(stackID, runner.RefreshSequence(stack, nil, runner.DefaultStopTimeout))}
+	if len(m.batchQueue) > 0 {
+		cmds = append(cmds, batchTickCmd(m.batchStagger))
+	}
+	return tea.Batch(cmds...)
+}
+
+func handleBatchOutputMsg(m *model, msg batchOutputMsg) tea.Cmd {
+	if m.batchOutputs == nil {
+		return nil
+	}
+	output := m.batchOutputs[msg.stackIdentifier]
+	if output == nil {
+		buffer := newOutputBuffer(m.outputMaxLines)
+		output = &buffer
+		m.batchOutputs[msg.stackIdentifier] = output
+	}
+	output.Append(msg.line.Line)
+	// Shown on the next flush rather than after every line
+	if m.currentState == stateRunningBatch && !m.outputFlushPending {
+		m.outputFlushPending = true
+		return outputFlushCmd()
+	}
+	return nil
+}
+
+func handleBatchStackFinishedMsg(m *model, msg batchStackFinishedMsg) tea.Cmd {
+	if m.batchRunning == nil {
+		return nil
+	}
+	var cmds []tea.Cmd
+	delete(m.batchRunning, msg.stackIdentifier)
+	m.batchResults[msg.stackIdentifier] = msg.err
+	if m.currentState == stateRunningBatch {
+		m.setOutputContent(m.renderBatchOutput())
+	}
+
+	// Refresh the status of the stack that just finished
+	for _, stack := range m.stacks {
+		if stack.Identifier() == msg.stackIdentifier && !m.loadingStatus[msg.stackIdentifier] {
+			m.loadingStatus[msg.stackIdentifier] = true
+			cmds = append(cmds, m.fetchStackStatusCmd(stack))
+			break
+		}
+	}
+
+	// A start was held back by the concurrency limit; the stagger has already elapsed
+	if m.batchWaiting {
+		m.batchWaiting = false
+		cmds = append(cmds, func() tea.Msg { return batchStartNextMsg{} })
+	}
+
+	if m.batchFinished() {
+		failed := 0
+		for _, err := range m.batchResults {
+			if err != nil {
+				failed++
+			}
+		}
+		summary := fmt.Sprintf("Refresh all completed on %d stacks", len(m.batchResults))
+		if failed > 0 {
+			summary = fmt.Sprintf("Refresh all failed on %d of %d stacks", failed, len(m.batchResults))
+		}
+		cmds = append(cmds, m.completionNotifyCmd(summary, failed > 0))
+	}
+	return tea.Batch(cmds...)
+}
+
 // Add other message handlers here as needed...
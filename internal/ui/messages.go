@@ -11,6 +11,7 @@ import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
 	"bucket-manager/internal/runner"
+	"os/exec"
 )
 
 // --- Message Types ---
@@ -21,12 +22,17 @@ import (
 // Stack discovery messages
 type stackDiscoveredMsg struct{ stack discovery.Stack } // Sent when a stack is found
 type discoveryErrorMsg struct{ err error }              // Sent when an error occurs during discovery
-type discoveryFinishedMsg struct{}                      // Sent when all stack discovery is complete
+type discoveryFinishedMsg struct{ hosts []string }      // Sent when all stack discovery is complete, with the hosts searched
 
 // SSH configuration messages
 type sshConfigLoadedMsg struct {
-	hosts []config.SSHHost
-	Err   error
+	hosts               []config.SSHHost
+	diskWarnFreePercent int // Free-space percentage below which disk usage is highlighted
+	Err                 error
+}
+type hostPickerLoadedMsg struct {
+	hosts []string // "local" followed by the enabled configured hosts
+	err   error
 }
 type sshHostAddedMsg struct{ err error }  // Result of adding a new SSH host
 type sshHostEditedMsg struct{ err error } // Result of editing an SSH host
@@ -34,20 +40,83 @@ type sshConfigParsedMsg struct {
 	potentialHosts []config.PotentialHost // Hosts found in ~/.ssh/config
 	err            error
 }
+type globalConfigSavedMsg struct{ err error } // Result of saving the global settings
+type pinnedStacksSavedMsg struct{ err error } // Result of saving the pinned stacks
 type sshHostsImportedMsg struct {
 	importedCount int   // Number of hosts successfully imported
 	skippedCount  int   // Number of hosts skipped (already exist or errors)
 	err           error // Any error that occurred during import
 }
 
+// passphrasePromptMsg asks for the passphrase of an encrypted SSH key. It is
+// sent by PromptKeyPassphrase from the goroutine connecting to the host.
+type passphrasePromptMsg struct {
+	host    config.SSHHost
+	keyPath string
+	retry   bool                   // The previous passphrase was wrong
+	reply   chan<- passphraseReply // Receives the passphrase, or why the prompt was cancelled
+}
+type passphraseReply struct {
+	passphrase string
+	err        error
+}
+
 // Command execution messages
 type outputLineMsg struct{ line runner.OutputLine } // Single line of command output
+type outputFlushMsg struct{}                        // Sent when output collected since the last flush should be shown
 type stepFinishedMsg struct{ err error }            // Notification that a command step finished
+type hostDiskUsageLoadedMsg struct {
+	serverName string               // "local" or the remote host name
+	usage      runner.HostDiskUsage // Disk usage information for the host
+}
+type composeVersionLoadedMsg struct {
+	serverName string                // "local" or the remote host name
+	version    runner.ComposeVersion // Runtime and compose versions of the host
+}
 type stackStatusLoadedMsg struct {
 	stackIdentifier string                  // Identifier of the stack that was checked
 	statusInfo      runner.StackRuntimeInfo // Status information for the stack
 }
+type stackServicesLoadedMsg struct {
+	stackIdentifier string   // Identifier of the stack whose services were listed
+	services        []string // Services defined in the compose file
+	err             error
+}
+type serviceExecReadyMsg struct{ cmd *exec.Cmd } // Sent when an interactive exec session can start
+type serviceExecFinishedMsg struct{ err error }  // Sent when an interactive exec session ends
+
+// stackShellFinishedMsg is sent when an interactive shell in a stack's directory ends.
+type stackShellFinishedMsg struct {
+	stack discovery.Stack
+	err   error
+}
+
+// urlOpenedMsg is sent once the browser was asked to open a stack's web address.
+type urlOpenedMsg struct {
+	url string
+	err error
+}
+
+// sequenceLockedMsg is sent once the lock of the stack whose steps a sequence
+// starts next is taken (or failed).
+type sequenceLockedMsg struct {
+	sequenceID      int
+	stackIdentifier string
+	lock            *runner.StackLock
+	err             error
+}
 type channelsAvailableMsg struct {
 	outChan <-chan runner.OutputLine // Channel for receiving command output
 	errChan <-chan error             // Channel for receiving command errors
 }
+
+// Batch ("refresh all") messages
+type batchStartNextMsg struct{} // Sent when the stagger delay elapses and the next queued stack may start
+type batchOutputMsg struct {
+	stackIdentifier string            // Stack that produced the output
+	line            runner.OutputLine // Chunk of command output
+}
+type batchStackFinishedMsg struct {
+	stackIdentifier string // Stack whose sequence finished
+	err             error  // Error from the first failing step, if any
+}
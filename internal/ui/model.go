@@ -9,11 +9,13 @@ package ui
 import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
+	"bucket-manager/internal/logger"
 	"bucket-manager/internal/runner"
 	"context"
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -30,6 +32,39 @@ import (
 // It's exposed to allow sending messages from outside the UI package
 var BubbleProgram *tea.Program
 
+// discoveryIssue is a collapsed discovery error shown in the stack list footer.
+// Errors from the same host (or identical errors not tied to a host) share one entry.
+type discoveryIssue struct {
+	host    string // Host the error belongs to, empty if not host-specific
+	message string // Most recent error message
+	count   int    // Number of errors collapsed into this entry
+	fatal   bool   // True if discovery as a whole failed (e.g. config load), not just one host
+}
+
+// stepOutput holds the output of one step of the running sequence, so that
+// successful steps can be collapsed when rendering.
+type stepOutput struct {
+	name    string       // Step name
+	target  string       // Identifier of the stack the step runs on
+	content outputBuffer // Output collected so far, with '\r' redraws collapsed
+	done    bool         // True once the step has finished
+	err     error        // Error the step failed with, if any
+}
+
+// lastOutput is the output of the last sequence run on a stack, kept after
+// leaving the output view so it can be reviewed again.
+type lastOutput struct {
+	steps      []stepOutput // The stack's steps, in the order they ran
+	finishedAt time.Time    // When the output view was left
+}
+
+// hostReachability records whether a host could be searched for stacks during
+// the last discovery, and when it last could.
+type hostReachability struct {
+	reachable bool      // True if the last discovery succeeded on the host
+	lastSeen  time.Time // When discovery last succeeded on the host (zero if never)
+}
+
 // model represents the TUI application state
 type model struct {
 	keymap               KeyMap            // Keyboard shortcuts configuration
@@ -37,6 +72,8 @@ type model struct {
 	cursor               int               // Current cursor position in the stack list
 	selectedStackIdxs    map[int]struct{}
 	configCursor         int
+	lastConfigClick      time.Time // When a host in the SSH config list was last clicked, for double-clicks
+	lastConfigClickIdx   int       // configCursor of the last clicked host
 	hostToRemove         *config.SSHHost
 	hostToEdit           *config.SSHHost
 	configuredHosts      []config.SSHHost
@@ -48,10 +85,23 @@ type model struct {
 	currentState         state
 	isDiscovering        bool
 	currentSequence      []runner.CommandStep
+	sequenceLock         *runner.StackLock // Lock held on the stack whose steps are running
+	sequenceID           int               // Incremented by each started sequence, to tell their messages apart
 	currentStepIndex     int
-	outputContent        string
+	outputContent        outputBuffer    // Output of the running host action
+	renderedOutput       string          // Output of the running sequence, host action or batch as last shown, see setOutputContent
+	outputFlushPending   bool            // Output arrived that an outputFlushMsg will show
+	viewportContent      string          // Content last set on viewport, so that View only sets changed content
+	stepOutputs          []stepOutput    // Output of the running sequence, per step
+	collapseStepOutput   bool            // Collapse the output of successful steps to one line
+	outputMaxLines       int             // Lines of output kept per step or host action, zero for all
+	completionNotify     string          // How to alert when the running action ends (config completion_notify, read as it starts)
+	defaultAction        string          // Action run by Enter on a single stack (config default_action)
+	readOnly             bool            // Read-only mode was enabled at startup (shown in the header)
+	pinnedStacks         map[string]bool // Identifiers of the pinned stacks, listed first (config pinned_stacks)
+	actionError          error           // Error from the last action started from the stack list (a stack shell, refresh all)
 	lastError            error
-	discoveryErrors      []error
+	discoveryErrors      []discoveryIssue
 	ready                bool
 	width                int
 	height               int
@@ -62,18 +112,75 @@ type model struct {
 	detailedStack        *discovery.Stack
 	sequenceStack        *discovery.Stack   // The primary stack for the current sequence (used for display)
 	stacksInSequence     []*discovery.Stack // All stacks involved in the current sequence
+	sequenceResults      map[string]error   // Result per stack identifier once its steps are done (nil on success)
+	summaryCursor        int                // Selected stack in the sequence summary
+	outputFilter         string             // Stack whose output is shown after drilling in from the summary ("" for all)
+
+	// Last output state, reviewed with the LastOutput key
+	lastOutputs      map[string]lastOutput // Output of the last sequence per stack identifier
+	lastOutputStack  string                // Stack whose last output is shown
+	lastOutputReturn state                 // View to return to from the last output view
+
+	// Compose command prompt state, opened with the ComposeCommand key
+	composeCommandInput  textinput.Model  // The compose subcommand being typed
+	composeCommandStack  *discovery.Stack // Stack the command will run on
+	composeCommandReturn state            // View to return to if the prompt is cancelled
+	composeCommandError  error            // Why the typed command was refused
+
+	// Passphrase prompt, shown over any view while an encrypted SSH key is decrypted
+	passphrasePrompt *passphrasePromptMsg
+	passphraseInput  textinput.Model
+
+	// Action picker state, opened with the StackActions key in the details view
+	actionPickerNames  []string // Names of the detailed stack's custom actions, sorted
+	actionPickerCursor int
+	actionPickerError  error // Why the chosen action couldn't be run
+
+	// Service list state (single stack details view)
+	detailServices  []string // Services defined in the detailed stack's compose file
+	loadingServices bool     // True while the service list is being fetched
+	servicesError   error    // Error from listing services or the last service action
+	serviceCursor   int      // Selected service in detailServices
+
+	// Host picker state ("jump to host")
+	hostPickerHosts  []string // "local" followed by enabled configured hosts; nil while loading
+	hostPickerCursor int
+	hostPickerError  error
 
 	// Host action state
-	hostsToPrune          []runner.HostTarget // Hosts targeted for prune action
+	hostActionTargets     []runner.HostTarget // Hosts targeted by the pending prune or runtime restart
 	currentHostActionStep runner.HostCommandStep
 	hostActionError       error
 
+	// Host disk usage state (shown in the SSH config list)
+	hostDiskUsage       map[string]runner.HostDiskUsage // Disk usage per server name
+	loadingDiskUsage    map[string]bool                 // Server names with a disk check in flight
+	diskWarnFreePercent int                             // Free-space percentage below which usage is highlighted
+
+	// Runtime and compose versions per server name (shown in the SSH config list)
+	composeVersions       map[string]runner.ComposeVersion
+	loadingComposeVersion map[string]bool // Server names with a version check in flight
+
+	// Outcome of the last discovery per server name (shown in the SSH config list)
+	hostReachability map[string]hostReachability
+
+	// Batch ("refresh all") state
+	batchQueue         []discovery.Stack        // Stacks waiting to be started
+	batchOrder         []string                 // Identifiers in the order they were queued
+	batchRunning       map[string]bool          // Identifiers of stacks currently running
+	batchResults       map[string]error         // Finished stacks and their result (nil on success)
+	batchOutputs       map[string]*outputBuffer // Output collected per stack
+	batchStagger       time.Duration            // Delay between stack starts
+	batchMaxConcurrent int                      // Maximum number of stacks running at once
+	batchWaiting       bool                     // True if a start was deferred because the concurrency limit was reached
+
 	// Form state (Add/Edit/Import Details)
 	formInputs     []textinput.Model
 	formFocusIndex int  // Logical focus index within the current form
 	formAuthMethod int  // Selected auth method (authMethodKey, etc.)
 	formDisabled   bool // For edit form's disabled toggle
 	formError      error
+	formSettings   globalSettings // Selector values of the global settings form
 
 	// Import state
 	importableHosts    []config.PotentialHost
@@ -84,9 +191,52 @@ type model struct {
 	hostsToConfigure   []config.SSHHost    // Hosts built from import details form
 	configuringHostIdx int                 // Index in importableHosts currently being configured
 	statusCheckSem     *semaphore.Weighted // Semaphore for limiting status checks
+	pendingStatuses    []discovery.Stack   // Discovered stacks whose statuses are fetched together once discovery finishes
 	sshConfigModified  bool                // Flag indicating if SSH config was changed since entering the view
 }
 
+// fetchHostDiskUsageCmd fetches disk usage for a host, sharing the status check concurrency limit.
+func (m *model) fetchHostDiskUsageCmd(target runner.HostTarget) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		if err := m.statusCheckSem.Acquire(ctx, 1); err != nil {
+			return hostDiskUsageLoadedMsg{
+				serverName: target.ServerName,
+				usage: runner.HostDiskUsage{
+					Target: target,
+					Error:  fmt.Errorf("failed to acquire status check semaphore: %w", err),
+				},
+			}
+		}
+		defer m.statusCheckSem.Release(1)
+
+		return hostDiskUsageLoadedMsg{
+			serverName: target.ServerName,
+			usage:      runner.GetHostDiskUsage(target),
+		}
+	}
+}
+
+// fetchComposeVersionCmd fetches the runtime and compose versions of a host,
+// sharing the status check concurrency limit.
+func (m *model) fetchComposeVersionCmd(target runner.HostTarget) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		if err := m.statusCheckSem.Acquire(ctx, 1); err != nil {
+			return composeVersionLoadedMsg{
+				serverName: target.ServerName,
+				version:    runner.ComposeVersion{Error: fmt.Errorf("failed to acquire status check semaphore: %w", err)},
+			}
+		}
+		defer m.statusCheckSem.Release(1)
+
+		return composeVersionLoadedMsg{
+			serverName: target.ServerName,
+			version:    runner.GetComposeVersion(target),
+		}
+	}
+}
+
 // fetchStackStatusCmd fetches the status for a single stack, respecting concurrency limits.
 func (m *model) fetchStackStatusCmd(stack discovery.Stack) tea.Cmd {
 	return func() tea.Msg {
@@ -111,29 +261,77 @@ func (m *model) fetchStackStatusCmd(stack discovery.Stack) tea.Cmd {
 	}
 }
 
+// fetchStackStatusesCmd fetches the statuses of many stacks at once with
+// runner.GetStackStatuses, which checks the stacks of each remote host with one
+// SSH command, sending a stackStatusLoadedMsg as each status comes in.
+func fetchStackStatusesCmd(stacks []discovery.Stack) tea.Cmd {
+	return func() tea.Msg {
+		for statusInfo := range runner.GetStackStatuses(stacks) {
+			if BubbleProgram != nil {
+				BubbleProgram.Send(stackStatusLoadedMsg{
+					stackIdentifier: statusInfo.Stack.Identifier(),
+					statusInfo:      statusInfo,
+				})
+			}
+		}
+		return nil
+	}
+}
+
+// loadKeyMap returns DefaultKeyMap with any keybinding overrides from the config
+// applied. Invalid overrides are logged and the defaults are used instead.
+func loadKeyMap() KeyMap {
+	cfg, err := config.LoadConfig()
+	if err != nil || len(cfg.Keybindings) == 0 {
+		return DefaultKeyMap
+	}
+	km, err := applyKeybindingOverrides(DefaultKeyMap, cfg.Keybindings)
+	if err != nil {
+		logger.Warn("Invalid keybindings in config, using defaults", "error", err)
+		return DefaultKeyMap
+	}
+	logger.Info("Applied custom keybindings", "overrides", len(cfg.Keybindings))
+	return km
+}
+
 func InitialModel() model {
 	vp := viewport.New(0, 0)
+	cfg, _ := config.LoadConfig()
 	m := model{
-		keymap:               DefaultKeyMap,
-		currentState:         stateLoadingStacks,
-		isDiscovering:        true,
-		cursor:               0,
-		selectedStackIdxs:    make(map[int]struct{}),
-		configCursor:         0,
-		stackStatuses:        make(map[string]runner.StackRuntimeInfo),
-		loadingStatus:        make(map[string]bool),
-		configuredHosts:      []config.SSHHost{},
-		discoveryErrors:      []error{},
-		detailedStack:        nil,
-		sequenceStack:        nil,
-		stacksInSequence:     nil,
-		viewport:             vp,
-		sshConfigViewport:    vp,
-		detailsViewport:      vp,
-		formViewport:         vp,
-		importSelectViewport: vp,
-		statusCheckSem:       semaphore.NewWeighted(maxConcurrentStatusChecks),
-		sshConfigModified:    false,
+		keymap:                loadKeyMap(),
+		defaultAction:         cfg.GetDefaultAction(),
+		readOnly:              config.IsReadOnly(),
+		pinnedStacks:          make(map[string]bool, len(cfg.PinnedStacks)),
+		currentState:          stateLoadingStacks,
+		isDiscovering:         true,
+		cursor:                0,
+		selectedStackIdxs:     make(map[int]struct{}),
+		configCursor:          0,
+		stackStatuses:         make(map[string]runner.StackRuntimeInfo),
+		loadingStatus:         make(map[string]bool),
+		hostDiskUsage:         make(map[string]runner.HostDiskUsage),
+		hostReachability:      make(map[string]hostReachability),
+		loadingDiskUsage:      make(map[string]bool),
+		composeVersions:       make(map[string]runner.ComposeVersion),
+		loadingComposeVersion: make(map[string]bool),
+		diskWarnFreePercent:   config.DefaultDiskWarnFreePercent,
+		outputMaxLines:        cfg.GetOutputMaxLines(),
+		completionNotify:      cfg.CompletionNotify,
+		configuredHosts:       []config.SSHHost{},
+		discoveryErrors:       []discoveryIssue{},
+		detailedStack:         nil,
+		sequenceStack:         nil,
+		stacksInSequence:      nil,
+		viewport:              vp,
+		sshConfigViewport:     vp,
+		detailsViewport:       vp,
+		formViewport:          vp,
+		importSelectViewport:  vp,
+		statusCheckSem:        semaphore.NewWeighted(maxConcurrentStatusChecks),
+		sshConfigModified:     false,
+	}
+	for _, identifier := range cfg.PinnedStacks {
+		m.pinnedStacks[identifier] = true
 	}
 	return m
 }
@@ -172,9 +370,11 @@ func getKeyBindings(km KeyMap) []key.Binding {
 		km.Up, km.Down, km.Left, km.Right, km.PgUp, km.PgDown, km.Home, km.End,
 		km.Quit, km.Enter, km.Esc, km.Back, km.Select, km.Tab, km.ShiftTab,
 		km.Yes, km.No,
-		km.Config, km.UpAction, km.DownAction, km.RefreshAction, km.PullAction,
-		km.Remove, km.Add, km.Import, km.Edit,
-		km.ToggleDisabled, km.PruneAction,
+		km.Config, km.UpAction, km.DownAction, km.RefreshAction, km.PullAction, km.CreateAction, km.StopAction, km.StartAction, km.RefreshAllAction, km.RecheckErrored, km.JumpToHost, km.PinAction, km.StackShell, km.ComposeCommand, km.StackActions,
+		km.ServiceLogsAction, km.ServiceRestartAction, km.ServiceExecAction, km.OpenURLAction,
+		km.ToggleStepOutput, km.LastOutput,
+		km.Remove, km.Add, km.Import, km.Edit, km.GlobalSettings,
+		km.ToggleDisabled, km.PruneAction, km.RestartRuntimeAction,
 	}
 }
 
@@ -199,6 +399,8 @@ func (m *model) getCurrentFooterString() string {
 		_, footerStr = m.renderSshConfigRemoveConfirmView()
 	case statePruneConfirm:
 		_, footerStr = m.renderPruneConfirmView()
+	case stateRestartRuntimeConfirm:
+		_, footerStr = m.renderRestartRuntimeConfirmView()
 	case stateRunningHostAction:
 		_, footerStr = m.renderRunningHostActionView()
 	case stateSshConfigAddForm:
@@ -209,9 +411,26 @@ func (m *model) getCurrentFooterString() string {
 		_, footerStr = m.renderSshConfigImportSelectView()
 	case stateSshConfigImportDetails:
 		_, footerStr = m.renderSshConfigImportDetailsView()
+	case stateRunningBatch:
+		_, footerStr = m.renderRunningBatchView()
+	case stateHostPicker:
+		_, footerStr = m.renderHostPickerView()
+	case stateSequenceSummary:
+		_, footerStr = m.renderSequenceSummaryView()
+	case stateLastOutput:
+		_, footerStr = m.renderLastOutputView()
+	case stateComposeCommand:
+		_, footerStr = m.renderComposeCommandView()
+	case stateActionPicker:
+		_, footerStr = m.renderActionPickerView()
+	case stateGlobalConfig:
+		_, footerStr = m.renderGlobalConfigView()
 	default:
 		footerStr = m.keymap.Quit.Help().Key + ": " + m.keymap.Quit.Help().Desc
 	}
+	if m.passphrasePrompt != nil {
+		_, footerStr = m.renderPassphrasePromptView()
+	}
 	return strings.TrimPrefix(footerStr, "\n")
 }
 
@@ -273,13 +492,13 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	var vpCmd tea.Cmd
 
-	viewportActive := m.currentState == stateRunningSequence
+	viewportActive := m.currentState == stateRunningSequence || m.currentState == stateSequenceError
 
 	switch msg := msg.(type) {
 	case tea.MouseMsg:
 		// Pass mouse messages to viewports for scrolling, etc.
 		switch m.currentState {
-		case stateStackList, stateRunningSequence, stateSequenceError, stateRunningHostAction:
+		case stateStackList, stateRunningSequence, stateSequenceError, stateRunningHostAction, stateRunningBatch, stateLastOutput:
 			m.viewport, vpCmd = m.viewport.Update(msg)
 			cmds = append(cmds, vpCmd)
 		case stateStackDetails:
@@ -288,7 +507,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case stateSshConfigList:
 			m.sshConfigViewport, vpCmd = m.sshConfigViewport.Update(msg)
 			cmds = append(cmds, vpCmd)
-		case stateSshConfigAddForm, stateSshConfigEditForm, stateSshConfigImportDetails:
+		case stateSshConfigAddForm, stateSshConfigEditForm, stateSshConfigImportDetails, stateGlobalConfig:
 			m.formViewport, vpCmd = m.formViewport.Update(msg)
 			cmds = append(cmds, vpCmd)
 		case stateSshConfigImportSelect:
@@ -320,6 +539,9 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							} else {
 								m.selectedStackIdxs[m.cursor] = struct{}{}
 							}
+						} else if len(m.selectedStackIdxs) == 0 {
+							// Clicking a stack always opens its details, whatever default_action is
+							cmds = append(cmds, m.showStackDetails(m.stacks[m.cursor])...)
 						} else {
 							enterKeyMsg := tea.KeyMsg{Type: tea.KeyEnter}
 							keyCmds := m.handleStackListKeys(enterKeyMsg)
@@ -327,6 +549,34 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 					}
 				}
+			case stateSshConfigList:
+				if clickedInBodyRelativeY >= 0 && clickedInBodyRelativeY < m.sshConfigViewport.Height {
+					bodyClicked = true
+					// Item 0 is "local", followed by the configured hosts
+					clickedItemIndex := m.sshConfigViewport.YOffset + clickedInBodyRelativeY - configListHeaderLines
+					if clickedItemIndex >= 0 && clickedItemIndex <= len(m.configuredHosts) {
+						doubleClick := clickedItemIndex == m.lastConfigClickIdx && time.Since(m.lastConfigClick) < doubleClickInterval
+						m.configCursor = clickedItemIndex
+						m.lastConfigClickIdx = clickedItemIndex
+						m.lastConfigClick = time.Now()
+						if doubleClick && clickedItemIndex > 0 {
+							m.lastConfigClick = time.Time{} // A third click starts a new double-click
+							cmds = append(cmds, m.createSimulatedKeyCmd(m.keymap.Edit))
+						}
+					}
+				}
+			case stateStackDetails:
+				if m.detailedStack != nil && !m.loadingServices && len(m.detailServices) > 0 &&
+					clickedInBodyRelativeY >= 0 && clickedInBodyRelativeY < m.detailsViewport.Height {
+					bodyClicked = true
+					// The services are the last lines of the single stack details
+					detailsBody, _ := m.renderStackDetailsView()
+					firstServiceLine := strings.Count(detailsBody, "\n") - len(m.detailServices)
+					clickedService := m.detailsViewport.YOffset + clickedInBodyRelativeY - firstServiceLine
+					if clickedService >= 0 && clickedService < len(m.detailServices) {
+						m.serviceCursor = clickedService
+					}
+				}
 			case stateSshConfigImportSelect:
 				if clickedInBodyRelativeY >= 0 && clickedInBodyRelativeY < m.importSelectViewport.Height {
 					bodyClicked = true
@@ -463,12 +713,18 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.KeyMsg:
+		if m.passphrasePrompt != nil {
+			return m.handlePassphraseKeys(msg)
+		}
 		if viewportActive {
 			return m.handleViewportKeys(msg)
 		}
+		if m.currentState == stateRunningBatch {
+			return m.handleBatchKeys(msg)
+		}
 
 		// --- Handle Form Input Updates First (if applicable) ---
-		isFormState := m.currentState == stateSshConfigAddForm || m.currentState == stateSshConfigEditForm || m.currentState == stateSshConfigImportDetails
+		isFormState := m.currentState == stateSshConfigAddForm || m.currentState == stateSshConfigEditForm || m.currentState == stateSshConfigImportDetails || m.currentState == stateGlobalConfig
 		if isFormState {
 			inputCmd := m.handleFormInputUpdates(msg)
 			if inputCmd != nil {
@@ -509,18 +765,49 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = slices.Concat(cmds, m.handleStackListKeys(msg))
 			}
 
+		case stateHostPicker:
+			if key.Matches(msg, m.keymap.Quit) {
+				return m, tea.Quit
+			}
+			cmds = slices.Concat(cmds, m.handleHostPickerKeys(msg))
+
+		case stateSequenceSummary:
+			if key.Matches(msg, m.keymap.Quit) {
+				return m, tea.Quit
+			}
+			cmds = slices.Concat(cmds, m.handleSequenceSummaryKeys(msg))
+
+		case stateLastOutput:
+			return m.handleLastOutputKeys(msg)
+
+		case stateComposeCommand:
+			return m.handleComposeCommandKeys(msg)
+
+		case stateActionPicker:
+			if key.Matches(msg, m.keymap.Quit) {
+				return m, tea.Quit
+			}
+			cmds = slices.Concat(cmds, m.handleActionPickerKeys(msg))
+
 		case stateStackDetails:
-			switch {
-			case key.Matches(msg, m.keymap.Quit):
+			if key.Matches(msg, m.keymap.Quit) {
 				return m, tea.Quit
-			case key.Matches(msg, m.keymap.Back):
-				m.currentState = stateStackList
-				m.detailedStack = nil
+			}
+			detailCmds, consumed := m.handleStackDetailsKeys(msg)
+			cmds = slices.Concat(cmds, detailCmds)
+			if consumed {
+				return m, tea.Batch(cmds...)
 			}
 
 		case stateSshConfigList:
 			totalItems := len(m.configuredHosts) + 1 // Includes "local"
 
+			if err := m.checkHostListKeyWritable(msg); err != nil {
+				m.lastError = err
+				m.importInfoMsg = ""
+				return m, nil
+			}
+
 			switch {
 			case key.Matches(msg, m.keymap.Quit):
 				return m, tea.Quit
@@ -597,28 +884,18 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					m.lastError = fmt.Errorf("cannot edit 'local' host")
 				}
+			case key.Matches(msg, m.keymap.GlobalSettings):
+				cmds = append(cmds, m.openGlobalConfigForm())
 			case key.Matches(msg, m.keymap.PruneAction):
-				m.hostsToPrune = nil
-				m.hostActionError = nil
-				m.lastError = nil
-
-				if m.configCursor == 0 { // "local" selected
-					m.hostsToPrune = []runner.HostTarget{{IsRemote: false, ServerName: "local"}}
-				} else if m.configCursor > 0 && m.configCursor < totalItems { // A remote host selected
-					remoteHostIndex := m.configCursor - 1
-					host := m.configuredHosts[remoteHostIndex]
-					if !host.Disabled {
-						m.hostsToPrune = append(m.hostsToPrune, runner.HostTarget{IsRemote: true, HostConfig: &host, ServerName: host.Name})
-					} else {
-						m.lastError = fmt.Errorf("cannot prune disabled host: %s", host.Name)
-					}
-				} else {
-					m.lastError = fmt.Errorf("invalid selection for prune action")
-				}
-
-				if len(m.hostsToPrune) > 0 && m.lastError == nil {
+				if m.selectHostActionTarget("prune") {
 					m.currentState = statePruneConfirm
 				}
+			case key.Matches(msg, m.keymap.RestartRuntimeAction):
+				if m.selectHostActionTarget("restart the runtime of") {
+					// Built now so the confirmation shows the command that will run
+					m.currentHostActionStep = runner.RestartRuntimeHostStep(m.hostActionTargets[0])
+					m.currentState = stateRestartRuntimeConfirm
+				}
 			}
 			if vpCmd == nil { // Update viewport only if no specific command was generated for it yet
 				m.sshConfigViewport, vpCmd = m.sshConfigViewport.Update(msg)
@@ -657,6 +934,18 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = slices.Concat(cmds, m.handleSshAddFormKeys(msg))
 			}
 
+		case stateGlobalConfig:
+			switch {
+			case key.Matches(msg, m.keymap.Esc):
+				m.currentState = stateSshConfigList
+				m.formError = nil
+				m.formInputs = nil
+			case key.Matches(msg, m.keymap.Quit):
+				return m, tea.Quit
+			default:
+				cmds = slices.Concat(cmds, m.handleGlobalConfigKeys(msg))
+			}
+
 		case stateSshConfigEditForm:
 			switch {
 			case key.Matches(msg, m.keymap.Esc):
@@ -778,13 +1067,18 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case statePruneConfirm:
 			switch {
 			case key.Matches(msg, m.keymap.Yes):
-				if len(m.hostsToPrune) > 0 {
-					m.outputContent = statusStyle.Render(fmt.Sprintf("Initiating prune for %s...", m.hostsToPrune[0].ServerName)) + "\n"
+				if len(m.hostActionTargets) > 0 {
+					m.outputContent = newOutputBuffer(m.outputMaxLines)
+					m.outputContent.Append(statusStyle.Render(fmt.Sprintf("Initiating prune for %s...", m.hostActionTargets[0].ServerName)) + "\n")
 					m.currentState = stateRunningHostAction
 					m.hostActionError = nil
-					step := runner.PruneHostStep(m.hostsToPrune[0])
+					// LoadConfig returns a zero Config on error, which gives no alert
+					cfg, _ := config.LoadConfig()
+					m.completionNotify = cfg.CompletionNotify
+					// The TUI always runs a full prune; narrower ones are left to the CLI and web API
+					step := runner.PruneHostStep(m.hostActionTargets[0], runner.PruneOptions{})
 					m.currentHostActionStep = step
-					m.viewport.SetContent(m.outputContent) // Ensure viewport shows the initial message
+					m.setOutputContent(m.outputContent.String()) // Ensure viewport shows the initial message
 					m.viewport.GotoBottom()
 					cmds = append(cmds, runHostActionCmd(step))
 				} else {
@@ -794,7 +1088,34 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			case key.Matches(msg, m.keymap.No), key.Matches(msg, m.keymap.Back):
 				m.currentState = stateSshConfigList
-				m.hostsToPrune = nil
+				m.hostActionTargets = nil
+				m.lastError = nil
+			case key.Matches(msg, m.keymap.Quit):
+				return m, tea.Quit
+			}
+
+		case stateRestartRuntimeConfirm:
+			switch {
+			case key.Matches(msg, m.keymap.Yes):
+				if len(m.hostActionTargets) > 0 && m.currentHostActionStep.Name != "" {
+					m.outputContent = newOutputBuffer(m.outputMaxLines)
+					m.outputContent.Append(statusStyle.Render(fmt.Sprintf("Restarting the container runtime on %s...", m.hostActionTargets[0].ServerName)) + "\n")
+					m.currentState = stateRunningHostAction
+					m.hostActionError = nil
+					// LoadConfig returns a zero Config on error, which gives no alert
+					cfg, _ := config.LoadConfig()
+					m.completionNotify = cfg.CompletionNotify
+					m.setOutputContent(m.outputContent.String())
+					m.viewport.GotoBottom()
+					cmds = append(cmds, runHostActionCmd(m.currentHostActionStep))
+				} else {
+					m.currentState = stateSshConfigList
+					m.lastError = fmt.Errorf("internal error: no host targeted for runtime restart")
+				}
+			case key.Matches(msg, m.keymap.No), key.Matches(msg, m.keymap.Back):
+				m.currentState = stateSshConfigList
+				m.hostActionTargets = nil
+				m.currentHostActionStep = runner.HostCommandStep{}
 				m.lastError = nil
 			case key.Matches(msg, m.keymap.Quit):
 				return m, tea.Quit
@@ -827,10 +1148,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 	case discoveryFinishedMsg:
-		// The 'msg' parameter is implicitly used by handleDiscoveryFinishedMsg
-		// if it needs to access fields from discoveryFinishedMsg.
-		// If not, it can be simplified further.
-		cmd := handleDiscoveryFinishedMsg(m)
+		cmd := handleDiscoveryFinishedMsg(m, msg)
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
@@ -839,6 +1157,21 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	case hostPickerLoadedMsg:
+		cmd := handleHostPickerLoadedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case hostDiskUsageLoadedMsg:
+		cmd := handleHostDiskUsageLoadedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case composeVersionLoadedMsg:
+		cmd := handleComposeVersionLoadedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 	case stackStatusLoadedMsg:
 		cmd := handleStackStatusLoadedMsg(m, msg)
 		if cmd != nil {
@@ -849,6 +1182,34 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	case stackServicesLoadedMsg:
+		cmd := handleStackServicesLoadedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case serviceExecReadyMsg:
+		cmds = append(cmds, tea.ExecProcess(msg.cmd, func(err error) tea.Msg {
+			return serviceExecFinishedMsg{err: err}
+		}))
+	case serviceExecFinishedMsg:
+		cmd := handleServiceExecFinishedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case stackShellFinishedMsg:
+		cmd := handleStackShellFinishedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case urlOpenedMsg:
+		handleURLOpenedMsg(m, msg)
+	case passphrasePromptMsg:
+		cmds = append(cmds, handlePassphrasePromptMsg(m, msg))
+	case sequenceLockedMsg:
+		cmd := handleSequenceLockedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 	case channelsAvailableMsg:
 		cmd := handleChannelsAvailableMsg(m, msg)
 		if cmd != nil {
@@ -859,20 +1220,46 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	case outputFlushMsg:
+		handleOutputFlushMsg(m)
 	case sshHostAddedMsg:
 		cmd := handleSshHostAddedMsg(m, msg)
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	case globalConfigSavedMsg:
+		cmd := handleGlobalConfigSavedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case pinnedStacksSavedMsg:
+		if msg.err != nil {
+			m.actionError = msg.err
+		}
 	case sshHostEditedMsg:
 		cmd := handleSshHostEditedMsg(m, msg)
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+	case batchStartNextMsg:
+		cmd := handleBatchStartNextMsg(m)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case batchOutputMsg:
+		cmd := handleBatchOutputMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case batchStackFinishedMsg:
+		cmd := handleBatchStackFinishedMsg(m, msg)
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 	}
 
 	// --- Viewport and Form Input Updates ---
-	isFormState := m.currentState == stateSshConfigAddForm || m.currentState == stateSshConfigEditForm || m.currentState == stateSshConfigImportDetails
+	isFormState := m.currentState == stateSshConfigAddForm || m.currentState == stateSshConfigEditForm || m.currentState == stateSshConfigImportDetails || m.currentState == stateGlobalConfig
 
 	if isFormState && vpCmd == nil {
 		shouldUpdateViewport := true
@@ -903,6 +1290,13 @@ func (m *model) View() string {
 
 	var header, bodyStr, footerStr, bodyContent string
 	header = titleStyle.Render("Bucket Manager")
+	if m.readOnly {
+		header += warningStyle.Render(" [read-only]")
+	}
+	// The summary is dropped rather than wrapped on narrow terminals, keeping the header one line
+	if summary := m.renderStatusSummary(); summary != "" && lipgloss.Width(header+"  "+summary) <= m.width {
+		header += "  " + summary
+	}
 
 	// Call state-specific render function
 	switch m.currentState {
@@ -922,6 +1316,8 @@ func (m *model) View() string {
 		bodyContent, footerStr = m.renderSshConfigRemoveConfirmView()
 	case statePruneConfirm:
 		bodyContent, footerStr = m.renderPruneConfirmView()
+	case stateRestartRuntimeConfirm:
+		bodyContent, footerStr = m.renderRestartRuntimeConfirmView()
 	case stateRunningHostAction:
 		bodyContent, footerStr = m.renderRunningHostActionView()
 	case stateSshConfigAddForm:
@@ -932,10 +1328,27 @@ func (m *model) View() string {
 		bodyContent, footerStr = m.renderSshConfigImportSelectView()
 	case stateSshConfigImportDetails:
 		bodyContent, footerStr = m.renderSshConfigImportDetailsView()
+	case stateRunningBatch:
+		bodyContent, footerStr = m.renderRunningBatchView()
+	case stateHostPicker:
+		bodyContent, footerStr = m.renderHostPickerView()
+	case stateSequenceSummary:
+		bodyContent, footerStr = m.renderSequenceSummaryView()
+	case stateLastOutput:
+		bodyContent, footerStr = m.renderLastOutputView()
+	case stateComposeCommand:
+		bodyContent, footerStr = m.renderComposeCommandView()
+	case stateActionPicker:
+		bodyContent, footerStr = m.renderActionPickerView()
+	case stateGlobalConfig:
+		bodyContent, footerStr = m.renderGlobalConfigView()
 	default:
 		bodyContent = errorStyle.Render(fmt.Sprintf("Error: Unknown view state %d", m.currentState))
 		footerStr = m.keymap.Quit.Help().Key + ": " + m.keymap.Quit.Help().Desc
 	}
+	if m.passphrasePrompt != nil {
+		bodyContent, footerStr = m.renderPassphrasePromptView()
+	}
 
 	actualHeaderRenderHeight := lipgloss.Height(header) // Should be 1 if titleStyle is single line
 	actualFooterRenderHeight := lipgloss.Height(footerStr)
@@ -975,10 +1388,15 @@ func (m *model) View() string {
 		}
 
 		switch m.currentState {
-		case stateStackList, stateRunningSequence, stateSequenceError, stateRunningHostAction:
+		case stateStackList, stateRunningSequence, stateSequenceError, stateRunningHostAction, stateRunningBatch, stateLastOutput:
 			m.viewport.Height = contentHeight
 			m.viewport.Width = contentWidth
-			m.viewport.SetContent(bodyContent)
+			if bodyContent != m.viewportContent {
+				// Setting the content splits it into lines, which gets slow on
+				// long output; View runs on every message, so only changes are set
+				m.viewport.SetContent(bodyContent)
+				m.viewportContent = bodyContent
+			}
 			renderedBodyContent = m.viewport.View()
 		case stateStackDetails:
 			m.detailsViewport.Height = contentHeight
@@ -990,7 +1408,7 @@ func (m *model) View() string {
 			m.sshConfigViewport.Width = contentWidth
 			m.sshConfigViewport.SetContent(bodyContent)
 			renderedBodyContent = m.sshConfigViewport.View()
-		case stateSshConfigAddForm, stateSshConfigEditForm, stateSshConfigImportDetails:
+		case stateSshConfigAddForm, stateSshConfigEditForm, stateSshConfigImportDetails, stateGlobalConfig:
 			m.formViewport.Height = contentHeight
 			m.formViewport.Width = contentWidth
 			m.formViewport.SetContent(bodyContent)
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package ui's output.go file collects command output for the output views. It
+// collapses carriage-return redraws, so progress bars don't fill the views with
+// intermediate frames, keeps appending cheap however long the output grows, and
+// caps how much of it is kept (output_max_lines) like a terminal's scrollback.
+
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// outputBuffer collects the output of a command with appendOutput's handling of
+// '\r'. Only the line being written is ever rewritten, so appending a chunk
+// costs the same after thousands of lines as after one. The zero value is an
+// empty buffer without a line limit.
+type outputBuffer struct {
+	text      []byte // Output collected so far
+	lineStart int    // Offset in text of the line being written
+	lines     int    // Number of complete lines in text
+	maxLines  int    // Most complete lines kept; zero keeps them all
+	dropped   int    // Number of earlier lines dropped to stay within maxLines
+}
+
+// newOutputBuffer returns an empty buffer that keeps the last maxLines complete
+// lines, dropping older ones; zero keeps everything.
+func newOutputBuffer(maxLines int) outputBuffer {
+	return outputBuffer{maxLines: maxLines}
+}
+
+// Append appends a chunk of command output.
+func (o *outputBuffer) Append(chunk string) {
+	line := o.text[o.lineStart:]
+	if bytes.IndexByte(line, '\r') < 0 && !strings.Contains(chunk, "\r") {
+		o.text = append(o.text, chunk...)
+	} else {
+		o.text = append(o.text[:o.lineStart], appendOutput(string(line), chunk)...)
+	}
+	if i := bytes.LastIndexByte(o.text[o.lineStart:], '\n'); i >= 0 {
+		o.lines += bytes.Count(o.text[o.lineStart:o.lineStart+i+1], []byte{'\n'})
+		o.lineStart += i + 1
+	}
+	if o.maxLines > 0 && o.lines > o.maxLines {
+		o.dropLines(o.lines - o.maxLines)
+	}
+}
+
+// dropLines drops the first n complete lines. The text is resliced rather than
+// copied, and the dropped bytes are freed once appending outgrows the array.
+func (o *outputBuffer) dropLines(n int) {
+	cut := 0
+	for range n {
+		cut += bytes.IndexByte(o.text[cut:], '\n') + 1
+	}
+	o.text = o.text[cut:]
+	o.lineStart -= cut
+	o.lines -= n
+	o.dropped += n
+}
+
+// String returns the collected output for rendering, as displayOutput does,
+// after a marker if earlier lines were dropped.
+func (o *outputBuffer) String() string {
+	if o.dropped > 0 {
+		return fmt.Sprintf("[output truncated, %d earlier lines dropped]\n", o.dropped) + displayOutput(string(o.text))
+	}
+	return displayOutput(string(o.text))
+}
+
+// appendOutput appends a chunk of command output to content the way a terminal
+// would show it: text after a '\r' replaces the line the '\r' returned to, so
+// only the final state of a redrawn line is kept. A '\r' at the very end is kept
+// pending, since the next chunk may start with the '\n' of a "\r\n".
+func appendOutput(content, chunk string) string {
+	lineStart := strings.LastIndexByte(content, '\n') + 1
+	if !strings.Contains(content[lineStart:], "\r") && !strings.Contains(chunk, "\r") {
+		return content + chunk
+	}
+
+	var b strings.Builder
+	b.WriteString(content[:lineStart])
+	lines := strings.Split(content[lineStart:]+chunk, "\n")
+	for i, line := range lines {
+		text := strings.TrimRight(line, "\r")
+		if cr := strings.LastIndexByte(text, '\r'); cr >= 0 {
+			text = text[cr+1:]
+		}
+		b.WriteString(text)
+		switch {
+		case i < len(lines)-1:
+			b.WriteByte('\n')
+		case len(text) < len(line) && strings.HasSuffix(line, "\r"):
+			b.WriteByte('\r')
+		}
+	}
+	return b.String()
+}
+
+// displayOutput returns output collected with appendOutput for rendering,
+// without a pending '\r' that would move the cursor when drawn.
+func displayOutput(content string) string {
+	return strings.TrimSuffix(content, "\r")
+}
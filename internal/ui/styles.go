@@ -16,6 +16,7 @@ var (
 	statusStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))            // Blue status messages
 	stepStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))            // Yellow step indicators
 	successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))            // Green success messages
+	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))            // Yellow warnings
 	cursorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))             // Magenta cursor indicator
 
 	// Stack status indicator styles
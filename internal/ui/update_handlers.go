@@ -11,9 +11,12 @@ import (
 	"bucket-manager/internal/config"
 	"bucket-manager/internal/discovery"
 	"bucket-manager/internal/runner"
+	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -35,6 +38,8 @@ import (
 // - Space: Select/deselect a stack for batch operations
 // - Enter: View detailed information about the selected stack
 // - u/d/r/p: Shortcut keys for stack operations (up/down/refresh/pull)
+// - R: Refresh all stacks using the staggered batch queue
+// - f: Pin the stack to the top of the list, or unpin it
 // - c: Switch to SSH configuration view
 // - q/Ctrl+C: Quit the application
 //
@@ -105,13 +110,49 @@ func (m *model) handleStackListKeys(msg tea.KeyMsg) []tea.Cmd {
 				}
 			}
 		case key.Matches(msg, m.keymap.UpAction):
-			cmds = slices.Concat(cmds, m.runSequenceOnSelection(runner.UpSequence))
+			cmds = slices.Concat(cmds, m.runSequenceOnSelection(withDefaultProfiles(runner.UpSequence)))
 		case key.Matches(msg, m.keymap.DownAction):
-			cmds = slices.Concat(cmds, m.runSequenceOnSelection(runner.DownSequence))
+			cmds = slices.Concat(cmds, m.runSequenceOnSelection(withDefaultProfiles(withDefaultStopTimeout(runner.DownSequence))))
 		case key.Matches(msg, m.keymap.RefreshAction):
-			cmds = slices.Concat(cmds, m.runSequenceOnSelection(runner.RefreshSequence))
+			cmds = slices.Concat(cmds, m.runSequenceOnSelection(withDefaultProfiles(withDefaultStopTimeout(runner.RefreshSequence))))
 		case key.Matches(msg, m.keymap.PullAction):
-			cmds = slices.Concat(cmds, m.runSequenceOnSelection(runner.PullSequence))
+			cmds = slices.Concat(cmds, m.runSequenceOnSelection(withDefaultProfiles(runner.PullSequence)))
+		case key.Matches(msg, m.keymap.CreateAction):
+			cmds = slices.Concat(cmds, m.runSequenceOnSelection(withDefaultProfiles(runner.CreateSequence)))
+		case key.Matches(msg, m.keymap.StopAction):
+			cmds = slices.Concat(cmds, m.runSequenceOnSelection(withDefaultProfiles(withDefaultStopTimeout(runner.StopSequence))))
+		case key.Matches(msg, m.keymap.StartAction):
+			cmds = slices.Concat(cmds, m.runSequenceOnSelection(withDefaultProfiles(runner.StartSequence)))
+		case key.Matches(msg, m.keymap.RefreshAllAction):
+			cmds = slices.Concat(cmds, m.startRefreshAll())
+		case key.Matches(msg, m.keymap.RecheckErrored):
+			cmds = slices.Concat(cmds, m.recheckErroredStatuses())
+		case key.Matches(msg, m.keymap.JumpToHost):
+			m.currentState = stateHostPicker
+			m.hostPickerHosts = nil
+			m.hostPickerCursor = 0
+			m.hostPickerError = nil
+			cmds = append(cmds, loadHostPickerCmd())
+		case key.Matches(msg, m.keymap.PinAction):
+			if len(m.stacks) > 0 && m.cursor >= 0 && m.cursor < len(m.stacks) {
+				if m.actionError = config.CheckWritable("pinning stacks"); m.actionError == nil {
+					cmds = append(cmds, m.togglePin(m.stacks[m.cursor].Identifier()))
+				}
+			}
+		case key.Matches(msg, m.keymap.StackShell):
+			if len(m.stacks) > 0 && m.cursor >= 0 && m.cursor < len(m.stacks) {
+				m.actionError = nil
+				cmds = append(cmds, stackShellCmd(m.stacks[m.cursor]))
+			}
+		case key.Matches(msg, m.keymap.LastOutput):
+			if len(m.stacks) > 0 && m.cursor >= 0 && m.cursor < len(m.stacks) {
+				m.actionError = m.showLastOutput(m.stacks[m.cursor].Identifier())
+			}
+		case key.Matches(msg, m.keymap.ComposeCommand):
+			if len(m.stacks) > 0 && m.cursor >= 0 && m.cursor < len(m.stacks) {
+				m.actionError = nil
+				cmds = append(cmds, m.openComposeCommandPrompt(&m.stacks[m.cursor]))
+			}
 		case key.Matches(msg, m.keymap.Enter):
 			if len(m.selectedStackIdxs) > 0 {
 				// Show details for multiple selected stacks
@@ -133,35 +174,185 @@ func (m *model) handleStackListKeys(msg tea.KeyMsg) []tea.Cmd {
 				m.currentState = stateStackDetails
 				m.detailsViewport.GotoTop()
 			} else if len(m.stacks) > 0 && m.cursor >= 0 && m.cursor < len(m.stacks) {
-				// Show details for the single stack under the cursor
-				stack := m.stacks[m.cursor] // Get a copy
-				m.detailedStack = &stack
-				m.stacksInSequence = nil // Clear multi-stack selection
-				m.currentState = stateStackDetails
-				m.detailsViewport.GotoTop()
-				// Fetch status if not already loaded/loading
-				stackID := m.detailedStack.Identifier()
-				if _, loaded := m.stackStatuses[stackID]; !loaded && !m.loadingStatus[stackID] {
-					m.loadingStatus[stackID] = true
-					cmds = append(cmds, m.fetchStackStatusCmd(*m.detailedStack))
+				// Run the configured default action on the stack under the cursor
+				if sequenceFunc, ok := defaultActionSequences[m.defaultAction]; ok {
+					cmds = slices.Concat(cmds, m.runSequenceOnSelection(sequenceFunc))
+				} else {
+					cmds = slices.Concat(cmds, m.showStackDetails(m.stacks[m.cursor]))
 				}
 			}
 		}
 	}
 
 	// If the cursor moved, fetch status for the newly highlighted stack if needed
-	if cursorMoved && len(m.stacks) > 0 && m.cursor >= 0 && m.cursor < len(m.stacks) {
-		selectedStack := m.stacks[m.cursor]
-		stackID := selectedStack.Identifier()
-		if _, loaded := m.stackStatuses[stackID]; !loaded && !m.loadingStatus[stackID] {
-			m.loadingStatus[stackID] = true
-			cmds = append(cmds, m.fetchStackStatusCmd(selectedStack))
-		}
+	if cursorMoved {
+		cmds = append(cmds, m.fetchCursorStatusCmd())
+	}
+
+	return cmds
+}
+
+// defaultActionSequences maps the default_action values that run a sequence
+// to the sequence they run. Any other value opens the details view.
+var defaultActionSequences = map[string]func(discovery.Stack) []runner.CommandStep{
+	"up":      withDefaultProfiles(runner.UpSequence),
+	"down":    withDefaultProfiles(withDefaultStopTimeout(runner.DownSequence)),
+	"refresh": withDefaultProfiles(withDefaultStopTimeout(runner.RefreshSequence)),
+	"pull":    withDefaultProfiles(runner.PullSequence),
+	"logs":    runner.LogsSequence,
+}
+
+// withDefaultProfiles adapts a sequence builder taking compose profiles to one
+// using each stack's default profiles (stack_compose_profiles).
+func withDefaultProfiles(build func(discovery.Stack, []string) []runner.CommandStep) func(discovery.Stack) []runner.CommandStep {
+	return func(stack discovery.Stack) []runner.CommandStep {
+		return build(stack, nil)
 	}
+}
 
+// withDefaultStopTimeout adapts a sequence builder that stops containers to
+// one leaving how long they get to stop to compose (runner.DefaultStopTimeout).
+func withDefaultStopTimeout(build func(discovery.Stack, []string, int) []runner.CommandStep) func(discovery.Stack, []string) []runner.CommandStep {
+	return func(stack discovery.Stack, profiles []string) []runner.CommandStep {
+		return build(stack, profiles, runner.DefaultStopTimeout)
+	}
+}
+
+// showStackDetails switches to the details view for a single stack, loading its
+// services and, if needed, its status.
+func (m *model) showStackDetails(stack discovery.Stack) []tea.Cmd {
+	var cmds []tea.Cmd
+	m.detailedStack = &stack
+	m.stacksInSequence = nil // Clear multi-stack selection
+	m.currentState = stateStackDetails
+	m.detailsViewport.GotoTop()
+	// List the services defined in the compose file, running or not
+	m.detailServices = nil
+	m.servicesError = nil
+	m.serviceCursor = 0
+	m.loadingServices = true
+	cmds = append(cmds, loadStackServicesCmd(stack))
+	// Fetch status if not already loaded/loading
+	stackID := m.detailedStack.Identifier()
+	if _, loaded := m.stackStatuses[stackID]; !loaded && !m.loadingStatus[stackID] {
+		m.loadingStatus[stackID] = true
+		cmds = append(cmds, m.fetchStackStatusCmd(*m.detailedStack))
+	}
 	return cmds
 }
 
+// fetchCursorStatusCmd returns a command fetching the status of the stack under
+// the cursor, or nil if it is already loaded or loading.
+func (m *model) fetchCursorStatusCmd() tea.Cmd {
+	if m.cursor < 0 || m.cursor >= len(m.stacks) {
+		return nil
+	}
+	selectedStack := m.stacks[m.cursor]
+	stackID := selectedStack.Identifier()
+	if _, loaded := m.stackStatuses[stackID]; loaded || m.loadingStatus[stackID] {
+		return nil
+	}
+	m.loadingStatus[stackID] = true
+	return m.fetchStackStatusCmd(selectedStack)
+}
+
+// checkHostListKeyWritable returns a read-only mode error for host list keys
+// that would change the configuration, prune a host or restart its runtime,
+// nil otherwise.
+func (m *model) checkHostListKeyWritable(msg tea.KeyMsg) error {
+	switch {
+	case key.Matches(msg, m.keymap.PruneAction):
+		return config.CheckWritable("pruning hosts")
+	case key.Matches(msg, m.keymap.RestartRuntimeAction):
+		return config.CheckWritable("restarting the container runtime")
+	case key.Matches(msg, m.keymap.Add, m.keymap.Edit, m.keymap.Remove, m.keymap.Import, m.keymap.GlobalSettings):
+		return config.CheckWritable("changing the configuration")
+	}
+	return nil
+}
+
+// togglePin pins the stack with the given identifier to the top of the stack
+// list, or unpins it, keeping the cursor on it, and saves the pinned stacks.
+func (m *model) togglePin(identifier string) tea.Cmd {
+	if m.pinnedStacks[identifier] {
+		delete(m.pinnedStacks, identifier)
+	} else {
+		m.pinnedStacks[identifier] = true
+	}
+	m.sortStacks()
+	if m.cursor < m.viewport.YOffset || m.cursor >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(m.cursor)
+	}
+	return savePinnedStacksCmd(slices.Sorted(maps.Keys(m.pinnedStacks)))
+}
+
+// selectHostActionTarget sets the host under the host list cursor as the
+// target of a host action, described by verb (e.g. "prune") in errors. It
+// reports whether a host was selected; disabled hosts can't be.
+func (m *model) selectHostActionTarget(verb string) bool {
+	m.hostActionTargets = nil
+	m.hostActionError = nil
+	m.lastError = nil
+
+	switch {
+	case m.configCursor == 0: // "local" selected
+		m.hostActionTargets = []runner.HostTarget{{IsRemote: false, ServerName: "local"}}
+	case m.configCursor > 0 && m.configCursor <= len(m.configuredHosts): // A remote host selected
+		host := m.configuredHosts[m.configCursor-1]
+		if host.Disabled {
+			m.lastError = fmt.Errorf("cannot %s disabled host: %s", verb, host.Name)
+			return false
+		}
+		m.hostActionTargets = []runner.HostTarget{{IsRemote: true, HostConfig: &host, ServerName: host.Name}}
+	default:
+		m.lastError = fmt.Errorf("invalid selection for host action")
+		return false
+	}
+	return true
+}
+
+// handleHostPickerKeys handles navigation in the "jump to host" picker. Enter
+// moves the stack list cursor to the first stack of the chosen host.
+func (m *model) handleHostPickerKeys(msg tea.KeyMsg) []tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keymap.Back):
+		m.currentState = stateStackList
+	case key.Matches(msg, m.keymap.Up):
+		if m.hostPickerCursor > 0 {
+			m.hostPickerCursor--
+		}
+	case key.Matches(msg, m.keymap.Down):
+		if m.hostPickerCursor < len(m.hostPickerHosts)-1 {
+			m.hostPickerCursor++
+		}
+	case key.Matches(msg, m.keymap.Home):
+		m.hostPickerCursor = 0
+	case key.Matches(msg, m.keymap.End):
+		m.hostPickerCursor = max(0, len(m.hostPickerHosts)-1)
+	case key.Matches(msg, m.keymap.Enter):
+		if m.hostPickerCursor >= len(m.hostPickerHosts) {
+			return nil // Still loading
+		}
+		host := m.hostPickerHosts[m.hostPickerCursor]
+		// Pinned stacks are listed first: prefer the host's place in the rest of the list
+		idx := slices.IndexFunc(m.stacks, func(s discovery.Stack) bool { return s.ServerName == host && !m.pinnedStacks[s.Identifier()] })
+		if idx == -1 {
+			idx = slices.IndexFunc(m.stacks, func(s discovery.Stack) bool { return s.ServerName == host })
+		}
+		if idx == -1 {
+			m.hostPickerError = fmt.Errorf("no stacks discovered on host '%s'", host)
+			return nil
+		}
+		m.cursor = idx
+		// Scroll so the host's first stack is near the top, keeping the line above
+		// (the list title or the previous host's last stack) for context.
+		m.viewport.SetYOffset(idx)
+		m.currentState = stateStackList
+		return []tea.Cmd{m.fetchCursorStatusCmd()}
+	}
+	return nil
+}
+
 // --- Form Navigation and Styling Helpers ---
 
 // handleFormNavigation manages keyboard-based navigation between form fields
@@ -243,6 +434,8 @@ func (m *model) updateFormFocusStyles() tea.Cmd {
 			}
 			// No input focus for authMethodFocusIndex or disabledToggleFocusIndex
 		}
+	case stateGlobalConfig:
+		focusedInputIndex = settingsInputIndex(m.formFocusIndex)
 	case stateSshConfigImportDetails:
 		const (
 			impRemoteRootFocusIndex    = 0
@@ -354,6 +547,74 @@ func (m *model) handleSshAddFormKeys(msg tea.KeyMsg) []tea.Cmd {
 	return cmds
 }
 
+// openGlobalConfigForm switches to the global settings form, filled with the
+// current configuration.
+func (m *model) openGlobalConfigForm() tea.Cmd {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		m.lastError = fmt.Errorf("failed to load config: %w", err)
+		return nil
+	}
+	m.formSettings = globalSettingsFromConfig(cfg)
+	m.formInputs = createGlobalConfigForm(m.formSettings)
+	m.formFocusIndex = settingsLocalRootField
+	m.formError = nil
+	m.lastError = nil
+	m.importInfoMsg = ""
+	m.currentState = stateGlobalConfig
+	m.formViewport.GotoTop()
+	return m.updateFormFocusStyles()
+}
+
+// handleGlobalConfigKeys processes keyboard input in the global settings form.
+// Left/Right change the selector fields and Enter saves the settings.
+func (m *model) handleGlobalConfigKeys(msg tea.KeyMsg) []tea.Cmd {
+	var cmds []tea.Cmd
+
+	focusMap := make([]int, settingsFieldCount)
+	for i := range focusMap {
+		focusMap[i] = i
+	}
+
+	if !m.handleFormNavigation(msg, focusMap) {
+		switch {
+		case key.Matches(msg, m.keymap.Left), key.Matches(msg, m.keymap.Right):
+			step := 1
+			if key.Matches(msg, m.keymap.Left) {
+				step = -1
+			}
+			switch m.formFocusIndex {
+			case settingsRuntimeField:
+				m.formSettings.ContainerRuntime = cycleOption(containerRuntimes, m.formSettings.ContainerRuntime, step)
+			case settingsDefaultActionField:
+				m.formSettings.DefaultAction = cycleOption(config.DefaultActions, m.formSettings.DefaultAction, step)
+			case settingsCollapseField:
+				m.formSettings.CollapseStepOutput = !m.formSettings.CollapseStepOutput
+			case settingsCompletionNotifyField:
+				m.formSettings.CompletionNotify = cycleOption(completionNotifyOptions, m.formSettings.CompletionNotify, step)
+			}
+		case key.Matches(msg, m.keymap.Enter):
+			m.formError = nil
+			settings, err := m.buildGlobalSettingsFromForm()
+			if err != nil {
+				m.formError = err
+			} else {
+				m.formSettings = settings
+				cmds = append(cmds, saveGlobalConfigCmd(settings))
+			}
+		}
+	}
+
+	cmds = append(cmds, m.updateFormFocusStyles())
+	return cmds
+}
+
+// cycleOption returns the option step places away from current, wrapping around.
+func cycleOption(options []string, current string, step int) string {
+	i := slices.Index(options, current)
+	return options[((i+step)%len(options)+len(options))%len(options)]
+}
+
 // handleSubmitEditForm validates and attempts to save an edited SSH host.
 func (m *model) handleSubmitEditForm() tea.Cmd {
 	// Define logical focus indices (constants could be defined globally if preferred)
@@ -614,8 +875,7 @@ func (m *model) runSequenceOnSelection(sequenceFunc func(discovery.Stack) []runn
 		return cmds
 	}
 
-	// Store the stacks involved and build the combined command sequence
-	m.stacksInSequence = stacksToRun
+	// Build the combined command sequence
 	for _, stackPtr := range stacksToRun {
 		if stackPtr != nil {
 			// Generate the sequence steps for the current stack and concatenate
@@ -623,26 +883,279 @@ func (m *model) runSequenceOnSelection(sequenceFunc func(discovery.Stack) []runn
 		}
 	}
 
-	// If any commands were generated, start the sequence
-	if len(combinedSequence) > 0 {
-		// Set the primary stack for display (usually the first one)
-		if len(stacksToRun) > 0 && stacksToRun[0] != nil {
-			m.sequenceStack = stacksToRun[0]
-		} else {
-			m.sequenceStack = nil // Should not happen if combinedSequence is not empty
+	return slices.Concat(cmds, m.startSequence(stacksToRun, combinedSequence))
+}
+
+// startSequence switches to the running sequence view and starts the first step
+// of sequence. stacks are the stacks involved, whose statuses are refreshed once
+// the sequence completes.
+func (m *model) startSequence(stacks []*discovery.Stack, sequence []runner.CommandStep) []tea.Cmd {
+	m.stacksInSequence = stacks
+	if len(sequence) == 0 {
+		return nil
+	}
+
+	// Set the primary stack for display (usually the first one)
+	if len(stacks) > 0 && stacks[0] != nil {
+		m.sequenceStack = stacks[0]
+	} else {
+		m.sequenceStack = nil // Should not happen if sequence is not empty
+	}
+	// Update model state for running a sequence
+	m.currentSequence = sequence
+	m.currentState = stateRunningSequence
+	m.currentStepIndex = 0
+	m.stepOutputs = nil    // Clear previous output
+	m.setOutputContent("") // And stop showing it
+	m.lastError = nil      // Clear previous error
+	m.viewport.GotoTop()   // Scroll output viewport to top
+	m.sequenceResults = make(map[string]error)
+	m.summaryCursor = 0
+	m.outputFilter = ""
+	m.sequenceID++ // Locks requested by an earlier sequence are released when they arrive
+
+	// LoadConfig returns a zero Config on error, which leaves output expanded
+	// and gives no alert
+	cfg, _ := config.LoadConfig()
+	m.collapseStepOutput = cfg.CollapseStepOutput
+	m.outputMaxLines = cfg.GetOutputMaxLines()
+	m.completionNotify = cfg.CompletionNotify
+	if err := runner.CheckSequenceWritable(sequence); err != nil {
+		// Refused in read-only mode; show the error instead of running anything
+		m.lastError = err
+		m.currentState = stateSequenceError
+		return nil
+	}
+	// Start the first step
+	return []tea.Cmd{m.runNextStepCmd()}
+}
+
+// runNextStepCmd starts the next step of the current sequence. Before the
+// first step of each stack, the stack's lock is taken instead if its steps
+// change it; the step is started once the lock is held.
+func (m *model) runNextStepCmd() tea.Cmd {
+	if m.sequenceLock == nil && m.currentStepIndex < len(m.currentSequence) {
+		step := m.currentSequence[m.currentStepIndex]
+		stackID := step.Stack.Identifier()
+		first := m.currentStepIndex == 0 || m.currentSequence[m.currentStepIndex-1].Stack.Identifier() != stackID
+		if first {
+			end := m.currentStepIndex
+			for end < len(m.currentSequence) && m.currentSequence[end].Stack.Identifier() == stackID {
+				end++
+			}
+			if runner.SequenceNeedsLock(m.currentSequence[m.currentStepIndex:end]) {
+				return lockStackCmd(m.sequenceID, step.Stack)
+			}
 		}
-		// Update model state for running a sequence
-		m.currentSequence = combinedSequence
-		m.currentState = stateRunningSequence
-		m.currentStepIndex = 0
-		m.outputContent = "" // Clear previous output
-		m.lastError = nil    // Clear previous error
-		m.viewport.GotoTop() // Scroll output viewport to top
-		// Start the first step
-		cmds = append(cmds, m.startNextStepCmd())
 	}
+	return m.startNextStepCmd()
+}
 
-	return cmds
+// handleStackDetailsKeys processes keyboard input in the stack details view.
+// For a single stack, Up/Down move through the services defined in its compose
+// file and the service action keys act on the selected service.
+//
+// Parameters:
+//   - msg: The keyboard message containing the pressed key
+//
+// Returns:
+//   - []tea.Cmd: Commands to be executed by the Bubble Tea framework
+//   - bool: True if the key was consumed and should not scroll the viewport
+func (m *model) handleStackDetailsKeys(msg tea.KeyMsg) ([]tea.Cmd, bool) {
+	if key.Matches(msg, m.keymap.Back) {
+		m.currentState = stateStackList
+		m.detailedStack = nil
+		m.detailServices = nil
+		m.servicesError = nil
+		return nil, true
+	}
+	if key.Matches(msg, m.keymap.StackShell) && m.detailedStack != nil {
+		m.servicesError = nil
+		return []tea.Cmd{stackShellCmd(*m.detailedStack)}, true
+	}
+	if key.Matches(msg, m.keymap.LastOutput) && m.detailedStack != nil {
+		m.servicesError = m.showLastOutput(m.detailedStack.Identifier())
+		return nil, true
+	}
+	if key.Matches(msg, m.keymap.ComposeCommand) && m.detailedStack != nil {
+		m.servicesError = nil
+		return []tea.Cmd{m.openComposeCommandPrompt(m.detailedStack)}, true
+	}
+	if key.Matches(msg, m.keymap.StackActions) && m.detailedStack != nil {
+		if len(m.detailedStack.Actions) == 0 {
+			m.servicesError = fmt.Errorf("%s defines no actions in its .bm.yaml", m.detailedStack.DisplayName())
+			return nil, true
+		}
+		m.servicesError = nil
+		m.actionPickerNames = slices.Sorted(maps.Keys(m.detailedStack.Actions))
+		m.actionPickerCursor = 0
+		m.actionPickerError = nil
+		m.currentState = stateActionPicker
+		return nil, true
+	}
+	if key.Matches(msg, m.keymap.OpenURLAction) && m.detailedStack != nil {
+		url := m.detailsURL()
+		if url == "" {
+			m.servicesError = fmt.Errorf("%s publishes no TCP ports", m.detailedStack.DisplayName())
+			return nil, true
+		}
+		m.servicesError = nil
+		return []tea.Cmd{openURLCmd(url)}, true
+	}
+
+	if m.detailedStack == nil || len(m.detailServices) == 0 {
+		return nil, false
+	}
+	if m.serviceCursor >= len(m.detailServices) {
+		m.serviceCursor = len(m.detailServices) - 1
+	}
+	service := m.detailServices[m.serviceCursor]
+
+	switch {
+	case key.Matches(msg, m.keymap.Up):
+		if m.serviceCursor > 0 {
+			m.serviceCursor--
+		}
+		return nil, true
+	case key.Matches(msg, m.keymap.Down):
+		if m.serviceCursor < len(m.detailServices)-1 {
+			m.serviceCursor++
+		}
+		return nil, true
+	case key.Matches(msg, m.keymap.ServiceLogsAction):
+		m.servicesError = nil
+		return m.startSequence([]*discovery.Stack{m.detailedStack}, runner.ServiceLogsSequence(*m.detailedStack, service)), true
+	case key.Matches(msg, m.keymap.ServiceRestartAction):
+		m.servicesError = nil
+		return m.startSequence([]*discovery.Stack{m.detailedStack}, runner.ServiceRestartSequence(*m.detailedStack, service)), true
+	case key.Matches(msg, m.keymap.ServiceExecAction):
+		m.servicesError = nil
+		return []tea.Cmd{execServiceCmd(*m.detailedStack, service)}, true
+	}
+	return nil, false
+}
+
+// detailsURL returns the web address opened by the OpenURLAction key in the
+// details view: the first one published by the selected service, or by the
+// stack if the selected service publishes none. It is empty if the stack
+// publishes no TCP ports or its status isn't loaded.
+func (m *model) detailsURL() string {
+	if m.detailedStack == nil {
+		return ""
+	}
+	urls := runner.StackURLs(m.stackStatuses[m.detailedStack.Identifier()])
+	if len(urls) == 0 {
+		return ""
+	}
+	if m.serviceCursor < len(m.detailServices) {
+		for _, url := range urls {
+			if url.Service == m.detailServices[m.serviceCursor] {
+				return url.URL
+			}
+		}
+	}
+	return urls[0].URL
+}
+
+// startRefreshAll queues a refresh sequence for every discovered stack.
+// Unlike runSequenceOnSelection, the sequences are not concatenated: stacks are
+// started one by one, separated by the configured stagger delay, and at most
+// the configured number of stacks run at the same time.
+//
+// Returns:
+//   - []tea.Cmd: Commands to be executed by the Bubble Tea framework
+func (m *model) startRefreshAll() []tea.Cmd {
+	if len(m.stacks) == 0 {
+		return nil
+	}
+	m.actionError = config.CheckWritable("refreshing stacks (which stops them)")
+	if m.actionError != nil {
+		return nil
+	}
+
+	// LoadConfig returns a zero Config on error, so the getters fall back to defaults
+	cfg, _ := config.LoadConfig()
+
+	m.batchQueue = slices.Clone(m.stacks)
+	m.batchOrder = make([]string, 0, len(m.batchQueue))
+	for _, stack := range m.batchQueue {
+		m.batchOrder = append(m.batchOrder, stack.Identifier())
+	}
+	m.batchRunning = make(map[string]bool)
+	m.batchResults = make(map[string]error)
+	m.batchOutputs = make(map[string]*outputBuffer)
+	m.batchStagger = cfg.GetRefreshAllStagger()
+	m.batchMaxConcurrent = cfg.GetRefreshAllMaxConcurrent()
+	m.outputMaxLines = cfg.GetOutputMaxLines()
+	m.completionNotify = cfg.CompletionNotify
+	m.batchWaiting = false
+
+	m.selectedStackIdxs = make(map[int]struct{}) // Selection is irrelevant for "refresh all"
+	m.lastError = nil
+	m.currentState = stateRunningBatch
+	m.setOutputContent(m.renderBatchOutput())
+	m.viewport.GotoTop()
+
+	// Start the first stack right away; later starts are staggered
+	return []tea.Cmd{func() tea.Msg { return batchStartNextMsg{} }}
+}
+
+// recheckErroredStatuses re-fetches the status of every stack whose last
+// status check failed, e.g. after a network blip, leaving the others as they
+// are. Stacks already being checked are skipped.
+//
+// Returns:
+//   - []tea.Cmd: Commands to be executed by the Bubble Tea framework
+func (m *model) recheckErroredStatuses() []tea.Cmd {
+	var stacks []discovery.Stack
+	for _, stack := range m.stacks {
+		stackID := stack.Identifier()
+		statusInfo, loaded := m.stackStatuses[stackID]
+		if !loaded || statusInfo.OverallStatus != runner.StatusError || m.loadingStatus[stackID] {
+			continue
+		}
+		m.loadingStatus[stackID] = true
+		stacks = append(stacks, stack)
+	}
+	if len(stacks) == 0 {
+		return nil
+	}
+	return []tea.Cmd{fetchStackStatusesCmd(stacks)}
+}
+
+// batchFinished reports whether every queued stack has completed.
+func (m *model) batchFinished() bool {
+	return len(m.batchQueue) == 0 && len(m.batchRunning) == 0
+}
+
+// handleBatchKeys processes keyboard input while a "refresh all" batch is shown.
+// Scrolling is always available; returning to the stack list is only allowed
+// once every stack in the batch has finished.
+func (m *model) handleBatchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	var vpCmd tea.Cmd
+
+	switch {
+	case key.Matches(msg, m.keymap.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keymap.Back), key.Matches(msg, m.keymap.Enter):
+		if !m.batchFinished() {
+			return m, nil
+		}
+		m.recordBatchOutputs()
+		m.currentState = stateStackList
+		m.batchQueue = nil
+		m.batchOrder = nil
+		m.batchRunning = nil
+		m.batchResults = nil
+		m.batchOutputs = nil
+		m.viewport.GotoTop()
+		return m, nil
+	}
+
+	m.viewport, vpCmd = m.viewport.Update(msg)
+	cmds = append(cmds, vpCmd)
+	return m, tea.Batch(cmds...)
 }
 
 // startNextStepCmd creates a command that will execute the next step in the
@@ -667,10 +1180,10 @@ func (m *model) startNextStepCmd() tea.Cmd {
 	}
 	// Get the current step
 	step := m.currentSequence[m.currentStepIndex]
-	// Add a header to the output indicating the step start
-	m.outputContent += stepStyle.Render(fmt.Sprintf("\n--- Starting Step: %s for %s ---", step.Name, step.Stack.Identifier())) + "\n"
+	// Start collecting output for this step
+	m.stepOutputs = append(m.stepOutputs, stepOutput{name: step.Name, target: step.Stack.Identifier(), content: newOutputBuffer(m.outputMaxLines)})
 	// Update the viewport content and scroll to bottom
-	m.viewport.SetContent(m.outputContent)
+	m.setOutputContent(m.renderSequenceOutput())
 	m.viewport.GotoBottom()
 	// Return the command to execute the step
 	return runStepCmd(step)
@@ -699,29 +1212,18 @@ func (m *model) handleViewportKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
 	case key.Matches(msg, m.keymap.Quit):
 		return m, tea.Quit
+	case key.Matches(msg, m.keymap.ToggleStepOutput):
+		m.collapseStepOutput = !m.collapseStepOutput
+		m.setOutputContent(m.renderSequenceOutput())
+		return m, nil
 	case key.Matches(msg, m.keymap.Back), key.Matches(msg, m.keymap.Enter):
-		// Return to stack list and refresh statuses
-		for _, stack := range m.stacksInSequence {
-			if stack != nil {
-				stackID := stack.Identifier()
-				// Check if status is not already loading or loaded to avoid redundant fetches
-				if !m.loadingStatus[stackID] {
-					if _, loaded := m.stackStatuses[stackID]; !loaded {
-						m.loadingStatus[stackID] = true
-						cmds = append(cmds, m.fetchStackStatusCmd(*stack))
-					}
-				}
-			}
+		if m.outputFilter != "" {
+			// Drilled in from the sequence summary; go back to it
+			m.outputFilter = ""
+			m.currentState = stateSequenceSummary
+			return m, nil
 		}
-		m.currentState = stateStackList
-		m.outputContent = ""
-		m.lastError = nil
-		m.currentSequence = nil
-		m.currentStepIndex = 0
-		m.sequenceStack = nil
-		m.stacksInSequence = nil
-		m.viewport.GotoTop()
-		return m, tea.Batch(cmds...) // Return immediately after state change and commands
+		return m, tea.Batch(m.leaveSequenceView()...) // Return immediately after state change and commands
 	}
 
 	// Default: Update the viewport for scrolling etc.
@@ -731,6 +1233,81 @@ func (m *model) handleViewportKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...) // Return model and any viewport commands
 }
 
+// leaveSequenceView returns from the sequence output or summary to the view the
+// sequence was started from, refreshing the statuses of the stacks involved.
+func (m *model) leaveSequenceView() []tea.Cmd {
+	m.recordLastOutputs()
+	var cmds []tea.Cmd
+	for _, stack := range m.stacksInSequence {
+		if stack != nil {
+			stackID := stack.Identifier()
+			// Check if status is not already loading or loaded to avoid redundant fetches
+			if !m.loadingStatus[stackID] {
+				if _, loaded := m.stackStatuses[stackID]; !loaded {
+					m.loadingStatus[stackID] = true
+					cmds = append(cmds, m.fetchStackStatusCmd(*stack))
+				}
+			}
+		}
+	}
+	m.currentState = stateStackList
+	if m.detailedStack != nil {
+		// The sequence was a service action started from the details view
+		m.currentState = stateStackDetails
+	}
+	if m.errorChan == nil {
+		// Otherwise the lock is released once the running step finishes
+		cmds = append(cmds, m.releaseSequenceLock())
+	}
+	m.stepOutputs = nil
+	m.lastError = nil
+	m.currentSequence = nil
+	m.currentStepIndex = 0
+	m.sequenceStack = nil
+	m.stacksInSequence = nil
+	m.sequenceResults = nil
+	m.outputFilter = ""
+	m.viewport.GotoTop()
+	return cmds
+}
+
+// handleSequenceSummaryKeys processes keyboard input in the summary shown after
+// a multi-stack sequence with failed or skipped stacks. Enter shows the output
+// of the selected stack, in the error view if it failed or was skipped.
+func (m *model) handleSequenceSummaryKeys(msg tea.KeyMsg) []tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keymap.Back):
+		return m.leaveSequenceView()
+	case key.Matches(msg, m.keymap.Up):
+		if m.summaryCursor > 0 {
+			m.summaryCursor--
+		}
+	case key.Matches(msg, m.keymap.Down):
+		if m.summaryCursor < len(m.stacksInSequence)-1 {
+			m.summaryCursor++
+		}
+	case key.Matches(msg, m.keymap.Home):
+		m.summaryCursor = 0
+	case key.Matches(msg, m.keymap.End):
+		m.summaryCursor = max(0, len(m.stacksInSequence)-1)
+	case key.Matches(msg, m.keymap.Enter):
+		if m.summaryCursor >= len(m.stacksInSequence) || m.stacksInSequence[m.summaryCursor] == nil {
+			return nil
+		}
+		stack := m.stacksInSequence[m.summaryCursor]
+		m.outputFilter = stack.Identifier()
+		m.sequenceStack = stack
+		m.lastError = m.sequenceResults[m.outputFilter]
+		m.currentState = stateRunningSequence
+		if m.lastError != nil {
+			m.currentState = stateSequenceError
+		}
+		m.setOutputContent(m.renderSequenceOutput())
+		m.viewport.GotoTop()
+	}
+	return nil
+}
+
 // handleFormInputUpdates processes keyboard input for text input fields in forms.
 // It maps the logical focus index to the actual input field index and routes
 // input to the appropriate field.
@@ -782,6 +1359,8 @@ func (m *model) handleFormInputUpdates(msg tea.KeyMsg) tea.Cmd {
 			}
 		}
 		// Note: Disabled toggle (index 8) doesn't have a text input
+	case stateGlobalConfig:
+		focusedInputIndex = settingsInputIndex(m.formFocusIndex)
 	case stateSshConfigImportDetails:
 		const (
 			remoteRootFocusIndex = 0
@@ -824,3 +1403,201 @@ func (m *model) handleFormInputUpdates(msg tea.KeyMsg) tea.Cmd {
 
 	return tea.Batch(cmds...)
 }
+
+// recordLastOutputs keeps the output of the current sequence per stack in
+// lastOutputs, replacing the output of each stack's previous sequence.
+func (m *model) recordLastOutputs() {
+	finishedAt := time.Now()
+	for _, stack := range m.stacksInSequence {
+		if stack == nil {
+			continue
+		}
+		stackID := stack.Identifier()
+		var steps []stepOutput
+		for _, step := range m.stepOutputs {
+			if step.target == stackID {
+				steps = append(steps, step)
+			}
+		}
+		if len(steps) > 0 {
+			m.storeLastOutput(stackID, lastOutput{steps: steps, finishedAt: finishedAt})
+		}
+	}
+}
+
+// batchOutput returns the output collected for a stack of the running batch.
+func (m *model) batchOutput(stackID string) outputBuffer {
+	if output := m.batchOutputs[stackID]; output != nil {
+		return *output
+	}
+	return outputBuffer{}
+}
+
+// recordBatchOutputs keeps the output of each stack of a finished "refresh all"
+// in lastOutputs, as a single step.
+func (m *model) recordBatchOutputs() {
+	finishedAt := time.Now()
+	for _, stackID := range m.batchOrder {
+		err, done := m.batchResults[stackID]
+		if !done {
+			continue
+		}
+		step := stepOutput{name: "Refresh", target: stackID, content: m.batchOutput(stackID), done: true, err: err}
+		m.storeLastOutput(stackID, lastOutput{steps: []stepOutput{step}, finishedAt: finishedAt})
+	}
+}
+
+// storeLastOutput sets the last output of a stack.
+func (m *model) storeLastOutput(stackID string, output lastOutput) {
+	if m.lastOutputs == nil {
+		m.lastOutputs = make(map[string]lastOutput)
+	}
+	m.lastOutputs[stackID] = output
+}
+
+// showLastOutput switches to the last output view for a stack, returning to
+// the current view when it is left. An error is returned if no sequence has
+// run on the stack yet.
+func (m *model) showLastOutput(stackID string) error {
+	if _, ok := m.lastOutputs[stackID]; !ok {
+		return fmt.Errorf("no output recorded for %s yet", stackID)
+	}
+	m.lastOutputStack = stackID
+	m.lastOutputReturn = m.currentState
+	m.currentState = stateLastOutput
+	m.viewport.GotoTop()
+	return nil
+}
+
+// handleLastOutputKeys processes keyboard input in the last output view.
+// Scrolling and collapsing steps work as in the sequence output view; Back or
+// Enter returns to the view it was opened from.
+func (m *model) handleLastOutputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var vpCmd tea.Cmd
+	switch {
+	case key.Matches(msg, m.keymap.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keymap.ToggleStepOutput):
+		m.collapseStepOutput = !m.collapseStepOutput
+		return m, nil
+	case key.Matches(msg, m.keymap.Back), key.Matches(msg, m.keymap.Enter):
+		m.currentState = m.lastOutputReturn
+		m.lastOutputStack = ""
+		m.viewport.GotoTop()
+		return m, nil
+	}
+	m.viewport, vpCmd = m.viewport.Update(msg)
+	return m, vpCmd
+}
+
+// openComposeCommandPrompt switches to the prompt for a compose subcommand to
+// run on stack, returning to the current view if it is cancelled.
+func (m *model) openComposeCommandPrompt(stack *discovery.Stack) tea.Cmd {
+	m.composeCommandInput = textinput.New()
+	m.composeCommandInput.Prompt = "compose "
+	m.composeCommandInput.Placeholder = "exec db backup.sh"
+	m.composeCommandInput.Width = max(m.width-12, 20)
+	m.composeCommandStack = stack
+	m.composeCommandReturn = m.currentState
+	m.composeCommandError = nil
+	m.currentState = stateComposeCommand
+	return m.composeCommandInput.Focus()
+}
+
+// handleComposeCommandKeys processes keyboard input in the compose command
+// prompt. Every key other than Enter, Esc and ctrl+c is typed into the
+// prompt; Enter runs the command as a sequence on the stack if it is a
+// compose subcommand.
+func (m *model) handleComposeCommandKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		return m, tea.Quit
+	case key.Matches(msg, m.keymap.Esc):
+		m.currentState = m.composeCommandReturn
+		m.composeCommandStack = nil
+		m.composeCommandError = nil
+		return m, nil
+	case key.Matches(msg, m.keymap.Enter):
+		sequence, err := runner.ComposeCommandSequence(*m.composeCommandStack, m.composeCommandInput.Value())
+		if err != nil {
+			m.composeCommandError = err
+			return m, nil
+		}
+		stack := m.composeCommandStack
+		m.composeCommandStack = nil
+		m.composeCommandError = nil
+		// leaveSequenceView returns to the details view if it is still open
+		m.currentState = m.composeCommandReturn
+		return m, tea.Batch(m.startSequence([]*discovery.Stack{stack}, sequence)...)
+	}
+
+	var cmd tea.Cmd
+	m.composeCommandInput, cmd = m.composeCommandInput.Update(msg)
+	m.composeCommandError = nil
+	return m, cmd
+}
+
+// handlePassphraseKeys processes keyboard input in the passphrase prompt.
+// Enter answers it with the typed passphrase; Esc cancels it, so that the key
+// is skipped and the host's other authentication methods are tried.
+func (m *model) handlePassphraseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		m.answerPassphrasePrompt("", errors.New("the TUI was closed"))
+		return m, tea.Quit
+	case key.Matches(msg, m.keymap.Esc):
+		m.answerPassphrasePrompt("", errors.New("the passphrase prompt was cancelled"))
+		return m, nil
+	case key.Matches(msg, m.keymap.Enter):
+		m.answerPassphrasePrompt(m.passphraseInput.Value(), nil)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.passphraseInput, cmd = m.passphraseInput.Update(msg)
+	return m, cmd
+}
+
+// answerPassphrasePrompt sends the answer of the open passphrase prompt to the
+// connection waiting for it and closes the prompt.
+func (m *model) answerPassphrasePrompt(passphrase string, err error) {
+	m.passphrasePrompt.reply <- passphraseReply{passphrase: passphrase, err: err}
+	m.passphrasePrompt = nil
+	m.passphraseInput.Reset()
+}
+
+// handleActionPickerKeys handles navigation in the picker of the detailed
+// stack's custom actions. Enter runs the chosen action as a sequence on the
+// stack, returning to the details view once it is done.
+func (m *model) handleActionPickerKeys(msg tea.KeyMsg) []tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keymap.Back):
+		m.currentState = stateStackDetails
+	case key.Matches(msg, m.keymap.Up):
+		if m.actionPickerCursor > 0 {
+			m.actionPickerCursor--
+		}
+	case key.Matches(msg, m.keymap.Down):
+		if m.actionPickerCursor < len(m.actionPickerNames)-1 {
+			m.actionPickerCursor++
+		}
+	case key.Matches(msg, m.keymap.Home):
+		m.actionPickerCursor = 0
+	case key.Matches(msg, m.keymap.End):
+		m.actionPickerCursor = max(0, len(m.actionPickerNames)-1)
+	case key.Matches(msg, m.keymap.Enter):
+		if m.detailedStack == nil || m.actionPickerCursor >= len(m.actionPickerNames) {
+			return nil
+		}
+		sequence, err := runner.ActionSequence(*m.detailedStack, m.actionPickerNames[m.actionPickerCursor])
+		if err != nil {
+			m.actionPickerError = err
+			return nil
+		}
+		m.actionPickerError = nil
+		// leaveSequenceView returns to the details view if it is still open
+		m.currentState = stateStackDetails
+		return m.startSequence([]*discovery.Stack{m.detailedStack}, sequence)
+	}
+	return nil
+}
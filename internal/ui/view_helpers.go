@@ -8,10 +8,14 @@
 package ui
 
 import (
+	"bucket-manager/internal/discovery"
 	"bucket-manager/internal/runner"
+	"bucket-manager/internal/util"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -19,6 +23,49 @@ import (
 // --- View Helper Methods ---
 // These methods generate specific UI components and format data for display
 
+// renderStatusSummary returns the header's overview of the stack list, e.g.
+// "12 stacks · 9 up · 2 down · 1 error", colored like the statuses in the list.
+// Statuses without stacks are left out, and stacks still being checked are
+// counted as loading. It is empty until stacks are discovered.
+func (m *model) renderStatusSummary() string {
+	if len(m.stacks) == 0 {
+		return ""
+	}
+	counts := make(map[runner.StackStatus]int)
+	loading := 0
+	for _, stack := range m.stacks {
+		stackID := stack.Identifier()
+		if statusInfo, ok := m.stackStatuses[stackID]; ok && !m.loadingStatus[stackID] {
+			counts[statusInfo.OverallStatus]++
+		} else {
+			loading++
+		}
+	}
+
+	noun := "stacks"
+	if len(m.stacks) == 1 {
+		noun = "stack"
+	}
+	parts := []string{fmt.Sprintf("%d %s", len(m.stacks), noun)}
+	for _, part := range []struct {
+		count int
+		label string
+		style lipgloss.Style
+	}{
+		{counts[runner.StatusUp], "up", statusUpStyle},
+		{counts[runner.StatusPartial], "partial", statusPartialStyle},
+		{counts[runner.StatusDown], "down", statusDownStyle},
+		{counts[runner.StatusError], "error", statusErrorStyle},
+		{counts[runner.StatusUnknown], "unknown", statusLoadingStyle},
+		{loading, "loading", statusLoadingStyle},
+	} {
+		if part.count > 0 {
+			parts = append(parts, part.style.Render(fmt.Sprintf("%d %s", part.count, part.label)))
+		}
+	}
+	return strings.Join(parts, statusLoadingStyle.Render(" · "))
+}
+
 // renderStackStatus appends the detailed status view for a given stack ID
 // to the provided strings.Builder. It uses the status information stored
 // in the model's stackStatuses and loadingStatus maps.
@@ -52,8 +99,14 @@ func (m *model) renderStackStatus(b *strings.Builder, stackID string) {
 		default:
 			statusStr = statusLoadingStyle.Render(" [Unknown]") // Should not happen
 		}
+		if statusInfo.IsStale() {
+			statusStr += statusPartialStyle.Render(" [stale]")
+		}
 	}
 	fmt.Fprintf(b, "\nOverall Status:%s\n", statusStr)
+	if !isLoading && loaded && statusInfo.IsStale() {
+		b.WriteString(statusPartialStyle.Render("  The compose files changed since the containers were created; run up to apply them.") + "\n")
+	}
 
 	// Display error if status fetch failed
 	if !isLoading && loaded && statusInfo.Error != nil {
@@ -88,7 +141,66 @@ func (m *model) renderStackStatus(b *strings.Builder, stackID string) {
 			// Only show "No containers" if the overall status isn't already an error
 			b.WriteString("\n  (No containers found or running)\n")
 		}
+
+		if urls := runner.StackURLs(statusInfo); len(urls) > 0 {
+			b.WriteString("\nPublished ports:\n")
+			for _, url := range urls {
+				fmt.Fprintf(b, "  %-20s %s\n", url.Service, url.URL)
+			}
+		}
+	}
+}
+
+// renderDiskUsage returns a short disk usage indicator for a host, based on the
+// filesystem with the least free space. It is highlighted as a warning when
+// free space drops below the configured threshold.
+func (m *model) renderDiskUsage(serverName string) string {
+	if m.loadingDiskUsage[serverName] {
+		return statusLoadingStyle.Render(" [disk: ...]")
+	}
+	usage, ok := m.hostDiskUsage[serverName]
+	if !ok {
+		return ""
+	}
+	if usage.Error != nil {
+		return statusErrorStyle.Render(" [disk: error]")
+	}
+	fs, ok := usage.LowestFree()
+	if !ok {
+		return ""
+	}
+	text := fmt.Sprintf(" [disk: %s free, %.0f%%", util.FormatKiB(fs.AvailableKiB), fs.FreePercent())
+	if fs.FreePercent() < float64(m.diskWarnFreePercent) {
+		return warningStyle.Render(text + " ⚠]")
+	}
+	return lipgloss.NewStyle().Faint(true).Render(text + "]")
+}
+
+// renderComposeVersion returns the runtime and compose versions of a host, e.g.
+// " [podman 4.9.3 / compose 1.0.6]", or "" while loading or if the check failed.
+func (m *model) renderComposeVersion(serverName string) string {
+	version, ok := m.composeVersions[serverName]
+	if !ok || version.Error != nil {
+		return ""
+	}
+	return lipgloss.NewStyle().Faint(true).Render(" [" + version.String() + "]")
+}
+
+// renderReachability renders the outcome of the last discovery on a host, e.g.
+// " ✓ seen 2m ago" or " ✗ unreachable (seen 1h ago)", or "" if it hasn't been searched yet.
+func (m *model) renderReachability(serverName string) string {
+	seen, ok := m.hostReachability[serverName]
+	if !ok {
+		return ""
+	}
+	if seen.reachable {
+		return successStyle.Render(fmt.Sprintf(" ✓ seen %s ago", util.FormatAge(time.Since(seen.lastSeen))))
 	}
+	text := " ✗ unreachable"
+	if !seen.lastSeen.IsZero() {
+		text += fmt.Sprintf(" (seen %s ago)", util.FormatAge(time.Since(seen.lastSeen)))
+	}
+	return errorStyle.Render(text)
 }
 
 // --- State-Specific View Renderers ---
@@ -107,6 +219,15 @@ func (m *model) renderLoadingView() (string, string) {
 	return body, footer
 }
 
+// stackLabel names a stack in the stack list and details view: "name (server)",
+// or the stack as shown with identifier_format if one is set.
+func stackLabel(stack discovery.Stack) string {
+	if discovery.HasIdentifierFormat() {
+		return stack.DisplayName()
+	}
+	return fmt.Sprintf("%s (%s)", stack.Name, serverNameStyle.Render(stack.ServerName))
+}
+
 // renderStackListView generates the main stack selection screen that displays
 // all available stacks across all configured hosts. This is the primary navigation
 // view from which users can select stacks to view details or perform operations.
@@ -150,10 +271,21 @@ func (m *model) renderStackListView() (string, string) {
 			default:
 				statusStr = statusLoadingStyle.Render(" [?]")
 			}
+			if statusInfo.IsStale() {
+				statusStr += statusPartialStyle.Render(" [stale]")
+			}
 		} else {
 			statusStr = statusLoadingStyle.Render(" [?]")
 		}
-		bodyContent.WriteString(fmt.Sprintf("%s%s %s (%s)%s\n", cursor, checkbox, stack.Name, serverNameStyle.Render(stack.ServerName), statusStr))
+		kind := ""
+		if stack.Quadlet != nil {
+			kind = statusLoadingStyle.Render(" quadlet")
+		}
+		pin := ""
+		if m.pinnedStacks[stackID] {
+			pin = warningStyle.Render("★ ")
+		}
+		bodyContent.WriteString(fmt.Sprintf("%s%s %s%s%s%s\n", cursor, checkbox, pin, stackLabel(stack), kind, statusStr))
 	}
 
 	footerContent := strings.Builder{}
@@ -162,14 +294,13 @@ func (m *model) renderStackListView() (string, string) {
 		footerContent.WriteString(statusLoadingStyle.Render("Discovering remote stacks...") + "\n")
 	}
 	if len(m.discoveryErrors) > 0 {
-		footerContent.WriteString(errorStyle.Render("Discovery Errors:"))
-		for _, err := range m.discoveryErrors {
-			footerContent.WriteString("\n  " + errorStyle.Render(err.Error()))
-		}
-		footerContent.WriteString("\n")
+		footerContent.WriteString(m.renderDiscoveryErrors())
 	} else if m.lastError != nil && strings.Contains(m.lastError.Error(), "discovery") {
 		footerContent.WriteString(errorStyle.Render(fmt.Sprintf("Discovery Warning: %v", m.lastError)) + "\n")
 	}
+	if m.actionError != nil {
+		footerContent.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.actionError)) + "\n")
+	}
 
 	help := strings.Builder{}
 	if len(m.selectedStackIdxs) > 0 {
@@ -177,12 +308,28 @@ func (m *model) renderStackListView() (string, string) {
 	}
 	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerDescStyle.Render(": navigate") + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Select.Help().Key) + footerDescStyle.Render(": "+m.keymap.Select.Help().Desc) + footerSeparatorStyle.Render(" | "))
-	help.WriteString(footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": details") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": "+m.defaultAction) + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.UpAction.Help().Key) + footerDescStyle.Render(": up") + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.DownAction.Help().Key) + footerDescStyle.Render(": down") + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.RefreshAction.Help().Key) + footerDescStyle.Render(": refresh") + footerSeparatorStyle.Render(" | "))
-	help.WriteString(footerKeyStyle.Render(m.keymap.PullAction.Help().Key) + footerDescStyle.Render(": pull"))
+	help.WriteString(footerKeyStyle.Render(m.keymap.PullAction.Help().Key) + footerDescStyle.Render(": pull") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.CreateAction.Help().Key) + footerDescStyle.Render(": create") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.StopAction.Help().Key) + footerDescStyle.Render(": stop") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.StartAction.Help().Key) + footerDescStyle.Render(": start") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.RefreshAllAction.Help().Key) + footerDescStyle.Render(": refresh all"))
 	help.WriteString(footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.RecheckErrored.Help().Key) + footerDescStyle.Render(": re-check errored") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.JumpToHost.Help().Key) + footerDescStyle.Render(": "+m.keymap.JumpToHost.Help().Desc) + footerSeparatorStyle.Render(" | "))
+	pinDesc := ": pin"
+	if len(m.stacks) > 0 && m.cursor >= 0 && m.cursor < len(m.stacks) && m.pinnedStacks[m.stacks[m.cursor].Identifier()] {
+		pinDesc = ": unpin"
+	}
+	help.WriteString(footerKeyStyle.Render(m.keymap.PinAction.Help().Key) + footerDescStyle.Render(pinDesc) + footerSeparatorStyle.Render(" | "))
+	if !m.readOnly { // Shells are refused in read-only mode
+		help.WriteString(footerKeyStyle.Render(m.keymap.StackShell.Help().Key) + footerDescStyle.Render(": shell") + footerSeparatorStyle.Render(" | "))
+	}
+	help.WriteString(footerKeyStyle.Render(m.keymap.ComposeCommand.Help().Key) + footerDescStyle.Render(": compose cmd") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.LastOutput.Help().Key) + footerDescStyle.Render(": "+m.keymap.LastOutput.Help().Desc) + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Config.Help().Key) + footerDescStyle.Render(": "+m.keymap.Config.Help().Desc) + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
 	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String())) // Keep lipgloss width rendering for wrapping
@@ -190,6 +337,75 @@ func (m *model) renderStackListView() (string, string) {
 	return bodyContent.String(), footerContent.String()
 }
 
+// renderHostPickerView generates the "jump to host" picker, listing local and
+// the enabled configured hosts with the number of stacks discovered on each.
+//
+// Returns:
+//   - string: The body content showing the hosts
+//   - string: The footer content with navigation key help
+func (m *model) renderHostPickerView() (string, string) {
+	bodyContent := strings.Builder{}
+	bodyContent.WriteString("Jump to host:\n")
+	if m.hostPickerHosts == nil {
+		bodyContent.WriteString(statusLoadingStyle.Render("  Loading hosts...") + "\n")
+	}
+
+	stackCounts := make(map[string]int)
+	for _, stack := range m.stacks {
+		stackCounts[stack.ServerName]++
+	}
+	for i, host := range m.hostPickerHosts {
+		cursor := "  "
+		if m.hostPickerCursor == i {
+			cursor = cursorStyle.Render("> ")
+		}
+		countStr := statusLoadingStyle.Render(" (no stacks)")
+		if n := stackCounts[host]; n == 1 {
+			countStr = " (1 stack)"
+		} else if n > 1 {
+			countStr = fmt.Sprintf(" (%d stacks)", n)
+		}
+		bodyContent.WriteString(fmt.Sprintf("%s%s%s\n", cursor, serverNameStyle.Render(host), countStr))
+	}
+
+	footerContent := strings.Builder{}
+	if m.hostPickerError != nil {
+		footerContent.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.hostPickerError)) + "\n")
+	}
+
+	help := strings.Builder{}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerDescStyle.Render(": navigate") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": jump") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": "+m.keymap.Back.Help().Desc) + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
+	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String()))
+
+	return bodyContent.String(), footerContent.String()
+}
+
+// renderDiscoveryErrors renders the collapsed discovery errors for the stack list
+// footer. Fatal errors are shown as errors and per-host failures as warnings; at
+// most maxDisplayedDiscoveryErrors entries are listed, followed by a "(+N more)" line.
+func (m *model) renderDiscoveryErrors() string {
+	b := strings.Builder{}
+	for i, issue := range m.discoveryErrors {
+		if i == maxDisplayedDiscoveryErrors {
+			b.WriteString(footerDescStyle.Render(fmt.Sprintf("  (+%d more)", len(m.discoveryErrors)-i)) + "\n")
+			break
+		}
+		line := issue.message
+		if issue.count > 1 {
+			line += fmt.Sprintf(" (x%d)", issue.count)
+		}
+		if issue.fatal {
+			b.WriteString(errorStyle.Render("Discovery error: "+line) + "\n")
+		} else {
+			b.WriteString(warningStyle.Render("Discovery warning: "+line) + "\n")
+		}
+	}
+	return b.String()
+}
+
 // renderRunningSequenceView generates the view that is displayed while a command
 // sequence (up, down, pull, etc.) is actively running. It shows real-time command
 // output and execution progress for the selected stack.
@@ -204,13 +420,13 @@ func (m *model) renderStackListView() (string, string) {
 //   - string: The body content showing raw command output
 //   - string: The footer content with progress information and cancel option
 func (m *model) renderRunningSequenceView() (string, string) {
-	bodyStr := m.outputContent // Use the raw content for setting viewport
+	bodyStr := m.renderedOutput
 
 	footerContent := strings.Builder{}
 
 	stackIdentifier := ""
 	if m.sequenceStack != nil {
-		stackIdentifier = fmt.Sprintf(" for %s", m.sequenceStack.Identifier())
+		stackIdentifier = fmt.Sprintf(" for %s", m.sequenceStack.DisplayName())
 	}
 	if m.currentSequence != nil && m.currentStepIndex < len(m.currentSequence) {
 		footerContent.WriteString(statusStyle.Render(fmt.Sprintf("Running step %d/%d%s: %s...", m.currentStepIndex+1, len(m.currentSequence), stackIdentifier, m.currentSequence[m.currentStepIndex].Name)))
@@ -222,13 +438,311 @@ func (m *model) renderRunningSequenceView() (string, string) {
 
 	help := strings.Builder{}
 	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.PgUp.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.PgDown.Help().Key) + footerDescStyle.Render(": scroll") + footerSeparatorStyle.Render(" | "))
-	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": back to list") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(m.sequenceBackDesc()) + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.ToggleStepOutput.Help().Key) + footerDescStyle.Render(": "+m.keymap.ToggleStepOutput.Help().Desc) + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
 	footerContent.WriteString("\n" + lipgloss.NewStyle().Width(m.width).Render(help.String())) // Keep lipgloss width rendering
 
 	return bodyStr, footerContent.String()
 }
 
+// sequenceBackDesc describes where Back leaves the sequence output view.
+func (m *model) sequenceBackDesc() string {
+	if m.outputFilter != "" {
+		return ": back to summary"
+	}
+	return ": back to list"
+}
+
+// setOutputContent shows content, the rendered output of the running
+// sequence, host action or batch, in the output view until it is set again.
+// Output is rendered when it changes rather than on every View, as it can
+// grow long.
+func (m *model) setOutputContent(content string) {
+	m.renderedOutput = content
+	m.viewport.SetContent(content)
+	m.viewportContent = content
+}
+
+// renderSequenceOutput renders the output of the current sequence step by step.
+// When collapseStepOutput is set, each successful step is reduced to a single
+// "✓ <step name>" line; running and failed steps are always shown in full.
+func (m *model) renderSequenceOutput() string {
+	b := strings.Builder{}
+	for _, step := range m.stepOutputs {
+		if m.outputFilter != "" && step.target != m.outputFilter {
+			continue
+		}
+		m.renderStepOutput(&b, step)
+	}
+	finished := m.currentSequence != nil && m.currentStepIndex >= len(m.currentSequence)
+	if finished && m.outputFilter == "" && m.sequenceFailures() == 0 {
+		b.WriteString(successStyle.Render("\n--- Action Sequence Completed Successfully ---") + "\n")
+	}
+	return b.String()
+}
+
+// renderStepOutput renders the output of one sequence step, reduced to a
+// single line if it succeeded and collapseStepOutput is set.
+func (m *model) renderStepOutput(b *strings.Builder, step stepOutput) {
+	if m.collapseStepOutput && step.done && step.err == nil {
+		b.WriteString(successStyle.Render("✓ "+step.name) + "\n")
+		return
+	}
+	b.WriteString(stepStyle.Render(fmt.Sprintf("\n--- Starting Step: %s for %s ---", step.name, discovery.DisplayIdentifier(step.target))) + "\n")
+	b.WriteString(step.content.String())
+	if !step.done {
+		return
+	}
+	if step.err != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("\n--- STEP FAILED: %v ---", step.err)) + "\n")
+	} else {
+		b.WriteString(successStyle.Render(fmt.Sprintf("\n--- Step '%s' Succeeded ---", step.name)) + "\n")
+	}
+}
+
+// renderLastOutputView generates the view reopening the output of the last
+// sequence run on a stack, after its output view was left.
+//
+// Returns:
+//   - string: The body content showing the stack's steps and their output
+//   - string: The footer content with navigation options
+func (m *model) renderLastOutputView() (string, string) {
+	bodyContent := strings.Builder{}
+	output := m.lastOutputs[m.lastOutputStack]
+	bodyContent.WriteString(titleStyle.Render(fmt.Sprintf("Last output for %s (finished %s)", identifierColor.Render(discovery.DisplayIdentifier(m.lastOutputStack)), output.finishedAt.Format("15:04:05"))) + "\n")
+	for _, step := range output.steps {
+		m.renderStepOutput(&bodyContent, step)
+	}
+	if !output.steps[len(output.steps)-1].done {
+		bodyContent.WriteString(warningStyle.Render("\n--- Output view was left before the step finished ---") + "\n")
+	}
+
+	footerContent := strings.Builder{}
+	help := strings.Builder{}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.PgUp.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.PgDown.Help().Key) + footerDescStyle.Render(": scroll") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.ToggleStepOutput.Help().Key) + footerDescStyle.Render(": "+m.keymap.ToggleStepOutput.Help().Desc) + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": back") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
+	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String())) // Keep lipgloss width rendering
+
+	return bodyContent.String(), footerContent.String()
+}
+
+// renderComposeCommandView generates the prompt for a compose subcommand to
+// run on a stack.
+//
+// Returns:
+//   - string: The body content with the prompt and any error
+//   - string: The footer content with navigation options
+func (m *model) renderComposeCommandView() (string, string) {
+	bodyContent := strings.Builder{}
+	if m.composeCommandStack != nil {
+		bodyContent.WriteString(fmt.Sprintf("Run a compose command on %s:\n\n", identifierColor.Render(m.composeCommandStack.DisplayName())))
+	}
+	bodyContent.WriteString(m.composeCommandInput.View() + "\n")
+	bodyContent.WriteString(statusLoadingStyle.Render("\nOnly compose subcommands can be run; exec and run don't get a TTY.") + "\n")
+	if m.composeCommandError != nil {
+		bodyContent.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.composeCommandError)) + "\n")
+	}
+
+	footerContent := strings.Builder{}
+	help := strings.Builder{}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": run") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Esc.Help().Key) + footerDescStyle.Render(": cancel") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render("ctrl+c") + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
+	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String()))
+
+	return bodyContent.String(), footerContent.String()
+}
+
+// renderPassphrasePromptView generates the prompt for the passphrase of an
+// encrypted SSH key, shown over the current view.
+//
+// Returns:
+//   - string: The body content with the prompt
+//   - string: The footer content with navigation options
+func (m *model) renderPassphrasePromptView() (string, string) {
+	prompt := m.passphrasePrompt
+	bodyContent := strings.Builder{}
+	bodyContent.WriteString(fmt.Sprintf("The SSH key %s used for %s is passphrase-protected.\n\n", prompt.keyPath, identifierColor.Render(prompt.host.Name)))
+	if prompt.retry {
+		bodyContent.WriteString(errorStyle.Render("Wrong passphrase, try again.") + "\n\n")
+	}
+	bodyContent.WriteString(m.passphraseInput.View() + "\n")
+	bodyContent.WriteString(statusLoadingStyle.Render("\nThe decrypted key is kept in memory until bm exits. Set key_passphrase_command to skip this prompt.") + "\n")
+
+	footerContent := strings.Builder{}
+	help := strings.Builder{}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": unlock") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Esc.Help().Key) + footerDescStyle.Render(": skip the key") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render("ctrl+c") + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
+	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String()))
+
+	return bodyContent.String(), footerContent.String()
+}
+
+// renderActionPickerView generates the picker of the custom actions defined
+// in the detailed stack's .bm.yaml, showing the command each one runs.
+//
+// Returns:
+//   - string: The body content showing the actions
+//   - string: The footer content with navigation key help
+func (m *model) renderActionPickerView() (string, string) {
+	bodyContent := strings.Builder{}
+	if m.detailedStack != nil {
+		bodyContent.WriteString(fmt.Sprintf("Run an action on %s:\n", identifierColor.Render(m.detailedStack.DisplayName())))
+	}
+	for i, name := range m.actionPickerNames {
+		cursor := "  "
+		if m.actionPickerCursor == i {
+			cursor = cursorStyle.Render("> ")
+		}
+		command := ""
+		if m.detailedStack != nil {
+			command = m.detailedStack.Actions[name]
+		}
+		bodyContent.WriteString(fmt.Sprintf("%s%-20s %s\n", cursor, name, statusLoadingStyle.Render(command)))
+	}
+
+	footerContent := strings.Builder{}
+	if m.actionPickerError != nil {
+		footerContent.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.actionPickerError)) + "\n")
+	}
+
+	help := strings.Builder{}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerDescStyle.Render(": navigate") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": run") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": "+m.keymap.Back.Help().Desc) + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
+	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String()))
+
+	return bodyContent.String(), footerContent.String()
+}
+
+// renderSequenceSummaryView generates the view shown once a multi-stack sequence
+// finishes with failed or skipped stacks. It lists the result of each stack;
+// the selected stack's output can be opened from here.
+//
+// Returns:
+//   - string: The body content listing each stack and its result
+//   - string: The footer content with the failure count and navigation options
+func (m *model) renderSequenceSummaryView() (string, string) {
+	bodyContent := strings.Builder{}
+	bodyContent.WriteString(fmt.Sprintf("Results for %d stacks:\n", len(m.stacksInSequence)))
+	for i, stack := range m.stacksInSequence {
+		if stack == nil {
+			continue
+		}
+		cursor := "  "
+		if m.summaryCursor == i {
+			cursor = cursorStyle.Render("> ")
+		}
+		stackID := stack.Identifier()
+		result := successStyle.Render("✓ succeeded")
+		if err, done := m.sequenceResults[stackID]; !done {
+			result = statusLoadingStyle.Render("- not run")
+		} else if errors.Is(err, runner.ErrStackBusy) {
+			result = statusLoadingStyle.Render("- skipped: busy with another operation")
+		} else if err != nil {
+			// Only the first line; the full error is shown with the output
+			firstLine, _, _ := strings.Cut(err.Error(), "\n")
+			result = errorStyle.Render("✗ failed: " + firstLine)
+		}
+		bodyContent.WriteString(fmt.Sprintf("%s%s %s\n", cursor, identifierColor.Render(stack.DisplayName()), result))
+	}
+
+	footerContent := strings.Builder{}
+	outcome := fmt.Sprintf("%d of %d stacks failed", m.sequenceFailures(), len(m.stacksInSequence))
+	if skipped := m.sequenceSkipped(); skipped > 0 {
+		outcome += fmt.Sprintf(", %d skipped as busy", skipped)
+	}
+	footerContent.WriteString(errorStyle.Render(outcome + "."))
+
+	help := strings.Builder{}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerDescStyle.Render(": navigate") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": show output") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": back to list") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
+	footerContent.WriteString("\n" + lipgloss.NewStyle().Width(m.width).Render(help.String())) // Keep lipgloss width rendering
+
+	return bodyContent.String(), footerContent.String()
+}
+
+// renderBatchOutput renders the state of each stack in the running "refresh
+// all" batch and the output collected so far, grouped per stack, for
+// setOutputContent.
+func (m *model) renderBatchOutput() string {
+	bodyContent := strings.Builder{}
+	bodyContent.WriteString(titleStyle.Render("Refresh All") + "\n\n")
+	for _, stackID := range m.batchOrder {
+		stateStr := statusLoadingStyle.Render("[queued]")
+		if m.batchRunning[stackID] {
+			stateStr = statusStyle.Render("[running]")
+		} else if err, done := m.batchResults[stackID]; done {
+			if err != nil {
+				stateStr = errorStyle.Render(fmt.Sprintf("[failed: %v]", err))
+			} else {
+				stateStr = successStyle.Render("[done]")
+			}
+		}
+		bodyContent.WriteString(fmt.Sprintf("  %s %s\n", identifierColor.Render(discovery.DisplayIdentifier(stackID)), stateStr))
+	}
+
+	for _, stackID := range m.batchOrder {
+		buffer := m.batchOutput(stackID)
+		output := buffer.String()
+		if output == "" {
+			continue
+		}
+		bodyContent.WriteString(stepStyle.Render(fmt.Sprintf("\n--- Output for %s ---", discovery.DisplayIdentifier(stackID))) + "\n")
+		bodyContent.WriteString(output)
+		if !strings.HasSuffix(output, "\n") {
+			bodyContent.WriteString("\n")
+		}
+	}
+	return bodyContent.String()
+}
+
+// renderRunningBatchView generates the view for a queued "refresh all" batch.
+// It shows overall queue progress, the state of each stack in the batch and the
+// output collected so far, grouped per stack, as last rendered by
+// renderBatchOutput.
+//
+// Returns:
+//   - string: The body content showing queue progress and per-stack output
+//   - string: The footer content with progress summary and navigation options
+func (m *model) renderRunningBatchView() (string, string) {
+	failed := 0
+	for _, err := range m.batchResults {
+		if err != nil {
+			failed++
+		}
+	}
+
+	footerContent := strings.Builder{}
+	progress := fmt.Sprintf("Refreshed %d/%d stacks (%d running, %d queued, %d failed)",
+		len(m.batchResults), len(m.batchOrder), len(m.batchRunning), len(m.batchQueue), failed)
+	switch {
+	case !m.batchFinished():
+		footerContent.WriteString(statusStyle.Render(progress + "..."))
+	case failed > 0:
+		footerContent.WriteString(errorStyle.Render(progress + "."))
+	default:
+		footerContent.WriteString(successStyle.Render(progress + "."))
+	}
+
+	help := strings.Builder{}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.PgUp.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.PgDown.Help().Key) + footerDescStyle.Render(": scroll") + footerSeparatorStyle.Render(" | "))
+	if m.batchFinished() {
+		help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": back to list") + footerSeparatorStyle.Render(" | "))
+	}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
+	footerContent.WriteString("\n" + lipgloss.NewStyle().Width(m.width).Render(help.String())) // Keep lipgloss width rendering
+
+	return m.renderedOutput, footerContent.String()
+}
+
 // renderSequenceErrorView generates the view shown when a command sequence
 // encounters an error. It displays the error message and output leading up to the failure.
 //
@@ -242,13 +756,13 @@ func (m *model) renderRunningSequenceView() (string, string) {
 //   - string: The body content showing command output up to the error
 //   - string: The footer content with error details and navigation options
 func (m *model) renderSequenceErrorView() (string, string) {
-	bodyStr := m.outputContent // Use the raw content
+	bodyStr := m.renderedOutput
 
 	footerContent := strings.Builder{}
 
 	stackIdentifier := ""
 	if m.sequenceStack != nil {
-		stackIdentifier = fmt.Sprintf(" for %s", m.sequenceStack.Identifier())
+		stackIdentifier = fmt.Sprintf(" for %s", m.sequenceStack.DisplayName())
 	}
 	if m.lastError != nil {
 		footerContent.WriteString(errorStyle.Render(fmt.Sprintf("Error%s: %v", stackIdentifier, m.lastError)))
@@ -258,13 +772,54 @@ func (m *model) renderSequenceErrorView() (string, string) {
 
 	help := strings.Builder{}
 	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.PgUp.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.PgDown.Help().Key) + footerDescStyle.Render(": scroll") + footerSeparatorStyle.Render(" | "))
-	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": back to list") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(m.sequenceBackDesc()) + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.ToggleStepOutput.Help().Key) + footerDescStyle.Render(": "+m.keymap.ToggleStepOutput.Help().Desc) + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
 	footerContent.WriteString("\n" + lipgloss.NewStyle().Width(m.width).Render(help.String())) // Keep lipgloss width rendering
 
 	return bodyStr, footerContent.String()
 }
 
+// renderStackServices writes the services defined in the detailed stack's compose
+// file, including ones without containers, with the selected service highlighted.
+func (m *model) renderStackServices(b *strings.Builder, stackID string) {
+	b.WriteString("\nServices:\n")
+	if m.loadingServices {
+		b.WriteString(statusLoadingStyle.Render("  [loading...]") + "\n")
+		return
+	}
+	if m.servicesError != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("  Error: %v", m.servicesError)) + "\n")
+	}
+	if len(m.detailServices) == 0 {
+		if m.servicesError == nil {
+			b.WriteString("  (No services defined)\n")
+		}
+		return
+	}
+
+	statusInfo := m.stackStatuses[stackID]
+	for i, service := range m.detailServices {
+		cursor := "  "
+		if i == m.serviceCursor {
+			cursor = cursorStyle.Render("> ")
+		}
+		state := statusDownStyle.Render("not running")
+		for _, c := range statusInfo.Containers {
+			if c.Service != service {
+				continue
+			}
+			statusLower := strings.ToLower(c.Status)
+			if strings.Contains(statusLower, "running") || strings.Contains(statusLower, "healthy") || strings.HasPrefix(statusLower, "up") {
+				state = statusUpStyle.Render(c.Status)
+				break
+			}
+			state = statusDownStyle.Render(c.Status)
+		}
+		fmt.Fprintf(b, "%s%-20s %s\n", cursor, service, state)
+	}
+}
+
 // renderStackDetailsView generates a detailed view for either a single stack or
 // multiple selected stacks. For a single stack, it shows comprehensive information
 // including status and available actions. For multiple stacks, it provides batch
@@ -284,8 +839,14 @@ func (m *model) renderStackDetailsView() (string, string) {
 	if m.detailedStack != nil {
 		stack := m.detailedStack
 		stackID := stack.Identifier()
-		bodyContent.WriteString(titleStyle.Render(fmt.Sprintf("Details for: %s (%s)", stack.Name, serverNameStyle.Render(stack.ServerName))) + "\n\n")
+		bodyContent.WriteString(titleStyle.Render(fmt.Sprintf("Details for: %s", stackLabel(*stack))) + "\n\n")
+		if stack.IsRemote {
+			fmt.Fprintf(&bodyContent, "Remote path: %s\n", stack.FullPath())
+		} else {
+			fmt.Fprintf(&bodyContent, "Path: %s\n", stack.FullPath())
+		}
 		m.renderStackStatus(&bodyContent, stackID) // Use the existing helper
+		m.renderStackServices(&bodyContent, stackID)
 	} else if len(m.stacksInSequence) > 0 {
 		bodyContent.WriteString(titleStyle.Render(fmt.Sprintf("Details for %d Selected Stacks:", len(m.stacksInSequence))) + "\n")
 		for i, stack := range m.stacksInSequence {
@@ -293,7 +854,7 @@ func (m *model) renderStackDetailsView() (string, string) {
 				continue
 			}
 			stackID := stack.Identifier()
-			bodyContent.WriteString(fmt.Sprintf("\n--- %s (%s) ---", stack.Name, serverNameStyle.Render(stack.ServerName)))
+			bodyContent.WriteString(fmt.Sprintf("\n--- %s ---", stackLabel(*stack)))
 			m.renderStackStatus(&bodyContent, stackID) // Use the existing helper
 			if i < len(m.stacksInSequence)-1 {
 				bodyContent.WriteString("\n")
@@ -305,6 +866,29 @@ func (m *model) renderStackDetailsView() (string, string) {
 
 	footerContent := strings.Builder{}
 	help := strings.Builder{}
+	if m.detailedStack != nil && len(m.detailServices) > 0 {
+		help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerDescStyle.Render(": navigate") + footerSeparatorStyle.Render(" | "))
+		help.WriteString(footerKeyStyle.Render(m.keymap.ServiceLogsAction.Help().Key) + footerDescStyle.Render(": logs") + footerSeparatorStyle.Render(" | "))
+		help.WriteString(footerKeyStyle.Render(m.keymap.ServiceRestartAction.Help().Key) + footerDescStyle.Render(": restart") + footerSeparatorStyle.Render(" | "))
+		if !m.readOnly { // Shells are refused in read-only mode
+			help.WriteString(footerKeyStyle.Render(m.keymap.ServiceExecAction.Help().Key) + footerDescStyle.Render(": exec") + footerSeparatorStyle.Render(" | "))
+		}
+	}
+	if m.detailedStack != nil {
+		if !m.readOnly {
+			help.WriteString(footerKeyStyle.Render(m.keymap.StackShell.Help().Key) + footerDescStyle.Render(": shell") + footerSeparatorStyle.Render(" | "))
+		}
+		help.WriteString(footerKeyStyle.Render(m.keymap.ComposeCommand.Help().Key) + footerDescStyle.Render(": compose cmd") + footerSeparatorStyle.Render(" | "))
+		if url := m.detailsURL(); url != "" {
+			help.WriteString(footerKeyStyle.Render(m.keymap.OpenURLAction.Help().Key) + footerDescStyle.Render(": open "+url) + footerSeparatorStyle.Render(" | "))
+		}
+		if len(m.detailedStack.Actions) > 0 {
+			help.WriteString(footerKeyStyle.Render(m.keymap.StackActions.Help().Key) + footerDescStyle.Render(": actions") + footerSeparatorStyle.Render(" | "))
+		}
+		if _, ok := m.lastOutputs[m.detailedStack.Identifier()]; ok {
+			help.WriteString(footerKeyStyle.Render(m.keymap.LastOutput.Help().Key) + footerDescStyle.Render(": "+m.keymap.LastOutput.Help().Desc) + footerSeparatorStyle.Render(" | "))
+		}
+	}
 	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": back to list") + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
 	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String())) // Keep lipgloss width rendering
@@ -334,7 +918,7 @@ func (m *model) renderSshConfigListView() (string, string) {
 	if m.configCursor == 0 {
 		localCursor = cursorStyle.Render("> ")
 	}
-	bodyContent.WriteString(fmt.Sprintf("%s%s (%s)\n", localCursor, "local", serverNameStyle.Render("Local")))
+	bodyContent.WriteString(fmt.Sprintf("%s%s (%s)%s%s%s\n", localCursor, "local", serverNameStyle.Render("Local"), m.renderReachability("local"), m.renderDiskUsage("local"), m.renderComposeVersion("local")))
 
 	if len(m.configuredHosts) == 0 {
 		bodyContent.WriteString("\n  (No remote SSH hosts configured yet)")
@@ -345,10 +929,7 @@ func (m *model) renderSshConfigListView() (string, string) {
 			if m.configCursor == i+1 {
 				cursor = cursorStyle.Render("> ")
 			}
-			details := fmt.Sprintf("%s@%s", host.User, host.Hostname)
-			if host.Port != 0 && host.Port != 22 {
-				details += fmt.Sprintf(":%d", host.Port)
-			}
+			details := host.DisplayAddress()
 			status := ""
 			if host.Disabled {
 				status = errorStyle.Render(" [Disabled]")
@@ -359,7 +940,12 @@ func (m *model) renderSshConfigListView() (string, string) {
 			} else {
 				remoteRootStr = fmt.Sprintf(" (Root: %s)", lipgloss.NewStyle().Faint(true).Render("[Default]"))
 			}
-			bodyContent.WriteString(fmt.Sprintf("%s%s (%s)%s%s\n", cursor, host.Name, serverNameStyle.Render(details), remoteRootStr, status))
+			diskStr := ""
+			if !host.Disabled {
+				status = m.renderReachability(host.Name)
+				diskStr = m.renderDiskUsage(host.Name) + m.renderComposeVersion(host.Name)
+			}
+			bodyContent.WriteString(fmt.Sprintf("%s%s (%s)%s%s%s\n", cursor, host.Name, serverNameStyle.Render(details), remoteRootStr, status, diskStr))
 		}
 	}
 
@@ -370,20 +956,23 @@ func (m *model) renderSshConfigListView() (string, string) {
 	// Show actions based on selection
 	if m.configCursor == 0 { // "local" selected
 		help.WriteString(footerKeyStyle.Render(m.keymap.PruneAction.Help().Key) + footerDescStyle.Render(": prune") + footerSeparatorStyle.Render(" | "))
+		help.WriteString(footerKeyStyle.Render(m.keymap.RestartRuntimeAction.Help().Key) + footerDescStyle.Render(": restart runtime") + footerSeparatorStyle.Render(" | "))
 	} else { // Remote host selected
 		help.WriteString(footerKeyStyle.Render(m.keymap.Edit.Help().Key) + footerDescStyle.Render(": edit") + footerSeparatorStyle.Render(" | "))
 		help.WriteString(footerKeyStyle.Render(m.keymap.Remove.Help().Key) + footerDescStyle.Render(": remove") + footerSeparatorStyle.Render(" | "))
 		help.WriteString(footerKeyStyle.Render(m.keymap.PruneAction.Help().Key) + footerDescStyle.Render(": prune") + footerSeparatorStyle.Render(" | "))
+		help.WriteString(footerKeyStyle.Render(m.keymap.RestartRuntimeAction.Help().Key) + footerDescStyle.Render(": restart runtime") + footerSeparatorStyle.Render(" | "))
 	}
 	// Add and Import are always available
 	help.WriteString(footerKeyStyle.Render(m.keymap.Add.Help().Key) + footerDescStyle.Render(": add") + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Import.Help().Key) + footerDescStyle.Render(": import") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.GlobalSettings.Help().Key) + footerDescStyle.Render(": settings") + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": back") + footerSeparatorStyle.Render(" | "))
 	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
 
 	errorOrInfo := ""
 	if m.hostActionError != nil { // Display host action error first
-		errorOrInfo = "\n" + errorStyle.Render(fmt.Sprintf("Prune Error: %v", m.hostActionError))
+		errorOrInfo = "\n" + errorStyle.Render(fmt.Sprintf("Host Action Error: %v", m.hostActionError))
 	} else if m.importInfoMsg != "" { // Then import info
 		errorOrInfo = "\n" + successStyle.Render(m.importInfoMsg)
 	} else if m.importError != nil { // Then import error
@@ -451,10 +1040,11 @@ func (m *model) renderSshConfigRemoveConfirmView() (string, string) {
 //   - string: The footer content with confirm/cancel options
 func (m *model) renderPruneConfirmView() (string, string) {
 	bodyContent := strings.Builder{}
-	if len(m.hostsToPrune) > 0 {
-		targetName := m.hostsToPrune[0].ServerName // TUI currently only prunes one host
+	if len(m.hostActionTargets) > 0 {
+		targetName := m.hostActionTargets[0].ServerName // TUI currently only prunes one host
 		bodyContent.WriteString(fmt.Sprintf("Are you sure you want to prune host '%s'?\n\n", identifierColor.Render(targetName)))
-		bodyContent.WriteString("This will remove all unused containers, networks, images, and build cache.\n\n")
+		bodyContent.WriteString("This will remove all unused containers, networks, images, and build cache.\n")
+		bodyContent.WriteString("To prune only some of them, or only old ones, use 'bm prune' instead.\n\n")
 		bodyContent.WriteString("[y] Yes, prune | [n/Esc/b] No, cancel")
 	} else {
 		bodyContent.WriteString(errorStyle.Render("Error: No host selected for prune. Press Esc/b to go back."))
@@ -462,8 +1052,8 @@ func (m *model) renderPruneConfirmView() (string, string) {
 
 	footerContent := strings.Builder{}
 	help := strings.Builder{}
-	if len(m.hostsToPrune) > 0 {
-		targetName := m.hostsToPrune[0].ServerName
+	if len(m.hostActionTargets) > 0 {
+		targetName := m.hostActionTargets[0].ServerName
 		help.WriteString(footerDescStyle.Render(fmt.Sprintf("Confirm prune action for host '%s'? ", identifierColor.Render(targetName))))
 		help.WriteString(footerKeyStyle.Render(m.keymap.Yes.Help().Key) + footerDescStyle.Render(": "+m.keymap.Yes.Help().Desc) + footerSeparatorStyle.Render(" | "))
 		help.WriteString(footerKeyStyle.Render(m.keymap.No.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": "+m.keymap.No.Help().Desc+"/cancel"))
@@ -476,6 +1066,46 @@ func (m *model) renderPruneConfirmView() (string, string) {
 	return bodyContent.String(), footerContent.String()
 }
 
+// renderRestartRuntimeConfirmView generates a confirmation dialog for
+// restarting the container runtime of a host, showing the command that will
+// be run there (its runtime_restart_command), taken from the step built when
+// the action was chosen.
+//
+// Returns:
+//   - string: The body content showing the command and confirmation request
+//   - string: The footer content with confirm/cancel options
+func (m *model) renderRestartRuntimeConfirmView() (string, string) {
+	bodyContent := strings.Builder{}
+	step := m.currentHostActionStep
+	if len(m.hostActionTargets) > 0 {
+		bodyContent.WriteString(fmt.Sprintf("Are you sure you want to restart the container runtime on host '%s'?\n\n", identifierColor.Render(m.hostActionTargets[0].ServerName)))
+		if len(step.Args) > 0 {
+			bodyContent.WriteString(fmt.Sprintf("This will run: %s\n", step.Args[len(step.Args)-1]))
+		} else {
+			// The default command is picked once the host's runtime is detected
+			bodyContent.WriteString("This will run the default restart command of the host's container runtime.\n")
+		}
+		bodyContent.WriteString("Containers that aren't set to restart automatically may stay stopped.\n\n")
+		bodyContent.WriteString("[y] Yes, restart | [n/Esc/b] No, cancel")
+	} else {
+		bodyContent.WriteString(errorStyle.Render("Error: No host selected for runtime restart. Press Esc/b to go back."))
+	}
+
+	footerContent := strings.Builder{}
+	help := strings.Builder{}
+	if len(m.hostActionTargets) > 0 {
+		help.WriteString(footerDescStyle.Render(fmt.Sprintf("Confirm runtime restart for host '%s'? ", identifierColor.Render(m.hostActionTargets[0].ServerName))))
+		help.WriteString(footerKeyStyle.Render(m.keymap.Yes.Help().Key) + footerDescStyle.Render(": "+m.keymap.Yes.Help().Desc) + footerSeparatorStyle.Render(" | "))
+		help.WriteString(footerKeyStyle.Render(m.keymap.No.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": "+m.keymap.No.Help().Desc+"/cancel"))
+	} else {
+		help.WriteString(errorStyle.Render("Error - no host selected. "))
+		help.WriteString(footerKeyStyle.Render(m.keymap.Back.Help().Key) + footerDescStyle.Render(": back"))
+	}
+	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String()))
+
+	return bodyContent.String(), footerContent.String()
+}
+
 // renderRunningHostActionView generates a view for displaying the output of
 // an SSH host action, such as testing a connection or validating configuration.
 // It shows the command output in real-time as it's executed.
@@ -489,7 +1119,7 @@ func (m *model) renderPruneConfirmView() (string, string) {
 //   - string: The body content showing raw command output
 //   - string: The footer content with action status and navigation options
 func (m *model) renderRunningHostActionView() (string, string) {
-	bodyStr := m.outputContent
+	bodyStr := m.renderedOutput
 
 	footerContent := strings.Builder{}
 
@@ -498,8 +1128,8 @@ func (m *model) renderRunningHostActionView() (string, string) {
 	if m.currentHostActionStep.Name != "" {
 		actionName = m.currentHostActionStep.Name
 	}
-	if len(m.hostsToPrune) > 0 {
-		targetName = m.hostsToPrune[0].ServerName
+	if len(m.hostActionTargets) > 0 {
+		targetName = m.hostActionTargets[0].ServerName
 	}
 	footerContent.WriteString(statusStyle.Render(fmt.Sprintf("Running %s on '%s'...", actionName, identifierColor.Render(targetName))))
 
@@ -654,6 +1284,82 @@ func (m *model) renderSshConfigEditFormView() (string, string) {
 	return bodyContent.String(), footerContent.String()
 }
 
+// renderGlobalConfigView generates the view for the global settings form, which
+// edits config.yaml settings that don't belong to a host.
+//
+// Returns:
+//   - string: The body content with one labelled line per setting
+//   - string: The footer content with form navigation and save options
+func (m *model) renderGlobalConfigView() (string, string) {
+	bodyContent := strings.Builder{}
+	bodyContent.WriteString(titleStyle.Render("Global Settings") + "\n\n")
+
+	const labelWidth = 28
+	label := func(text string) string {
+		return fmt.Sprintf("%-*s", labelWidth, text)
+	}
+	selector := func(field int, text string) string {
+		if m.formFocusIndex == field {
+			return cursorStyle.Render("> ") + cursorStyle.Render(text+" [←/→ to change]")
+		}
+		return "  " + text
+	}
+	collapse := "off"
+	if m.formSettings.CollapseStepOutput {
+		collapse = "on"
+	}
+
+	lines := []struct {
+		field int
+		label string
+	}{
+		{settingsLocalRootField, "Local root"},
+		{settingsRuntimeField, "Container runtime"},
+		{settingsMaxConcurrentField, "Refresh all: max concurrent"},
+		{settingsStaggerField, "Refresh all: stagger"},
+		{settingsPullParallelField, "Parallel image pulls"},
+		{settingsDiskWarnField, "Disk warning (% free)"},
+		{settingsDefaultActionField, "Enter on a stack"},
+		{settingsCollapseField, "Collapse step output"},
+		{settingsCompletionNotifyField, "Alert when an action ends"},
+	}
+	for _, line := range lines {
+		value := ""
+		switch line.field {
+		case settingsRuntimeField:
+			value = selector(line.field, m.formSettings.ContainerRuntime)
+		case settingsDefaultActionField:
+			value = selector(line.field, m.formSettings.DefaultAction)
+		case settingsCollapseField:
+			value = selector(line.field, collapse)
+		case settingsCompletionNotifyField:
+			value = selector(line.field, m.formSettings.CompletionNotify)
+		default:
+			if i := settingsInputIndex(line.field); i >= 0 && i < len(m.formInputs) {
+				value = m.formInputs[i].View()
+			}
+		}
+		bodyContent.WriteString(label(line.label) + value + "\n")
+	}
+	bodyContent.WriteString("\n" + footerDescStyle.Render("Empty fields use the defaults. Stacks are reloaded when leaving the host list."))
+
+	// Footer generation
+	footerContent := strings.Builder{}
+
+	if m.formError != nil {
+		footerContent.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.formError)) + "\n")
+	}
+	help := strings.Builder{}
+	help.WriteString(footerKeyStyle.Render(m.keymap.Up.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Down.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Tab.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.ShiftTab.Help().Key) + footerDescStyle.Render(": navigate") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Left.Help().Key) + footerSeparatorStyle.Render("/") + footerKeyStyle.Render(m.keymap.Right.Help().Key) + footerDescStyle.Render(": change option") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Enter.Help().Key) + footerDescStyle.Render(": save") + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Esc.Help().Key) + footerDescStyle.Render(": "+m.keymap.Esc.Help().Desc) + footerSeparatorStyle.Render(" | "))
+	help.WriteString(footerKeyStyle.Render(m.keymap.Quit.Help().Key) + footerDescStyle.Render(": "+m.keymap.Quit.Help().Desc))
+	footerContent.WriteString(lipgloss.NewStyle().Width(m.width).Render(help.String()))
+
+	return bodyContent.String(), footerContent.String()
+}
+
 // renderSshConfigImportSelectView generates the view for selecting hosts to import
 // from the user's SSH config file (~/.ssh/config). It allows users to select
 // multiple hosts for batch import.
@@ -682,10 +1388,7 @@ func (m *model) renderSshConfigImportSelectView() (string, string) {
 			if _, selected := m.selectedImportIdxs[i]; selected {
 				checkbox = successStyle.Render("[x]")
 			}
-			details := fmt.Sprintf("%s@%s", pHost.User, pHost.Hostname)
-			if pHost.Port != 0 && pHost.Port != 22 {
-				details += fmt.Sprintf(":%d", pHost.Port)
-			}
+			details := pHost.DisplayAddress()
 			keyInfo := ""
 			if pHost.KeyPath != "" {
 				keyInfo = fmt.Sprintf(" (Key: %s)", lipgloss.NewStyle().Faint(true).Render(filepath.Base(pHost.KeyPath)))
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package util's diff.go file compares two texts line by line, to show what
+// changed between two versions of a file.
+
+package util
+
+import "strings"
+
+// DiffOp tells whether a DiffLine is in both texts or only in one of them.
+type DiffOp int
+
+const (
+	DiffEqual  DiffOp = iota // Line of both texts
+	DiffDelete               // Line of the old text only
+	DiffInsert               // Line of the new text only
+)
+
+// DiffLine is a line of the result of LineDiff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// LineDiff returns the lines of oldText and newText in order, each marked as
+// in both, deleted or inserted, keeping as many lines unchanged as possible.
+// It takes time and memory proportional to the product of the line counts,
+// which is fine for config files but not for large outputs.
+func LineDiff(oldText, newText string) []DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	diff := make([]DiffLine, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{DiffEqual, a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			diff = append(diff, DiffLine{DiffDelete, a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{DiffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{DiffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{DiffInsert, b[j]})
+	}
+	return diff
+}
+
+// splitLines splits text into lines, without a trailing empty line for a
+// final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package util's format.go file contains helpers for presenting values
+// such as byte sizes and ages in a human-readable form and fitting text into columns.
+
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+// FormatKiB renders a size given in kibibytes using the largest fitting
+// binary unit, similar to the output of `df -h`.
+func FormatKiB(kib uint64) string {
+	units := []string{"K", "M", "G", "T", "P"}
+	size := float64(kib)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if size < 10 && unit > 0 {
+		return fmt.Sprintf("%.1f%s", size, units[unit])
+	}
+	return fmt.Sprintf("%.0f%s", size, units[unit])
+}
+
+// Truncate shortens s to at most limit runes, replacing the last rune with an
+// ellipsis if anything was cut. A limit of 0 or less leaves s unchanged.
+func Truncate(s string, limit int) string {
+	if limit <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// FormatAge renders a duration as a short, coarse age such as "45s", "3h" or
+// "12d", for display in tables.
+func FormatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+//go:build !unix
+
+// Package util's lock_other.go file provides a no-op file lock on platforms
+// without flock(2).
+
+package util
+
+// LockFile is a no-op on this platform. The returned function does nothing.
+func LockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+// Package util's notify.go file sends desktop notifications through
+// notify-send or terminal-notifier, whichever is installed.
+
+package util
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// ErrNoDesktopNotifier is returned when neither notify-send nor
+// terminal-notifier is installed.
+var ErrNoDesktopNotifier = errors.New("desktop notifications need notify-send or terminal-notifier to be installed")
+
+// CheckDesktopNotifications returns ErrNoDesktopNotifier if desktop
+// notifications can't be sent.
+func CheckDesktopNotifications() error {
+	for _, name := range []string{"notify-send", "terminal-notifier"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return nil
+		}
+	}
+	return ErrNoDesktopNotifier
+}
+
+// SendDesktopNotification shows a desktop notification. Urgent ones stay on
+// screen until dismissed where notify-send supports it.
+func SendDesktopNotification(title, message string, urgent bool) error {
+	if path, err := exec.LookPath("notify-send"); err == nil {
+		args := []string{"--app-name=bm", title, message}
+		if urgent {
+			args = append([]string{"--urgency=critical"}, args...)
+		}
+		return exec.Command(path, args...).Run()
+	}
+	if path, err := exec.LookPath("terminal-notifier"); err == nil {
+		return exec.Command(path, "-title", title, "-message", message).Run()
+	}
+	return ErrNoDesktopNotifier
+}
@@ -5,7 +5,11 @@
 // It includes helper functions for string manipulation, shell commands, and other common tasks.
 package util
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // QuoteArgForShell quotes an argument for safe use in a POSIX shell command.
 // It uses single quotes and escapes any internal single quotes following the POSIX shell escaping rules.
@@ -26,3 +30,63 @@ func QuoteArgForShell(arg string) string {
 	quotedArg := strings.ReplaceAll(arg, "'", `'\''`)
 	return `'` + quotedArg + `'`
 }
+
+// SplitShellWords splits a command line into words like a POSIX shell, without
+// expanding anything: words are separated by unquoted whitespace, single quotes
+// keep their content as is, double quotes allow \" \\ \$ and \` escapes, and a
+// backslash outside quotes escapes the next character. Outside single quotes,
+// a backslash-newline continues the line and is dropped. An unterminated quote
+// or trailing backslash is an error.
+func SplitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune // The open quote, or 0 outside quotes
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+			if r == '\n' {
+				continue // Line continuation
+			}
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				word.WriteRune('\\') // Kept inside double quotes unless it escapes a special character
+			}
+			word.WriteRune(r)
+			inWord = true
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2025 Mufeed Ali
+
+package util
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "plain words", input: "  sh -c\tls\n", want: []string{"sh", "-c", "ls"}},
+		{name: "empty", input: "", want: nil},
+		{name: "single quotes", input: `echo 'a "b" \c'`, want: []string{"echo", `a "b" \c`}},
+		{name: "double quotes", input: `echo "a 'b' c"`, want: []string{"echo", "a 'b' c"}},
+		{name: "quotes within a word", input: `a'b c'"d"e`, want: []string{"ab cde"}},
+		{name: "empty quoted words", input: `a '' "" b`, want: []string{"a", "", "", "b"}},
+		{name: "escapes outside quotes", input: `a\ b \'c\" \\`, want: []string{"a b", `'c"`, `\`}},
+		{name: "escapes inside double quotes", input: `"\" \\ \$ \` + "`" + ` \n"`, want: []string{`" \ $ ` + "`" + ` \n`}},
+		{name: "line continuation", input: "ls \\\n-la", want: []string{"ls", "-la"}},
+		{name: "line continuation within a word", input: "l\\\ns", want: []string{"ls"}},
+		{name: "line continuation inside double quotes", input: "\"a\\\nb\"", want: []string{"ab"}},
+		{name: "newline escaped inside single quotes", input: "'a\\\nb'", want: []string{"a\\\nb"}},
+		{name: "unterminated single quote", input: "echo 'a", wantErr: true},
+		{name: "unterminated double quote", input: `echo "a`, wantErr: true},
+		{name: "trailing backslash", input: `echo a\`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitShellWords(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitShellWords(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SplitShellWords(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
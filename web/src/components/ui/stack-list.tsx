@@ -35,6 +35,25 @@ interface StackWithStatus {
   status: string;
 }
 
+interface HostStackStatus {
+  name: string;
+  status: string;
+  stale: boolean;
+  statusError?: string;
+}
+
+type StackAction = 'up' | 'down' | 'pull' | 'refresh';
+
+// The running operation is remembered for the browser tab, so reloading the
+// page can resume showing its output from the server.
+const OPERATION_STORAGE_KEY = 'bucket-manager-operation';
+
+interface SavedOperation {
+  id: string;
+  stack: StackWithStatus;
+  action: StackAction;
+}
+
 function StackList() {
   const [stacks, setStacks] = useState<StackWithStatus[]>([]);
   const [loading, setLoading] = useState<boolean>(true);
@@ -46,106 +65,66 @@ function StackList() {
   const [currentStack, setCurrentStack] = useState<StackWithStatus | null>(null);
   const [isAlertDialogOpen, setIsAlertDialogOpen] = useState<boolean>(false);
   const [pendingStack, setPendingStack] = useState<StackWithStatus | null>(null);
-  const [pendingAction, setPendingAction] = useState<'up' | 'down' | 'pull' | 'refresh' | null>(null);
+  const [pendingAction, setPendingAction] = useState<StackAction | null>(null);
+  const [refreshingHost, setRefreshingHost] = useState<string | null>(null);
   const eventSourceRef = useRef<EventSource | null>(null);
 
-  const fetchLocalStacks = async () => {
-    try {
-      const response = await fetch('/api/stacks/local');
-      if (!response.ok) {
-        throw new Error(`HTTP error fetching local stacks! status: ${response.status}`);
-      }
-      const localStacks: StackWithStatus[] = await response.json();
-      setStacks(currentStacks => {
-        // Keep any existing remote stacks, replace local ones
-        const remoteStacks = currentStacks.filter(s => s.IsRemote);
-        return [...localStacks, ...remoteStacks];
-      });
-    } catch (err) {
-      console.error('Failed to fetch local stacks:', err);
-      setError(err instanceof Error ? err.message : 'Failed to fetch local stacks');
-      return false;
-    }
-    return true;
-  };
-
-  const fetchRemoteStacks = async () => {
-    try {
-      setRemoteLoading(true);
-      const sshHostsResponse = await fetch('/api/ssh/hosts');
-      if (!sshHostsResponse.ok) {
-        throw new Error(`HTTP error fetching SSH hosts! status: ${sshHostsResponse.status}`);
-      }
-
-      const sshHosts: { Name: string }[] = await sshHostsResponse.json();
-
-      // Start all fetches in parallel
-      const remotePromises = sshHosts.map(host => {
-        return fetch(`/api/ssh/hosts/${host.Name}/stacks`)
-          .then(async response => {
-            if (!response.ok) {
-              throw new Error(`HTTP error: ${response.status}`);
-            }
-            const remoteStacks: StackWithStatus[] = await response.json();
-            // Update stacks immediately for this host
-            setStacks(currentStacks => {
-              // Remove any existing stacks for this host
-              const otherStacks = currentStacks.filter(s => s.ServerName !== host.Name);
-              return [...otherStacks, ...remoteStacks];
-            });
-          })
-          .catch(hostErr => {
-            console.error(`Failed to fetch stacks for host ${host.Name}:`, hostErr);
-            setStacks(currentStacks => {
-              // Remove any existing stacks for this host
-              const otherStacks = currentStacks.filter(s => s.ServerName !== host.Name);
-              return [...otherStacks, {
-                Name: `Error: ${host.Name} stacks unavailable`,
-                Path: '',
-                ServerName: host.Name,
-                IsRemote: true,
-                status: 'Load Error',
-              }];
-            });
-          });
-      });
+  const stacksStreamRef = useRef<EventSource | null>(null);
 
-      // Wait for all remote fetches to complete
-      await Promise.allSettled(remotePromises);
-    } catch (err) {
-      console.error('Failed to fetch remote hosts:', err);
-      setError(prev => {
-        const errMsg = err instanceof Error ? err.message : 'Failed to fetch remote hosts';
-        return prev ? `${prev}. ${errMsg}` : errMsg;
-      });
-    } finally {
-      setRemoteLoading(false);
-    }
-  };
-
-  const fetchAllStacks = useCallback(async () => {
+  // Discovers stacks and receives their statuses as they resolve, plus periodic
+  // re-checks, from a single Server-Sent Events stream.
+  const fetchAllStacks = useCallback(() => {
+    stacksStreamRef.current?.close();
     setLoading(true);
+    setRemoteLoading(true);
     setError(null);
     setStacks([]);
 
-    try {
-      // First load local stacks
-      const localSuccess = await fetchLocalStacks();
-      // Then start loading remote stacks
-      if (localSuccess) {
-        fetchRemoteStacks();
-      }
-    } finally {
-      // We can show the table once local stacks are loaded
+    const isSameStack = (a: StackWithStatus, b: StackWithStatus) =>
+      a.Name === b.Name && a.ServerName === b.ServerName;
+
+    const stream = new EventSource('/api/stacks/stream');
+    stacksStreamRef.current = stream;
+
+    stream.addEventListener('stack', (event: MessageEvent) => {
+      const stack: StackWithStatus = JSON.parse(event.data);
+      // The stream restarts discovery when it reconnects, so skip stacks already listed
+      setStacks(currentStacks => currentStacks.some(s => isSameStack(s, stack))
+        ? currentStacks
+        : [...currentStacks, stack]);
       setLoading(false);
-    }
+    });
+
+    stream.addEventListener('status', (event: MessageEvent) => {
+      const updated: StackWithStatus = JSON.parse(event.data);
+      setStacks(currentStacks => currentStacks.map(s =>
+        isSameStack(s, updated) ? { ...s, status: updated.status } : s
+      ));
+    });
+
+    stream.addEventListener('discovery-error', (event: MessageEvent) => {
+      const errMsg: string = JSON.parse(event.data);
+      setError(prev => prev ? `${prev}. ${errMsg}` : errMsg);
+    });
+
+    stream.addEventListener('discovered', () => {
+      setLoading(false);
+      setRemoteLoading(false);
+    });
+
+    stream.onerror = () => {
+      // EventSource reconnects on its own; just stop showing the initial spinner
+      setLoading(false);
+    };
   }, []);
 
   const updateStackStatus = async (stack: StackWithStatus) => {
+    if (stack.ServerName !== 'local') {
+      await updateHostStatuses(stack.ServerName);
+      return;
+    }
     try {
-      const response = await fetch(stack.ServerName === 'local'
-        ? `/api/stacks/local/${stack.Name}/status`
-        : `/api/ssh/hosts/${stack.ServerName}/stacks/${stack.Name}/status`);
+      const response = await fetch(`/api/stacks/local/${stack.Name}/status`);
       if (response.ok) {
         const updatedStatus = await response.json();
         setStacks(prevStacks => prevStacks.map(s =>
@@ -159,7 +138,27 @@ function StackList() {
     }
   };
 
-  const executeStackAction = (stack: StackWithStatus, action: 'up' | 'down' | 'pull' | 'refresh') => {
+  // Updates the statuses of every stack on a remote host with a single request,
+  // which discovers the host's stacks once rather than once per stack.
+  const updateHostStatuses = async (serverName: string) => {
+    setRefreshingHost(serverName);
+    try {
+      const response = await fetch(`/api/ssh/hosts/${serverName}/status`);
+      if (response.ok) {
+        const statuses: HostStackStatus[] = await response.json();
+        setStacks(prevStacks => prevStacks.map(s => {
+          const updated = s.ServerName === serverName && statuses.find(status => status.name === s.Name);
+          return updated ? { ...s, status: updated.status } : s;
+        }));
+      }
+    } catch (err) {
+      console.error('Failed to update host statuses:', err);
+    } finally {
+      setRefreshingHost(null);
+    }
+  };
+
+  const executeStackAction = (stack: StackWithStatus, action: StackAction) => {
     setCurrentStack(stack);
     setIsDialogOpen(true);
     setStreamedOutput('');
@@ -186,11 +185,15 @@ function StackList() {
 
     // Use the appropriate streaming endpoint based on the action
     const streamUrl = `/api/run/stack/${action}/stream?name=${stack.Name}&serverName=${stack.ServerName}`;
+    followOperation(new EventSource(streamUrl), stack, action);
+  };
 
-    const eventSource = new EventSource(streamUrl);
+  // Shows the output of an operation stream in the dialog until it's done.
+  const followOperation = (eventSource: EventSource, stack: StackWithStatus, action: StackAction) => {
     eventSourceRef.current = eventSource;
 
     let lastLine = '';
+    let started = false; // Whether the server sent the operation ID
 
     const handleStreamOutput = (event: MessageEvent) => {
       // Split on newlines and filter out empty lines
@@ -207,6 +210,12 @@ function StackList() {
       });
     };
 
+    eventSource.addEventListener('operation', (event: MessageEvent) => {
+      started = true;
+      const saved: SavedOperation = { id: event.data, stack, action };
+      sessionStorage.setItem(OPERATION_STORAGE_KEY, JSON.stringify(saved));
+    });
+
     eventSource.addEventListener('stdout', handleStreamOutput);
     eventSource.addEventListener('stderr', handleStreamOutput);
 
@@ -220,6 +229,10 @@ function StackList() {
       eventSource.close();
       setRunningCommand(null);
       eventSourceRef.current = null;
+      if (!started) {
+        // The operation couldn't be started or is gone, e.g. after a server restart
+        sessionStorage.removeItem(OPERATION_STORAGE_KEY);
+      }
     });
 
     eventSource.addEventListener('done', async (event: MessageEvent) => {
@@ -227,13 +240,36 @@ function StackList() {
       eventSource.close();
       eventSourceRef.current = null;
       setRunningCommand(null);
+      sessionStorage.removeItem(OPERATION_STORAGE_KEY);
 
       // Update the stack's status after any action completes
       await updateStackStatus(stack);
     });
   };
 
-  const confirmStackAction = (stack: StackWithStatus, action: 'up' | 'down' | 'pull' | 'refresh') => {
+  // Reopens the output of an operation that was running when the page was
+  // reloaded, replaying what it printed so far and following the rest.
+  const resumeOperation = () => {
+    const stored = sessionStorage.getItem(OPERATION_STORAGE_KEY);
+    if (!stored) {
+      return;
+    }
+    let saved: SavedOperation;
+    try {
+      saved = JSON.parse(stored);
+    } catch {
+      sessionStorage.removeItem(OPERATION_STORAGE_KEY);
+      return;
+    }
+
+    setCurrentStack(saved.stack);
+    setIsDialogOpen(true);
+    setStreamedOutput('');
+    setRunningCommand(`${saved.stack.ServerName}:${saved.stack.Name}:${saved.action}`);
+    followOperation(new EventSource(`/api/run/result/${saved.id}/stream`), saved.stack, saved.action);
+  };
+
+  const confirmStackAction = (stack: StackWithStatus, action: StackAction) => {
     setPendingStack(stack);
     setPendingAction(action);
     setIsAlertDialogOpen(true);
@@ -250,6 +286,12 @@ function StackList() {
     fetchAllStacks();
   }, [fetchAllStacks]);
 
+  useEffect(() => {
+    resumeOperation();
+    // Only on page load
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
   // Clean up EventSource on unmount or when dialog closes
   useEffect(() => {
     return () => {
@@ -257,6 +299,8 @@ function StackList() {
         eventSourceRef.current.close();
         eventSourceRef.current = null;
       }
+      stacksStreamRef.current?.close();
+      stacksStreamRef.current = null;
     };
   }, []);
 
@@ -316,6 +360,18 @@ function StackList() {
           <div className="flex items-center gap-2 mb-3">
             <Server className="h-5 w-5 text-primary" />
             <h4 className="text-md font-medium">{serverName}</h4>
+            {serverName !== 'local' && (
+              <Button
+                variant="ghost"
+                size="icon"
+                className="h-6 w-6"
+                onClick={() => updateHostStatuses(serverName)}
+                disabled={refreshingHost !== null}
+                title="Refresh statuses"
+              >
+                <RefreshCw className={`h-3 w-3 ${refreshingHost === serverName ? 'animate-spin' : ''}`} />
+              </Button>
+            )}
           </div>
 
           <div className="grid grid-cols-1 md:grid-cols-3 lg:grid-cols-4 xl:grid-cols-5 gap-3">
@@ -411,6 +467,9 @@ function StackList() {
 
       <Dialog open={isDialogOpen} onOpenChange={(open) => {
         setIsDialogOpen(open);
+        if (!open) {
+          sessionStorage.removeItem(OPERATION_STORAGE_KEY);
+        }
         if (!open && eventSourceRef.current) {
           eventSourceRef.current.close();
           eventSourceRef.current = null;